@@ -1,6 +1,12 @@
 package botconfig_test
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/pem"
+
 	"github.com/golang/mock/gomock"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
@@ -8,6 +14,7 @@ import (
 	"github.com/palantir/go-githubapp/githubapp"
 	"github.com/rotisserie/eris"
 	"github.com/solo-io/go-utils/botutils/botconfig"
+	"github.com/solo-io/go-utils/githubutils"
 )
 
 var _ = Describe("BotconfigTest", func() {
@@ -141,6 +148,28 @@ var _ = Describe("BotconfigTest", func() {
 
 })
 
+var _ = Describe("Config", func() {
+	It("provides the Github App config", func() {
+		Expect(getValidConfig().GithubAppConfig()).To(Equal(githubutils.GithubAppConfig{
+			AppID:      12345,
+			PrivateKey: []byte("bar\nbaz\n"),
+			BaseURL:    "https://api.github.com/",
+		}))
+	})
+
+	It("builds a Github App that calls github.com", func() {
+		key, err := rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		config := getValidConfig()
+		config.Github.App.PrivateKey = string(pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)}))
+		app, err := githubutils.NewGithubApp(config.GithubAppConfig())
+		Expect(err).NotTo(HaveOccurred())
+		client := app.Client(context.Background(), "solo-io", "gloo")
+		Expect(client.BaseURL.String()).To(Equal("https://api.github.com/"))
+		Expect(client.UploadURL.String()).To(Equal("https://uploads.github.com/"))
+	})
+})
+
 func getValidConfig() *botconfig.Config {
 	c := getValidConfigTrimmed()
 	c.Github.App.IntegrationID = 12345
@@ -4,6 +4,7 @@ import (
 	"strconv"
 
 	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/githubutils"
 	"github.com/solo-io/go-utils/osutils"
 
 	"github.com/palantir/go-baseapp/baseapp"
@@ -47,6 +48,16 @@ type Config struct {
 	Github githubapp.Config   `yaml:"github"`
 }
 
+// The app ID, private key and API URL of the bot's Github App, to build clients authenticated as its installations
+// with githubutils.NewGithubApp
+func (c *Config) GithubAppConfig() githubutils.GithubAppConfig {
+	return githubutils.GithubAppConfig{
+		AppID:      c.Github.App.IntegrationID,
+		PrivateKey: []byte(c.Github.App.PrivateKey),
+		BaseURL:    c.Github.V3APIURL,
+	}
+}
+
 func ReadConfig() (*Config, error) {
 	configReader := &configReader{
 		os: osutils.NewOsClient(),
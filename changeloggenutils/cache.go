@@ -0,0 +1,421 @@
+package changelogdocutils
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/githubutils"
+	. "github.com/solo-io/go-utils/versionutils"
+	"github.com/spf13/afero"
+)
+
+const (
+	releaseCacheFile    = "releases.json"
+	dependencyCacheFile = "dependencies.json"
+)
+
+var (
+	CacheMissError = func(what string) error {
+		return eris.Errorf("%s is not in the changelog cache, and the cache is offline", what)
+	}
+	ReadCacheError = func(err error, path string) error {
+		return eris.Wrapf(err, "unable to read changelog cache file %s", path)
+	}
+	WriteCacheError = func(err error, path string) error {
+		return eris.Wrapf(err, "unable to write changelog cache file %s", path)
+	}
+	CachedDependencyNotFoundError = func(dependency, owner, repo, tag string) error {
+		return &DependencyNotFoundError{Err: eris.Errorf("%s/%s %s doesn't use %s", owner, repo, tag, dependency)}
+	}
+)
+
+type CacheOptions struct {
+	// Only read from the cache, never from Github. Releases and dependency versions that aren't cached are errors.
+	Offline bool
+	// By default, only the pages of releases up to the first one without new or edited releases are fetched,
+	// since Github lists the newest releases first. A full refresh fetches every page, so that edits to older
+	// releases are picked up and deleted releases are dropped from the cache.
+	FullRefresh bool
+}
+
+// ReleaseCache persists the releases and dependency versions fetched by the changelog generators in a directory,
+// so that regenerating a changelog only fetches what changed since the last time, or nothing at all when offline.
+//
+// Releases are cached by owner/repo and release ID. The releases API doesn't report when a release was edited,
+// so a digest of the release's content stands in for its update time. Dependency versions are cached by
+// owner/repo, dependency and tag; tags are expected not to move, so they are only refetched after being invalidated.
+type ReleaseCache struct {
+	fs   afero.Fs
+	dir  string
+	opts CacheOptions
+	mu   sync.Mutex
+}
+
+func NewReleaseCache(dir string, opts CacheOptions) *ReleaseCache {
+	return NewReleaseCacheForFs(afero.NewOsFs(), dir, opts)
+}
+
+func NewReleaseCacheForFs(fs afero.Fs, dir string, opts CacheOptions) *ReleaseCache {
+	return &ReleaseCache{
+		fs:   fs,
+		dir:  dir,
+		opts: opts,
+	}
+}
+
+type cachedRelease struct {
+	Digest  string
+	Release *github.RepositoryRelease
+}
+
+type cachedReleases struct {
+	FetchedAt time.Time
+	// True once every page of releases has been fetched
+	Complete bool
+	Releases map[int64]*cachedRelease
+}
+
+// Dependency versions by dependency (owner/repo) and tag
+type cachedDependencies map[string]map[string]string
+
+// Returns the releases of owner/repo, newest first like Github lists them, fetching only the pages that have new
+// or edited releases. At most maxReleases are returned.
+func (c *ReleaseCache) GetReleases(ctx context.Context, client *github.Client, owner, repo string, maxReleases int) ([]*github.RepositoryRelease, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	path := c.path(owner, repo, releaseCacheFile)
+	cached := &cachedReleases{Releases: map[int64]*cachedRelease{}}
+	exists, err := c.read(path, cached)
+	if err != nil {
+		return nil, err
+	}
+	if c.opts.Offline {
+		if !exists {
+			return nil, CacheMissError(fmt.Sprintf("the releases of %s/%s", owner, repo))
+		}
+		return sortCachedReleases(cached.Releases, maxReleases), nil
+	}
+
+	seen := map[int64]bool{}
+	lastPage := false
+	for page := githubutils.MIN_GITHUB_PAGE_NUM; len(seen) < maxReleases; page++ {
+		releases, _, err := client.Repositories.ListReleases(ctx, owner, repo, &github.ListOptions{
+			Page:    page,
+			PerPage: githubutils.MAX_GITHUB_RESULTS_PER_PAGE,
+		})
+		if err != nil {
+			return nil, err
+		}
+		changed := false
+		for _, release := range releases {
+			seen[release.GetID()] = true
+			digest := releaseDigest(release)
+			if existing, ok := cached.Releases[release.GetID()]; ok && existing.Digest == digest {
+				continue
+			}
+			cached.Releases[release.GetID()] = &cachedRelease{Digest: digest, Release: release}
+			changed = true
+		}
+		if len(releases) < githubutils.MAX_GITHUB_RESULTS_PER_PAGE {
+			lastPage = true
+			break
+		}
+		if !changed && cached.Complete && !c.opts.FullRefresh {
+			break
+		}
+	}
+	if lastPage {
+		if c.opts.FullRefresh || !cached.Complete {
+			for id := range cached.Releases {
+				if !seen[id] {
+					delete(cached.Releases, id)
+				}
+			}
+		}
+		cached.Complete = true
+	}
+	cached.FetchedAt = time.Now()
+	if err := c.write(path, cached); err != nil {
+		return nil, err
+	}
+	return sortCachedReleases(cached.Releases, maxReleases), nil
+}
+
+// Wraps a DependencyFn of owner/repo's releases on owner/dependentRepo so that it is only called for the tags
+// whose dependency version isn't cached. Errors aren't cached.
+func (c *ReleaseCache) DependencyFunc(owner, repo, dependentRepo string, fn DependencyFn) DependencyFn {
+	return c.dependencyFunc(owner, repo, owner+"/"+dependentRepo, fn)
+}
+
+func (c *ReleaseCache) dependencyFunc(owner, repo, dependency string, fn DependencyFn) DependencyFn {
+	return func(version *Version) (*Version, error) {
+		tag := version.String()
+		if dep, ok, err := c.getDependency(owner, repo, dependency, tag); err != nil {
+			return nil, err
+		} else if ok && dep == "" {
+			return nil, CachedDependencyNotFoundError(dependency, owner, repo, tag)
+		} else if ok {
+			return ParseVersion(dep)
+		}
+		if c.opts.Offline {
+			return nil, CacheMissError(fmt.Sprintf("the %s version of %s/%s %s", dependency, owner, repo, tag))
+		}
+		dep, err := fn(version)
+		if isDependencyNotFound(err) {
+			// cached as empty, so that an offline cache knows the release doesn't use the dependency
+			if err := c.putDependency(owner, repo, dependency, tag, ""); err != nil {
+				return nil, err
+			}
+			return nil, err
+		}
+		if err != nil {
+			return nil, err
+		}
+		if err := c.putDependency(owner, repo, dependency, tag, dep.String()); err != nil {
+			return nil, err
+		}
+		return dep, nil
+	}
+}
+
+func (c *ReleaseCache) getDependency(owner, repo, dependency, tag string) (string, bool, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	cached := cachedDependencies{}
+	if _, err := c.read(c.path(owner, repo, dependencyCacheFile), &cached); err != nil {
+		return "", false, err
+	}
+	dep, ok := cached[dependency][tag]
+	return dep, ok, nil
+}
+
+func (c *ReleaseCache) putDependency(owner, repo, dependency, tag, version string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	path := c.path(owner, repo, dependencyCacheFile)
+	cached := cachedDependencies{}
+	if _, err := c.read(path, &cached); err != nil {
+		return err
+	}
+	if cached[dependency] == nil {
+		cached[dependency] = map[string]string{}
+	}
+	cached[dependency][tag] = version
+	return c.write(path, cached)
+}
+
+// What the cache holds for a repo
+type CachedRepoInfo struct {
+	Owner, Repo string
+	// When the releases were last fetched, zero if they aren't cached
+	FetchedAt time.Time
+	// True if every release has been fetched at least once
+	Complete bool
+	Releases int
+	// The number of cached dependency versions, by dependency (owner/repo)
+	Dependencies map[string]int
+}
+
+// Describes the cached repos, sorted by owner/repo
+func (c *ReleaseCache) Inspect() ([]*CachedRepoInfo, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	owners, err := afero.ReadDir(c.fs, c.dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+	var infos []*CachedRepoInfo
+	for _, owner := range owners {
+		if !owner.IsDir() {
+			continue
+		}
+		repos, err := afero.ReadDir(c.fs, filepath.Join(c.dir, owner.Name()))
+		if err != nil {
+			return nil, err
+		}
+		for _, repo := range repos {
+			if !repo.IsDir() {
+				continue
+			}
+			info := &CachedRepoInfo{
+				Owner:        owner.Name(),
+				Repo:         repo.Name(),
+				Dependencies: map[string]int{},
+			}
+			releases := &cachedReleases{}
+			if _, err := c.read(c.path(info.Owner, info.Repo, releaseCacheFile), releases); err != nil {
+				return nil, err
+			}
+			info.FetchedAt = releases.FetchedAt
+			info.Complete = releases.Complete
+			info.Releases = len(releases.Releases)
+			dependencies := cachedDependencies{}
+			if _, err := c.read(c.path(info.Owner, info.Repo, dependencyCacheFile), &dependencies); err != nil {
+				return nil, err
+			}
+			for dependency, versions := range dependencies {
+				info.Dependencies[dependency] = len(versions)
+			}
+			infos = append(infos, info)
+		}
+	}
+	return infos, nil
+}
+
+// Drops the cached releases of a repo, so that they are all fetched again
+func (c *ReleaseCache) InvalidateReleases(owner, repo string) error {
+	return c.remove(c.path(owner, repo, releaseCacheFile))
+}
+
+// Drops the cached dependency versions of a repo's tags
+func (c *ReleaseCache) InvalidateDependencies(owner, repo string) error {
+	return c.remove(c.path(owner, repo, dependencyCacheFile))
+}
+
+// Drops a single tag of a repo: its release and its dependency versions
+func (c *ReleaseCache) InvalidateTag(owner, repo, tag string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	releasesPath := c.path(owner, repo, releaseCacheFile)
+	releases := &cachedReleases{}
+	if exists, err := c.read(releasesPath, releases); err != nil {
+		return err
+	} else if exists {
+		for id, release := range releases.Releases {
+			if release.Release.GetTagName() == tag {
+				delete(releases.Releases, id)
+				// the release has to be fetched again, wherever it is in the list
+				releases.Complete = false
+			}
+		}
+		if err := c.write(releasesPath, releases); err != nil {
+			return err
+		}
+	}
+	dependenciesPath := c.path(owner, repo, dependencyCacheFile)
+	dependencies := cachedDependencies{}
+	if exists, err := c.read(dependenciesPath, &dependencies); err != nil || !exists {
+		return err
+	}
+	for _, versions := range dependencies {
+		delete(versions, tag)
+	}
+	return c.write(dependenciesPath, dependencies)
+}
+
+// Empties the cache
+func (c *ReleaseCache) Clear() error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.fs.RemoveAll(c.dir)
+}
+
+func (c *ReleaseCache) path(owner, repo, file string) string {
+	return filepath.Join(c.dir, owner, repo, file)
+}
+
+func (c *ReleaseCache) read(path string, into interface{}) (bool, error) {
+	contents, err := afero.ReadFile(c.fs, path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return false, nil
+		}
+		return false, ReadCacheError(err, path)
+	}
+	if err := json.Unmarshal(contents, into); err != nil {
+		return false, ReadCacheError(err, path)
+	}
+	return true, nil
+}
+
+// Writes to a temporary file first, so that an interrupted write doesn't corrupt the cache
+func (c *ReleaseCache) write(path string, contents interface{}) error {
+	bytes, err := json.Marshal(contents)
+	if err != nil {
+		return WriteCacheError(err, path)
+	}
+	if err := c.fs.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return WriteCacheError(err, path)
+	}
+	tmp := path + ".tmp"
+	if err := afero.WriteFile(c.fs, tmp, bytes, 0644); err != nil {
+		return WriteCacheError(err, path)
+	}
+	if err := c.fs.Rename(tmp, path); err != nil {
+		return WriteCacheError(err, path)
+	}
+	return nil
+}
+
+func (c *ReleaseCache) remove(path string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if err := c.fs.Remove(path); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// The parts of a release that the changelogs depend on
+func releaseDigest(release *github.RepositoryRelease) string {
+	contents, _ := json.Marshal([]interface{}{
+		release.GetTagName(),
+		release.GetName(),
+		release.GetBody(),
+		release.GetDraft(),
+		release.GetPrerelease(),
+		release.GetCreatedAt().Unix(),
+	})
+	sum := sha256.Sum256(contents)
+	return hex.EncodeToString(sum[:])
+}
+
+// Newest first, like Github lists releases
+func sortCachedReleases(cached map[int64]*cachedRelease, maxReleases int) []*github.RepositoryRelease {
+	releases := make([]*github.RepositoryRelease, 0, len(cached))
+	for _, release := range cached {
+		releases = append(releases, release.Release)
+	}
+	sort.Slice(releases, func(i, j int) bool {
+		if !releases[i].GetCreatedAt().Equal(releases[j].GetCreatedAt()) {
+			return releases[i].GetCreatedAt().After(releases[j].GetCreatedAt().Time)
+		}
+		return releases[i].GetID() > releases[j].GetID()
+	})
+	if len(releases) > maxReleases {
+		releases = releases[:maxReleases]
+	}
+	return releases
+}
+
+// Like GetOSDependencyFunc, but only clones the enterprise repo the first time a dependency version isn't cached,
+// so that regenerating a changelog doesn't clone it at all when every version is cached.
+func GetCachedOSDependencyFunc(cache *ReleaseCache, repoOwner, enterpriseRepo, osRepo, githubToken string) DependencyFn {
+	var (
+		once         sync.Once
+		dependencyFn DependencyFn
+		cloneErr     error
+	)
+	return cache.DependencyFunc(repoOwner, enterpriseRepo, osRepo, func(v *Version) (*Version, error) {
+		once.Do(func() {
+			dependencyFn, cloneErr = GetOSDependencyFunc(repoOwner, enterpriseRepo, osRepo, githubToken)
+		})
+		if cloneErr != nil {
+			return nil, cloneErr
+		}
+		return dependencyFn(v)
+	})
+}
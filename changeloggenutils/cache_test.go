@@ -0,0 +1,251 @@
+package changelogdocutils_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sort"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/solo-io/go-utils/changeloggenutils"
+	. "github.com/solo-io/go-utils/versionutils"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("ReleaseCache", func() {
+
+	var (
+		ctx      = context.Background()
+		fs       afero.Fs
+		server   *httptest.Server
+		client   *github.Client
+		releases []*github.RepositoryRelease
+		// the pages that were requested
+		requests []int
+	)
+
+	newRelease := func(id int) *github.RepositoryRelease {
+		return &github.RepositoryRelease{
+			ID:        github.Int64(int64(id)),
+			TagName:   github.String(fmt.Sprintf("v0.%d.%d", id/10, id%10)),
+			Body:      github.String(fmt.Sprintf("**Fixes**\n- fix %d\n", id)),
+			CreatedAt: &github.Timestamp{Time: time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC).Add(time.Duration(id) * time.Hour)},
+		}
+	}
+
+	newCache := func(opts CacheOptions) *ReleaseCache {
+		return NewReleaseCacheForFs(fs, "/cache", opts)
+	}
+
+	tags := func(releases []*github.RepositoryRelease) []string {
+		var tags []string
+		for _, release := range releases {
+			tags = append(tags, release.GetTagName())
+		}
+		return tags
+	}
+
+	BeforeEach(func() {
+		fs = afero.NewMemMapFs()
+		requests = nil
+		releases = nil
+		for id := 1; id <= 250; id++ {
+			releases = append(releases, newRelease(id))
+		}
+		server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+			defer GinkgoRecover()
+			Expect(r.URL.Path).To(Equal("/repos/solo-io/testrepo/releases"))
+			page, err := strconv.Atoi(r.URL.Query().Get("page"))
+			Expect(err).NotTo(HaveOccurred())
+			perPage, err := strconv.Atoi(r.URL.Query().Get("per_page"))
+			Expect(err).NotTo(HaveOccurred())
+			requests = append(requests, page)
+
+			// newest first
+			sorted := append([]*github.RepositoryRelease{}, releases...)
+			sort.Slice(sorted, func(i, j int) bool {
+				return sorted[i].GetCreatedAt().After(sorted[j].GetCreatedAt().Time)
+			})
+			start, end := (page-1)*perPage, page*perPage
+			if start > len(sorted) {
+				start = len(sorted)
+			}
+			if end > len(sorted) {
+				end = len(sorted)
+			}
+			Expect(json.NewEncoder(w).Encode(sorted[start:end])).To(Succeed())
+		}))
+		client = github.NewClient(nil)
+		client.BaseURL, _ = url.Parse(server.URL + "/")
+	})
+
+	AfterEach(func() {
+		server.Close()
+	})
+
+	It("only fetches the pages with new or edited releases", func() {
+		cache := newCache(CacheOptions{})
+		fetched, err := cache.GetReleases(ctx, client, "solo-io", "testrepo", 1000)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fetched).To(HaveLen(250))
+		Expect(fetched[0].GetTagName()).To(Equal("v0.25.0"))
+		Expect(requests).To(Equal([]int{1, 2, 3}))
+
+		requests = nil
+		releases = append(releases, newRelease(251))
+		fetched, err = cache.GetReleases(ctx, client, "solo-io", "testrepo", 1000)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fetched).To(HaveLen(251))
+		Expect(fetched[0].GetTagName()).To(Equal("v0.25.1"))
+		Expect(requests).To(Equal([]int{1, 2}))
+
+		requests = nil
+		fetched, err = cache.GetReleases(ctx, client, "solo-io", "testrepo", 10)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tags(fetched)).To(Equal([]string{"v0.25.1", "v0.25.0", "v0.24.9", "v0.24.8", "v0.24.7", "v0.24.6", "v0.24.5", "v0.24.4", "v0.24.3", "v0.24.2"}))
+		Expect(requests).To(Equal([]int{1}))
+	})
+
+	It("picks up edits to older releases and deleted releases on a full refresh", func() {
+		_, err := newCache(CacheOptions{}).GetReleases(ctx, client, "solo-io", "testrepo", 1000)
+		Expect(err).NotTo(HaveOccurred())
+
+		releases[0].Body = github.String("**Fixes**\n- edited\n")
+		releases = releases[1:]
+		fetched, err := newCache(CacheOptions{}).GetReleases(ctx, client, "solo-io", "testrepo", 1000)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fetched).To(HaveLen(250))
+
+		requests = nil
+		fetched, err = newCache(CacheOptions{FullRefresh: true}).GetReleases(ctx, client, "solo-io", "testrepo", 1000)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(requests).To(Equal([]int{1, 2, 3}))
+		Expect(fetched).To(HaveLen(249))
+		Expect(fetched[248].GetTagName()).To(Equal("v0.0.2"))
+	})
+
+	It("works offline from the cache", func() {
+		_, err := newCache(CacheOptions{}).GetReleases(ctx, client, "solo-io", "testrepo", 1000)
+		Expect(err).NotTo(HaveOccurred())
+
+		requests = nil
+		offline := newCache(CacheOptions{Offline: true})
+		fetched, err := offline.GetReleases(ctx, nil, "solo-io", "testrepo", 1000)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fetched).To(HaveLen(250))
+		Expect(requests).To(BeEmpty())
+
+		_, err = offline.GetReleases(ctx, nil, "solo-io", "other", 1000)
+		Expect(err).To(MatchError(CacheMissError("the releases of solo-io/other")))
+
+		generator := NewMinorReleaseGroupedChangelogGenerator(Options{
+			RepoOwner: "solo-io",
+			MainRepo:  "testrepo",
+			Cache:     offline,
+		}, nil)
+		releaseData, err := generator.GetReleaseData(ctx, nil)
+		Expect(err).NotTo(HaveOccurred())
+		notes, err := releaseData.GetChangelogNotes(Version{Major: 0, Minor: 24, Patch: 9})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(notes.Categories["Fixes"][0].Note).To(Equal("fix 249"))
+	})
+
+	It("caches dependency versions", func() {
+		calls := 0
+		depFn := func(v *Version) (*Version, error) {
+			calls++
+			if v.Patch == 0 {
+				return nil, fmt.Errorf("no dependency")
+			}
+			return &Version{Major: 1, Minor: v.Minor, Patch: v.Patch}, nil
+		}
+		cached := newCache(CacheOptions{}).DependencyFunc("solo-io", "solo-projects", "gloo", depFn)
+
+		for i := 0; i < 2; i++ {
+			dep, err := cached(&Version{Major: 0, Minor: 2, Patch: 3})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dep.String()).To(Equal("v1.2.3"))
+			_, err = cached(&Version{Major: 0, Minor: 2})
+			Expect(err).To(HaveOccurred())
+		}
+		Expect(calls).To(Equal(3))
+
+		offline := newCache(CacheOptions{Offline: true}).DependencyFunc("solo-io", "solo-projects", "gloo", depFn)
+		dep, err := offline(&Version{Major: 0, Minor: 2, Patch: 3})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(dep.String()).To(Equal("v1.2.3"))
+		_, err = offline(&Version{Major: 0, Minor: 2, Patch: 4})
+		Expect(err).To(MatchError(CacheMissError("the solo-io/gloo version of solo-io/solo-projects v0.2.4")))
+		Expect(calls).To(Equal(3))
+	})
+
+	It("caches that a release doesn't use a dependency", func() {
+		depFn := func(v *Version) (*Version, error) {
+			return nil, &DependencyNotFoundError{Err: fmt.Errorf("no dependency")}
+		}
+		_, err := newCache(CacheOptions{}).DependencyFunc("solo-io", "solo-projects", "gloo", depFn)(&Version{Major: 0, Minor: 2})
+		Expect(err).To(BeAssignableToTypeOf(&DependencyNotFoundError{}))
+
+		offline := newCache(CacheOptions{Offline: true}).DependencyFunc("solo-io", "solo-projects", "gloo", depFn)
+		_, err = offline(&Version{Major: 0, Minor: 2})
+		Expect(err).To(MatchError(CachedDependencyNotFoundError("solo-io/gloo", "solo-io", "solo-projects", "v0.2.0")))
+	})
+
+	It("can be inspected and invalidated", func() {
+		cache := newCache(CacheOptions{})
+		infos, err := cache.Inspect()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(infos).To(BeEmpty())
+
+		_, err = cache.GetReleases(ctx, client, "solo-io", "testrepo", 1000)
+		Expect(err).NotTo(HaveOccurred())
+		depFn := cache.DependencyFunc("solo-io", "testrepo", "gloo", func(v *Version) (*Version, error) {
+			return v, nil
+		})
+		_, err = depFn(&Version{Major: 0, Minor: 1, Patch: 1})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = depFn(&Version{Major: 0, Minor: 1, Patch: 2})
+		Expect(err).NotTo(HaveOccurred())
+
+		infos, err = cache.Inspect()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(infos).To(HaveLen(1))
+		Expect(infos[0].Owner).To(Equal("solo-io"))
+		Expect(infos[0].Repo).To(Equal("testrepo"))
+		Expect(infos[0].Complete).To(BeTrue())
+		Expect(infos[0].Releases).To(Equal(250))
+		Expect(infos[0].Dependencies).To(Equal(map[string]int{"solo-io/gloo": 2}))
+
+		Expect(cache.InvalidateTag("solo-io", "testrepo", "v0.1.1")).To(Succeed())
+		infos, err = cache.Inspect()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(infos[0].Complete).To(BeFalse())
+		Expect(infos[0].Releases).To(Equal(249))
+		Expect(infos[0].Dependencies).To(Equal(map[string]int{"solo-io/gloo": 1}))
+
+		requests = nil
+		fetched, err := cache.GetReleases(ctx, client, "solo-io", "testrepo", 1000)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fetched).To(HaveLen(250))
+		Expect(requests).To(Equal([]int{1, 2, 3}))
+
+		Expect(cache.InvalidateDependencies("solo-io", "testrepo")).To(Succeed())
+		Expect(cache.InvalidateReleases("solo-io", "testrepo")).To(Succeed())
+		infos, err = cache.Inspect()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(infos[0].Releases).To(BeZero())
+		Expect(infos[0].Dependencies).To(BeEmpty())
+
+		Expect(cache.Clear()).To(Succeed())
+		infos, err = cache.Inspect()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(infos).To(BeEmpty())
+	})
+})
@@ -0,0 +1,322 @@
+package changelogdocutils
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"path"
+	"regexp"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/google/go-github/v32/github"
+	"github.com/rotisserie/eris"
+	. "github.com/solo-io/go-utils/versionutils"
+	"github.com/solo-io/go-utils/versionutils/gomod"
+)
+
+const (
+	helmChartFile        = "Chart.yaml"
+	helmRequirementsFile = "requirements.yaml"
+)
+
+var (
+	HelmDependencyNotFoundError = func(chart, dir string) error {
+		return &DependencyNotFoundError{Err: eris.Errorf("chart %s is not a dependency of the helm chart in %s", chart, dir)}
+	}
+	ImageTagNotFoundError = func(what, file string) error {
+		return &DependencyNotFoundError{Err: eris.Errorf("unable to find %s in %s", what, file)}
+	}
+	MissingDependencyResolverError = func(repo string) error {
+		return eris.Errorf("dependency %s has no resolver", repo)
+	}
+	InvalidDependencyVersionError = func(err error, version, file string) error {
+		return eris.Wrapf(err, "version %s in %s is not an exact semver version", version, file)
+	}
+)
+
+// A repo whose release notes are merged into the changelog of the main repo
+type Dependency struct {
+	// Github user/org of the dependency, defaults to Options.RepoOwner
+	Owner string
+	Repo  string
+	// Names the dependency in the headers of the main repo's releases, e.g. "(Uses OSS v1.8.0)".
+	// Defaults to Repo.
+	DisplayName string
+	// Finds the version of the dependency that a release of the main repo uses
+	Resolver DependencyResolver `json:"-"`
+	// Releases of the dependency, fetched from Github if nil
+	Releases []*github.RepositoryRelease `json:"-"`
+}
+
+func (d *Dependency) owner(opts Options) string {
+	if d.Owner != "" {
+		return d.Owner
+	}
+	return opts.RepoOwner
+}
+
+func (d *Dependency) displayName() string {
+	if d.DisplayName != "" {
+		return d.DisplayName
+	}
+	return d.Repo
+}
+
+// Reads a file of the main repo at a tag
+type MainRepoFileReader func(ctx context.Context, tag, path string) ([]byte, error)
+
+// Finds the version of a dependency that a release of the main repo uses
+type DependencyResolver interface {
+	ResolveDependency(ctx context.Context, files MainRepoFileReader, owner, repo string, version *Version) (*Version, error)
+}
+
+// The error of a resolver for a release of the main repo that doesn't use the dependency, e.g. because it predates
+// it. The dependency's notes aren't merged into that release, while any other error fails the generation.
+type DependencyNotFoundError struct {
+	Err error
+}
+
+func (e *DependencyNotFoundError) Error() string {
+	return e.Err.Error()
+}
+
+func (e *DependencyNotFoundError) Unwrap() error {
+	return e.Err
+}
+
+func isDependencyNotFound(err error) bool {
+	var notFound *DependencyNotFoundError
+	return errors.As(err, &notFound)
+}
+
+// Adapts a DependencyFn to a DependencyResolver
+type DependencyFnResolver DependencyFn
+
+func (fn DependencyFnResolver) ResolveDependency(_ context.Context, _ MainRepoFileReader, _, _ string, version *Version) (*Version, error) {
+	return fn(version)
+}
+
+// Resolves the dependency from the version of its module that the main repo's go.mod requires.
+// A replacement with another version of the same module is honoured; a replacement with a fork is not.
+type GoModResolver struct {
+	// Directory of the go.mod file in the main repo, defaults to the root
+	Dir string
+	// Path of the dependency's module, defaults to github.com/<owner>/<repo>
+	Module string
+}
+
+func (r *GoModResolver) ResolveDependency(ctx context.Context, files MainRepoFileReader, owner, repo string, version *Version) (*Version, error) {
+	file := path.Join(r.Dir, gomod.GoModFile)
+	contents, err := files(ctx, version.String(), file)
+	if err != nil {
+		return nil, err
+	}
+	goMod, err := gomod.ParseGoMod(file, contents)
+	if err != nil {
+		return nil, err
+	}
+	modulePath := r.Module
+	if modulePath == "" {
+		modulePath = fmt.Sprintf("github.com/%s/%s", owner, repo)
+	}
+	module, err := goMod.Module(modulePath)
+	if err != nil {
+		if eris.Is(err, gomod.ModuleNotRequiredError(modulePath)) {
+			return nil, &DependencyNotFoundError{Err: err}
+		}
+		return nil, err
+	}
+	return ParseVersion(module.UpstreamVersion())
+}
+
+// Resolves the dependency from a dependency of a helm chart in the main repo: from the chart's Chart.yaml,
+// or its requirements.yaml for charts that predate Helm 3.
+type HelmChartResolver struct {
+	// Directory of the chart in the main repo
+	ChartDir string
+	// Name (or alias) of the dependency's chart, defaults to the dependency's repo
+	Chart string
+}
+
+type helmChartDependencies struct {
+	Dependencies []struct {
+		Name    string `json:"name"`
+		Alias   string `json:"alias"`
+		Version string `json:"version"`
+	} `json:"dependencies"`
+}
+
+func (r *HelmChartResolver) ResolveDependency(ctx context.Context, files MainRepoFileReader, _, repo string, version *Version) (*Version, error) {
+	chart := r.Chart
+	if chart == "" {
+		chart = repo
+	}
+	for _, name := range []string{helmChartFile, helmRequirementsFile} {
+		file := path.Join(r.ChartDir, name)
+		contents, err := files(ctx, version.String(), file)
+		if err != nil {
+			if name == helmRequirementsFile {
+				break
+			}
+			return nil, err
+		}
+		var deps helmChartDependencies
+		if err := yaml.Unmarshal(contents, &deps); err != nil {
+			return nil, err
+		}
+		for _, dep := range deps.Dependencies {
+			if dep.Name == chart || dep.Alias == chart {
+				return parseDependencyVersion(dep.Version, file)
+			}
+		}
+	}
+	return nil, HelmDependencyNotFoundError(chart, r.ChartDir)
+}
+
+// Resolves the dependency from the tag of an image it publishes, read from a file in the main repo.
+// If Key is set, the file is YAML (e.g. helm values) and the tag is the value at that dotted path. Otherwise,
+// if Image is set, the tag is the one of the first reference to the image. Otherwise the whole file is the tag.
+type ImageTagResolver struct {
+	Path  string
+	Key   string
+	Image string
+}
+
+func (r *ImageTagResolver) ResolveDependency(ctx context.Context, files MainRepoFileReader, _, _ string, version *Version) (*Version, error) {
+	contents, err := files(ctx, version.String(), r.Path)
+	if err != nil {
+		return nil, err
+	}
+	switch {
+	case r.Key != "":
+		var values interface{}
+		if err := yaml.Unmarshal(contents, &values); err != nil {
+			return nil, err
+		}
+		for _, key := range strings.Split(r.Key, ".") {
+			object, ok := values.(map[string]interface{})
+			if !ok {
+				return nil, ImageTagNotFoundError(r.Key, r.Path)
+			}
+			values = object[key]
+		}
+		tag, ok := values.(string)
+		if !ok {
+			return nil, ImageTagNotFoundError(r.Key, r.Path)
+		}
+		return parseDependencyVersion(tag, r.Path)
+	case r.Image != "":
+		matches := regexp.MustCompile(regexp.QuoteMeta(r.Image) + `:([^\s"'@]+)`).FindSubmatch(contents)
+		if matches == nil {
+			return nil, ImageTagNotFoundError(r.Image, r.Path)
+		}
+		return parseDependencyVersion(string(matches[1]), r.Path)
+	default:
+		return parseDependencyVersion(string(contents), r.Path)
+	}
+}
+
+// Helm versions and image tags often omit the "v" of the release tag
+func parseDependencyVersion(version, file string) (*Version, error) {
+	version = strings.Trim(strings.TrimSpace(version), `"'`)
+	tag := version
+	if !strings.HasPrefix(tag, "v") {
+		tag = "v" + tag
+	}
+	parsed, err := ParseVersion(tag)
+	if err != nil {
+		return nil, InvalidDependencyVersionError(err, version, file)
+	}
+	return parsed, nil
+}
+
+func validateDependencies(deps []*Dependency) error {
+	for _, dep := range deps {
+		if dep.Resolver == nil {
+			return MissingDependencyResolverError(dep.Repo)
+		}
+	}
+	return nil
+}
+
+// Merges the notes of every dependency in Options.Dependencies into the main repo's release data. Each merged note
+// is attributed to its repo and version.
+func (g *MergedReleaseGenerator) mergeDependencies(ctx context.Context, mainReleases *ReleaseData) (*ReleaseData, error) {
+	uses := map[Version][]string{}
+	for _, dep := range g.opts.Dependencies {
+		depOpts := g.opts
+		depOpts.RepoOwner = dep.owner(g.opts)
+		depOpts.MainRepo = dep.Repo
+		depReleases, err := NewMinorReleaseGroupedChangelogGenerator(depOpts, g.client).GetReleaseData(ctx, dep.Releases)
+		if err != nil {
+			return nil, err
+		}
+		if err := g.mergeDependency(ctx, mainReleases, dep, depReleases, uses); err != nil {
+			return nil, err
+		}
+	}
+	for version, used := range uses {
+		notes, err := mainReleases.GetChangelogNotes(version)
+		if err != nil {
+			return nil, err
+		}
+		notes.HeaderSuffix = fmt.Sprintf(" (Uses %s)", strings.Join(used, ", "))
+	}
+	return mainReleases, nil
+}
+
+func (g *MergedReleaseGenerator) mergeDependency(ctx context.Context, mainReleases *ReleaseData, dep *Dependency, depReleases *ReleaseData, uses map[Version][]string) error {
+	owner := dep.owner(g.opts)
+	depRepo := owner + "/" + dep.Repo
+	resolve := func(version *Version) (*Version, error) {
+		return dep.Resolver.ResolveDependency(ctx, g.fileReader, owner, dep.Repo, version)
+	}
+	if g.opts.Cache != nil {
+		resolve = g.opts.Cache.dependencyFunc(g.opts.RepoOwner, g.opts.MainRepo, depRepo, resolve)
+	}
+
+	// Both sorted from the greatest version to the least
+	mainSorted := mainReleases.GetReleasesSorted()
+	depSorted := depReleases.GetReleasesSorted()
+	depVersions := make([]*Version, len(mainSorted))
+	for i := range mainSorted {
+		version, err := resolve(&mainSorted[i])
+		if err != nil {
+			// Releases that don't use the dependency, e.g. that predate it, don't merge its notes
+			if isDependencyNotFound(err) {
+				continue
+			}
+			return eris.Wrapf(err, "unable to resolve the version of %s that %s uses", depRepo, mainSorted[i].String())
+		}
+		depVersions[i] = version
+	}
+
+	for i, release := range mainSorted {
+		current := depVersions[i]
+		if current == nil {
+			continue
+		}
+		// The dependency version of the previous release that has one
+		var earlier *Version
+		for j := i + 1; j < len(mainSorted) && earlier == nil; j++ {
+			earlier = depVersions[j]
+		}
+		merged := NewChangelogNotes()
+		for _, version := range GetOtherRepoDepsBetweenVersions(depSorted, earlier, current) {
+			notes, err := depReleases.GetChangelogNotes(version)
+			if err != nil || notes == nil {
+				// the dependency has no release notes for this version
+				continue
+			}
+			merged.AddFromRepo(notes, depRepo, version)
+		}
+		notes, err := mainReleases.GetChangelogNotes(release)
+		if err != nil {
+			return err
+		}
+		notes.Add(merged)
+		uses[release] = append(uses[release], fmt.Sprintf("%s %s", dep.displayName(), getGithubReleaseMarkdownLink(current.String(), owner, dep.Repo)))
+	}
+	return nil
+}
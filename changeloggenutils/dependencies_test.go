@@ -0,0 +1,233 @@
+package changelogdocutils_test
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	"github.com/google/go-github/v32/github"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/solo-io/go-utils/changeloggenutils"
+	. "github.com/solo-io/go-utils/versionutils"
+	"github.com/solo-io/go-utils/versionutils/gomod"
+)
+
+var _ = Describe("dependencies", func() {
+
+	var (
+		ctx = context.Background()
+		// file contents by tag and path
+		files map[string]map[string]string
+	)
+
+	fileReader := func(_ context.Context, tag, path string) ([]byte, error) {
+		contents, ok := files[tag][path]
+		if !ok {
+			return nil, os.ErrNotExist
+		}
+		return []byte(contents), nil
+	}
+
+	resolve := func(resolver DependencyResolver, tag string) (string, error) {
+		version, err := resolver.ResolveDependency(ctx, fileReader, "solo-io", "gloo", MustParseVersion(tag))
+		if err != nil {
+			return "", err
+		}
+		return version.String(), nil
+	}
+
+	BeforeEach(func() {
+		files = map[string]map[string]string{}
+	})
+
+	Context("resolvers", func() {
+		It("resolves go.mod requirements", func() {
+			files["v1.0.0"] = map[string]string{
+				"go.mod": `module github.com/solo-io/solo-projects
+
+require (
+	github.com/solo-io/gloo v1.8.3
+	github.com/solo-io/envoy-fork v1.2.0
+)
+
+replace github.com/solo-io/gloo => github.com/solo-io/gloo v1.8.4
+`,
+				"projects/ui/go.mod": "module github.com/solo-io/solo-projects/projects/ui\n\nrequire github.com/solo-io/gloo v1.8.1\n",
+			}
+			Expect(resolve(&GoModResolver{}, "v1.0.0")).To(Equal("v1.8.4"))
+			Expect(resolve(&GoModResolver{Dir: "projects/ui"}, "v1.0.0")).To(Equal("v1.8.1"))
+			Expect(resolve(&GoModResolver{Module: "github.com/solo-io/envoy-fork"}, "v1.0.0")).To(Equal("v1.2.0"))
+
+			_, err := resolve(&GoModResolver{Module: "github.com/solo-io/gloo-ui"}, "v1.0.0")
+			Expect(err).To(BeAssignableToTypeOf(&DependencyNotFoundError{}))
+			Expect(err).To(MatchError(gomod.ModuleNotRequiredError("github.com/solo-io/gloo-ui")))
+		})
+
+		It("resolves helm chart dependencies", func() {
+			files["v1.0.0"] = map[string]string{
+				"install/helm/gloo-ee/Chart.yaml": `apiVersion: v2
+name: gloo-ee
+dependencies:
+- name: gloo
+  alias: gloo-oss
+  version: 1.8.3
+  repository: https://storage.googleapis.com/solo-public-helm
+`,
+			}
+			files["v0.1.0"] = map[string]string{
+				"install/helm/gloo-ee/Chart.yaml":        "apiVersion: v1\nname: gloo-ee\n",
+				"install/helm/gloo-ee/requirements.yaml": "dependencies:\n- name: gloo\n  version: v0.21.0\n- name: grafana\n  version: ~5.0.0\n",
+			}
+			Expect(resolve(&HelmChartResolver{ChartDir: "install/helm/gloo-ee"}, "v1.0.0")).To(Equal("v1.8.3"))
+			Expect(resolve(&HelmChartResolver{ChartDir: "install/helm/gloo-ee", Chart: "gloo-oss"}, "v1.0.0")).To(Equal("v1.8.3"))
+			Expect(resolve(&HelmChartResolver{ChartDir: "install/helm/gloo-ee"}, "v0.1.0")).To(Equal("v0.21.0"))
+
+			_, err := resolve(&HelmChartResolver{ChartDir: "install/helm/gloo-ee", Chart: "grafana"}, "v0.1.0")
+			Expect(err).To(MatchError(ContainSubstring("version ~5.0.0 in install/helm/gloo-ee/requirements.yaml is not an exact semver version")))
+			_, err = resolve(&HelmChartResolver{ChartDir: "install/helm/gloo-ee", Chart: "prometheus"}, "v1.0.0")
+			Expect(err).To(MatchError(HelmDependencyNotFoundError("prometheus", "install/helm/gloo-ee")))
+		})
+
+		It("resolves image tags", func() {
+			files["v1.0.0"] = map[string]string{
+				"values.yaml":   "gloo:\n  gateway:\n    image:\n      repository: gloo\n      tag: \"1.8.3\"\n",
+				"manifest.yaml": "containers:\n- image: quay.io/solo-io/envoy-fork:v1.19.0-patch2\n- image: quay.io/solo-io/gloo-ui:2.0.1\n",
+				"ENVOY_VERSION": "v1.19.0-patch2\n",
+			}
+			Expect(resolve(&ImageTagResolver{Path: "values.yaml", Key: "gloo.gateway.image.tag"}, "v1.0.0")).To(Equal("v1.8.3"))
+			Expect(resolve(&ImageTagResolver{Path: "manifest.yaml", Image: "quay.io/solo-io/gloo-ui"}, "v1.0.0")).To(Equal("v2.0.1"))
+			Expect(resolve(&ImageTagResolver{Path: "ENVOY_VERSION"}, "v1.0.0")).To(Equal("v1.19.0-patch2"))
+
+			_, err := resolve(&ImageTagResolver{Path: "values.yaml", Key: "gloo.gateway.image.missing"}, "v1.0.0")
+			Expect(err).To(MatchError(ImageTagNotFoundError("gloo.gateway.image.missing", "values.yaml")))
+			_, err = resolve(&ImageTagResolver{Path: "manifest.yaml", Image: "quay.io/solo-io/gloo"}, "v1.0.0")
+			Expect(err).To(MatchError(ImageTagNotFoundError("quay.io/solo-io/gloo", "manifest.yaml")))
+		})
+	})
+
+	Context("MergedReleaseGenerator", func() {
+
+		release := func(tag, notes string) *github.RepositoryRelease {
+			return &github.RepositoryRelease{
+				TagName: github.String(tag),
+				Body:    github.String(fmt.Sprintf("**Fixes**\n- %s\n", notes)),
+			}
+		}
+
+		It("merges the notes of every dependency, attributed to their repos", func() {
+			// go.mod requires gloo; the envoy version is in a file
+			for tag, versions := range map[string][2]string{
+				"v1.2.0": {"v1.2.0", "v1.19.0"},
+				"v1.2.1": {"v1.2.2", "v1.19.0"},
+				"v1.2.2": {"v1.2.2", "v1.19.1"},
+			} {
+				files[tag] = map[string]string{
+					"go.mod":        fmt.Sprintf("module github.com/solo-io/solo-projects\n\nrequire github.com/solo-io/gloo %s\n", versions[0]),
+					"ENVOY_VERSION": versions[1],
+				}
+			}
+			// the UI versions are known without reading any file
+			uiVersions := map[string]string{"v1.2.2": "v0.5.0"}
+
+			generator := NewMergedReleaseGeneratorWithFileReader(Options{
+				RepoOwner: "solo-io",
+				MainRepo:  "solo-projects",
+				MainRepoReleases: []*github.RepositoryRelease{
+					release("v1.2.2", "enterprise fix 3"),
+					release("v1.2.1", "enterprise fix 2"),
+					release("v1.2.0", "enterprise fix 1"),
+				},
+				Dependencies: []*Dependency{
+					{
+						Repo:        "gloo",
+						DisplayName: "OSS",
+						Resolver:    &GoModResolver{},
+						Releases: []*github.RepositoryRelease{
+							release("v1.2.2", "oss fix 3"),
+							release("v1.2.1", "oss fix 2"),
+							release("v1.2.0", "oss fix 1"),
+						},
+					},
+					{
+						Owner:       "envoyproxy",
+						Repo:        "envoy-fork",
+						DisplayName: "Envoy",
+						Resolver:    &ImageTagResolver{Path: "ENVOY_VERSION"},
+						Releases: []*github.RepositoryRelease{
+							release("v1.19.1", "envoy fix"),
+							release("v1.19.0", "envoy release"),
+						},
+					},
+					{
+						Repo: "gloo-ui",
+						Resolver: DependencyFnResolver(func(v *Version) (*Version, error) {
+							if ui, ok := uiVersions[v.String()]; ok {
+								return ParseVersion(ui)
+							}
+							return nil, &DependencyNotFoundError{Err: fmt.Errorf("no ui in %s", v)}
+						}),
+						Releases: []*github.RepositoryRelease{
+							release("v0.5.0", "ui fix"),
+						},
+					},
+				},
+			}, nil, fileReader)
+
+			releaseData, err := generator.GetMergedEnterpriseRelease(ctx)
+			Expect(err).NotTo(HaveOccurred())
+
+			notes, err := releaseData.GetChangelogNotes(*MustParseVersion("v1.2.2"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(notes.HeaderSuffix).To(Equal(" (Uses OSS [v1.2.2](https://github.com/solo-io/gloo/releases/tag/v1.2.2), " +
+				"Envoy [v1.19.1](https://github.com/envoyproxy/envoy-fork/releases/tag/v1.19.1), " +
+				"gloo-ui [v0.5.0](https://github.com/solo-io/gloo-ui/releases/tag/v0.5.0))"))
+			Expect(notes.Categories["Fixes"]).To(Equal([]*Note{
+				{Note: "enterprise fix 3"},
+				{Note: "envoy fix", FromDependentVersion: MustParseVersion("v1.19.1"), FromRepo: "envoyproxy/envoy-fork"},
+				{Note: "ui fix", FromDependentVersion: MustParseVersion("v0.5.0"), FromRepo: "solo-io/gloo-ui"},
+			}))
+
+			notes, err = releaseData.GetChangelogNotes(*MustParseVersion("v1.2.1"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(notes.Categories["Fixes"]).To(Equal([]*Note{
+				{Note: "enterprise fix 2"},
+				{Note: "oss fix 3", FromDependentVersion: MustParseVersion("v1.2.2"), FromRepo: "solo-io/gloo"},
+				{Note: "oss fix 2", FromDependentVersion: MustParseVersion("v1.2.1"), FromRepo: "solo-io/gloo"},
+			}))
+
+			notes, err = releaseData.GetChangelogNotes(*MustParseVersion("v1.2.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(notes.HeaderSuffix).To(Equal(" (Uses OSS [v1.2.0](https://github.com/solo-io/gloo/releases/tag/v1.2.0), " +
+				"Envoy [v1.19.0](https://github.com/envoyproxy/envoy-fork/releases/tag/v1.19.0))"))
+			Expect(notes.Categories["Fixes"]).To(HaveLen(3))
+
+			dump, err := notes.Dump()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(dump).To(ContainSubstring(`{"Note":"envoy release","FromDependentVersion":"v1.19.0","FromRepo":"envoyproxy/envoy-fork"}`))
+		})
+
+		It("fails on dependencies that can't be resolved", func() {
+			opts := Options{
+				RepoOwner:        "solo-io",
+				MainRepo:         "solo-projects",
+				MainRepoReleases: []*github.RepositoryRelease{release("v1.2.0", "enterprise fix 1")},
+				Dependencies:     []*Dependency{{Repo: "gloo", Releases: []*github.RepositoryRelease{release("v1.2.0", "oss fix 1")}}},
+			}
+			_, err := NewMergedReleaseGeneratorWithFileReader(opts, nil, fileReader).GetMergedEnterpriseRelease(ctx)
+			Expect(err).To(MatchError(MissingDependencyResolverError("gloo")))
+
+			// unlike a go.mod without the dependency, a missing go.mod is an error
+			opts.Dependencies[0].Resolver = &GoModResolver{}
+			_, err = NewMergedReleaseGeneratorWithFileReader(opts, nil, fileReader).GetMergedEnterpriseRelease(ctx)
+			Expect(err).To(MatchError(ContainSubstring("unable to resolve the version of solo-io/gloo that v1.2.0 uses")))
+
+			files["v1.2.0"] = map[string]string{"go.mod": "module github.com/solo-io/solo-projects\n"}
+			releaseData, err := NewMergedReleaseGeneratorWithFileReader(opts, nil, fileReader).GetMergedEnterpriseRelease(ctx)
+			Expect(err).NotTo(HaveOccurred())
+			notes, err := releaseData.GetChangelogNotes(*MustParseVersion("v1.2.0"))
+			Expect(err).NotTo(HaveOccurred())
+			Expect(notes.HeaderSuffix).To(BeEmpty())
+		})
+	})
+})
@@ -4,7 +4,6 @@ import (
 	"context"
 	"encoding/json"
 	"fmt"
-	"regexp"
 
 	"github.com/go-git/go-git/v5"
 	http2 "github.com/go-git/go-git/v5/plumbing/transport/http"
@@ -12,6 +11,7 @@ import (
 	"github.com/google/go-github/v32/github"
 	"github.com/solo-io/go-utils/githubutils"
 	. "github.com/solo-io/go-utils/versionutils"
+	"github.com/solo-io/go-utils/versionutils/gomod"
 )
 
 type DependencyFn func(*Version) (*Version, error)
@@ -40,6 +40,15 @@ type Options struct {
 	// Minimum release version to display on this changelog. If not specified, all releases <= MaxVersion
 	// will be included
 	MinVersion *Version
+	// Constraint on the release versions to be included in this changelog, in addition to MinVersion and MaxVersion,
+	// e.g. ">= v1.6.0, < v1.9.0 || ~v1.10"
+	VersionConstraint *Constraint
+	// Repos whose notes the MergedReleaseGenerator merges into the MainRepo's, each with the resolver of the version
+	// that a MainRepo release uses. If empty, the DependentRepo is merged, with the generator's DependencyFn.
+	Dependencies []*Dependency `json:",omitempty"`
+	// If set, releases are fetched through the cache, and the dependency versions of the MergedReleaseGenerator
+	// are cached
+	Cache *ReleaseCache `json:"-"`
 }
 
 type MergedReleaseGenerator struct {
@@ -47,6 +56,7 @@ type MergedReleaseGenerator struct {
 	releaseDepMap  map[Version]*Version
 	opts           Options
 	dependencyFunc DependencyFn
+	fileReader     MainRepoFileReader
 }
 
 func NewMergedReleaseGenerator(opts Options, client *github.Client) *MergedReleaseGenerator {
@@ -55,16 +65,33 @@ func NewMergedReleaseGenerator(opts Options, client *github.Client) *MergedRelea
 		client:        client,
 		releaseDepMap: map[Version]*Version{},
 	}
-	generator.dependencyFunc = generator.GetOpenSourceDependency
+	generator.dependencyFunc = generator.cachedDependencyFunc(generator.GetOpenSourceDependency)
+	generator.fileReader = func(ctx context.Context, tag, path string) ([]byte, error) {
+		return gomod.NewGithubFileReader(client, opts.RepoOwner, opts.MainRepo, tag).GetFileContents(ctx, path)
+	}
 	return generator
 }
 
+// The dependency resolvers read the main repo's files with fileReader instead of the Github API
+func NewMergedReleaseGeneratorWithFileReader(opts Options, client *github.Client, fileReader MainRepoFileReader) *MergedReleaseGenerator {
+	gen := NewMergedReleaseGenerator(opts, client)
+	gen.fileReader = fileReader
+	return gen
+}
+
 func NewMergedReleaseGeneratorWithDepFn(opts Options, client *github.Client, depFn DependencyFn) *MergedReleaseGenerator {
 	gen := NewMergedReleaseGenerator(opts, client)
-	gen.dependencyFunc = depFn
+	gen.dependencyFunc = gen.cachedDependencyFunc(depFn)
 	return gen
 }
 
+func (g *MergedReleaseGenerator) cachedDependencyFunc(depFn DependencyFn) DependencyFn {
+	if g.opts.Cache == nil {
+		return depFn
+	}
+	return g.opts.Cache.DependencyFunc(g.opts.RepoOwner, g.opts.MainRepo, g.opts.DependentRepo, depFn)
+}
+
 /*
 The merged release generator has 3 steps:
 1. Fetches enterprise repo release notes
@@ -104,6 +131,7 @@ func (g *MergedReleaseGenerator) GenerateJSON(ctx context.Context) (string, erro
 		RepoOwner:     g.opts.RepoOwner,
 		MainRepo:      g.opts.MainRepo,
 		DependentRepo: g.opts.DependentRepo,
+		Dependencies:  g.opts.Dependencies,
 	}
 	out.ReleaseData = enterpriseReleases
 	res, err := json.Marshal(out)
@@ -111,12 +139,18 @@ func (g *MergedReleaseGenerator) GenerateJSON(ctx context.Context) (string, erro
 }
 
 func (g *MergedReleaseGenerator) GetMergedEnterpriseRelease(ctx context.Context) (*ReleaseData, error) {
+	if err := validateDependencies(g.opts.Dependencies); err != nil {
+		return nil, err
+	}
 
 	enterpriseReleases, err := NewMinorReleaseGroupedChangelogGenerator(g.opts, g.client).
 		GetReleaseData(ctx, g.opts.MainRepoReleases)
 	if err != nil {
 		return nil, err
 	}
+	if len(g.opts.Dependencies) > 0 {
+		return g.mergeDependencies(ctx, enterpriseReleases)
+	}
 	ossOpts := g.opts
 	ossOpts.MainRepo = g.opts.DependentRepo
 	ossReleases, err := NewMinorReleaseGroupedChangelogGenerator(ossOpts, g.client).
@@ -212,19 +246,18 @@ func getGithubReleaseMarkdownLink(tag, repoOwner, repo string) string {
 	return fmt.Sprintf("[%s](https://github.com/%s/%s/releases/tag/%s)", tag, repoOwner, repo, tag)
 }
 
-// Looks for
-// repoOwner/otherRepo v1.x.x-x
-// in go.mod string (goMod) and returns the package version
+// Returns the version of github.com/repoOwner/repo that the go.mod string (goMod) requires. A replacement
+// with another version of the same module is honoured; a replacement with a fork is not.
 func getPkgVersionFromGoMod(goMod, repoOwner, repo string) (*Version, error) {
-	semverRegex := fmt.Sprintf("%s/%s\\s+(v((([0-9]+)\\.([0-9]+)\\.([0-9]+)(?:-([0-9a-zA-Z-]+(?:\\.[0-9a-zA-Z-]+)*))?)(?:\\+([0-9a-zA-Z-]+(?:\\.[0-9a-zA-Z-]+)*))?))",
-		repoOwner, repo)
-	regex := regexp.MustCompile(semverRegex)
-	// Find version of open source dependency
-	matches := regex.FindStringSubmatch(goMod)
-	if len(matches) < 2 {
-		return nil, fmt.Errorf("unable to find dependency")
+	mod, err := gomod.ParseGoMod(gomod.GoModFile, []byte(goMod))
+	if err != nil {
+		return nil, err
+	}
+	dependency, err := mod.Module(fmt.Sprintf("github.com/%s/%s", repoOwner, repo))
+	if err != nil {
+		return nil, err
 	}
-	return ParseVersion(matches[1])
+	return ParseVersion(dependency.UpstreamVersion())
 }
 
 /*
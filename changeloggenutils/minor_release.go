@@ -5,7 +5,6 @@ import (
 	"encoding/json"
 	"fmt"
 	"math"
-	"sort"
 	"strings"
 
 	"github.com/google/go-github/v32/github"
@@ -102,6 +101,11 @@ func (g *MinorReleaseGroupedChangelogGenerator) GetReleaseData(ctx context.Conte
 	)
 	if cachedReleases != nil {
 		releases = cachedReleases
+	} else if g.opts.Cache != nil {
+		releases, err = g.opts.Cache.GetReleases(ctx, g.Client, g.opts.RepoOwner, g.opts.MainRepo, g.opts.NumVersions)
+		if err != nil {
+			return nil, err
+		}
 	} else {
 		releases, err = githubutils.GetAllRepoReleasesWithMax(ctx, g.Client, g.opts.RepoOwner, g.opts.MainRepo, g.opts.NumVersions)
 		if err != nil {
@@ -177,6 +181,9 @@ func (g *MinorReleaseGroupedChangelogGenerator) isBetweenMinAndMaxVersions(versi
 	if g.opts.MaxVersion != nil {
 		res = res && !version.MustIsGreaterThan(*g.opts.MaxVersion)
 	}
+	if g.opts.VersionConstraint != nil {
+		res = res && g.opts.VersionConstraint.Check(version)
+	}
 	return res
 }
 
@@ -294,7 +301,7 @@ func (c *ChangelogNotes) Add(other *ChangelogNotes) {
 func (c *ChangelogNotes) AddWithDependentVersion(other *ChangelogNotes, depVersion Version) {
 	for header, notes := range other.Categories {
 		for _, note := range notes {
-			c.Categories[header] = append(c.Categories[header], &Note{note.Note, &depVersion})
+			c.Categories[header] = append(c.Categories[header], &Note{Note: note.Note, FromDependentVersion: &depVersion})
 		}
 	}
 }
@@ -302,7 +309,16 @@ func (c *ChangelogNotes) AddWithDependentVersion(other *ChangelogNotes, depVersi
 func (c *ChangelogNotes) AddWithDependentVersionIncludeExtraNotes(other *ChangelogNotes, depVersion Version) {
 	c.AddWithDependentVersion(other, depVersion)
 	for _, note := range other.ExtraNotes {
-		c.ExtraNotes = append(c.ExtraNotes, &Note{note.Note, &depVersion})
+		c.ExtraNotes = append(c.ExtraNotes, &Note{Note: note.Note, FromDependentVersion: &depVersion})
+	}
+}
+
+// Adds the notes of a release of another repo (owner/repo), attributed to the repo and the version
+func (c *ChangelogNotes) AddFromRepo(other *ChangelogNotes, repo string, version Version) {
+	for header, notes := range other.Categories {
+		for _, note := range notes {
+			c.Categories[header] = append(c.Categories[header], &Note{Note: note.Note, FromDependentVersion: &version, FromRepo: repo})
+		}
 	}
 }
 
@@ -310,6 +326,8 @@ type Note struct {
 	Note string
 	// Indicates which version of the dependent Repo that this note is from
 	FromDependentVersion *Version
+	// Indicates which dependent repo (owner/repo) this note is from, when there are several
+	FromRepo string
 }
 
 func (c *Note) MarshalJSON() ([]byte, error) {
@@ -325,10 +343,19 @@ func (c *Note) MarshalJSON() ([]byte, error) {
 		}
 		str += fmt.Sprintf(`, "FromDependentVersion":%s`, version)
 	}
+	if c.FromRepo != "" {
+		repo, err := json.Marshal(c.FromRepo)
+		if err != nil {
+			return nil, err
+		}
+		str += fmt.Sprintf(`, "FromRepo":%s`, repo)
+	}
 	str += "}"
 	return []byte(str), nil
 }
 
+// Parses the notes of a release body by section. See ParseStructuredReleaseBody for a parse that keeps the markdown
+// of each entry.
 func ParseReleaseBody(body string) ([]*Note, map[string][]*Note, error) {
 	var (
 		currentHeader string
@@ -386,9 +413,7 @@ func ParseReleaseBody(body string) ([]*Note, map[string][]*Note, error) {
 
 // Sorts a slice of versions in descending order by version e.g. v1.6.1, v1.6.0, v1.6.0-beta9
 func SortReleaseVersions(versions []Version) {
-	sort.Slice(versions, func(i, j int) bool {
-		return versions[i].MustIsGreaterThanOrEqualTo(versions[j])
-	})
+	SortVersions(versions, nil)
 }
 
 func GetMajorAndMinorVersion(v *Version) Version {
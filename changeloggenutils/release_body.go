@@ -0,0 +1,468 @@
+package changelogdocutils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/changelogutils"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/ast"
+	"github.com/yuin/goldmark/extension"
+	"github.com/yuin/goldmark/text"
+)
+
+const noUserFacingChanges = "This release contained no user-facing changes."
+
+type ReleaseBodyDriftKind string
+
+const (
+	DRIFT_SUMMARY          ReleaseBodyDriftKind = "summary"
+	DRIFT_CLOSING          ReleaseBodyDriftKind = "closing"
+	DRIFT_MISSING_ENTRY    ReleaseBodyDriftKind = "missing entry"
+	DRIFT_UNEXPECTED_ENTRY ReleaseBodyDriftKind = "unexpected entry"
+)
+
+var (
+	// The issue link that GenerateChangelogMarkdown appends to an entry, e.g. "Fixed it. (https://github.com/o/r/issues/1)"
+	entryIssueLinkRegex = regexp.MustCompile(`(?s)^(.*) \(([a-zA-Z][a-zA-Z0-9+.-]*://[^\s()]+)\)$`)
+	githubIssueURLRegex = regexp.MustCompile(`^https?://github\.com/([\w.-]+)/([\w.-]+)/(?:issues|pull)/(\d+)`)
+	issueReferenceRegex = regexp.MustCompile(`(?:\b([\w.-]+)/([\w.-]+))?#(\d+)\b`)
+	blankLineRegex      = regexp.MustCompile(`\n[ \t]*\n`)
+
+	ValidateReleaseBodyError = func(err error, tag string) error {
+		return eris.Wrapf(err, "unable to validate the release notes of %s", tag)
+	}
+)
+
+// A release body in the layout GenerateChangelogMarkdown renders: a summary, "**Heading**" sections of list entries,
+// and a closing. Unlike ParseReleaseBody, it keeps the whole markdown of each entry, so that it can be rendered back.
+type ReleaseBody struct {
+	// Markdown before the first section
+	Summary  string
+	Sections []*ReleaseBodySection
+	// Markdown after the entries of the last section
+	Closing string
+}
+
+type ReleaseBodySection struct {
+	Heading string
+	Entries []*ReleaseBodyEntry
+	// Markdown in the section other than its entries, which GenerateChangelogMarkdown doesn't render
+	Extra string
+}
+
+type ReleaseBodyEntry struct {
+	// Markdown of the entry's first paragraph, without its issue link
+	Description string
+	// The link GenerateChangelogMarkdown appends to the description in parentheses, if any
+	IssueLink string
+	// Links in the description, inline or bare
+	Links []*ReleaseBodyLink
+	// References to issues and PRs in the description, as links or as #123 or owner/repo#123
+	IssueRefs []*IssueRef
+	// Contents of the code spans in the description
+	Code []string
+	// Nested entries, e.g. the upstream releases under a dependency bump
+	Children []*ReleaseBodyEntry
+	// Markdown following the first paragraph, unindented, when it isn't only nested entries; e.g. the release
+	// notes of an upstream release
+	Body string
+}
+
+type ReleaseBodyLink struct {
+	Text string
+	URL  string
+}
+
+type IssueRef struct {
+	// Empty for a reference to an issue of the same repo, e.g. #123
+	Owner, Repo string
+	Number      int
+}
+
+func (r *IssueRef) String() string {
+	if r.Owner == "" {
+		return fmt.Sprintf("#%d", r.Number)
+	}
+	return fmt.Sprintf("%s/%s#%d", r.Owner, r.Repo, r.Number)
+}
+
+// The markdown of the entry's first paragraph, as GenerateChangelogMarkdown renders it
+func (e *ReleaseBodyEntry) Text() string {
+	if e.IssueLink == "" {
+		return e.Description
+	}
+	return e.Description + " (" + e.IssueLink + ")"
+}
+
+// Parses a release body into sections of entries. Rendering the result with Markdown gives back the body, if
+// GenerateChangelogMarkdown rendered it.
+func ParseStructuredReleaseBody(body string) *ReleaseBody {
+	src := []byte(strings.ReplaceAll(body, "\r\n", "\n"))
+	root := goldmark.New(goldmark.WithExtensions(extension.Linkify)).Parser().Parse(text.NewReader(src))
+
+	releaseBody := &ReleaseBody{}
+	var (
+		current *ReleaseBodySection
+		// where the markdown that isn't part of the current section's entries starts
+		end int
+	)
+	addExtra := func(to int) {
+		if extra := strings.TrimSpace(string(src[end:to])); extra != "" {
+			if current.Extra != "" {
+				current.Extra += "\n\n"
+			}
+			current.Extra += extra
+		}
+	}
+	for n := root.FirstChild(); n != nil; n = n.NextSibling() {
+		if heading, ok := sectionHeading(n, src); ok {
+			start := n.Lines().At(0).Start
+			if current == nil {
+				releaseBody.Summary = strings.TrimSpace(string(src[:start]))
+			} else {
+				addExtra(start)
+			}
+			current = &ReleaseBodySection{Heading: heading}
+			releaseBody.Sections = append(releaseBody.Sections, current)
+			end = blockEnd(n)
+			continue
+		}
+		list, ok := n.(*ast.List)
+		if current == nil || !ok {
+			continue
+		}
+		addExtra(lineStart(src, blockStart(list)))
+		for item := list.FirstChild(); item != nil; item = item.NextSibling() {
+			current.Entries = append(current.Entries, parseReleaseBodyEntry(item, src))
+		}
+		end = blockEnd(list)
+	}
+	if current == nil {
+		releaseBody.Summary = strings.TrimSpace(string(src))
+	} else {
+		releaseBody.Closing = strings.TrimSpace(string(src[end:]))
+	}
+	if len(releaseBody.Sections) == 0 && releaseBody.Summary == noUserFacingChanges {
+		releaseBody.Summary = ""
+	}
+	return releaseBody
+}
+
+// A paragraph that is only bold text, e.g. **New Features**
+func sectionHeading(n ast.Node, src []byte) (string, bool) {
+	paragraph, ok := n.(*ast.Paragraph)
+	if !ok || paragraph.ChildCount() != 1 {
+		return "", false
+	}
+	emphasis, ok := paragraph.FirstChild().(*ast.Emphasis)
+	if !ok || emphasis.Level != 2 {
+		return "", false
+	}
+	return string(paragraph.Text(src)), true
+}
+
+func parseReleaseBodyEntry(item ast.Node, src []byte) *ReleaseBodyEntry {
+	entry := &ReleaseBodyEntry{}
+	first := item.FirstChild()
+	if first == nil {
+		return entry
+	}
+	description := ""
+	if first.Lines().Len() > 0 {
+		description = string(src[first.Lines().At(0).Start:first.Lines().At(first.Lines().Len()-1).Stop])
+	}
+	if match := entryIssueLinkRegex.FindStringSubmatch(description); match != nil {
+		description, entry.IssueLink = match[1], match[2]
+	}
+	entry.Description = description
+	collectInlines(entry, first, src)
+
+	// nested entries directly follow the first paragraph; anything else, like an upstream release's notes, is a body
+	onlyLists := first.NextSibling() == nil || !blankLineRegex.Match(src[blockEnd(first):lineStart(src, blockStart(first.NextSibling()))])
+	for child := first.NextSibling(); child != nil; child = child.NextSibling() {
+		if _, ok := child.(*ast.List); !ok {
+			onlyLists = false
+		}
+	}
+	if first.NextSibling() == nil {
+		return entry
+	}
+	if onlyLists {
+		for list := first.NextSibling(); list != nil; list = list.NextSibling() {
+			for child := list.FirstChild(); child != nil; child = child.NextSibling() {
+				entry.Children = append(entry.Children, parseReleaseBodyEntry(child, src))
+			}
+		}
+		return entry
+	}
+	entry.Body = unindent(strings.TrimLeft(string(src[blockEnd(first):blockEnd(item)]), "\n"))
+	return entry
+}
+
+func collectInlines(entry *ReleaseBodyEntry, block ast.Node, src []byte) {
+	addRef := func(owner, repo, number string) {
+		n, err := strconv.Atoi(number)
+		if err != nil {
+			return
+		}
+		entry.IssueRefs = append(entry.IssueRefs, &IssueRef{Owner: owner, Repo: repo, Number: n})
+	}
+	addLink := func(linkText, url string) {
+		entry.Links = append(entry.Links, &ReleaseBodyLink{Text: linkText, URL: url})
+		if match := githubIssueURLRegex.FindStringSubmatch(url); match != nil {
+			addRef(match[1], match[2], match[3])
+		}
+	}
+	_ = ast.Walk(block, func(n ast.Node, entering bool) (ast.WalkStatus, error) {
+		if !entering {
+			return ast.WalkContinue, nil
+		}
+		switch typed := n.(type) {
+		case *ast.Link:
+			addLink(string(typed.Text(src)), string(typed.Destination))
+			return ast.WalkSkipChildren, nil
+		case *ast.AutoLink:
+			addLink(string(typed.Label(src)), string(typed.URL(src)))
+			return ast.WalkSkipChildren, nil
+		case *ast.CodeSpan:
+			entry.Code = append(entry.Code, string(typed.Text(src)))
+			return ast.WalkSkipChildren, nil
+		case *ast.Text:
+			for _, match := range issueReferenceRegex.FindAllStringSubmatch(string(typed.Segment.Value(src)), -1) {
+				addRef(match[1], match[2], match[3])
+			}
+		}
+		return ast.WalkContinue, nil
+	})
+}
+
+// The offset of the first line of a block or its first descendant block
+func blockStart(n ast.Node) int {
+	for ; n != nil; n = n.FirstChild() {
+		if n.Type() == ast.TypeBlock && n.Lines().Len() > 0 {
+			return n.Lines().At(0).Start
+		}
+	}
+	return 0
+}
+
+// The offset of the end of the last line of a block and its descendant blocks
+func blockEnd(n ast.Node) int {
+	end := 0
+	if lines := n.Lines(); n.Type() == ast.TypeBlock && lines.Len() > 0 {
+		end = lines.At(lines.Len() - 1).Stop
+	}
+	for child := n.FirstChild(); child != nil; child = child.NextSibling() {
+		if child.Type() != ast.TypeBlock {
+			continue
+		}
+		if childEnd := blockEnd(child); childEnd > end {
+			end = childEnd
+		}
+	}
+	return end
+}
+
+func lineStart(src []byte, offset int) int {
+	return bytes.LastIndexByte(src[:offset], '\n') + 1
+}
+
+// Removes the indentation common to every non-blank line, and trailing blank lines
+func unindent(markdown string) string {
+	lines := strings.Split(strings.TrimRight(markdown, " \n"), "\n")
+	indent := -1
+	for _, line := range lines {
+		if strings.TrimSpace(line) == "" {
+			continue
+		}
+		if lineIndent := len(line) - len(strings.TrimLeft(line, " ")); indent < 0 || lineIndent < indent {
+			indent = lineIndent
+		}
+	}
+	for i, line := range lines {
+		if len(line) >= indent && indent > 0 {
+			lines[i] = line[indent:]
+		} else if strings.TrimSpace(line) == "" {
+			lines[i] = ""
+		}
+	}
+	return strings.Join(lines, "\n")
+}
+
+// Renders the release body in the layout of GenerateChangelogMarkdown
+func (b *ReleaseBody) Markdown() string {
+	var out strings.Builder
+	if b.Summary != "" {
+		out.WriteString(b.Summary + "\n\n")
+	}
+	for _, section := range b.Sections {
+		if len(section.Entries) == 0 && section.Extra == "" {
+			continue
+		}
+		out.WriteString("**" + section.Heading + "**\n\n")
+		for _, entry := range section.Entries {
+			entry.render(&out, "")
+		}
+		out.WriteString("\n")
+		if section.Extra != "" {
+			out.WriteString(section.Extra + "\n\n")
+		}
+	}
+	if b.Closing != "" {
+		out.WriteString(b.Closing + "\n\n")
+	}
+	if out.Len() == 0 {
+		return noUserFacingChanges + "\n\n"
+	}
+	return out.String()
+}
+
+func (e *ReleaseBodyEntry) render(out *strings.Builder, indent string) {
+	out.WriteString(indent + "- " + e.Text() + "\n")
+	if e.Body != "" {
+		out.WriteString("\n")
+		for _, line := range strings.Split(e.Body, "\n") {
+			if strings.TrimSpace(line) == "" {
+				out.WriteString("\n")
+				continue
+			}
+			out.WriteString(indent + "  " + line + "\n")
+		}
+	}
+	// like the upstream releases under a dependency bump, nested entries with a body are separated by a blank line
+	separate := false
+	for _, child := range e.Children {
+		if separate {
+			out.WriteString("\n")
+		}
+		child.render(out, indent+"  ")
+		separate = child.Body != ""
+	}
+}
+
+// A difference between a release body and the release notes of its changelog
+type ReleaseBodyDrift struct {
+	Kind ReleaseBodyDriftKind
+	// The section of a missing or unexpected entry
+	Section string
+	// The markdown expected from the changelog and the markdown in the release body; Expected is empty for an
+	// unexpected entry and Actual is empty for a missing one.
+	Expected, Actual string
+}
+
+func (d *ReleaseBodyDrift) String() string {
+	switch d.Kind {
+	case DRIFT_MISSING_ENTRY:
+		return fmt.Sprintf("%s is missing %q", d.Section, d.Expected)
+	case DRIFT_UNEXPECTED_ENTRY:
+		return fmt.Sprintf("%s has %q, which is not in the changelog", d.Section, d.Actual)
+	default:
+		return fmt.Sprintf("the %s is %q instead of %q", d.Kind, d.Actual, d.Expected)
+	}
+}
+
+// Compares a release body with the one expected, ignoring differences in whitespace and the order of entries
+func CompareReleaseBodies(expected, actual *ReleaseBody) []*ReleaseBodyDrift {
+	var drifts []*ReleaseBodyDrift
+	if normalizeMarkdown(expected.Summary) != normalizeMarkdown(actual.Summary) {
+		drifts = append(drifts, &ReleaseBodyDrift{Kind: DRIFT_SUMMARY, Expected: expected.Summary, Actual: actual.Summary})
+	}
+
+	var headings []string
+	expectedEntries := map[string][]string{}
+	actualEntries := map[string][]string{}
+	for _, body := range []*ReleaseBody{expected, actual} {
+		for _, section := range body.Sections {
+			if _, ok := expectedEntries[section.Heading]; !ok {
+				if _, ok := actualEntries[section.Heading]; !ok {
+					headings = append(headings, section.Heading)
+				}
+			}
+			entries := actualEntries
+			if body == expected {
+				entries = expectedEntries
+			}
+			for _, entry := range section.Entries {
+				var out strings.Builder
+				entry.render(&out, "")
+				entries[section.Heading] = append(entries[section.Heading], strings.TrimSpace(out.String()))
+			}
+			if entries[section.Heading] == nil {
+				entries[section.Heading] = []string{}
+			}
+		}
+	}
+	for _, heading := range headings {
+		missing, unexpected := diffEntries(expectedEntries[heading], actualEntries[heading])
+		for _, entry := range missing {
+			drifts = append(drifts, &ReleaseBodyDrift{Kind: DRIFT_MISSING_ENTRY, Section: heading, Expected: entry})
+		}
+		for _, entry := range unexpected {
+			drifts = append(drifts, &ReleaseBodyDrift{Kind: DRIFT_UNEXPECTED_ENTRY, Section: heading, Actual: entry})
+		}
+	}
+
+	if normalizeMarkdown(expected.Closing) != normalizeMarkdown(actual.Closing) {
+		drifts = append(drifts, &ReleaseBodyDrift{Kind: DRIFT_CLOSING, Expected: expected.Closing, Actual: actual.Closing})
+	}
+	return drifts
+}
+
+// Returns the expected entries that are missing and the actual entries that aren't expected
+func diffEntries(expected, actual []string) ([]string, []string) {
+	remaining := map[string]int{}
+	for _, entry := range actual {
+		remaining[normalizeMarkdown(entry)]++
+	}
+	var missing []string
+	for _, entry := range expected {
+		if remaining[normalizeMarkdown(entry)] > 0 {
+			remaining[normalizeMarkdown(entry)]--
+			continue
+		}
+		missing = append(missing, entry)
+	}
+	var unexpected []string
+	for _, entry := range actual {
+		if remaining[normalizeMarkdown(entry)] > 0 {
+			remaining[normalizeMarkdown(entry)]--
+			unexpected = append(unexpected, entry)
+		}
+	}
+	return missing, unexpected
+}
+
+func normalizeMarkdown(markdown string) string {
+	return strings.Join(strings.Fields(markdown), " ")
+}
+
+// Reports how a release body drifted from the release notes that GenerateChangelogMarkdown renders for its changelog,
+// e.g. because the release was edited on Github or the changelog was changed after the release
+func ValidateReleaseBody(body string, changelog *changelogutils.Changelog) []*ReleaseBodyDrift {
+	expected := ParseStructuredReleaseBody(changelogutils.GenerateChangelogMarkdown(changelog))
+	return CompareReleaseBodies(expected, ParseStructuredReleaseBody(body))
+}
+
+// Validates the body of each release against the changelog of its tag, and returns the drifts by tag. Releases
+// that haven't drifted are omitted.
+func ValidateReleaseBodies(ctx context.Context, reader changelogutils.ChangelogReader, releases []*github.RepositoryRelease) (map[string][]*ReleaseBodyDrift, error) {
+	drifted := map[string][]*ReleaseBodyDrift{}
+	for _, release := range releases {
+		changelog, err := reader.GetChangelogForTag(ctx, release.GetTagName())
+		if err != nil {
+			return nil, ValidateReleaseBodyError(err, release.GetTagName())
+		}
+		drifts := ValidateReleaseBody(release.GetBody(), changelog)
+		if len(drifts) > 0 {
+			drifted[release.GetTagName()] = drifts
+		}
+	}
+	return drifted, nil
+}
@@ -0,0 +1,163 @@
+package changelogdocutils_test
+
+import (
+	"context"
+
+	"github.com/google/go-github/v32/github"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/solo-io/go-utils/changeloggenutils"
+	"github.com/solo-io/go-utils/changelogutils"
+)
+
+type fakeChangelogReader struct {
+	changelogutils.ChangelogReader
+	changelogs map[string]*changelogutils.Changelog
+}
+
+func (r *fakeChangelogReader) GetChangelogForTag(_ context.Context, tag string) (*changelogutils.Changelog, error) {
+	return r.changelogs[tag], nil
+}
+
+var _ = Describe("release bodies", func() {
+
+	var changelog *changelogutils.Changelog
+
+	BeforeEach(func() {
+		changelog = &changelogutils.Changelog{
+			Summary: "A summary with a [link](https://docs.solo.io).",
+			Closing: "Thanks to all contributors!",
+			Files: []*changelogutils.ChangelogFile{
+				{Entries: []*changelogutils.ChangelogEntry{
+					{Type: changelogutils.NEW_FEATURE, Description: "Added `--dry-run` to the CLI, see solo-io/gloo#12.", IssueLink: "https://github.com/solo-io/go-utils/issues/3"},
+					{Type: changelogutils.FIX, Description: "Fixed a crash\nwhen the config is empty.", IssueLink: "https://github.com/solo-io/go-utils/pull/4"},
+				}},
+				{Entries: []*changelogutils.ChangelogEntry{
+					{Type: changelogutils.FIX, Description: "Fixed #5, reported in https://example.com/report.", IssueLink: "https://github.com/solo-io/go-utils/issues/5"},
+					{Type: changelogutils.DEPENDENCY_BUMP, DependencyOwner: "solo-io", DependencyRepo: "gloo", DependencyTag: "v1.2.0"},
+					{Type: changelogutils.DEPENDENCY_BUMP, DependencyOwner: "solo-io", DependencyRepo: "solo-kit", DependencyTag: "v0.3.0"},
+				}},
+			},
+			DependencyNotes: []*changelogutils.DependencyNotes{
+				{
+					Owner: "solo-io", Repo: "gloo", FromTag: "v1.0.0", ToTag: "v1.2.0",
+					Mode: changelogutils.DependencyNotesInline,
+					Releases: []*changelogutils.UpstreamRelease{
+						{Tag: "v1.2.0", Body: "**Fixes**\n- gloo fix\n  more detail\n\n**New Features**\n- gloo feature"},
+						{Tag: "v1.1.0"},
+						{Tag: "v1.0.1", Body: "- only a list"},
+					},
+				},
+				{
+					Owner: "solo-io", Repo: "solo-kit", ToTag: "v0.3.0",
+					Mode: changelogutils.DependencyNotesSummary,
+					Releases: []*changelogutils.UpstreamRelease{
+						{Tag: "v0.3.0", Body: "**Breaking Changes**\n- removed it"},
+					},
+				},
+			},
+		}
+	})
+
+	It("parses and renders release bodies as GenerateChangelogMarkdown does", func() {
+		markdown := changelogutils.GenerateChangelogMarkdown(changelog)
+		body := ParseStructuredReleaseBody(markdown)
+		Expect(body.Markdown()).To(Equal(markdown))
+
+		Expect(body.Summary).To(Equal(changelog.Summary))
+		Expect(body.Closing).To(Equal(changelog.Closing))
+		var headings []string
+		for _, section := range body.Sections {
+			headings = append(headings, section.Heading)
+		}
+		Expect(headings).To(Equal([]string{"Dependency Bumps", "New Features", "Fixes"}))
+
+		feature := body.Sections[1].Entries[0]
+		Expect(feature.Description).To(Equal("Added `--dry-run` to the CLI, see solo-io/gloo#12."))
+		Expect(feature.IssueLink).To(Equal("https://github.com/solo-io/go-utils/issues/3"))
+		Expect(feature.Code).To(Equal([]string{"--dry-run"}))
+		Expect(feature.IssueRefs).To(Equal([]*IssueRef{
+			{Owner: "solo-io", Repo: "gloo", Number: 12},
+			{Owner: "solo-io", Repo: "go-utils", Number: 3},
+		}))
+
+		fixes := body.Sections[2].Entries
+		Expect(fixes).To(HaveLen(2))
+		Expect(fixes[0].Description).To(Equal("Fixed a crash\nwhen the config is empty."))
+		Expect(fixes[1].Links).To(Equal([]*ReleaseBodyLink{
+			{Text: "https://example.com/report", URL: "https://example.com/report"},
+			{Text: "https://github.com/solo-io/go-utils/issues/5", URL: "https://github.com/solo-io/go-utils/issues/5"},
+		}))
+		Expect(fixes[1].IssueRefs[0].String()).To(Equal("#5"))
+
+		bumps := body.Sections[0].Entries
+		Expect(bumps[0].Text()).To(Equal("solo-io/gloo has been upgraded to v1.2.0."))
+		Expect(bumps[0].Children).To(HaveLen(3))
+		Expect(bumps[0].Children[0].Description).To(Equal("v1.2.0"))
+		Expect(bumps[0].Children[0].Body).To(Equal("**Fixes**\n- gloo fix\n  more detail\n\n**New Features**\n- gloo feature"))
+		Expect(bumps[0].Children[1].Body).To(BeEmpty())
+		Expect(bumps[0].Children[2].Body).To(Equal("- only a list"))
+		Expect(bumps[1].Children).To(HaveLen(1))
+		Expect(bumps[1].Children[0].Description).To(Equal("v0.3.0: 1 Breaking Changes; breaking: removed it"))
+	})
+
+	It("round trips release bodies without sections", func() {
+		for _, changelog := range []*changelogutils.Changelog{
+			{},
+			{Summary: "Only a summary.\n\nIn two paragraphs."},
+		} {
+			markdown := changelogutils.GenerateChangelogMarkdown(changelog)
+			body := ParseStructuredReleaseBody(markdown)
+			Expect(body.Sections).To(BeEmpty())
+			Expect(body.Summary).To(Equal(changelog.Summary))
+			Expect(body.Markdown()).To(Equal(markdown))
+		}
+	})
+
+	It("reports how a release body drifted from its changelog", func() {
+		body := changelogutils.GenerateChangelogMarkdown(changelog)
+		drifts := ValidateReleaseBody(body, changelog)
+		Expect(drifts).To(BeEmpty())
+
+		// whitespace and the order of entries don't matter
+		parsed := ParseStructuredReleaseBody(body)
+		fixes := parsed.Sections[2].Entries
+		fixes[0], fixes[1] = fixes[1], fixes[0]
+		fixes[0].Description = "Fixed   #5, reported in https://example.com/report."
+		drifts = ValidateReleaseBody(parsed.Markdown(), changelog)
+		Expect(drifts).To(BeEmpty())
+
+		// the release was edited on Github
+		fixes[0].Description = "Fixed #6."
+		parsed.Closing = ""
+		parsed.Sections = append(parsed.Sections, &ReleaseBodySection{
+			Heading: "Upgrade Notes",
+			Entries: []*ReleaseBodyEntry{{Description: "Run the migration first."}},
+		})
+		drifts = ValidateReleaseBody(parsed.Markdown(), changelog)
+		Expect(drifts).To(Equal([]*ReleaseBodyDrift{
+			{Kind: DRIFT_MISSING_ENTRY, Section: "Fixes", Expected: "- Fixed #5, reported in https://example.com/report. (https://github.com/solo-io/go-utils/issues/5)"},
+			{Kind: DRIFT_UNEXPECTED_ENTRY, Section: "Fixes", Actual: "- Fixed #6. (https://github.com/solo-io/go-utils/issues/5)"},
+			{Kind: DRIFT_UNEXPECTED_ENTRY, Section: "Upgrade Notes", Actual: "- Run the migration first."},
+			{Kind: DRIFT_CLOSING, Expected: "Thanks to all contributors!"},
+		}))
+		Expect(drifts[2].String()).To(Equal(`Upgrade Notes has "- Run the migration first.", which is not in the changelog`))
+		Expect(drifts[3].String()).To(Equal(`the closing is "" instead of "Thanks to all contributors!"`))
+	})
+
+	It("validates releases against the changelogs of their tags", func() {
+		edited := &changelogutils.Changelog{Summary: "Edited."}
+		reader := &fakeChangelogReader{changelogs: map[string]*changelogutils.Changelog{
+			"v1.0.0": changelog,
+			"v1.0.1": edited,
+		}}
+		drifted, err := ValidateReleaseBodies(context.Background(), reader, []*github.RepositoryRelease{
+			{TagName: github.String("v1.0.0"), Body: github.String(changelogutils.GenerateChangelogMarkdown(changelog))},
+			{TagName: github.String("v1.0.1"), Body: github.String("Original.\n\n")},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(drifted).To(Equal(map[string][]*ReleaseBodyDrift{
+			"v1.0.1": {{Kind: DRIFT_SUMMARY, Expected: "Edited.", Actual: "Original."}},
+		}))
+	})
+})
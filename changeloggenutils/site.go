@@ -0,0 +1,509 @@
+package changelogdocutils
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"html/template"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/changelogutils"
+	. "github.com/solo-io/go-utils/versionutils"
+	"github.com/spf13/afero"
+	"github.com/yuin/goldmark"
+	"github.com/yuin/goldmark/text"
+)
+
+type SiteFormat string
+
+const (
+	// A standalone static site: index.html, and a page per minor version, e.g. v1.8.html
+	SiteFormatHTML SiteFormat = "html"
+	// A Hugo content section: _index.md, and a page per minor version, e.g. v1.8.md
+	SiteFormatHugo SiteFormat = "hugo"
+
+	// Written next to the pages, it lists every note with the URL of its version
+	SearchIndexFile = "search-index.json"
+
+	defaultSiteTitle       = "Changelog"
+	defaultBreakingHeading = "Breaking Changes"
+)
+
+var (
+	NilReleaseDataError    = eris.New("cannot render a changelog site without release data")
+	UnknownSiteFormatError = func(format SiteFormat) error {
+		return eris.Errorf("unknown changelog site format %q, expected %q or %q", format, SiteFormatHTML, SiteFormatHugo)
+	}
+	WriteSiteError = func(err error, path string) error {
+		return eris.Wrapf(err, "unable to write changelog site file %s", path)
+	}
+)
+
+type SiteOptions struct {
+	// Defaults to SiteFormatHTML
+	Format SiteFormat
+	// Title of the index page, defaults to "Changelog"
+	Title string
+	// Github user/org and repo of the releases, to link each version to its Github release
+	RepoOwner, MainRepo string
+	// Repo (of RepoOwner) that merged notes without a FromRepo are from, like those merged by MergeEnterpriseReleaseWithOS
+	DependentRepo string
+	// The order of the categories of each version, defaults to the built-in entry types. Categories that aren't
+	// entry type headings follow, alphabetically.
+	EntryTypes *changelogutils.EntryTypes
+	// Categories highlighted as breaking changes, defaults to "Breaking Changes"
+	BreakingCategories []string
+	// Prefix of the page URLs in the search index, e.g. "/changelog/"
+	BaseURL string
+}
+
+// Renders ReleaseData, from either generator, as a static site or a Hugo content section, so that the changelog
+// docs don't need a frontend for the generated JSON.
+//
+// Each minor version has a page with an anchor per version. Breaking changes are listed first in each version and
+// highlighted, and the index marks the versions with breaking changes. Notes merged from a dependency link to the
+// dependency's release.
+type SiteRenderer struct {
+	fs   afero.Fs
+	opts SiteOptions
+}
+
+func NewSiteRenderer(opts SiteOptions) *SiteRenderer {
+	return NewSiteRendererForFs(afero.NewOsFs(), opts)
+}
+
+func NewSiteRendererForFs(fs afero.Fs, opts SiteOptions) *SiteRenderer {
+	if opts.Format == "" {
+		opts.Format = SiteFormatHTML
+	}
+	if opts.Title == "" {
+		opts.Title = defaultSiteTitle
+	}
+	if len(opts.BreakingCategories) == 0 {
+		opts.BreakingCategories = []string{defaultBreakingHeading}
+	}
+	return &SiteRenderer{
+		fs:   fs,
+		opts: opts,
+	}
+}
+
+// An entry of the search index
+type SearchIndexEntry struct {
+	Version  string `json:"version"`
+	Minor    string `json:"minor"`
+	Category string `json:"category,omitempty"`
+	// The note as plain text
+	Note     string `json:"note"`
+	URL      string `json:"url"`
+	Breaking bool   `json:"breaking,omitempty"`
+	// The dependency (owner/repo) and version that a merged note is from
+	FromRepo    string `json:"fromRepo,omitempty"`
+	FromVersion string `json:"fromVersion,omitempty"`
+}
+
+type sitePage struct {
+	Minor    string
+	File     string
+	URL      string
+	Weight   int
+	Versions []*siteVersion
+}
+
+type siteVersion struct {
+	Version string
+	Anchor  string
+	// The release date, the link to the Github release, and the dependencies of a merged release, in markdown
+	Details    string
+	Breaking   bool
+	ExtraNotes []*siteNote
+	Categories []*siteCategory
+}
+
+type siteCategory struct {
+	Heading  string
+	Breaking bool
+	Notes    []*siteNote
+}
+
+type siteNote struct {
+	// The note, followed by its attribution if it was merged from a dependency
+	Markdown    string
+	FromRepo    string
+	FromVersion string
+}
+
+// Writes the site into dir, overwriting the files of a previous render
+func (r *SiteRenderer) Render(dir string, data *ReleaseData) error {
+	if data == nil {
+		return NilReleaseDataError
+	}
+	if r.opts.Format != SiteFormatHTML && r.opts.Format != SiteFormatHugo {
+		return UnknownSiteFormatError(r.opts.Format)
+	}
+	pages := r.sitePages(data)
+
+	var err error
+	files := map[string][]byte{}
+	for _, page := range pages {
+		if files[page.File], err = r.renderPage(page); err != nil {
+			return err
+		}
+	}
+	index := "index.html"
+	if r.opts.Format == SiteFormatHugo {
+		index = "_index.md"
+	}
+	if files[index], err = r.renderIndex(pages); err != nil {
+		return err
+	}
+	if files[SearchIndexFile], err = json.MarshalIndent(r.searchIndex(pages), "", "  "); err != nil {
+		return err
+	}
+
+	if err := r.fs.MkdirAll(dir, 0755); err != nil {
+		return WriteSiteError(err, dir)
+	}
+	var names []string
+	for name := range files {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	for _, name := range names {
+		path := filepath.Join(dir, name)
+		if err := afero.WriteFile(r.fs, path, files[name], 0644); err != nil {
+			return WriteSiteError(err, path)
+		}
+	}
+	return nil
+}
+
+// Builds a page per minor version, from the greatest to the least
+func (r *SiteRenderer) sitePages(data *ReleaseData) []*sitePage {
+	var minors []Version
+	for minor := range data.Releases {
+		minors = append(minors, minor)
+	}
+	SortReleaseVersions(minors)
+
+	var pages []*sitePage
+	for i, minor := range minors {
+		name := fmt.Sprintf("v%d.%d", minor.Major, minor.Minor)
+		page := &sitePage{
+			Minor:  name,
+			Weight: i + 1,
+		}
+		if r.opts.Format == SiteFormatHugo {
+			page.File, page.URL = name+".md", name+"/"
+		} else {
+			page.File, page.URL = name+".html", name+".html"
+		}
+		var versions []Version
+		for version := range data.Releases[minor].ChangelogNotes {
+			versions = append(versions, version)
+		}
+		SortReleaseVersions(versions)
+		for _, version := range versions {
+			if notes := data.Releases[minor].ChangelogNotes[version]; notes != nil {
+				page.Versions = append(page.Versions, r.siteVersion(version, notes))
+			}
+		}
+		pages = append(pages, page)
+	}
+	return pages
+}
+
+func (r *SiteRenderer) siteVersion(version Version, notes *ChangelogNotes) *siteVersion {
+	v := &siteVersion{
+		Version: version.String(),
+		Anchor:  versionAnchor(version),
+	}
+	var details []string
+	if notes.CreatedAt > 0 {
+		details = append(details, "Released "+time.Unix(notes.CreatedAt, 0).UTC().Format("2006-01-02"))
+	}
+	if r.opts.RepoOwner != "" && r.opts.MainRepo != "" {
+		details = append(details, fmt.Sprintf("[Github release](%s)", githubReleaseURL(r.opts.RepoOwner, r.opts.MainRepo, v.Version)))
+	}
+	// e.g. " (Uses OSS [v1.8.2](...))"
+	if uses := strings.TrimSpace(notes.HeaderSuffix); uses != "" {
+		details = append(details, strings.TrimSuffix(strings.TrimPrefix(uses, "("), ")"))
+	}
+	v.Details = strings.Join(details, " · ")
+	for _, note := range notes.ExtraNotes {
+		v.ExtraNotes = append(v.ExtraNotes, r.siteNote(note))
+	}
+	for _, heading := range r.categoryOrder(notes.Categories) {
+		category := &siteCategory{
+			Heading:  heading,
+			Breaking: r.isBreaking(heading),
+		}
+		for _, note := range notes.Categories[heading] {
+			category.Notes = append(category.Notes, r.siteNote(note))
+		}
+		v.Breaking = v.Breaking || category.Breaking
+		v.Categories = append(v.Categories, category)
+	}
+	return v
+}
+
+func (r *SiteRenderer) siteNote(note *Note) *siteNote {
+	n := &siteNote{Markdown: strings.TrimSpace(note.Note)}
+	if note.FromDependentVersion == nil {
+		return n
+	}
+	n.FromVersion = note.FromDependentVersion.String()
+	n.FromRepo = note.FromRepo
+	if n.FromRepo == "" && r.opts.DependentRepo != "" {
+		n.FromRepo = r.opts.RepoOwner + "/" + r.opts.DependentRepo
+	}
+	if n.FromRepo == "" {
+		n.Markdown += fmt.Sprintf(" (from %s)", n.FromVersion)
+		return n
+	}
+	owner, repo := splitRepo(n.FromRepo)
+	n.Markdown += fmt.Sprintf(" (from %s %s)", n.FromRepo, getGithubReleaseMarkdownLink(n.FromVersion, owner, repo))
+	return n
+}
+
+// Breaking categories first, then the entry type headings in order, then the remaining categories alphabetically
+func (r *SiteRenderer) categoryOrder(categories map[string][]*Note) []string {
+	rank := map[string]int{}
+	for i, heading := range r.opts.BreakingCategories {
+		rank[heading] = i - len(r.opts.BreakingCategories)
+	}
+	for i, section := range r.opts.EntryTypes.Sections() {
+		if _, ok := rank[section.Heading]; !ok {
+			rank[section.Heading] = i + 1
+		}
+	}
+	var headings []string
+	for heading, notes := range categories {
+		if len(notes) > 0 {
+			headings = append(headings, heading)
+		}
+	}
+	sort.Slice(headings, func(i, j int) bool {
+		ri, rj := rank[headings[i]], rank[headings[j]]
+		if ri == 0 || rj == 0 {
+			if ri == rj {
+				return headings[i] < headings[j]
+			}
+			return rj == 0
+		}
+		return ri < rj
+	})
+	return headings
+}
+
+func (r *SiteRenderer) isBreaking(heading string) bool {
+	for _, breaking := range r.opts.BreakingCategories {
+		if strings.EqualFold(heading, breaking) {
+			return true
+		}
+	}
+	return false
+}
+
+func (r *SiteRenderer) searchIndex(pages []*sitePage) []*SearchIndexEntry {
+	entries := []*SearchIndexEntry{}
+	for _, page := range pages {
+		for _, version := range page.Versions {
+			url := r.opts.BaseURL + page.URL + "#" + version.Anchor
+			add := func(category string, breaking bool, note *siteNote) {
+				entries = append(entries, &SearchIndexEntry{
+					Version:     version.Version,
+					Minor:       page.Minor,
+					Category:    category,
+					Note:        markdownText(note.Markdown),
+					URL:         url,
+					Breaking:    breaking,
+					FromRepo:    note.FromRepo,
+					FromVersion: note.FromVersion,
+				})
+			}
+			for _, note := range version.ExtraNotes {
+				add("", false, note)
+			}
+			for _, category := range version.Categories {
+				for _, note := range category.Notes {
+					add(category.Heading, category.Breaking, note)
+				}
+			}
+		}
+	}
+	return entries
+}
+
+func (r *SiteRenderer) renderPage(page *sitePage) ([]byte, error) {
+	if r.opts.Format == SiteFormatHTML {
+		return executeSiteTemplate(sitePageTmpl, struct {
+			Title string
+			Page  *sitePage
+		}{r.opts.Title, page})
+	}
+
+	var b strings.Builder
+	writeHugoFrontMatter(&b, page.Minor, page.Weight)
+	for _, version := range page.Versions {
+		b.WriteString(fmt.Sprintf("## %s {#%s}\n\n", version.Version, version.Anchor))
+		if version.Details != "" {
+			b.WriteString("*" + version.Details + "*\n\n")
+		}
+		for _, note := range version.ExtraNotes {
+			b.WriteString(note.Markdown + "\n\n")
+		}
+		for _, category := range version.Categories {
+			prefix := ""
+			if category.Breaking {
+				// breaking changes are quoted, so that they stand out
+				prefix = "> "
+				b.WriteString("> **" + category.Heading + "**\n>\n")
+			} else {
+				b.WriteString("**" + category.Heading + "**\n\n")
+			}
+			for _, note := range category.Notes {
+				b.WriteString(prefix + "- " + note.Markdown + "\n")
+			}
+			b.WriteString("\n")
+		}
+	}
+	return []byte(b.String()), nil
+}
+
+func (r *SiteRenderer) renderIndex(pages []*sitePage) ([]byte, error) {
+	if r.opts.Format == SiteFormatHTML {
+		return executeSiteTemplate(siteIndexTmpl, struct {
+			Title string
+			Pages []*sitePage
+		}{r.opts.Title, pages})
+	}
+
+	var b strings.Builder
+	writeHugoFrontMatter(&b, r.opts.Title, 0)
+	for _, page := range pages {
+		b.WriteString(fmt.Sprintf("## [%s](%s)\n\n", page.Minor, page.URL))
+		for _, version := range page.Versions {
+			b.WriteString(fmt.Sprintf("- [%s](%s#%s)", version.Version, page.URL, version.Anchor))
+			if version.Breaking {
+				b.WriteString(" (breaking changes)")
+			}
+			b.WriteString("\n")
+		}
+		b.WriteString("\n")
+	}
+	return []byte(b.String()), nil
+}
+
+func writeHugoFrontMatter(b *strings.Builder, title string, weight int) {
+	b.WriteString("---\n")
+	b.WriteString(fmt.Sprintf("title: %q\n", title))
+	if weight > 0 {
+		b.WriteString(fmt.Sprintf("weight: %d\n", weight))
+	}
+	b.WriteString("---\n\n")
+}
+
+// e.g. v1.8.0-beta1 -> v1-8-0-beta1
+func versionAnchor(version Version) string {
+	return strings.ReplaceAll(version.String(), ".", "-")
+}
+
+func githubReleaseURL(owner, repo, tag string) string {
+	return fmt.Sprintf("https://github.com/%s/%s/releases/tag/%s", owner, repo, tag)
+}
+
+func splitRepo(ownerRepo string) (string, string) {
+	parts := strings.SplitN(ownerRepo, "/", 2)
+	if len(parts) == 1 {
+		return "", parts[0]
+	}
+	return parts[0], parts[1]
+}
+
+// The text of a markdown note, without its markup
+func markdownText(markdown string) string {
+	src := []byte(markdown)
+	root := goldmark.DefaultParser().Parse(text.NewReader(src))
+	return strings.Join(strings.Fields(string(root.Text(src))), " ")
+}
+
+// Renders a markdown note as HTML. Raw HTML in the note is omitted.
+func markdownHTML(markdown string) (template.HTML, error) {
+	var b bytes.Buffer
+	if err := goldmark.Convert([]byte(markdown), &b); err != nil {
+		return "", err
+	}
+	html := strings.TrimSpace(b.String())
+	// a single paragraph is rendered inline, e.g. in a list item
+	if strings.HasPrefix(html, "<p>") && strings.HasSuffix(html, "</p>") && strings.Count(html, "<p>") == 1 {
+		html = strings.TrimSuffix(strings.TrimPrefix(html, "<p>"), "</p>")
+	}
+	return template.HTML(html), nil
+}
+
+func executeSiteTemplate(tmpl *template.Template, data interface{}) ([]byte, error) {
+	var b bytes.Buffer
+	if err := tmpl.Execute(&b, data); err != nil {
+		return nil, err
+	}
+	return b.Bytes(), nil
+}
+
+var siteTemplateFuncs = template.FuncMap{"markdown": markdownHTML}
+
+const siteHeadTmpl = `<!DOCTYPE html>
+<html lang="en">
+<head>
+<meta charset="utf-8">
+<title>{{ .Title }}</title>
+<style>
+body { font-family: sans-serif; max-width: 60em; margin: 0 auto; padding: 1em; }
+.details { color: #666; font-style: italic; }
+.breaking { border-left: 4px solid #d73a49; background: #fff5f5; padding: 0.5em 1em; }
+.breaking-badge { color: #d73a49; font-weight: bold; }
+</style>
+</head>
+<body>
+`
+
+var siteIndexTmpl = template.Must(template.New("index").Funcs(siteTemplateFuncs).Parse(siteHeadTmpl + `<h1>{{ .Title }}</h1>
+{{ range $page := .Pages -}}
+<h2><a href="{{ $page.URL }}">{{ $page.Minor }}</a></h2>
+<ul>
+{{ range $version := $page.Versions }}<li><a href="{{ $page.URL }}#{{ $version.Anchor }}">{{ $version.Version }}</a>{{ if $version.Breaking }} <span class="breaking-badge">breaking changes</span>{{ end }}</li>
+{{ end -}}
+</ul>
+{{ end -}}
+<p><a href="` + SearchIndexFile + `">Search index</a></p>
+</body>
+</html>
+`))
+
+var sitePageTmpl = template.Must(template.New("page").Funcs(siteTemplateFuncs).Parse(siteHeadTmpl + `<p><a href="index.html">{{ .Title }}</a></p>
+<h1>{{ .Page.Minor }}</h1>
+{{ range .Page.Versions -}}
+<section id="{{ .Anchor }}">
+<h2><a href="#{{ .Anchor }}">{{ .Version }}</a></h2>
+{{ if .Details }}<p class="details">{{ markdown .Details }}</p>
+{{ end -}}
+{{ range .ExtraNotes }}<p>{{ markdown .Markdown }}</p>
+{{ end -}}
+{{ range .Categories -}}
+<div{{ if .Breaking }} class="breaking"{{ end }}>
+<h3>{{ .Heading }}</h3>
+<ul>
+{{ range .Notes }}<li>{{ markdown .Markdown }}</li>
+{{ end -}}
+</ul>
+</div>
+{{ end -}}
+</section>
+{{ end -}}
+</body>
+</html>
+`))
@@ -0,0 +1,170 @@
+package changelogdocutils_test
+
+import (
+	"encoding/json"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	. "github.com/solo-io/go-utils/changeloggenutils"
+	. "github.com/solo-io/go-utils/versionutils"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("SiteRenderer", func() {
+
+	var (
+		fs          afero.Fs
+		releaseData *ReleaseData
+	)
+
+	readFile := func(path string) string {
+		contents, err := afero.ReadFile(fs, path)
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	BeforeEach(func() {
+		fs = afero.NewMemMapFs()
+		released := time.Date(2021, 7, 1, 12, 0, 0, 0, time.UTC).Unix()
+		releaseData = &ReleaseData{Releases: map[Version]*VersionData{
+			*MustParseVersion("v1.8.0"): {ChangelogNotes: map[Version]*ChangelogNotes{
+				*MustParseVersion("v1.8.1"): {
+					Categories: map[string][]*Note{
+						"Fixes":            {{Note: "Fixed the `gateway` <script>crash</script>."}},
+						"Breaking Changes": {{Note: "Removed the foo flag."}},
+						"Custom":           {{Note: "Something else."}},
+					},
+					HeaderSuffix: " (Uses OSS [v1.8.3](https://github.com/solo-io/gloo/releases/tag/v1.8.3))",
+					CreatedAt:    released,
+				},
+				*MustParseVersion("v1.8.0"): {
+					Categories: map[string][]*Note{
+						"New Features": {
+							{Note: "A feature."},
+							{Note: "An OSS feature.", FromDependentVersion: MustParseVersion("v1.8.2")},
+							{Note: "An Envoy feature.", FromDependentVersion: MustParseVersion("v1.19.0"), FromRepo: "envoyproxy/envoy"},
+						},
+					},
+					ExtraNotes: []*Note{{Note: "This release build failed."}},
+				},
+			}},
+			*MustParseVersion("v1.7.0"): {ChangelogNotes: map[Version]*ChangelogNotes{
+				*MustParseVersion("v1.7.0-beta1"): {
+					Categories: map[string][]*Note{"Fixes": {{Note: "An old fix."}}},
+				},
+			}},
+		}}
+	})
+
+	It("renders a Hugo content section", func() {
+		renderer := NewSiteRendererForFs(fs, SiteOptions{
+			Format:        SiteFormatHugo,
+			RepoOwner:     "solo-io",
+			MainRepo:      "solo-projects",
+			DependentRepo: "gloo",
+			BaseURL:       "/changelog/",
+		})
+		Expect(renderer.Render("/site", releaseData)).To(Succeed())
+
+		Expect(readFile("/site/_index.md")).To(Equal(`---
+title: "Changelog"
+---
+
+## [v1.8](v1.8/)
+
+- [v1.8.1](v1.8/#v1-8-1) (breaking changes)
+- [v1.8.0](v1.8/#v1-8-0)
+
+## [v1.7](v1.7/)
+
+- [v1.7.0-beta1](v1.7/#v1-7-0-beta1)
+
+`))
+		Expect(readFile("/site/v1.8.md")).To(Equal(`---
+title: "v1.8"
+weight: 1
+---
+
+## v1.8.1 {#v1-8-1}
+
+*Released 2021-07-01 · [Github release](https://github.com/solo-io/solo-projects/releases/tag/v1.8.1) · Uses OSS [v1.8.3](https://github.com/solo-io/gloo/releases/tag/v1.8.3)*
+
+> **Breaking Changes**
+>
+> - Removed the foo flag.
+
+**Fixes**
+
+- Fixed the ` + "`gateway`" + ` <script>crash</script>.
+
+**Custom**
+
+- Something else.
+
+## v1.8.0 {#v1-8-0}
+
+*[Github release](https://github.com/solo-io/solo-projects/releases/tag/v1.8.0)*
+
+This release build failed.
+
+**New Features**
+
+- A feature.
+- An OSS feature. (from solo-io/gloo [v1.8.2](https://github.com/solo-io/gloo/releases/tag/v1.8.2))
+- An Envoy feature. (from envoyproxy/envoy [v1.19.0](https://github.com/envoyproxy/envoy/releases/tag/v1.19.0))
+
+`))
+		Expect(readFile("/site/v1.7.md")).To(ContainSubstring("weight: 2\n"))
+
+		var index []*SearchIndexEntry
+		Expect(json.Unmarshal([]byte(readFile("/site/"+SearchIndexFile)), &index)).To(Succeed())
+		Expect(index).To(HaveLen(8))
+		Expect(index[0]).To(Equal(&SearchIndexEntry{
+			Version:  "v1.8.1",
+			Minor:    "v1.8",
+			Category: "Breaking Changes",
+			Note:     "Removed the foo flag.",
+			URL:      "/changelog/v1.8/#v1-8-1",
+			Breaking: true,
+		}))
+		Expect(index[1].Note).To(Equal("Fixed the gateway crash."))
+		Expect(index[3]).To(Equal(&SearchIndexEntry{
+			Version: "v1.8.0",
+			Minor:   "v1.8",
+			Note:    "This release build failed.",
+			URL:     "/changelog/v1.8/#v1-8-0",
+		}))
+		Expect(index[5].FromRepo).To(Equal("solo-io/gloo"))
+		Expect(index[5].FromVersion).To(Equal("v1.8.2"))
+		Expect(index[6].Note).To(Equal("An Envoy feature. (from envoyproxy/envoy v1.19.0)"))
+		Expect(index[7].URL).To(Equal("/changelog/v1.7/#v1-7-0-beta1"))
+	})
+
+	It("renders a static HTML site", func() {
+		Expect(NewSiteRendererForFs(fs, SiteOptions{Title: "Gloo Changelog"}).Render("/site", releaseData)).To(Succeed())
+
+		index := readFile("/site/index.html")
+		Expect(index).To(ContainSubstring("<title>Gloo Changelog</title>"))
+		Expect(index).To(ContainSubstring(`<li><a href="v1.8.html#v1-8-1">v1.8.1</a> <span class="breaking-badge">breaking changes</span></li>`))
+		Expect(index).To(ContainSubstring(`<li><a href="v1.7.html#v1-7-0-beta1">v1.7.0-beta1</a></li>`))
+
+		page := readFile("/site/v1.8.html")
+		Expect(page).To(ContainSubstring(`<section id="v1-8-1">`))
+		Expect(page).To(ContainSubstring(`<p class="details">Released 2021-07-01 · Uses OSS <a href="https://github.com/solo-io/gloo/releases/tag/v1.8.3">v1.8.3</a></p>`))
+		Expect(page).To(ContainSubstring("<div class=\"breaking\">\n<h3>Breaking Changes</h3>\n<ul>\n<li>Removed the foo flag.</li>"))
+		// raw HTML in notes is omitted
+		Expect(page).To(ContainSubstring("<li>Fixed the <code>gateway</code> <!-- raw HTML omitted -->crash<!-- raw HTML omitted -->.</li>"))
+		Expect(page).To(ContainSubstring("<li>An OSS feature. (from v1.8.2)</li>"))
+		Expect(page).NotTo(ContainSubstring("<script>"))
+
+		Expect(readFile("/site/v1.7.html")).To(ContainSubstring(`<section id="v1-7-0-beta1">`))
+		Expect(readFile("/site/" + SearchIndexFile)).To(ContainSubstring(`"url": "v1.7.html#v1-7-0-beta1"`))
+	})
+
+	It("rejects unknown formats", func() {
+		err := NewSiteRendererForFs(fs, SiteOptions{Format: "pdf"}).Render("/site", releaseData)
+		Expect(err).To(MatchError(UnknownSiteFormatError("pdf")))
+		Expect(NewSiteRendererForFs(fs, SiteOptions{}).Render("/site", nil)).To(MatchError(NilReleaseDataError))
+	})
+})
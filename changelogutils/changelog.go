@@ -2,6 +2,7 @@ package changelogutils
 
 import (
 	"context"
+	"encoding/json"
 	"fmt"
 	"path/filepath"
 	"sort"
@@ -24,6 +25,52 @@ type ChangelogEntry struct {
 	DependencyRepo  string             `json:"dependencyRepo,omitempty"`
 	DependencyTag   string             `json:"dependencyTag,omitempty"`
 	ResolvesIssue   *bool              `json:"resolvesIssue,omitempty"`
+
+	// the name of a type that isn't built in, as written in the changelog file
+	typeName string
+}
+
+// Returns the name of the type of the entry, as written in the changelog file
+func (c *ChangelogEntry) TypeName() string {
+	if c.typeName != "" {
+		return c.typeName
+	}
+	return c.Type.String()
+}
+
+type changelogEntryFields ChangelogEntry
+
+func (c ChangelogEntry) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		*changelogEntryFields
+	}{
+		Type:                 c.TypeName(),
+		changelogEntryFields: (*changelogEntryFields)(&c),
+	})
+}
+
+// Entries of a type that isn't built in are read with an undeclared type, until they are resolved against the
+// custom entry types of the repo
+func (c *ChangelogEntry) UnmarshalJSON(data []byte) error {
+	entry := struct {
+		Type string `json:"type"`
+		*changelogEntryFields
+	}{
+		changelogEntryFields: (*changelogEntryFields)(c),
+	}
+	if err := json.Unmarshal(data, &entry); err != nil {
+		return err
+	}
+	c.Type, c.typeName = undeclaredEntryType, ""
+	if entryType, err := ParseChangelogEntryType(entry.Type); err == nil {
+		c.Type = entryType
+	} else if customEntryTypeNameRegex.MatchString(entry.Type) {
+		c.typeName = entry.Type
+	} else {
+		return err
+	}
+	return nil
 }
 
 func (c *ChangelogEntry) GetResolvesIssue() bool {
@@ -59,6 +106,10 @@ type Changelog struct {
 	Summary string
 	Version *versionutils.Version
 	Closing string
+	// The entry types used to render the changelog. Nil unless the repo declares custom entry types.
+	EntryTypes *EntryTypes
+	// Upstream release notes rendered under the dependency bumps, see AddDependencyNotes
+	DependencyNotes []*DependencyNotes
 }
 
 const (
@@ -220,6 +271,9 @@ func ReadChangelogFile(fs afero.Fs, path string) (*ChangelogFile, error) {
 	}
 
 	for _, entry := range changelog.Entries {
+		if entry.Type.IsCustom() {
+			return nil, UndeclaredEntryTypeError(entry.TypeName())
+		}
 		if entry.Type != NON_USER_FACING && entry.Type != DEPENDENCY_BUMP {
 			if entry.IssueLink == "" {
 				return nil, eris.Errorf("Changelog entries must have an issue link")
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/changelogutils"
+	"github.com/solo-io/go-utils/log"
+	"github.com/solo-io/go-utils/vfsutils"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// Runs the lint rules enabled in changelog/validation.yaml over the changelog files of a local checkout, and
+// optionally fixes the mechanical problems in place.
+//
+// Example, from the root of a repo:
+//
+//	go run github.com/solo-io/go-utils/changelogutils/cmd/lintchangelog --version v1.2.0 --fix
+func main() {
+	ctx := context.Background()
+	if err := rootCommand(ctx).Execute(); err != nil {
+		log.Fatalf("unable to run: %v", err)
+	}
+}
+
+type options struct {
+	repoRootPath string
+	owner        string
+	repo         string
+	versions     []string
+	allRules     bool
+	fix          bool
+	json         bool
+}
+
+func rootCommand(ctx context.Context) *cobra.Command {
+	opts := &options{}
+	cmd := &cobra.Command{
+		Use:   "lintchangelog",
+		Short: "Check the content of changelog entries",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(ctx, cmd, opts)
+		},
+		SilenceUsage: true,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.repoRootPath, "repo-root", ".", "path to the local checkout of the repo")
+	flags.StringVar(&opts.owner, "owner", "solo-io", "owner of the repo")
+	flags.StringVar(&opts.repo, "repo", "", "name of the repo")
+	flags.StringSliceVar(&opts.versions, "version", nil, "version directories to lint (defaults to all of them)")
+	flags.BoolVar(&opts.allRules, "all-rules", false, "run every lint rule as a warning, instead of the rules enabled in validation.yaml")
+	flags.BoolVar(&opts.fix, "fix", false, "fix trailing whitespace and description formatting in place before linting")
+	flags.BoolVar(&opts.json, "json", false, "print the report as JSON")
+	return cmd
+}
+
+func run(ctx context.Context, cmd *cobra.Command, opts *options) error {
+	code, err := vfsutils.NewLocalMountedRepoForFs(opts.repoRootPath, opts.owner, opts.repo)
+	if err != nil {
+		return err
+	}
+	var settings *changelogutils.LintSettings
+	if opts.allRules {
+		settings = changelogutils.DefaultLintSettings()
+	}
+
+	versions := opts.versions
+	if len(versions) == 0 {
+		versions, err = changelogutils.ListChangelogVersions(ctx, code)
+		if err != nil {
+			return err
+		}
+	}
+
+	out := cmd.OutOrStdout()
+	report := changelogutils.NewValidationReport()
+	for _, version := range versions {
+		if opts.fix {
+			changed, err := changelogutils.FixChangelogForTag(ctx, code, afero.NewOsFs(), opts.repoRootPath, version, settings)
+			if err != nil {
+				return err
+			}
+			for _, path := range changed {
+				fmt.Fprintf(cmd.ErrOrStderr(), "fixed %s\n", path)
+			}
+		}
+		versionReport, err := changelogutils.LintChangelogForTag(ctx, code, version, settings)
+		if err != nil {
+			return err
+		}
+		report.Merge(versionReport)
+	}
+
+	if opts.json {
+		err = report.RenderJSON(out)
+	} else {
+		err = report.RenderText(out)
+	}
+	if err != nil {
+		return err
+	}
+	if report.HasErrors() {
+		return eris.New("changelog lint found errors")
+	}
+	return nil
+}
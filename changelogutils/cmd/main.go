@@ -2,14 +2,20 @@ package main
 
 import (
 	"context"
+	"flag"
 	"os"
 
 	"github.com/solo-io/go-utils/changelogutils"
+	"github.com/solo-io/go-utils/githubutils"
 	"github.com/solo-io/go-utils/log"
 )
 
 // This is a reference script, showing how to call the generation script.
 // See the README.md file for an example of the output
+//
+// The output format can be selected with -format (markdown, keepachangelog, json, html or asciidoc), or a custom
+// text/template can be supplied with -template. With -dependency-notes, the release notes of the upstream releases
+// covered by dependency bumps are fetched from GitHub and rendered under the bumps.
 func main() {
 	ctx := context.Background()
 	repoRootPath := "."
@@ -17,9 +23,33 @@ func main() {
 	repo := "go-utils"
 	changelogDirPath := "changelog"
 
+	format := flag.String("format", changelogutils.MarkdownFormat, "output format")
+	templatePath := flag.String("template", "", "path to a text/template to render the changelogs with, instead of -format")
+	dependencyNotes := flag.String("dependency-notes", "", "render the upstream release notes of dependency bumps (inline or summary)")
+	dependencyNotesCache := flag.String("dependency-notes-cache", "", "directory to cache upstream release notes in")
+	flag.Parse()
+
+	var renderer changelogutils.ChangelogRenderer
+	var err error
+	if *templatePath != "" {
+		renderer, err = changelogutils.NewTemplateRendererFromFile(*templatePath)
+	} else {
+		renderer, err = changelogutils.NewChangelogRenderer(*format)
+	}
+	if err != nil {
+		log.Fatalf("unable to run: %v", err)
+	}
+	if *dependencyNotes != "" {
+		source := changelogutils.NewGithubReleaseSource(githubutils.GetClientWithOrWithoutToken(ctx))
+		renderer = changelogutils.NewDependencyNotesRenderer(ctx, renderer, changelogutils.DependencyNotesOptions{
+			Source: changelogutils.NewCachingReleaseSource(source, *dependencyNotesCache),
+			Mode:   *dependencyNotes,
+		})
+	}
+
 	// consider writing to stdout to enhance makefile/io readability `go run cmd/main.go > changelogSummary.md`
 	w := os.Stdout
-	err := changelogutils.GenerateChangelogFromLocalDirectory(ctx, repoRootPath, owner, repo, changelogDirPath, w)
+	err = changelogutils.GenerateChangelogFromLocalDirectoryWithRenderer(ctx, repoRootPath, owner, repo, changelogDirPath, renderer, w)
 	if err != nil {
 		log.Fatalf("unable to run: %v", err)
 	}
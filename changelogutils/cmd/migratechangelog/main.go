@@ -0,0 +1,81 @@
+package main
+
+import (
+	"context"
+
+	"github.com/solo-io/go-utils/changelogutils"
+	"github.com/solo-io/go-utils/log"
+	"github.com/solo-io/go-utils/vfsutils"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// Nests the version directories of a local checkout into minor version directories and merges the changelog files
+// of released versions, checking that the generated changelog doesn't change.
+//
+// Example, from the root of a repo:
+//
+//	go run github.com/solo-io/go-utils/changelogutils/cmd/migratechangelog --nest --compact-min-files 2 --latest-tag v1.10.3
+func main() {
+	ctx := context.Background()
+	if err := rootCommand(ctx).Execute(); err != nil {
+		log.Fatalf("unable to run: %v", err)
+	}
+}
+
+type options struct {
+	repoRootPath      string
+	owner             string
+	repo              string
+	nest              bool
+	compactMinFiles   int
+	compactedFileName string
+	latestTag         string
+	dryRun            bool
+}
+
+func rootCommand(ctx context.Context) *cobra.Command {
+	opts := &options{}
+	cmd := &cobra.Command{
+		Use:   "migratechangelog",
+		Short: "Nest and compact the changelog directory without changing the generated changelog",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(ctx, cmd, opts)
+		},
+		SilenceUsage: true,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.repoRootPath, "repo-root", ".", "path to the local checkout of the repo")
+	flags.StringVar(&opts.owner, "owner", "solo-io", "owner of the repo")
+	flags.StringVar(&opts.repo, "repo", "", "name of the repo")
+	flags.BoolVar(&opts.nest, "nest", false, "move each version directory into a directory for its minor version, and update validation.yaml")
+	flags.IntVar(&opts.compactMinFiles, "compact-min-files", 0, "merge the changelog files of released versions with at least this many files (0 to not merge)")
+	flags.StringVar(&opts.compactedFileName, "compacted-file-name", changelogutils.DefaultCompactedFileName, "name of the merged changelog file")
+	flags.StringVar(&opts.latestTag, "latest-tag", "", "latest release; greater versions are not merged (defaults to merging every version)")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "print the changes without making them")
+	return cmd
+}
+
+func run(ctx context.Context, cmd *cobra.Command, opts *options) error {
+	code, err := vfsutils.NewLocalMountedRepoForFs(opts.repoRootPath, opts.owner, opts.repo)
+	if err != nil {
+		return err
+	}
+	migration, err := changelogutils.PlanChangelogMigration(ctx, code, changelogutils.ChangelogMigrationOptions{
+		Nest:              opts.nest,
+		CompactMinFiles:   opts.compactMinFiles,
+		CompactedFileName: opts.compactedFileName,
+		LatestTag:         opts.latestTag,
+	})
+	if err != nil {
+		return err
+	}
+	if err := migration.RenderText(cmd.OutOrStdout()); err != nil {
+		return err
+	}
+	if opts.dryRun {
+		return nil
+	}
+	return migration.Apply(afero.NewOsFs(), opts.repoRootPath)
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/solo-io/go-utils/changelogutils"
+	"github.com/solo-io/go-utils/githubutils"
+	"github.com/solo-io/go-utils/log"
+	"github.com/solo-io/go-utils/vfsutils"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// Generates a changelog file for a PR, or for the checked out branch of a local repo, and places it in the
+// directory of the next version.
+//
+// Example, from the root of a repo:
+//
+//	go run github.com/solo-io/go-utils/changelogutils/cmd/newchangelog --repo gloo --pr 1234 --interactive
+func main() {
+	ctx := context.Background()
+	if err := rootCommand(ctx).Execute(); err != nil {
+		log.Fatalf("unable to run: %v", err)
+	}
+}
+
+type options struct {
+	repoRootPath  string
+	owner         string
+	repo          string
+	base          string
+	pr            int
+	interactive   bool
+	place         bool
+	dryRun        bool
+	resolvesIssue bool
+	scaffold      changelogutils.ScaffoldOptions
+}
+
+func rootCommand(ctx context.Context) *cobra.Command {
+	opts := &options{}
+	cmd := &cobra.Command{
+		Use:   "newchangelog",
+		Short: "Generate a changelog file from the metadata of a PR or the current branch",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if cmd.Flags().Changed("resolves-issue") {
+				opts.scaffold.ResolvesIssue = &opts.resolvesIssue
+			}
+			opts.scaffold.Interactive = opts.interactive
+			return run(ctx, cmd, opts)
+		},
+		SilenceUsage: true,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.repoRootPath, "repo-root", ".", "path to the local checkout of the repo")
+	flags.StringVar(&opts.owner, "owner", "solo-io", "owner of the repo")
+	flags.StringVar(&opts.repo, "repo", "", "name of the repo")
+	flags.StringVar(&opts.base, "base", changelogutils.MasterBranch, "git ref the changes will be merged into, used to compute the next version")
+	flags.IntVar(&opts.pr, "pr", 0, "number of the PR to generate the changelog for (requires GITHUB_TOKEN); if not set, the checked out branch and its latest commit are used")
+	flags.BoolVarP(&opts.interactive, "interactive", "i", false, "prompt for each field, using the inferred values as defaults")
+	flags.BoolVar(&opts.place, "place", true, "move the changelog file into the directory of the next version")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "print the changelog file instead of writing it")
+	flags.StringVar(&opts.scaffold.Type, "type", "", "type of the entry (e.g. NEW_FEATURE); inferred from the PR labels or title if not set")
+	flags.StringVar(&opts.scaffold.DefaultType, "default-type", "", "type of the entry when none can be inferred (default FIX)")
+	flags.StringVar(&opts.scaffold.Description, "description", "", "description of the change; defaults to the PR title")
+	flags.StringVar(&opts.scaffold.IssueLink, "issue-link", "", "link to the issue; inferred from the PR body if not set")
+	flags.BoolVar(&opts.resolvesIssue, "resolves-issue", true, "whether the issue should be closed when the change is released")
+	flags.StringVar(&opts.scaffold.DependencyOwner, "dependency-owner", "", "owner of the dependency, for DEPENDENCY_BUMP entries")
+	flags.StringVar(&opts.scaffold.DependencyRepo, "dependency-repo", "", "repo of the dependency, for DEPENDENCY_BUMP entries")
+	flags.StringVar(&opts.scaffold.DependencyTag, "dependency-tag", "", "new tag of the dependency, for DEPENDENCY_BUMP entries")
+	flags.StringToStringVar(&opts.scaffold.LabelEntryTypes, "label-type", nil, "additional PR label to entry type mappings (e.g. security=SECURITY)")
+	flags.StringVar(&opts.scaffold.FileName, "name", "", "name of the changelog file; defaults to the branch name")
+	return cmd
+}
+
+func run(ctx context.Context, cmd *cobra.Command, opts *options) error {
+	code, err := vfsutils.NewLocalMountedRepoForFs(opts.repoRootPath, opts.owner, opts.repo)
+	if err != nil {
+		return err
+	}
+
+	var info *changelogutils.ChangeInfo
+	if opts.pr != 0 {
+		client, err := githubutils.GetClient(ctx)
+		if err != nil {
+			return err
+		}
+		repoClient := githubutils.NewRepoClient(client, opts.owner, opts.repo)
+		info, err = changelogutils.GetChangeInfoForPR(ctx, repoClient, opts.owner, opts.repo, opts.pr)
+		if err != nil {
+			return err
+		}
+	} else {
+		info, err = changelogutils.GetChangeInfoForLocalBranch(opts.repoRootPath, opts.owner, opts.repo)
+		if err != nil {
+			return err
+		}
+	}
+
+	scaffolded, err := changelogutils.ScaffoldChangelogFile(ctx, code, info, opts.scaffold)
+	if err != nil {
+		return err
+	}
+	out := cmd.OutOrStdout()
+	if opts.dryRun {
+		fmt.Fprintf(out, "# %s\n%s", scaffolded.Path, scaffolded.Contents)
+		return nil
+	}
+	fs := afero.NewOsFs()
+	if err := changelogutils.WriteScaffoldedChangelogFile(fs, opts.repoRootPath, scaffolded); err != nil {
+		return err
+	}
+	if !opts.place {
+		fmt.Fprintf(out, "wrote %s\n", scaffolded.Path)
+		return nil
+	}
+
+	// the next version is computed with the scaffolded file, which is only left behind once it has been placed
+	newPath, err := place(ctx, fs, code, opts, scaffolded)
+	if err != nil {
+		if removeErr := fs.Remove(filepath.Join(opts.repoRootPath, scaffolded.Path)); removeErr != nil && !os.IsNotExist(removeErr) {
+			err = multierror.Append(err, removeErr)
+		}
+		return err
+	}
+	fmt.Fprintf(out, "wrote %s\n", newPath)
+	return nil
+}
+
+func place(ctx context.Context, fs afero.Fs, code vfsutils.MountedRepo, opts *options, scaffolded *changelogutils.ScaffoldedChangelogFile) (string, error) {
+	history, err := changelogutils.NewLocalRepoHistory(opts.repoRootPath)
+	if err != nil {
+		return "", err
+	}
+	next, err := changelogutils.ComputeNextVersion(ctx, history, code, opts.base,
+		changelogutils.NextVersionOptions{AdditionalFiles: []string{scaffolded.Path}})
+	if err != nil {
+		return "", err
+	}
+	return changelogutils.PlaceChangelogFile(fs, opts.repoRootPath, next, scaffolded.Path)
+}
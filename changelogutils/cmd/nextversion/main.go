@@ -0,0 +1,101 @@
+package main
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/solo-io/go-utils/changelogutils"
+	"github.com/solo-io/go-utils/log"
+	"github.com/solo-io/go-utils/vfsutils"
+	"github.com/spf13/afero"
+	"github.com/spf13/cobra"
+)
+
+// Prints the version that the pending changelog entries of a local checkout should be released as, and optionally
+// moves a changelog file into the directory of that version.
+//
+// Example, from the root of a repo:
+//
+//	go run github.com/solo-io/go-utils/changelogutils/cmd/nextversion --move changelog/my-change.yaml
+func main() {
+	ctx := context.Background()
+	if err := rootCommand(ctx).Execute(); err != nil {
+		log.Fatalf("unable to run: %v", err)
+	}
+}
+
+type options struct {
+	repoRootPath string
+	owner        string
+	repo         string
+	base         string
+	label        string
+	dropLabel    bool
+	labelOrder   []string
+	move         string
+	dryRun       bool
+}
+
+func rootCommand(ctx context.Context) *cobra.Command {
+	opts := &options{}
+	cmd := &cobra.Command{
+		Use:   "nextversion",
+		Short: "Compute the next version from the latest tag and the pending changelog entries",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return run(ctx, cmd, opts)
+		},
+		SilenceUsage: true,
+	}
+
+	flags := cmd.Flags()
+	flags.StringVar(&opts.repoRootPath, "repo-root", ".", "path to the local checkout of the repo")
+	flags.StringVar(&opts.owner, "owner", "solo-io", "owner of the repo")
+	flags.StringVar(&opts.repo, "repo", "", "name of the repo")
+	flags.StringVar(&opts.base, "base", changelogutils.MasterBranch, "git ref the changes will be merged into; the latest release is the greatest tag reachable from it")
+	flags.StringVar(&opts.label, "label", "", "start or switch to a prerelease with this label (e.g. rc)")
+	flags.BoolVar(&opts.dropLabel, "drop-label", false, "release the version the latest prerelease precedes (e.g. v1.0.0-rc2 -> v1.0.0)")
+	flags.StringSliceVar(&opts.labelOrder, "label-order", nil, "tie-break order for labels, greatest first (e.g. rc,beta,alpha)")
+	flags.StringVar(&opts.move, "move", "", "changelog file, relative to the repo root, to move into the directory of the next version")
+	flags.BoolVar(&opts.dryRun, "dry-run", false, "print where the changelog file would be moved, without moving it")
+	return cmd
+}
+
+func run(ctx context.Context, cmd *cobra.Command, opts *options) error {
+	code, err := vfsutils.NewLocalMountedRepoForFs(opts.repoRootPath, opts.owner, opts.repo)
+	if err != nil {
+		return err
+	}
+	history, err := changelogutils.NewLocalRepoHistory(opts.repoRootPath)
+	if err != nil {
+		return err
+	}
+
+	nextVersionOpts := changelogutils.NextVersionOptions{
+		Label:      opts.label,
+		DropLabel:  opts.dropLabel,
+		LabelOrder: opts.labelOrder,
+	}
+	if opts.move != "" {
+		nextVersionOpts.AdditionalFiles = []string{opts.move}
+	}
+	next, err := changelogutils.ComputeNextVersion(ctx, history, code, opts.base, nextVersionOpts)
+	if err != nil {
+		return err
+	}
+
+	out := cmd.OutOrStdout()
+	if opts.move == "" {
+		fmt.Fprintln(out, next.Version.String())
+		return nil
+	}
+	if opts.dryRun {
+		fmt.Fprintf(out, "%s would be moved to %s\n", opts.move, next.Directory())
+		return nil
+	}
+	newPath, err := changelogutils.PlaceChangelogFile(afero.NewOsFs(), opts.repoRootPath, next, opts.move)
+	if err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "%s is in %s\n", newPath, next.Version.String())
+	return nil
+}
@@ -0,0 +1,378 @@
+package changelogutils
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"sync"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/pkg/errors"
+	"github.com/solo-io/go-utils/githubutils"
+	"github.com/solo-io/go-utils/versionutils"
+	"github.com/solo-io/go-utils/vfsutils"
+)
+
+var (
+	FetchUpstreamReleasesError = func(err error, owner, repo, fromTag, toTag string) error {
+		return errors.Wrapf(err, "unable to fetch releases of %s/%s between %s and %s", owner, repo, fromTag, toTag)
+	}
+)
+
+// How the upstream release notes of a dependency bump are rendered
+const (
+	// The release notes of each upstream release are nested under the dependency bump
+	DependencyNotesInline = "inline"
+	// Each upstream release is listed with the number of entries in each section, and its breaking changes
+	DependencyNotesSummary = "summary"
+)
+
+// A release of a dependency
+type UpstreamRelease struct {
+	Tag string `json:"tag"`
+	// The release notes, in markdown
+	Body string `json:"body"`
+}
+
+// The upstream releases covered by a dependency bump
+type DependencyNotes struct {
+	Owner string `json:"owner"`
+	Repo  string `json:"repo"`
+	// The tag the dependency was previously pinned to, or empty if it isn't known
+	FromTag string `json:"fromTag,omitempty"`
+	ToTag   string `json:"toTag"`
+	// Releases after FromTag, up to and including ToTag, newest first
+	Releases []*UpstreamRelease `json:"releases"`
+	// One of DependencyNotesInline or DependencyNotesSummary
+	Mode string `json:"mode"`
+}
+
+// Provides the release notes of dependencies
+type UpstreamReleaseSource interface {
+	// Returns the releases of owner/repo after fromTag, up to and including toTag, newest first.
+	// If fromTag is empty, only the release for toTag is returned.
+	GetReleasesInRange(ctx context.Context, owner, repo, fromTag, toTag string) ([]*UpstreamRelease, error)
+}
+
+// Returns true if tag is in the range (fromTag, toTag]. Tags that aren't semver are never in range.
+func tagInRange(tag, fromTag, toTag string) bool {
+	if tag == toTag {
+		return true
+	}
+	if fromTag == "" {
+		return false
+	}
+	version, err := versionutils.ParseVersion(tag)
+	if err != nil {
+		return false
+	}
+	from, err := versionutils.ParseVersion(fromTag)
+	if err != nil {
+		return false
+	}
+	to, err := versionutils.ParseVersion(toTag)
+	if err != nil {
+		return false
+	}
+	return version.MustIsGreaterThan(*from) && to.MustIsGreaterThan(*version)
+}
+
+func sortUpstreamReleases(releases []*UpstreamRelease) {
+	sort.SliceStable(releases, func(i, j int) bool {
+		greater, determinable, err := versionutils.IsGreaterThanTag(releases[i].Tag, releases[j].Tag)
+		return err == nil && determinable && greater
+	})
+}
+
+type githubReleaseSource struct {
+	client *github.Client
+}
+
+// Reads the release notes of dependencies from the bodies of their GitHub releases
+func NewGithubReleaseSource(client *github.Client) UpstreamReleaseSource {
+	return &githubReleaseSource{client: client}
+}
+
+func (s *githubReleaseSource) GetReleasesInRange(ctx context.Context, owner, repo, fromTag, toTag string) ([]*UpstreamRelease, error) {
+	releases, err := githubutils.GetAllRepoReleases(ctx, s.client, owner, repo)
+	if err != nil {
+		return nil, FetchUpstreamReleasesError(err, owner, repo, fromTag, toTag)
+	}
+	var upstream []*UpstreamRelease
+	for _, release := range releases {
+		if release.GetDraft() || !tagInRange(release.GetTagName(), fromTag, toTag) {
+			continue
+		}
+		upstream = append(upstream, &UpstreamRelease{Tag: release.GetTagName(), Body: release.GetBody()})
+	}
+	sortUpstreamReleases(upstream)
+	return upstream, nil
+}
+
+type changelogReleaseSource struct {
+	mount func(owner, repo, ref string) vfsutils.MountedRepo
+}
+
+// Reads the release notes of dependencies from their changelog directories, using a ChangelogReader. The dependency
+// is mounted at the tag it was bumped to, e.g. with:
+//
+//	func(owner, repo, ref string) vfsutils.MountedRepo {
+//	    return vfsutils.NewLazilyMountedRepo(client, owner, repo, ref)
+//	}
+func NewChangelogReleaseSource(mount func(owner, repo, ref string) vfsutils.MountedRepo) UpstreamReleaseSource {
+	return &changelogReleaseSource{mount: mount}
+}
+
+func (s *changelogReleaseSource) GetReleasesInRange(ctx context.Context, owner, repo, fromTag, toTag string) ([]*UpstreamRelease, error) {
+	code := s.mount(owner, repo, toTag)
+	settings, err := readValidationSettings(ctx, code)
+	if err != nil {
+		return nil, FetchUpstreamReleasesError(err, owner, repo, fromTag, toTag)
+	}
+	tags, err := listChangelogVersions(ctx, code, settings)
+	if err != nil {
+		return nil, FetchUpstreamReleasesError(err, owner, repo, fromTag, toTag)
+	}
+	reader := NewChangelogReader(code)
+	var upstream []*UpstreamRelease
+	for _, tag := range tags {
+		if !tagInRange(tag, fromTag, toTag) {
+			continue
+		}
+		changelog, err := reader.GetChangelogForTag(ctx, tag)
+		if err != nil {
+			return nil, FetchUpstreamReleasesError(err, owner, repo, fromTag, toTag)
+		}
+		upstream = append(upstream, &UpstreamRelease{Tag: tag, Body: GenerateChangelogMarkdown(changelog)})
+	}
+	sortUpstreamReleases(upstream)
+	return upstream, nil
+}
+
+type cachingReleaseSource struct {
+	source   UpstreamReleaseSource
+	cacheDir string
+	lock     sync.Mutex
+	cache    map[string][]*UpstreamRelease
+}
+
+// Caches the releases returned by source, so that repeated generation doesn't refetch them. Releases are cached in
+// memory and, if cacheDir is not empty, as JSON files in cacheDir so that they are reused across runs. The releases
+// between two tags rarely change once both are published; remove the cache directory to refetch them.
+func NewCachingReleaseSource(source UpstreamReleaseSource, cacheDir string) UpstreamReleaseSource {
+	return &cachingReleaseSource{
+		source:   source,
+		cacheDir: cacheDir,
+		cache:    make(map[string][]*UpstreamRelease),
+	}
+}
+
+func (s *cachingReleaseSource) GetReleasesInRange(ctx context.Context, owner, repo, fromTag, toTag string) ([]*UpstreamRelease, error) {
+	key := filepath.Join(owner, repo, fromTag+".."+toTag+".json")
+	s.lock.Lock()
+	defer s.lock.Unlock()
+	if releases, ok := s.cache[key]; ok {
+		return releases, nil
+	}
+	if s.cacheDir != "" {
+		if contents, err := os.ReadFile(filepath.Join(s.cacheDir, key)); err == nil {
+			var releases []*UpstreamRelease
+			if err := json.Unmarshal(contents, &releases); err == nil {
+				s.cache[key] = releases
+				return releases, nil
+			}
+		}
+	}
+
+	releases, err := s.source.GetReleasesInRange(ctx, owner, repo, fromTag, toTag)
+	if err != nil {
+		return nil, err
+	}
+	s.cache[key] = releases
+	if s.cacheDir != "" {
+		contents, err := json.Marshal(releases)
+		if err != nil {
+			return nil, err
+		}
+		path := filepath.Join(s.cacheDir, key)
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return nil, err
+		}
+		if err := os.WriteFile(path, contents, 0644); err != nil {
+			return nil, err
+		}
+	}
+	return releases, nil
+}
+
+type DependencyNotesOptions struct {
+	Source UpstreamReleaseSource
+	// DependencyNotesInline (the default) or DependencyNotesSummary
+	Mode string
+}
+
+// Fetches the upstream release notes for the dependency bumps in the changelogs, and attaches them to the changelogs
+// so they are rendered under the bumps. The tag a dependency was previously pinned to is the greatest tag it was
+// bumped to in an earlier version in changelogs; if there is none, only the notes of the new tag are fetched.
+func AddDependencyNotes(ctx context.Context, changelogs ChangelogList, opts DependencyNotesOptions) error {
+	mode := opts.Mode
+	if mode == "" {
+		mode = DependencyNotesInline
+	}
+	for _, changelog := range changelogs {
+		changelog.DependencyNotes = nil
+		for _, file := range changelog.Files {
+			for _, entry := range file.Entries {
+				if entry.Type != DEPENDENCY_BUMP {
+					continue
+				}
+				fromTag := previousDependencyTag(changelogs, changelog, entry)
+				releases, err := opts.Source.GetReleasesInRange(ctx, entry.DependencyOwner, entry.DependencyRepo, fromTag, entry.DependencyTag)
+				if err != nil {
+					return err
+				}
+				changelog.DependencyNotes = append(changelog.DependencyNotes, &DependencyNotes{
+					Owner:    entry.DependencyOwner,
+					Repo:     entry.DependencyRepo,
+					FromTag:  fromTag,
+					ToTag:    entry.DependencyTag,
+					Releases: releases,
+					Mode:     mode,
+				})
+			}
+		}
+	}
+	return nil
+}
+
+func previousDependencyTag(changelogs ChangelogList, current *Changelog, bump *ChangelogEntry) string {
+	var previous *versionutils.Version
+	var previousTag string
+	for _, changelog := range changelogs {
+		if changelog.Version == nil || current.Version == nil || !current.Version.MustIsGreaterThan(*changelog.Version) {
+			continue
+		}
+		for _, file := range changelog.Files {
+			for _, entry := range file.Entries {
+				if entry.Type != DEPENDENCY_BUMP || entry.DependencyOwner != bump.DependencyOwner || entry.DependencyRepo != bump.DependencyRepo {
+					continue
+				}
+				version, err := versionutils.ParseVersion(entry.DependencyTag)
+				if err != nil || (previous != nil && !version.MustIsGreaterThan(*previous)) {
+					continue
+				}
+				previous, previousTag = version, entry.DependencyTag
+			}
+		}
+	}
+	return previousTag
+}
+
+type dependencyNotesRenderer struct {
+	ctx      context.Context
+	renderer ChangelogRenderer
+	opts     DependencyNotesOptions
+}
+
+// Wraps a renderer so that the upstream release notes of dependency bumps are added before rendering (see
+// AddDependencyNotes). The markdown and JSON renderers, and custom templates, render the notes.
+func NewDependencyNotesRenderer(ctx context.Context, renderer ChangelogRenderer, opts DependencyNotesOptions) ChangelogRenderer {
+	return &dependencyNotesRenderer{ctx: ctx, renderer: renderer, opts: opts}
+}
+
+func (r *dependencyNotesRenderer) Render(w io.Writer, changelogs ChangelogList) error {
+	if err := AddDependencyNotes(r.ctx, changelogs, r.opts); err != nil {
+		return err
+	}
+	return r.renderer.Render(w, changelogs)
+}
+
+// Returns the notes for a dependency bump entry, or nil
+func (c *Changelog) dependencyNotesFor(entry *ChangelogEntry) *DependencyNotes {
+	for _, notes := range c.DependencyNotes {
+		if notes.Owner == entry.DependencyOwner && notes.Repo == entry.DependencyRepo && notes.ToTag == entry.DependencyTag {
+			return notes
+		}
+	}
+	return nil
+}
+
+// Renders the upstream notes as a nested markdown list under a dependency bump
+func renderDependencyNotes(w io.StringWriter, notes *DependencyNotes) {
+	// a blank line separates a release with inlined notes from the next one
+	separate := false
+	for _, release := range notes.Releases {
+		if notes.Mode == DependencyNotesSummary {
+			w.WriteString("  - " + summarizeReleaseNotes(release) + "\n")
+			continue
+		}
+		if separate {
+			w.WriteString("\n")
+		}
+		w.WriteString("  - " + release.Tag + "\n")
+		body := strings.TrimSpace(release.Body)
+		separate = body != ""
+		if !separate {
+			continue
+		}
+		w.WriteString("\n")
+		for _, line := range strings.Split(body, "\n") {
+			if strings.TrimSpace(line) == "" {
+				w.WriteString("\n")
+				continue
+			}
+			w.WriteString("    " + line + "\n")
+		}
+	}
+}
+
+var (
+	// e.g. **New Features** or ### New Features
+	releaseNotesHeadingRegex = regexp.MustCompile(`^(?:\*\*(.+)\*\*|#{1,6}\s+(.+))$`)
+	releaseNotesBulletRegex  = regexp.MustCompile(`^[-*]\s+(.+)$`)
+)
+
+// Summarizes markdown release notes as the number of entries in each section, followed by any breaking changes,
+// e.g. "v1.2.0: 2 New Features, 1 Fixes; breaking: Removed the foo flag."
+func summarizeReleaseNotes(release *UpstreamRelease) string {
+	var headings []string
+	counts := make(map[string]int)
+	var breaking []string
+	heading := ""
+	for _, line := range strings.Split(release.Body, "\n") {
+		line = strings.TrimSpace(line)
+		if match := releaseNotesHeadingRegex.FindStringSubmatch(line); match != nil {
+			heading = strings.TrimSpace(match[1] + match[2])
+			continue
+		}
+		match := releaseNotesBulletRegex.FindStringSubmatch(line)
+		if match == nil || heading == "" {
+			continue
+		}
+		if _, ok := counts[heading]; !ok {
+			headings = append(headings, heading)
+		}
+		counts[heading]++
+		if strings.EqualFold(heading, builtinEntryTypeHeadings[BREAKING_CHANGE]) {
+			breaking = append(breaking, match[1])
+		}
+	}
+	if len(headings) == 0 {
+		return release.Tag + ": " + noUserFacingChanges
+	}
+	var parts []string
+	for _, h := range headings {
+		parts = append(parts, fmt.Sprintf("%d %s", counts[h], h))
+	}
+	summary := release.Tag + ": " + strings.Join(parts, ", ")
+	if len(breaking) > 0 {
+		summary += "; breaking: " + strings.Join(breaking, " ")
+	}
+	return summary
+}
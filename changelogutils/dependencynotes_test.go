@@ -0,0 +1,132 @@
+package changelogutils_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/solo-io/go-utils/changelogutils"
+	"github.com/solo-io/go-utils/versionutils"
+	"github.com/solo-io/go-utils/vfsutils"
+)
+
+// counts the calls to the source it wraps
+type countingReleaseSource struct {
+	source changelogutils.UpstreamReleaseSource
+	calls  int
+}
+
+func (s *countingReleaseSource) GetReleasesInRange(ctx context.Context, owner, repo, fromTag, toTag string) ([]*changelogutils.UpstreamRelease, error) {
+	s.calls++
+	return s.source.GetReleasesInRange(ctx, owner, repo, fromTag, toTag)
+}
+
+var _ = Describe("dependency bump release notes", func() {
+
+	var (
+		ctx          = context.Background()
+		upstreamRoot string
+		source       *countingReleaseSource
+		changelogs   changelogutils.ChangelogList
+	)
+
+	writeUpstreamFile := func(path, contents string) {
+		fullPath := filepath.Join(upstreamRoot, path)
+		Expect(os.MkdirAll(filepath.Dir(fullPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(fullPath, []byte(contents), 0644)).To(Succeed())
+	}
+
+	dependencyBump := func(tag string) *changelogutils.ChangelogFile {
+		return &changelogutils.ChangelogFile{Entries: []*changelogutils.ChangelogEntry{
+			{Type: changelogutils.DEPENDENCY_BUMP, DependencyOwner: "solo-io", DependencyRepo: "upstream", DependencyTag: tag},
+		}}
+	}
+
+	BeforeEach(func() {
+		var err error
+		upstreamRoot, err = os.MkdirTemp("", "upstream")
+		Expect(err).NotTo(HaveOccurred())
+		writeUpstreamFile("changelog/v0.1.0/a.yaml", validChangelog1)
+		writeUpstreamFile("changelog/v0.1.1/a.yaml", validChangelog2)
+		writeUpstreamFile("changelog/v0.2.0/a.yaml", validBreakingChangelog)
+		source = &countingReleaseSource{
+			source: changelogutils.NewChangelogReleaseSource(func(owner, repo, ref string) vfsutils.MountedRepo {
+				code, err := vfsutils.NewLocalMountedRepoForFs(upstreamRoot, owner, repo)
+				Expect(err).NotTo(HaveOccurred())
+				return code
+			}),
+		}
+		changelogs = changelogutils.ChangelogList{
+			{Version: versionutils.NewVersion(1, 1, 0, "", 0), Files: []*changelogutils.ChangelogFile{dependencyBump("v0.2.0")}},
+			{Version: versionutils.NewVersion(1, 0, 0, "", 0), Files: []*changelogutils.ChangelogFile{dependencyBump("v0.1.0")}},
+		}
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(upstreamRoot)
+	})
+
+	It("inlines the upstream notes between the previously pinned tag and the new tag", func() {
+		Expect(changelogutils.AddDependencyNotes(ctx, changelogs, changelogutils.DependencyNotesOptions{Source: source})).To(Succeed())
+
+		notes := changelogs[0].DependencyNotes
+		Expect(notes).To(HaveLen(1))
+		Expect(notes[0].FromTag).To(Equal("v0.1.0"))
+		Expect(notes[0].Releases).To(HaveLen(2))
+		Expect(notes[0].Releases[0].Tag).To(Equal("v0.2.0"))
+		Expect(notes[0].Releases[1].Tag).To(Equal("v0.1.1"))
+		Expect(changelogs[1].DependencyNotes[0].FromTag).To(BeEmpty())
+		Expect(changelogs[1].DependencyNotes[0].Releases).To(HaveLen(1))
+
+		Expect(changelogutils.GenerateChangelogMarkdown(changelogs[0])).To(Equal(`**Dependency Bumps**
+
+- solo-io/upstream has been upgraded to v0.2.0.
+  - v0.2.0
+
+    **Breaking Changes**
+
+    - foo (bar)
+
+  - v0.1.1
+
+    **Fixes**
+
+    - foo4 (bar4)
+
+`))
+	})
+
+	It("summarizes the upstream notes", func() {
+		Expect(changelogutils.AddDependencyNotes(ctx, changelogs, changelogutils.DependencyNotesOptions{
+			Source: source,
+			Mode:   changelogutils.DependencyNotesSummary,
+		})).To(Succeed())
+		Expect(changelogutils.GenerateChangelogMarkdown(changelogs[0])).To(Equal(`**Dependency Bumps**
+
+- solo-io/upstream has been upgraded to v0.2.0.
+  - v0.2.0: 1 Breaking Changes; breaking: foo (bar)
+  - v0.1.1: 1 Fixes
+
+`))
+	})
+
+	It("caches upstream releases in memory and on disk", func() {
+		cacheDir, err := os.MkdirTemp("", "upstream-cache")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(cacheDir)
+
+		opts := changelogutils.DependencyNotesOptions{Source: changelogutils.NewCachingReleaseSource(source, cacheDir)}
+		Expect(changelogutils.AddDependencyNotes(ctx, changelogs, opts)).To(Succeed())
+		Expect(changelogutils.AddDependencyNotes(ctx, changelogs, opts)).To(Succeed())
+		Expect(source.calls).To(Equal(2))
+		Expect(filepath.Join(cacheDir, "solo-io/upstream/v0.1.0..v0.2.0.json")).To(BeARegularFile())
+
+		// a new caching source reads the releases from disk
+		opts = changelogutils.DependencyNotesOptions{Source: changelogutils.NewCachingReleaseSource(source, cacheDir)}
+		Expect(changelogutils.AddDependencyNotes(ctx, changelogs, opts)).To(Succeed())
+		Expect(source.calls).To(Equal(2))
+		Expect(changelogs[0].DependencyNotes[0].Releases).To(HaveLen(2))
+	})
+})
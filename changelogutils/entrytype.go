@@ -3,6 +3,8 @@ package changelogutils
 import (
 	"encoding/json"
 	"fmt"
+	"regexp"
+	"sort"
 )
 
 type ChangelogEntryType int
@@ -39,8 +41,32 @@ var (
 	}
 )
 
+var customEntryTypeNameRegex = regexp.MustCompile("^[A-Z][A-Z0-9_]*$")
+
+// The type of a changelog entry whose type isn't built in, until it is resolved against the custom entry types
+// declared in the validation settings of the repo (see EntryTypes)
+const undeclaredEntryType ChangelogEntryType = -1
+
+// Returns the name of a built-in entry type. Custom entry types are named by the EntryTypes that declares them.
 func (clt ChangelogEntryType) String() string {
-	return [...]string{"BREAKING_CHANGE", "FIX", "NEW_FEATURE", "NON_USER_FACING", "DEPENDENCY_BUMP", "HELM", "UPGRADE"}[clt]
+	if !clt.IsCustom() {
+		return [...]string{"BREAKING_CHANGE", "FIX", "NEW_FEATURE", "NON_USER_FACING", "DEPENDENCY_BUMP", "HELM", "UPGRADE"}[clt]
+	}
+	return fmt.Sprintf("ChangelogEntryType(%d)", int(clt))
+}
+
+// Returns true for entry types that are not built in, and must be declared in the validation settings of the repo
+func (clt ChangelogEntryType) IsCustom() bool {
+	return clt < BREAKING_CHANGE || clt > UPGRADE
+}
+
+// Returns the built-in entry type with the given name. Custom entry types are parsed with
+// EntryTypes.ParseChangelogEntryType.
+func ParseChangelogEntryType(name string) (ChangelogEntryType, error) {
+	if v, ok := _ChangelogEntryTypeToValue[name]; ok {
+		return v, nil
+	}
+	return 0, fmt.Errorf("invalid ChangelogEntryType %q", name)
 }
 
 func (clt ChangelogEntryType) BreakingChange() bool {
@@ -67,10 +93,280 @@ func (clt *ChangelogEntryType) UnmarshalJSON(data []byte) error {
 	if err := json.Unmarshal(data, &s); err != nil {
 		return fmt.Errorf("ChangelogEntryType should be a string, got %s", data)
 	}
-	v, ok := _ChangelogEntryTypeToValue[s]
-	if !ok {
-		return fmt.Errorf("invalid ChangelogEntryType %q", s)
+	v, err := ParseChangelogEntryType(s)
+	if err != nil {
+		return err
 	}
 	*clt = v
 	return nil
 }
+
+const (
+	VersionBumpPatch = "patch"
+	VersionBumpMinor = "minor"
+	VersionBumpMajor = "major"
+)
+
+// Names of the changelog entry fields that can be required by EntryTypeSettings
+const (
+	IssueLinkField       = "issueLink"
+	DescriptionField     = "description"
+	DependencyOwnerField = "dependencyOwner"
+	DependencyRepoField  = "dependencyRepo"
+	DependencyTagField   = "dependencyTag"
+)
+
+// Declares a custom changelog entry type in validation.yaml, for example:
+//
+//	customEntryTypes:
+//	  - name: SECURITY
+//	    heading: Security Fixes
+//	    order: 25
+//	    versionBump: minor
+type EntryTypeSettings struct {
+	// The value used for the type of changelog entries, in upper snake case (e.g. SECURITY)
+	Name string `json:"name"`
+
+	// The heading of the section these entries are rendered under in the release notes.
+	// If empty, entries of this type are not rendered (like NON_USER_FACING).
+	Heading string `json:"heading"`
+
+	// The position of the section in the release notes, relative to the built-in sections
+	// (see BuiltinEntryTypeOrder). Sections with the same order are rendered in the order they are declared.
+	Order int `json:"order"`
+
+	// The entry fields that must be set. If not specified, defaults to the description and issue link.
+	RequiredFields []string `json:"requiredFields"`
+
+	// How an entry of this type increments the version: "patch" (the default), "minor" (like NEW_FEATURE)
+	// or "major" (like BREAKING_CHANGE).
+	VersionBump string `json:"versionBump"`
+}
+
+// The section order of the built-in entry types; custom entry types are ordered relative to these
+var BuiltinEntryTypeOrder = map[ChangelogEntryType]int{
+	DEPENDENCY_BUMP: 10,
+	BREAKING_CHANGE: 20,
+	UPGRADE:         30,
+	HELM:            40,
+	NEW_FEATURE:     50,
+	FIX:             60,
+}
+
+var builtinEntryTypeHeadings = map[ChangelogEntryType]string{
+	DEPENDENCY_BUMP: "Dependency Bumps",
+	BREAKING_CHANGE: "Breaking Changes",
+	UPGRADE:         "Upgrade Notes",
+	HELM:            "Helm Changes",
+	NEW_FEATURE:     "New Features",
+	FIX:             "Fixes",
+}
+
+var (
+	InvalidEntryTypeNameError = func(name string) error {
+		return fmt.Errorf("custom changelog entry type %q must be upper snake case, and not the name of a built-in type", name)
+	}
+	DuplicateEntryTypeError = func(name string) error {
+		return fmt.Errorf("custom changelog entry type %q is declared more than once", name)
+	}
+	InvalidRequiredFieldError = func(name, field string) error {
+		return fmt.Errorf("custom changelog entry type %q requires unknown field %q", name, field)
+	}
+	InvalidVersionBumpError = func(name, bump string) error {
+		return fmt.Errorf("custom changelog entry type %q has invalid versionBump %q, must be one of patch, minor, major", name, bump)
+	}
+	UndeclaredEntryTypeError = func(name string) error {
+		return fmt.Errorf("invalid ChangelogEntryType %q", name)
+	}
+)
+
+// A section of the rendered release notes
+type EntryTypeSection struct {
+	Type    ChangelogEntryType
+	Heading string
+}
+
+// The set of entry types that can be used in a repo: the built-in types, plus any custom types declared in
+// its validation settings. A nil *EntryTypes only contains the built-in types.
+// Custom types are numbered after the built-in types in the order they are declared, so the EntryTypes of the
+// same settings agree on their values.
+type EntryTypes struct {
+	custom map[ChangelogEntryType]*EntryTypeSettings
+	byName map[string]ChangelogEntryType
+	// custom types in the order they are declared
+	customOrder []ChangelogEntryType
+	sections    []EntryTypeSection
+}
+
+func NewEntryTypes(custom []*EntryTypeSettings) (*EntryTypes, error) {
+	types := &EntryTypes{
+		custom: make(map[ChangelogEntryType]*EntryTypeSettings),
+		byName: make(map[string]ChangelogEntryType),
+	}
+	type orderedSection struct {
+		EntryTypeSection
+		order int
+	}
+	var sections []orderedSection
+	for _, builtin := range []ChangelogEntryType{DEPENDENCY_BUMP, BREAKING_CHANGE, UPGRADE, HELM, NEW_FEATURE, FIX} {
+		sections = append(sections, orderedSection{
+			EntryTypeSection: EntryTypeSection{Type: builtin, Heading: builtinEntryTypeHeadings[builtin]},
+			order:            BuiltinEntryTypeOrder[builtin],
+		})
+	}
+
+	for _, settings := range custom {
+		if _, builtin := _ChangelogEntryTypeToValue[settings.Name]; builtin || !customEntryTypeNameRegex.MatchString(settings.Name) {
+			return nil, InvalidEntryTypeNameError(settings.Name)
+		}
+		if _, ok := types.byName[settings.Name]; ok {
+			return nil, DuplicateEntryTypeError(settings.Name)
+		}
+		entryType := UPGRADE + ChangelogEntryType(len(types.customOrder)+1)
+		for _, field := range settings.RequiredFields {
+			switch field {
+			case IssueLinkField, DescriptionField, DependencyOwnerField, DependencyRepoField, DependencyTagField:
+			default:
+				return nil, InvalidRequiredFieldError(settings.Name, field)
+			}
+		}
+		switch settings.VersionBump {
+		case "", VersionBumpPatch, VersionBumpMinor, VersionBumpMajor:
+		default:
+			return nil, InvalidVersionBumpError(settings.Name, settings.VersionBump)
+		}
+		types.custom[entryType] = settings
+		types.byName[settings.Name] = entryType
+		types.customOrder = append(types.customOrder, entryType)
+		if settings.Heading != "" {
+			sections = append(sections, orderedSection{
+				EntryTypeSection: EntryTypeSection{Type: entryType, Heading: settings.Heading},
+				order:            settings.Order,
+			})
+		}
+	}
+
+	// stable, so built-in sections come before custom sections with the same order
+	sort.SliceStable(sections, func(i, j int) bool {
+		return sections[i].order < sections[j].order
+	})
+	for _, section := range sections {
+		types.sections = append(types.sections, section.EntryTypeSection)
+	}
+	return types, nil
+}
+
+// Returns the entry type with the given name, which must be built in or declared as a custom type
+func (t *EntryTypes) ParseChangelogEntryType(name string) (ChangelogEntryType, error) {
+	if v, ok := _ChangelogEntryTypeToValue[name]; ok {
+		return v, nil
+	}
+	if t != nil {
+		if v, ok := t.byName[name]; ok {
+			return v, nil
+		}
+	}
+	return 0, UndeclaredEntryTypeError(name)
+}
+
+// Returns the name of a built-in or declared entry type
+func (t *EntryTypes) Name(entryType ChangelogEntryType) string {
+	if settings := t.customSettings(entryType); settings != nil {
+		return settings.Name
+	}
+	return entryType.String()
+}
+
+// Sets the type of the entry, so that it is written with the name of the type
+func (t *EntryTypes) SetType(entry *ChangelogEntry, entryType ChangelogEntryType) {
+	entry.Type = entryType
+	entry.typeName = ""
+	if entryType.IsCustom() {
+		entry.typeName = t.Name(entryType)
+	}
+}
+
+// Resolves the types of the entries of a file that aren't built in against the declared custom types.
+// Entries of undeclared types are left with a type that IsKnown is false for.
+func (t *EntryTypes) resolve(file *ChangelogFile) {
+	for _, entry := range file.Entries {
+		if entry.typeName == "" {
+			continue
+		}
+		entryType, err := t.ParseChangelogEntryType(entry.typeName)
+		if err != nil {
+			entryType = undeclaredEntryType
+		}
+		entry.Type = entryType
+	}
+}
+
+// Returns true if the entry type is built in or declared as a custom type
+func (t *EntryTypes) IsKnown(entryType ChangelogEntryType) bool {
+	if !entryType.IsCustom() {
+		return true
+	}
+	if t == nil {
+		return false
+	}
+	_, ok := t.custom[entryType]
+	return ok
+}
+
+func (t *EntryTypes) BreakingChange(entryType ChangelogEntryType) bool {
+	if settings := t.customSettings(entryType); settings != nil {
+		return settings.VersionBump == VersionBumpMajor
+	}
+	return entryType.BreakingChange()
+}
+
+func (t *EntryTypes) NewFeature(entryType ChangelogEntryType) bool {
+	if settings := t.customSettings(entryType); settings != nil {
+		return settings.VersionBump == VersionBumpMinor
+	}
+	return entryType.NewFeature()
+}
+
+// Returns the entry fields that must be set for the entry type
+func (t *EntryTypes) RequiredFields(entryType ChangelogEntryType) []string {
+	if settings := t.customSettings(entryType); settings != nil {
+		if settings.RequiredFields == nil {
+			return []string{IssueLinkField, DescriptionField}
+		}
+		return settings.RequiredFields
+	}
+	switch entryType {
+	case NON_USER_FACING:
+		return nil
+	case DEPENDENCY_BUMP:
+		return []string{DependencyOwnerField, DependencyRepoField, DependencyTagField}
+	default:
+		return []string{IssueLinkField, DescriptionField}
+	}
+}
+
+// Returns the sections of the rendered release notes, in order
+func (t *EntryTypes) Sections() []EntryTypeSection {
+	if t == nil {
+		return defaultEntryTypes.sections
+	}
+	return t.sections
+}
+
+// Returns every entry type that can be used: the built-in types, followed by the custom types in the order they are declared
+func (t *EntryTypes) All() []ChangelogEntryType {
+	all := []ChangelogEntryType{BREAKING_CHANGE, FIX, NEW_FEATURE, NON_USER_FACING, DEPENDENCY_BUMP, HELM, UPGRADE}
+	if t != nil {
+		all = append(all, t.customOrder...)
+	}
+	return all
+}
+
+func (t *EntryTypes) customSettings(entryType ChangelogEntryType) *EntryTypeSettings {
+	if t == nil || !entryType.IsCustom() {
+		return nil
+	}
+	return t.custom[entryType]
+}
+
+var defaultEntryTypes, _ = NewEntryTypes(nil)
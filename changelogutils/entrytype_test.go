@@ -0,0 +1,203 @@
+package changelogutils_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/solo-io/go-utils/changelogutils"
+	"github.com/solo-io/go-utils/vfsutils"
+)
+
+var _ = Describe("custom changelog entry types", func() {
+
+	Context("declaring entry types", func() {
+
+		It("orders sections relative to the built-in sections", func() {
+			entryTypes, err := changelogutils.NewEntryTypes([]*changelogutils.EntryTypeSettings{
+				{Name: "SECURITY", Heading: "Security Fixes", Order: 20},
+				{Name: "DEPRECATION", Heading: "Deprecations", Order: 100},
+				{Name: "INTERNAL_NOTE"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+
+			var headings []string
+			for _, section := range entryTypes.Sections() {
+				headings = append(headings, section.Heading)
+			}
+			Expect(headings).To(Equal([]string{
+				"Dependency Bumps",
+				"Breaking Changes",
+				"Security Fixes",
+				"Upgrade Notes",
+				"Helm Changes",
+				"New Features",
+				"Fixes",
+				"Deprecations",
+			}))
+		})
+
+		It("derives version bumps and required fields", func() {
+			entryTypes, err := changelogutils.NewEntryTypes([]*changelogutils.EntryTypeSettings{
+				{Name: "SECURITY", VersionBump: changelogutils.VersionBumpMajor},
+				{Name: "DEPRECATION", VersionBump: changelogutils.VersionBumpMinor, RequiredFields: []string{}},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			security, err := entryTypes.ParseChangelogEntryType("SECURITY")
+			Expect(err).NotTo(HaveOccurred())
+			deprecation, err := entryTypes.ParseChangelogEntryType("DEPRECATION")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(security.IsCustom()).To(BeTrue())
+			Expect(entryTypes.Name(deprecation)).To(Equal("DEPRECATION"))
+
+			Expect(entryTypes.BreakingChange(security)).To(BeTrue())
+			Expect(entryTypes.NewFeature(security)).To(BeFalse())
+			Expect(entryTypes.BreakingChange(deprecation)).To(BeFalse())
+			Expect(entryTypes.NewFeature(deprecation)).To(BeTrue())
+			Expect(entryTypes.RequiredFields(security)).To(Equal([]string{changelogutils.IssueLinkField, changelogutils.DescriptionField}))
+			Expect(entryTypes.RequiredFields(deprecation)).To(BeEmpty())
+			Expect(entryTypes.BreakingChange(changelogutils.BREAKING_CHANGE)).To(BeTrue())
+			Expect(entryTypes.NewFeature(changelogutils.NEW_FEATURE)).To(BeTrue())
+		})
+
+		It("only knows custom types that are declared", func() {
+			entryTypes, err := changelogutils.NewEntryTypes([]*changelogutils.EntryTypeSettings{{Name: "SECURITY"}})
+			Expect(err).NotTo(HaveOccurred())
+			security, err := entryTypes.ParseChangelogEntryType("SECURITY")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = entryTypes.ParseChangelogEntryType("UNDECLARED")
+			Expect(err).To(MatchError(changelogutils.UndeclaredEntryTypeError("UNDECLARED")))
+			_, err = changelogutils.ParseChangelogEntryType("SECURITY")
+			Expect(err).To(HaveOccurred())
+
+			var builtinOnly *changelogutils.EntryTypes
+			Expect(builtinOnly.IsKnown(changelogutils.FIX)).To(BeTrue())
+			Expect(builtinOnly.IsKnown(security)).To(BeFalse())
+			_, err = builtinOnly.ParseChangelogEntryType("SECURITY")
+			Expect(err).To(HaveOccurred())
+		})
+
+		DescribeTable("rejects invalid declarations",
+			func(settings []*changelogutils.EntryTypeSettings, expected error) {
+				_, err := changelogutils.NewEntryTypes(settings)
+				Expect(err).To(MatchError(expected))
+			},
+			Entry("built-in name", []*changelogutils.EntryTypeSettings{{Name: "FIX"}},
+				changelogutils.InvalidEntryTypeNameError("FIX")),
+			Entry("lower case name", []*changelogutils.EntryTypeSettings{{Name: "security"}},
+				changelogutils.InvalidEntryTypeNameError("security")),
+			Entry("duplicate name", []*changelogutils.EntryTypeSettings{{Name: "SECURITY"}, {Name: "SECURITY"}},
+				changelogutils.DuplicateEntryTypeError("SECURITY")),
+			Entry("unknown field", []*changelogutils.EntryTypeSettings{{Name: "SECURITY", RequiredFields: []string{"cve"}}},
+				changelogutils.InvalidRequiredFieldError("SECURITY", "cve")),
+			Entry("unknown version bump", []*changelogutils.EntryTypeSettings{{Name: "SECURITY", VersionBump: "huge"}},
+				changelogutils.InvalidVersionBumpError("SECURITY", "huge")),
+		)
+	})
+
+	Context("reading and rendering", func() {
+
+		var (
+			ctx      = context.Background()
+			repoRoot string
+			reader   changelogutils.ChangelogReader
+		)
+
+		writeFile := func(path, contents string) {
+			fullPath := filepath.Join(repoRoot, path)
+			Expect(os.MkdirAll(filepath.Dir(fullPath), 0755)).To(Succeed())
+			Expect(os.WriteFile(fullPath, []byte(contents), 0644)).To(Succeed())
+		}
+
+		BeforeEach(func() {
+			var err error
+			repoRoot, err = os.MkdirTemp("", "custom-entry-types")
+			Expect(err).NotTo(HaveOccurred())
+			writeFile(changelogutils.GetValidationSettingsPath(), customEntryTypesYaml)
+			code, err := vfsutils.NewLocalMountedRepoForFs(repoRoot, "solo-io", "testrepo")
+			Expect(err).NotTo(HaveOccurred())
+			reader = changelogutils.NewChangelogReader(code)
+		})
+
+		AfterEach(func() {
+			os.RemoveAll(repoRoot)
+		})
+
+		It("renders custom entry types in their declared sections", func() {
+			writeFile("changelog/v0.1.0/security.yaml", customEntryTypesChangelog)
+
+			changelog, err := reader.GetChangelogForTag(ctx, "v0.1.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(changelogutils.GenerateChangelogMarkdown(changelog)).To(Equal(`**Breaking Changes**
+
+- foo (bar)
+
+**Security Fixes**
+
+- Fixed CVE-1234. (https://github.com/solo-io/testrepo/issues/1)
+
+**Fixes**
+
+- foo1 (bar1)
+
+**Deprecations**
+
+- The old flag is deprecated.
+
+`))
+		})
+
+		It("enforces the required fields of custom entry types", func() {
+			writeFile("changelog/v0.1.0/security.yaml", `
+changelog:
+  - type: SECURITY
+    description: Fixed CVE-1234.
+`)
+			_, err := reader.GetChangelogForTag(ctx, "v0.1.0")
+			Expect(err).To(Equal(changelogutils.MissingIssueLinkError))
+		})
+
+		It("rejects entry types that aren't declared", func() {
+			writeFile("changelog/v0.1.0/security.yaml", `
+changelog:
+  - type: UNDECLARED
+    description: foo
+    issueLink: bar
+`)
+			_, err := reader.ReadChangelogFile(ctx, "changelog/v0.1.0/security.yaml")
+			Expect(err).To(MatchError(`invalid ChangelogEntryType "UNDECLARED"`))
+		})
+	})
+})
+
+const (
+	customEntryTypesYaml = `
+customEntryTypes:
+  - name: SECURITY
+    heading: Security Fixes
+    order: 25
+    versionBump: major
+  - name: DEPRECATION
+    heading: Deprecations
+    order: 100
+    requiredFields:
+      - description
+`
+
+	customEntryTypesChangelog = `
+changelog:
+  - type: DEPRECATION
+    description: The old flag is deprecated.
+  - type: FIX
+    description: foo1
+    issueLink: bar1
+  - type: SECURITY
+    description: Fixed CVE-1234.
+    issueLink: https://github.com/solo-io/testrepo/issues/1
+  - type: BREAKING_CHANGE
+    description: foo
+    issueLink: bar
+`
+)
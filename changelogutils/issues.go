@@ -0,0 +1,235 @@
+package changelogutils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/hashicorp/go-multierror"
+	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/githubutils"
+)
+
+var (
+	MalformedIssueLinkError = func(link string) error {
+		return eris.Errorf("Issue link %s is not of the form https://github.com/<owner>/<repo>/issues/<number>", link)
+	}
+	NoChangelogVersionError = eris.Errorf("Changelog has no version")
+)
+
+var issueLinkRegex = regexp.MustCompile(`^https?://github\.com/([\w.-]+)/([\w.-]+)/issues/(\d+)/?$`)
+
+// An issue referenced by a changelog entry
+type IssueReference struct {
+	Owner  string `json:"owner"`
+	Repo   string `json:"repo"`
+	Number int    `json:"number"`
+}
+
+func (r IssueReference) String() string {
+	return fmt.Sprintf("%s/%s#%d", r.Owner, r.Repo, r.Number)
+}
+
+// Parses an issue link of the form https://github.com/<owner>/<repo>/issues/<number>
+func ParseIssueLink(link string) (*IssueReference, error) {
+	match := issueLinkRegex.FindStringSubmatch(strings.TrimSpace(link))
+	if match == nil {
+		return nil, MalformedIssueLinkError(link)
+	}
+	number, err := strconv.Atoi(match[3])
+	if err != nil {
+		return nil, MalformedIssueLinkError(link)
+	}
+	return &IssueReference{Owner: match[1], Repo: match[2], Number: number}, nil
+}
+
+// What happened to an issue link when resolving the issues of a release
+type IssueLinkStatus string
+
+const (
+	// The issue was commented on and closed (or would have been, in dry-run mode)
+	IssueLinkResolved IssueLinkStatus = "resolved"
+	// No entry referencing the issue sets resolvesIssue
+	IssueLinkNotResolved IssueLinkStatus = "not-resolved"
+	// The issue is already closed
+	IssueLinkAlreadyClosed IssueLinkStatus = "already-closed"
+	// The link isn't a GitHub issue link
+	IssueLinkMalformed IssueLinkStatus = "malformed"
+	// The issue is in another repo, and IssueResolverOptions.ResolveCrossRepoIssues is not set
+	IssueLinkCrossRepo IssueLinkStatus = "cross-repo"
+	// The issue couldn't be read, commented on or closed
+	IssueLinkFailed IssueLinkStatus = "failed"
+)
+
+type IssueLinkResult struct {
+	Link string `json:"link"`
+	// nil if the link is malformed
+	Issue  *IssueReference `json:"issue,omitempty"`
+	Status IssueLinkStatus `json:"status"`
+	Err    error           `json:"-"`
+	// the entries that reference the issue
+	Entries []*ChangelogEntry `json:"-"`
+}
+
+// The outcome of ResolveIssuesForRelease, with a result per distinct issue link in the changelog
+type IssueResolutionReport struct {
+	Version string             `json:"version"`
+	DryRun  bool               `json:"dryRun"`
+	Results []*IssueLinkResult `json:"results"`
+}
+
+// Returns the results with the given statuses
+func (r *IssueResolutionReport) Filter(statuses ...IssueLinkStatus) []*IssueLinkResult {
+	var filtered []*IssueLinkResult
+	for _, result := range r.Results {
+		for _, status := range statuses {
+			if result.Status == status {
+				filtered = append(filtered, result)
+				break
+			}
+		}
+	}
+	return filtered
+}
+
+// Returns the links that may need attention: malformed, cross-repo and already closed links, and failures
+func (r *IssueResolutionReport) Problems() []*IssueLinkResult {
+	return r.Filter(IssueLinkMalformed, IssueLinkCrossRepo, IssueLinkAlreadyClosed, IssueLinkFailed)
+}
+
+// Returns the errors of failed and malformed links combined into a single error, or nil
+func (r *IssueResolutionReport) ToError() error {
+	var result *multierror.Error
+	for _, link := range r.Filter(IssueLinkFailed, IssueLinkMalformed) {
+		result = multierror.Append(result, link.Err)
+	}
+	return result.ErrorOrNil()
+}
+
+func (r *IssueResolutionReport) RenderText(w io.Writer) error {
+	action := "Resolved"
+	if r.DryRun {
+		action = "Would resolve"
+	}
+	if _, err := fmt.Fprintf(w, "Issues referenced by the changelog for %s:\n", r.Version); err != nil {
+		return err
+	}
+	if len(r.Results) == 0 {
+		_, err := fmt.Fprintln(w, "No issue links found.")
+		return err
+	}
+	for _, result := range r.Results {
+		var line string
+		switch result.Status {
+		case IssueLinkResolved:
+			line = fmt.Sprintf("%s %s", action, result.Issue)
+		case IssueLinkNotResolved:
+			line = fmt.Sprintf("Skipped %s: resolvesIssue is false", result.Issue)
+		case IssueLinkAlreadyClosed:
+			line = fmt.Sprintf("Skipped %s: already closed", result.Issue)
+		case IssueLinkCrossRepo:
+			line = fmt.Sprintf("Skipped %s: issue is in another repo", result.Issue)
+		case IssueLinkMalformed:
+			line = fmt.Sprintf("Malformed issue link %q", result.Link)
+		default:
+			line = fmt.Sprintf("Failed to resolve %s: %v", result.Link, result.Err)
+		}
+		if _, err := fmt.Fprintf(w, "- %s\n", line); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+type IssueResolverOptions struct {
+	// The repo the release is published in
+	Owner string
+	Repo  string
+	// If true, issues are read but not commented on or closed
+	DryRun bool
+	// If true, issues in repos other than Owner/Repo are resolved as well. Otherwise they are only reported.
+	ResolveCrossRepoIssues bool
+	// Format of the comment posted on resolved issues, with the version and the URL of the release as arguments.
+	// Defaults to DefaultIssueResolvedComment.
+	CommentFormat string
+}
+
+const DefaultIssueResolvedComment = "This issue has been resolved in [%s](%s)."
+
+// Comments on and closes the issues referenced by the entries of a released changelog that set resolvesIssue
+// (which defaults to true), and reports on every issue link found. Failures to resolve an individual issue are
+// recorded in the report rather than stopping the remaining issues from being resolved; use ToError on the report
+// to fail a release step on them.
+func ResolveIssuesForRelease(ctx context.Context, client githubutils.IssueClient, changelog *Changelog, opts IssueResolverOptions) (*IssueResolutionReport, error) {
+	if changelog.Version == nil {
+		return nil, NoChangelogVersionError
+	}
+	version := changelog.Version.String()
+	report := &IssueResolutionReport{Version: version, DryRun: opts.DryRun}
+	commentFormat := opts.CommentFormat
+	if commentFormat == "" {
+		commentFormat = DefaultIssueResolvedComment
+	}
+	releaseUrl := fmt.Sprintf("https://github.com/%s/%s/releases/tag/%s", opts.Owner, opts.Repo, version)
+
+	// group entries by link, so that an issue referenced by several entries is only commented on once
+	resultsByLink := make(map[string]*IssueLinkResult)
+	resolves := make(map[string]bool)
+	for _, file := range changelog.Files {
+		for _, entry := range file.Entries {
+			link := strings.TrimSpace(entry.IssueLink)
+			if link == "" {
+				continue
+			}
+			result, ok := resultsByLink[link]
+			if !ok {
+				result = &IssueLinkResult{Link: link}
+				resultsByLink[link] = result
+				report.Results = append(report.Results, result)
+			}
+			result.Entries = append(result.Entries, entry)
+			resolves[link] = resolves[link] || entry.GetResolvesIssue()
+		}
+	}
+
+	for _, result := range report.Results {
+		issue, err := ParseIssueLink(result.Link)
+		if err != nil {
+			result.Status, result.Err = IssueLinkMalformed, err
+			continue
+		}
+		result.Issue = issue
+		if !resolves[result.Link] {
+			result.Status = IssueLinkNotResolved
+			continue
+		}
+		if !opts.ResolveCrossRepoIssues && (!strings.EqualFold(issue.Owner, opts.Owner) || !strings.EqualFold(issue.Repo, opts.Repo)) {
+			result.Status = IssueLinkCrossRepo
+			continue
+		}
+		existing, err := client.GetIssue(ctx, issue.Owner, issue.Repo, issue.Number)
+		if err != nil {
+			result.Status, result.Err = IssueLinkFailed, err
+			continue
+		}
+		if existing.GetState() == githubutils.ISSUE_STATE_CLOSED {
+			result.Status = IssueLinkAlreadyClosed
+			continue
+		}
+		result.Status = IssueLinkResolved
+		if opts.DryRun {
+			continue
+		}
+		if _, err := client.CreateIssueComment(ctx, issue.Owner, issue.Repo, issue.Number, fmt.Sprintf(commentFormat, version, releaseUrl)); err != nil {
+			result.Status, result.Err = IssueLinkFailed, err
+			continue
+		}
+		if err := client.CloseIssue(ctx, issue.Owner, issue.Repo, issue.Number); err != nil {
+			result.Status, result.Err = IssueLinkFailed, err
+		}
+	}
+	return report, nil
+}
@@ -0,0 +1,160 @@
+package changelogutils_test
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+
+	"github.com/google/go-github/v32/github"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/changelogutils"
+	"github.com/solo-io/go-utils/githubutils"
+	"github.com/solo-io/go-utils/versionutils"
+)
+
+// records the comments and closed issues, keyed by owner/repo#number
+type fakeIssueClient struct {
+	states   map[string]string
+	comments map[string][]string
+	closed   []string
+}
+
+var _ githubutils.IssueClient = &fakeIssueClient{}
+
+func issueKey(owner, repo string, number int) string {
+	return fmt.Sprintf("%s/%s#%d", owner, repo, number)
+}
+
+func (c *fakeIssueClient) GetIssue(_ context.Context, owner, repo string, number int) (*github.Issue, error) {
+	state, ok := c.states[issueKey(owner, repo, number)]
+	if !ok {
+		return nil, eris.Errorf("issue %s not found", issueKey(owner, repo, number))
+	}
+	return &github.Issue{Number: github.Int(number), State: github.String(state)}, nil
+}
+
+func (c *fakeIssueClient) CreateIssueComment(_ context.Context, owner, repo string, number int, body string) (*github.IssueComment, error) {
+	key := issueKey(owner, repo, number)
+	c.comments[key] = append(c.comments[key], body)
+	return &github.IssueComment{Body: github.String(body)}, nil
+}
+
+func (c *fakeIssueClient) CloseIssue(_ context.Context, owner, repo string, number int) error {
+	c.closed = append(c.closed, issueKey(owner, repo, number))
+	return nil
+}
+
+var _ = Describe("resolving issues on release", func() {
+
+	var (
+		ctx       = context.Background()
+		client    *fakeIssueClient
+		changelog *changelogutils.Changelog
+		opts      changelogutils.IssueResolverOptions
+	)
+
+	BeforeEach(func() {
+		client = &fakeIssueClient{
+			states: map[string]string{
+				"solo-io/testrepo#1": githubutils.ISSUE_STATE_OPEN,
+				"solo-io/testrepo#2": githubutils.ISSUE_STATE_OPEN,
+				"solo-io/testrepo#3": githubutils.ISSUE_STATE_CLOSED,
+				"solo-io/other#4":    githubutils.ISSUE_STATE_OPEN,
+			},
+			comments: map[string][]string{},
+		}
+		resolvesIssue := false
+		changelog = &changelogutils.Changelog{
+			Version: versionutils.NewVersion(1, 2, 3, "", 0),
+			Files: []*changelogutils.ChangelogFile{
+				{Entries: []*changelogutils.ChangelogEntry{
+					{Type: changelogutils.FIX, Description: "a", IssueLink: "https://github.com/solo-io/testrepo/issues/1"},
+					{Type: changelogutils.FIX, Description: "b", IssueLink: "https://github.com/solo-io/testrepo/issues/2", ResolvesIssue: &resolvesIssue},
+					{Type: changelogutils.NON_USER_FACING},
+				}},
+				{Entries: []*changelogutils.ChangelogEntry{
+					{Type: changelogutils.NEW_FEATURE, Description: "c", IssueLink: "https://github.com/solo-io/testrepo/issues/1"},
+					{Type: changelogutils.FIX, Description: "d", IssueLink: "https://github.com/solo-io/testrepo/issues/3"},
+					{Type: changelogutils.FIX, Description: "e", IssueLink: "https://github.com/solo-io/other/issues/4"},
+					{Type: changelogutils.FIX, Description: "f", IssueLink: "https://github.com/solo-io/testrepo/pull/5"},
+				}},
+			},
+		}
+		opts = changelogutils.IssueResolverOptions{Owner: "solo-io", Repo: "testrepo"}
+	})
+
+	statuses := func(report *changelogutils.IssueResolutionReport) map[string]changelogutils.IssueLinkStatus {
+		result := make(map[string]changelogutils.IssueLinkStatus)
+		for _, link := range report.Results {
+			result[link.Link] = link.Status
+		}
+		return result
+	}
+
+	It("comments on and closes resolved issues, and reports the other links", func() {
+		report, err := changelogutils.ResolveIssuesForRelease(ctx, client, changelog, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statuses(report)).To(Equal(map[string]changelogutils.IssueLinkStatus{
+			"https://github.com/solo-io/testrepo/issues/1": changelogutils.IssueLinkResolved,
+			"https://github.com/solo-io/testrepo/issues/2": changelogutils.IssueLinkNotResolved,
+			"https://github.com/solo-io/testrepo/issues/3": changelogutils.IssueLinkAlreadyClosed,
+			"https://github.com/solo-io/other/issues/4":    changelogutils.IssueLinkCrossRepo,
+			"https://github.com/solo-io/testrepo/pull/5":   changelogutils.IssueLinkMalformed,
+		}))
+		Expect(report.Results[0].Entries).To(HaveLen(2))
+		Expect(client.closed).To(Equal([]string{"solo-io/testrepo#1"}))
+		Expect(client.comments).To(Equal(map[string][]string{
+			"solo-io/testrepo#1": {"This issue has been resolved in [v1.2.3](https://github.com/solo-io/testrepo/releases/tag/v1.2.3)."},
+		}))
+		Expect(report.Problems()).To(HaveLen(3))
+		Expect(report.ToError()).To(MatchError(ContainSubstring(changelogutils.MalformedIssueLinkError("https://github.com/solo-io/testrepo/pull/5").Error())))
+
+		var buf bytes.Buffer
+		Expect(report.RenderText(&buf)).To(Succeed())
+		Expect(buf.String()).To(Equal(`Issues referenced by the changelog for v1.2.3:
+- Resolved solo-io/testrepo#1
+- Skipped solo-io/testrepo#2: resolvesIssue is false
+- Skipped solo-io/testrepo#3: already closed
+- Skipped solo-io/other#4: issue is in another repo
+- Malformed issue link "https://github.com/solo-io/testrepo/pull/5"
+`))
+	})
+
+	It("does not modify issues in dry-run mode", func() {
+		opts.DryRun = true
+		opts.ResolveCrossRepoIssues = true
+		report, err := changelogutils.ResolveIssuesForRelease(ctx, client, changelog, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Filter(changelogutils.IssueLinkResolved)).To(HaveLen(2))
+		Expect(client.closed).To(BeEmpty())
+		Expect(client.comments).To(BeEmpty())
+	})
+
+	It("records failures and continues", func() {
+		delete(client.states, "solo-io/testrepo#1")
+		opts.ResolveCrossRepoIssues = true
+		report, err := changelogutils.ResolveIssuesForRelease(ctx, client, changelog, opts)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(statuses(report)["https://github.com/solo-io/testrepo/issues/1"]).To(Equal(changelogutils.IssueLinkFailed))
+		Expect(client.closed).To(Equal([]string{"solo-io/other#4"}))
+		Expect(report.ToError()).To(HaveOccurred())
+	})
+
+	DescribeTable("parses issue links",
+		func(link string, expected *changelogutils.IssueReference) {
+			issue, err := changelogutils.ParseIssueLink(link)
+			if expected == nil {
+				Expect(err).To(MatchError(changelogutils.MalformedIssueLinkError(link)))
+				return
+			}
+			Expect(err).NotTo(HaveOccurred())
+			Expect(issue).To(Equal(expected))
+		},
+		Entry("issue", "https://github.com/solo-io/gloo/issues/12", &changelogutils.IssueReference{Owner: "solo-io", Repo: "gloo", Number: 12}),
+		Entry("trailing slash", " https://github.com/solo-io/gloo/issues/12/ ", &changelogutils.IssueReference{Owner: "solo-io", Repo: "gloo", Number: 12}),
+		Entry("no number", "https://github.com/solo-io/gloo/issues", nil),
+		Entry("not github", "https://example.com/solo-io/gloo/issues/12", nil),
+	)
+})
@@ -0,0 +1,638 @@
+package changelogutils
+
+import (
+	"bytes"
+	"context"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+
+	"github.com/ghodss/yaml"
+	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/vfsutils"
+	"github.com/spf13/afero"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+// Lint rule IDs. Unlike the other rules, lint rules only run when they are enabled in the lint section of
+// validation.yaml (see LintSettings).
+const (
+	RuleDuplicateEntry            = "duplicate-entry"
+	RuleShortDescription          = "short-description"
+	RuleTrailingWhitespace        = "trailing-whitespace"
+	RuleDescriptionFormat         = "description-format"
+	RuleIssueLinkRepo             = "issue-link-repo"
+	RuleBreakingChangeUpgradeNote = "breaking-change-upgrade-note"
+)
+
+const (
+	// Disables a lint rule
+	LintRuleOff Severity = "off"
+
+	DefaultMinDescriptionLength = 15
+)
+
+var (
+	LintRules = []string{
+		RuleDuplicateEntry,
+		RuleShortDescription,
+		RuleTrailingWhitespace,
+		RuleDescriptionFormat,
+		RuleIssueLinkRepo,
+		RuleBreakingChangeUpgradeNote,
+	}
+
+	// the rules whose problems FixChangelogFileContents can fix
+	fixableLintRules = []string{
+		RuleTrailingWhitespace,
+		RuleDescriptionFormat,
+	}
+
+	UnknownLintRuleError = func(rule string) error {
+		return eris.Errorf("Unknown lint rule %s, must be one of %v", rule, LintRules)
+	}
+	InvalidLintSeverityError = func(rule string, severity Severity) error {
+		return eris.Errorf("Invalid severity %s for lint rule %s, must be one of error, warning, notice or off", severity, rule)
+	}
+	InvalidIssueLinkRepoError = func(repo string) error {
+		return eris.Errorf("Issue link repo %s is not of the form <owner>/<repo>", repo)
+	}
+	DuplicateEntryError = func(file string, index int) error {
+		return eris.Errorf("Entry has the same type and description as entry %d in %s", index, file)
+	}
+	ShortDescriptionError = func(length, minLength int) error {
+		return eris.Errorf("Description is %d characters long, expected at least %d", length, minLength)
+	}
+	TrailingWhitespaceError         = eris.Errorf("Line has trailing whitespace")
+	DescriptionNotCapitalizedError  = eris.Errorf("Description should start with a capital letter")
+	DescriptionMissingFullStopError = eris.Errorf("Description should end with a period")
+	IssueLinkRepoError              = func(link string, repos []string) error {
+		return eris.Errorf("Issue link %s does not point at an issue in %s", link, strings.Join(repos, ", "))
+	}
+	BreakingChangeWithoutUpgradeNoteError = func(version string) error {
+		return eris.Errorf("Breaking change in %s has no UPGRADE entry in the same file or with the same issue link describing how to upgrade", version)
+	}
+)
+
+var issueLinkRepoRegex = regexp.MustCompile(`^[\w.-]+/[\w.-]+$`)
+
+// Configures the lint rules, in the lint section of validation.yaml:
+//
+//	lint:
+//	  rules:
+//	    duplicate-entry: error
+//	    description-format: warning
+//	  minDescriptionLength: 20
+type LintSettings struct {
+	// The severity each lint rule is reported with, keyed by rule ID. Rules that are not listed (or are "off") don't run.
+	Rules map[string]Severity `json:"rules"`
+	// The minimum length of a description for the short-description rule. Defaults to DefaultMinDescriptionLength.
+	MinDescriptionLength int `json:"minDescriptionLength"`
+	// The repos (as owner/repo) that issue links may point at for the issue-link-repo rule.
+	// Defaults to the repo the changelog is in.
+	IssueLinkRepos []string `json:"issueLinkRepos"`
+}
+
+// Returns settings that report every lint rule as a warning
+func DefaultLintSettings() *LintSettings {
+	rules := make(map[string]Severity)
+	for _, rule := range LintRules {
+		rules[rule] = SeverityWarning
+	}
+	return &LintSettings{Rules: rules}
+}
+
+func (s *LintSettings) Validate() error {
+	if s == nil {
+		return nil
+	}
+	for rule, severity := range s.Rules {
+		if !isLintRule(rule) {
+			return UnknownLintRuleError(rule)
+		}
+		switch severity {
+		case SeverityError, SeverityWarning, SeverityNotice, LintRuleOff:
+		default:
+			return InvalidLintSeverityError(rule, severity)
+		}
+	}
+	for _, repo := range s.IssueLinkRepos {
+		if !issueLinkRepoRegex.MatchString(repo) {
+			return InvalidIssueLinkRepoError(repo)
+		}
+	}
+	return nil
+}
+
+func (s *LintSettings) Enabled(rule string) bool {
+	return s.severity(rule) != LintRuleOff
+}
+
+func (s *LintSettings) severity(rule string) Severity {
+	if s == nil {
+		return LintRuleOff
+	}
+	severity, ok := s.Rules[rule]
+	if !ok || severity == "" {
+		return LintRuleOff
+	}
+	return severity
+}
+
+func (s *LintSettings) minDescriptionLength() int {
+	if s.MinDescriptionLength > 0 {
+		return s.MinDescriptionLength
+	}
+	return DefaultMinDescriptionLength
+}
+
+func isLintRule(rule string) bool {
+	for _, r := range LintRules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+func isFixableLintRule(rule string) bool {
+	for _, r := range fixableLintRules {
+		if r == rule {
+			return true
+		}
+	}
+	return false
+}
+
+// A parsed changelog file to lint
+type LintFile struct {
+	// path of the file, relative to the repo root
+	Path     string
+	Contents []byte
+	File     *ChangelogFile
+}
+
+type LintOptions struct {
+	// The repo the changelog is in, used by the issue-link-repo rule when LintSettings.IssueLinkRepos is empty
+	Owner string
+	Repo  string
+	// The entry types of the repo, used to find breaking changes. Nil if the repo has no custom entry types.
+	EntryTypes *EntryTypes
+}
+
+// Runs the enabled lint rules over the changelog files of a single version, and returns the problems found.
+// Files are expected to have passed validation already; entries with an unknown type are linted like any other.
+func LintChangelogFiles(version string, files []*LintFile, settings *LintSettings, opts LintOptions) *ValidationReport {
+	report := NewValidationReport()
+	if settings == nil {
+		return report
+	}
+	add := func(file *LintFile, lines []int, index int, field, rule string, err error) {
+		violation := &Violation{
+			File:       file.Path,
+			EntryIndex: index,
+			Field:      field,
+			RuleId:     rule,
+			Severity:   settings.severity(rule),
+			Err:        err,
+			Fixable:    isFixableLintRule(rule),
+		}
+		if index != NoEntryIndex && index < len(lines) {
+			violation.Line = lines[index]
+		}
+		report.Add(violation)
+	}
+
+	for _, file := range files {
+		if file.File != nil {
+			opts.EntryTypes.resolve(file.File)
+		}
+	}
+
+	issueLinkRepos := settings.IssueLinkRepos
+	if len(issueLinkRepos) == 0 && opts.Owner != "" && opts.Repo != "" {
+		issueLinkRepos = []string{opts.Owner + "/" + opts.Repo}
+	}
+
+	type entryRef struct {
+		path  string
+		index int
+		link  string
+	}
+	seen := make(map[string]entryRef)
+	var breakingChanges []entryRef
+	// an upgrade note describes the breaking changes in its file, and those with the same issue link
+	upgradeNoteFiles := make(map[string]bool)
+	upgradeNoteLinks := make(map[string]bool)
+
+	for _, file := range files {
+		if file.File == nil {
+			continue
+		}
+		lines := entryLines(file.Contents)
+
+		if settings.Enabled(RuleTrailingWhitespace) {
+			for i, line := range strings.Split(string(file.Contents), "\n") {
+				if strings.TrimRight(line, " \t\r") == line {
+					continue
+				}
+				violation := &Violation{
+					File:       file.Path,
+					EntryIndex: entryIndexForLine(lines, i+1),
+					Line:       i + 1,
+					RuleId:     RuleTrailingWhitespace,
+					Severity:   settings.severity(RuleTrailingWhitespace),
+					Err:        TrailingWhitespaceError,
+					Fixable:    true,
+				}
+				report.Add(violation)
+			}
+		}
+
+		for i, entry := range file.File.Entries {
+			link := normalizeIssueLink(entry.IssueLink)
+			if opts.EntryTypes.BreakingChange(entry.Type) {
+				breakingChanges = append(breakingChanges, entryRef{path: file.Path, index: i, link: link})
+			}
+			if entry.Type == UPGRADE {
+				upgradeNoteFiles[file.Path] = true
+				if link != "" {
+					upgradeNoteLinks[link] = true
+				}
+			}
+
+			description := strings.TrimSpace(entry.Description)
+			if description != "" {
+				if settings.Enabled(RuleDuplicateEntry) {
+					key := entry.Type.String() + ":" + normalizeDescription(description)
+					if first, ok := seen[key]; ok {
+						add(file, lines, i, DescriptionField, RuleDuplicateEntry, DuplicateEntryError(first.path, first.index))
+					} else {
+						seen[key] = entryRef{path: file.Path, index: i}
+					}
+				}
+				if settings.Enabled(RuleShortDescription) {
+					if length := utf8.RuneCountInString(description); length < settings.minDescriptionLength() {
+						add(file, lines, i, DescriptionField, RuleShortDescription, ShortDescriptionError(length, settings.minDescriptionLength()))
+					}
+				}
+				if settings.Enabled(RuleDescriptionFormat) {
+					capitalize, fullStop := descriptionFormatProblems(description)
+					if capitalize {
+						add(file, lines, i, DescriptionField, RuleDescriptionFormat, DescriptionNotCapitalizedError)
+					}
+					if fullStop {
+						add(file, lines, i, DescriptionField, RuleDescriptionFormat, DescriptionMissingFullStopError)
+					}
+				}
+			}
+
+			if link := strings.TrimSpace(entry.IssueLink); link != "" && settings.Enabled(RuleIssueLinkRepo) {
+				issue, err := ParseIssueLink(link)
+				if err != nil {
+					add(file, lines, i, IssueLinkField, RuleIssueLinkRepo, err)
+				} else if len(issueLinkRepos) > 0 && !containsRepo(issueLinkRepos, issue.Owner, issue.Repo) {
+					add(file, lines, i, IssueLinkField, RuleIssueLinkRepo, IssueLinkRepoError(link, issueLinkRepos))
+				}
+			}
+		}
+	}
+
+	if settings.Enabled(RuleBreakingChangeUpgradeNote) {
+		for _, ref := range breakingChanges {
+			if upgradeNoteFiles[ref.path] || (ref.link != "" && upgradeNoteLinks[ref.link]) {
+				continue
+			}
+			for _, file := range files {
+				if file.Path == ref.path {
+					add(file, entryLines(file.Contents), ref.index, "type", RuleBreakingChangeUpgradeNote, BreakingChangeWithoutUpgradeNoteError(version))
+					break
+				}
+			}
+		}
+	}
+	return report
+}
+
+// Returns the index of the entry that contains the 1-based line, given the line each entry starts on
+func entryIndexForLine(entryLines []int, line int) int {
+	index := NoEntryIndex
+	for i, start := range entryLines {
+		if start <= line {
+			index = i
+		}
+	}
+	return index
+}
+
+func normalizeIssueLink(link string) string {
+	return strings.ToLower(strings.TrimSuffix(strings.TrimSpace(link), "/"))
+}
+
+func normalizeDescription(description string) string {
+	return strings.TrimRight(strings.ToLower(strings.Join(strings.Fields(description), " ")), ".")
+}
+
+func containsRepo(repos []string, owner, repo string) bool {
+	for _, r := range repos {
+		if strings.EqualFold(r, owner+"/"+repo) {
+			return true
+		}
+	}
+	return false
+}
+
+// Descriptions should be complete sentences. A description is only expected to start with a capital letter if its
+// first word is a plain lowercase word (rather than e.g. `code`, a flag or a path), and to end with a period if it ends
+// with a letter, digit, closing parenthesis or code span.
+func descriptionFormatProblems(description string) (capitalize, fullStop bool) {
+	description = strings.TrimSpace(description)
+	if description == "" {
+		return false, false
+	}
+	firstWord := strings.TrimRight(strings.Fields(description)[0], ",:;")
+	capitalize = firstWord != ""
+	for _, r := range firstWord {
+		if !unicode.IsLower(r) {
+			capitalize = false
+			break
+		}
+	}
+	last, _ := utf8.DecodeLastRuneInString(description)
+	fullStop = unicode.IsLetter(last) || unicode.IsDigit(last) || last == ')' || last == '`'
+	return capitalize, fullStop
+}
+
+// Fixes the mechanical problems reported by the enabled lint rules in the contents of a changelog file: trailing
+// whitespace, and descriptions that don't start with a capital letter or end with a period. Only the offending
+// characters are changed, so the rest of the file keeps its formatting and comments.
+// Returns the contents unchanged if there is nothing to fix.
+func FixChangelogFileContents(contents []byte, settings *LintSettings) ([]byte, error) {
+	fixed := contents
+	if settings.Enabled(RuleDescriptionFormat) {
+		var err error
+		fixed, err = fixDescriptions(fixed)
+		if err != nil {
+			return nil, err
+		}
+	}
+	if settings.Enabled(RuleTrailingWhitespace) {
+		lines := strings.Split(string(fixed), "\n")
+		for i, line := range lines {
+			lines[i] = strings.TrimRight(line, " \t\r")
+		}
+		fixed = []byte(strings.Join(lines, "\n"))
+	}
+	return fixed, nil
+}
+
+// an edit to the contents of a file, replacing length bytes at offset with text
+type textEdit struct {
+	offset int
+	length int
+	text   string
+}
+
+func fixDescriptions(contents []byte) ([]byte, error) {
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(contents, &root); err != nil {
+		return nil, err
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yamlv3.MappingNode {
+		return contents, nil
+	}
+
+	// yaml.v3 only records where a node starts, so a scalar is taken to end where the next node starts
+	lineOffsets := []int{0}
+	for i, c := range contents {
+		if c == '\n' {
+			lineOffsets = append(lineOffsets, i+1)
+		}
+	}
+	var nodeLines []int
+	var collectLines func(node *yamlv3.Node)
+	collectLines = func(node *yamlv3.Node) {
+		nodeLines = append(nodeLines, node.Line)
+		for _, child := range node.Content {
+			collectLines(child)
+		}
+	}
+	collectLines(&root)
+	scalarEnd := func(node *yamlv3.Node) int {
+		end := len(contents)
+		for _, line := range nodeLines {
+			if line > node.Line && line <= len(lineOffsets) && lineOffsets[line-1] < end {
+				end = lineOffsets[line-1]
+			}
+		}
+		return end
+	}
+
+	var edits []textEdit
+	doc := root.Content[0]
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value != "changelog" || doc.Content[i+1].Kind != yamlv3.SequenceNode {
+			continue
+		}
+		for _, entry := range doc.Content[i+1].Content {
+			if entry.Kind != yamlv3.MappingNode {
+				continue
+			}
+			for j := 0; j+1 < len(entry.Content); j += 2 {
+				value := entry.Content[j+1]
+				if entry.Content[j].Value != DescriptionField || value.Kind != yamlv3.ScalarNode {
+					continue
+				}
+				capitalize, fullStop := descriptionFormatProblems(value.Value)
+				if !capitalize && !fullStop {
+					continue
+				}
+				start := lineOffsets[value.Line-1] + value.Column - 1
+				source := string(contents[start:scalarEnd(value)])
+				source = source[:scalarSourceLength(source, value.Style)]
+				words := strings.Fields(value.Value)
+				if capitalize {
+					if index := strings.Index(source, words[0]); index >= 0 {
+						first, size := utf8.DecodeRuneInString(words[0])
+						edits = append(edits, textEdit{offset: start + index, length: size, text: string(unicode.ToUpper(first))})
+					}
+				}
+				if fullStop {
+					lastWord := words[len(words)-1]
+					if index := strings.LastIndex(source, lastWord); index >= 0 {
+						edits = append(edits, textEdit{offset: start + index + len(lastWord), text: "."})
+					}
+				}
+			}
+		}
+	}
+	if len(edits) == 0 {
+		return contents, nil
+	}
+	sort.Slice(edits, func(i, j int) bool {
+		return edits[i].offset > edits[j].offset
+	})
+	fixed := string(contents)
+	for _, edit := range edits {
+		fixed = fixed[:edit.offset] + edit.text + fixed[edit.offset+edit.length:]
+	}
+	return []byte(fixed), nil
+}
+
+// Returns the length of the source of a scalar at the start of source, leaving out the comments and blank lines
+// that follow it
+func scalarSourceLength(source string, style yamlv3.Style) int {
+	switch {
+	case style&yamlv3.DoubleQuotedStyle != 0:
+		for i := 1; i < len(source); i++ {
+			switch source[i] {
+			case '\\':
+				i++
+			case '"':
+				return i + 1
+			}
+		}
+	case style&yamlv3.SingleQuotedStyle != 0:
+		for i := 1; i < len(source); i++ {
+			if source[i] != '\'' {
+				continue
+			}
+			// a quote is escaped by doubling it
+			if i+1 < len(source) && source[i+1] == '\'' {
+				i++
+				continue
+			}
+			return i + 1
+		}
+	case style&(yamlv3.LiteralStyle|yamlv3.FoldedStyle) != 0:
+		// the header (which may have a comment), then every line indented at least as much as the first line of content
+		header := strings.IndexByte(source, '\n')
+		if header < 0 {
+			return len(source)
+		}
+		end, offset, indent := header, header+1, -1
+		for _, line := range strings.SplitAfter(source[header+1:], "\n") {
+			offset += len(line)
+			content := strings.TrimLeft(line, " ")
+			if strings.TrimSpace(content) == "" {
+				continue
+			}
+			if indent < 0 {
+				indent = len(line) - len(content)
+			} else if len(line)-len(content) < indent {
+				break
+			}
+			end = offset
+		}
+		return end
+	default:
+		// a comment starts with a # after whitespace, which a plain scalar can't contain
+		for i := 1; i < len(source); i++ {
+			if source[i] == '#' && strings.ContainsRune(" \t\n", rune(source[i-1])) {
+				return len(strings.TrimRight(source[:i], " \t\n"))
+			}
+		}
+	}
+	return len(source)
+}
+
+// Lints the changelog files of a version in the changelog directory of the repo. If settings is nil, the lint
+// section of validation.yaml is used, and no rules run if there isn't one.
+func LintChangelogForTag(ctx context.Context, code vfsutils.MountedRepo, tag string, settings *LintSettings) (*ValidationReport, error) {
+	validationSettings, err := readValidationSettings(ctx, code)
+	if err != nil {
+		return nil, UnableToGetSettingsError(err)
+	}
+	if settings == nil {
+		settings = validationSettings.Lint
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, UnableToGetSettingsError(err)
+	}
+	files, err := readLintFiles(ctx, code, getVersionDirectory(validationSettings, tag))
+	if err != nil {
+		return nil, err
+	}
+	return LintChangelogFiles(tag, files, settings, LintOptions{
+		Owner:      code.GetOwner(),
+		Repo:       code.GetRepo(),
+		EntryTypes: entryTypesOrDefault(validationSettings),
+	}), nil
+}
+
+// Fixes the mechanical lint problems in the changelog files of a version, writing the fixed files to fs under
+// repoRootPath. code should be mounted from the same directory. If settings is nil, the lint section of
+// validation.yaml is used. Returns the paths of the files that were changed.
+func FixChangelogForTag(ctx context.Context, code vfsutils.MountedRepo, fs afero.Fs, repoRootPath, tag string, settings *LintSettings) ([]string, error) {
+	validationSettings, err := readValidationSettings(ctx, code)
+	if err != nil {
+		return nil, UnableToGetSettingsError(err)
+	}
+	if settings == nil {
+		settings = validationSettings.Lint
+	}
+	if err := settings.Validate(); err != nil {
+		return nil, UnableToGetSettingsError(err)
+	}
+	files, err := readLintFiles(ctx, code, getVersionDirectory(validationSettings, tag))
+	if err != nil {
+		return nil, err
+	}
+	var changed []string
+	for _, file := range files {
+		if file.File == nil {
+			continue
+		}
+		fixed, err := FixChangelogFileContents(file.Contents, settings)
+		if err != nil {
+			return nil, UnableToParseChangelogError(err, file.Path)
+		}
+		if bytes.Equal(fixed, file.Contents) {
+			continue
+		}
+		if err := afero.WriteFile(fs, filepath.Join(repoRootPath, file.Path), fixed, 0644); err != nil {
+			return nil, err
+		}
+		changed = append(changed, file.Path)
+	}
+	return changed, nil
+}
+
+// Returns the names of the version directories in the changelog directory of the repo, e.g. to lint all of them
+func ListChangelogVersions(ctx context.Context, code vfsutils.MountedRepo) ([]string, error) {
+	settings, err := readValidationSettings(ctx, code)
+	if err != nil {
+		return nil, UnableToGetSettingsError(err)
+	}
+	return listChangelogVersions(ctx, code, settings)
+}
+
+// Reads the changelog files of a version, leaving out the summary and closing files. Files that can't be parsed
+// are returned without a parsed ChangelogFile, since the validator already reports them.
+func readLintFiles(ctx context.Context, code vfsutils.MountedRepo, changelogPath string) ([]*LintFile, error) {
+	children, err := code.ListFiles(ctx, changelogPath)
+	if err != nil {
+		return nil, UnableToListFilesError(err, changelogPath)
+	}
+	var files []*LintFile
+	for _, child := range children {
+		if child.IsDir() || child.Name() == SummaryFile || child.Name() == ClosingFile {
+			continue
+		}
+		path := filepath.Join(changelogPath, child.Name())
+		contents, err := code.GetFileContents(ctx, path)
+		if err != nil {
+			return nil, err
+		}
+		file := &LintFile{Path: path, Contents: contents}
+		var changelogFile ChangelogFile
+		if err := yaml.Unmarshal(contents, &changelogFile); err == nil {
+			file.File = &changelogFile
+		}
+		files = append(files, file)
+	}
+	return files, nil
+}
@@ -0,0 +1,196 @@
+package changelogutils_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/solo-io/go-utils/changelogutils"
+	"github.com/solo-io/go-utils/vfsutils"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("changelog lint", func() {
+
+	var (
+		ctx  = context.Background()
+		root string
+	)
+
+	writeFile := func(path, contents string) {
+		fullPath := filepath.Join(root, path)
+		Expect(os.MkdirAll(filepath.Dir(fullPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(fullPath, []byte(contents), 0644)).To(Succeed())
+	}
+
+	readFile := func(path string) string {
+		contents, err := os.ReadFile(filepath.Join(root, path))
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	mount := func() vfsutils.MountedRepo {
+		code, err := vfsutils.NewLocalMountedRepoForFs(root, "solo-io", "testrepo")
+		Expect(err).NotTo(HaveOccurred())
+		return code
+	}
+
+	rules := func(report *changelogutils.ValidationReport) []string {
+		var ids []string
+		for _, v := range report.Violations {
+			ids = append(ids, v.RuleId)
+		}
+		return ids
+	}
+
+	BeforeEach(func() {
+		var err error
+		root, err = os.MkdirTemp("", "changelog-lint")
+		Expect(err).NotTo(HaveOccurred())
+		writeFile("changelog/v1.1.0/first.yaml", `changelog:
+  - type: FIX
+    description: Fixed the thing that was broken.
+    issueLink: https://github.com/solo-io/testrepo/issues/1
+  - type: BREAKING_CHANGE
+    description: Removed the deprecated foo flag.
+    issueLink: https://github.com/solo-io/other/issues/2
+`)
+		writeFile("changelog/v1.1.0/second.yaml", `changelog:
+  - type: FIX
+    description: >
+      fixed the thing that was  broken
+    issueLink: https://github.com/solo-io/testrepo/issues/3
+  - type: NEW_FEATURE 
+    description: Short.
+    issueLink: https://github.com/solo-io/testrepo/pull/4
+`)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(root)
+	})
+
+	It("runs no rules unless they are enabled", func() {
+		report, err := changelogutils.LintChangelogForTag(ctx, mount(), "v1.1.0", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Violations).To(BeEmpty())
+	})
+
+	It("reports every problem found by the enabled rules", func() {
+		report, err := changelogutils.LintChangelogForTag(ctx, mount(), "v1.1.0", changelogutils.DefaultLintSettings())
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rules(report)).To(ConsistOf(
+			changelogutils.RuleIssueLinkRepo,
+			changelogutils.RuleTrailingWhitespace,
+			changelogutils.RuleDuplicateEntry,
+			changelogutils.RuleDescriptionFormat,
+			changelogutils.RuleDescriptionFormat,
+			changelogutils.RuleShortDescription,
+			changelogutils.RuleIssueLinkRepo,
+			changelogutils.RuleBreakingChangeUpgradeNote,
+		))
+		Expect(report.HasErrors()).To(BeFalse())
+
+		var duplicate *changelogutils.Violation
+		for _, v := range report.Violations {
+			if v.RuleId == changelogutils.RuleDuplicateEntry {
+				duplicate = v
+			}
+		}
+		Expect(duplicate.String()).To(Equal("changelog/v1.1.0/second.yaml:2 (entry 0, field description): warning [duplicate-entry] " +
+			"Entry has the same type and description as entry 0 in changelog/v1.1.0/first.yaml"))
+	})
+
+	It("uses the severities and options from validation.yaml", func() {
+		writeFile(changelogutils.GetValidationSettingsPath(), `lint:
+  rules:
+    issue-link-repo: error
+    breaking-change-upgrade-note: "off"
+  issueLinkRepos:
+    - solo-io/testrepo
+    - solo-io/other
+`)
+		report, err := changelogutils.LintChangelogForTag(ctx, mount(), "v1.1.0", nil)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Violations).To(HaveLen(1))
+		Expect(report.Violations[0].Severity).To(Equal(changelogutils.SeverityError))
+		Expect(report.Violations[0].Err).To(MatchError(changelogutils.MalformedIssueLinkError("https://github.com/solo-io/testrepo/pull/4")))
+		Expect(report.Violations[0].Line).To(Equal(6))
+	})
+
+	It("does not report a breaking change with an upgrade note", func() {
+		settings := &changelogutils.LintSettings{
+			Rules: map[string]changelogutils.Severity{changelogutils.RuleBreakingChangeUpgradeNote: changelogutils.SeverityError},
+		}
+		// an upgrade note for an unrelated issue doesn't count
+		writeFile("changelog/v1.1.0/upgrade.yaml", `changelog:
+  - type: UPGRADE
+    description: Replace the foo flag with bar.
+    issueLink: https://github.com/solo-io/testrepo/issues/2
+`)
+		report, err := changelogutils.LintChangelogForTag(ctx, mount(), "v1.1.0", settings)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(rules(report)).To(Equal([]string{changelogutils.RuleBreakingChangeUpgradeNote}))
+
+		writeFile("changelog/v1.1.0/upgrade.yaml", `changelog:
+  - type: UPGRADE
+    description: Replace the foo flag with bar.
+    issueLink: https://github.com/solo-io/other/issues/2
+`)
+		report, err = changelogutils.LintChangelogForTag(ctx, mount(), "v1.1.0", settings)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Violations).To(BeEmpty())
+	})
+
+	It("rejects unknown rules", func() {
+		writeFile(changelogutils.GetValidationSettingsPath(), `lint:
+  rules:
+    no-such-rule: error
+`)
+		_, err := changelogutils.LintChangelogForTag(ctx, mount(), "v1.1.0", nil)
+		Expect(err).To(MatchError(ContainSubstring(changelogutils.UnknownLintRuleError("no-such-rule").Error())))
+	})
+
+	It("fixes the mechanical problems", func() {
+		settings := changelogutils.DefaultLintSettings()
+		changed, err := changelogutils.FixChangelogForTag(ctx, mount(), afero.NewOsFs(), root, "v1.1.0", settings)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changed).To(Equal([]string{"changelog/v1.1.0/second.yaml"}))
+		Expect(readFile("changelog/v1.1.0/second.yaml")).To(Equal(`changelog:
+  - type: FIX
+    description: >
+      Fixed the thing that was  broken.
+    issueLink: https://github.com/solo-io/testrepo/issues/3
+  - type: NEW_FEATURE
+    description: Short.
+    issueLink: https://github.com/solo-io/testrepo/pull/4
+`))
+
+		report, err := changelogutils.LintChangelogForTag(ctx, mount(), "v1.1.0", settings)
+		Expect(err).NotTo(HaveOccurred())
+		for _, v := range report.Violations {
+			Expect(v.Fixable).To(BeFalse())
+		}
+	})
+
+	DescribeTable("description format",
+		func(description, fixed string) {
+			contents := "changelog:\n  - type: FIX\n    description: " + description + "\n"
+			result, err := changelogutils.FixChangelogFileContents([]byte(contents), &changelogutils.LintSettings{
+				Rules: map[string]changelogutils.Severity{changelogutils.RuleDescriptionFormat: changelogutils.SeverityWarning},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(string(result)).To(Equal("changelog:\n  - type: FIX\n    description: " + fixed + "\n"))
+		},
+		Entry("well formed", "Fixed foo.", "Fixed foo."),
+		Entry("lowercase", "fixed foo.", "Fixed foo."),
+		Entry("no period", "Fixed foo (bar)", "Fixed foo (bar)."),
+		Entry("code", "'`foo` is fixed'", "'`foo` is fixed.'"),
+		Entry("flag", "--foo is fixed!", "--foo is fixed!"),
+		Entry("comment", "Fixed foo  # foo is the same as the comment foo", "Fixed foo.  # foo is the same as the comment foo"),
+		Entry("quoted with comment", "\"fixed `foo`\" # `foo`", "\"Fixed `foo`.\" # `foo`"),
+		Entry("block with comment", ">\n      fixed foo\n      and bar\n    # and bar", ">\n      Fixed foo\n      and bar.\n    # and bar"),
+	)
+})
@@ -0,0 +1,312 @@
+package changelogutils
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/go-git/go-git/v5/utils/merkletrie"
+	"github.com/google/go-github/v32/github"
+	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/githubutils"
+	"github.com/solo-io/go-utils/versionutils"
+	"github.com/solo-io/go-utils/vfsutils"
+)
+
+var (
+	OpenLocalRepoError = func(err error, path string) error {
+		return eris.Wrapf(err, "unable to open git repository at %s", path)
+	}
+	ResolveRefError = func(err error, ref string) error {
+		return eris.Wrapf(err, "unable to resolve git ref %s", ref)
+	}
+	NoMergeBaseError = func(base, sha string) error {
+		return eris.Errorf("no common ancestor found between %s and %s", base, sha)
+	}
+)
+
+// Creates a validator that runs the same checks as NewChangelogValidator, but reads the history of the repo from
+// a local git checkout instead of the GitHub API. This allows the changelog to be validated before it is pushed,
+// for example from a pre-commit hook.
+//
+// repoRootPath is the path to the local checkout (or any directory inside it), base is any git revision (branch,
+// tag or sha) that the changes are compared against, and code is a MountedRepo for the same checkout.
+// If code.GetSha() is empty (as is the case for vfsutils.NewLocalMountedRepoForFs) the working tree is validated,
+// including files that have been added, modified or deleted but not yet committed, whether they are staged or not.
+//
+// Note that the latest release is determined from the tags reachable from base, rather than from GitHub releases.
+func NewLocalChangelogValidator(repoRootPath string, code vfsutils.MountedRepo, base string) (ChangelogReportValidator, error) {
+	return NewLocalChangelogValidatorWithLabelOrder(repoRootPath, code, base, nil)
+}
+
+func NewLocalChangelogValidatorWithLabelOrder(repoRootPath string, code vfsutils.MountedRepo, base string, labelOrder []string) (ChangelogReportValidator, error) {
+	history, err := NewLocalRepoHistory(repoRootPath)
+	if err != nil {
+		return nil, err
+	}
+	return newChangelogValidator(history, code, base, labelOrder), nil
+}
+
+type localRepoHistory struct {
+	repo     *git.Repository
+	worktree *git.Worktree
+}
+
+// Returns a RepoHistory backed by the local git checkout containing repoRootPath.
+// An empty sha passed to any of its methods refers to the working tree.
+func NewLocalRepoHistory(repoRootPath string) (RepoHistory, error) {
+	repo, err := git.PlainOpenWithOptions(repoRootPath, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, OpenLocalRepoError(err, repoRootPath)
+	}
+	worktree, err := repo.Worktree()
+	if err != nil {
+		return nil, OpenLocalRepoError(err, repoRootPath)
+	}
+	return &localRepoHistory{
+		repo:     repo,
+		worktree: worktree,
+	}, nil
+}
+
+func (l *localRepoHistory) CompareCommits(ctx context.Context, base, sha string) (*github.CommitsComparison, error) {
+	baseCommit, err := l.resolveCommit(base)
+	if err != nil {
+		return nil, err
+	}
+	headCommit, err := l.resolveCommit(sha)
+	if err != nil {
+		return nil, err
+	}
+	mergeBases, err := baseCommit.MergeBase(headCommit)
+	if err != nil {
+		return nil, err
+	}
+	if len(mergeBases) == 0 {
+		return nil, NoMergeBaseError(base, sha)
+	}
+	fromTree, err := mergeBases[0].Tree()
+	if err != nil {
+		return nil, err
+	}
+	toTree, err := headCommit.Tree()
+	if err != nil {
+		return nil, err
+	}
+	changes, err := object.DiffTreeWithOptions(ctx, fromTree, toTree, object.DefaultDiffTreeOptions)
+	if err != nil {
+		return nil, err
+	}
+
+	filesByName := make(map[string]*github.CommitFile)
+	var files []*github.CommitFile
+	addFile := func(name, status string) {
+		if existing, ok := filesByName[name]; ok {
+			// the file was already changed since the merge base, and has been changed again in the working tree
+			switch {
+			case status == githubutils.COMMIT_FILE_STATUS_MODIFIED:
+				// keeps its status, e.g. a modified file that was added is still added
+			case existing.GetStatus() == githubutils.COMMIT_FILE_STATUS_ADDED && status == githubutils.COMMIT_FILE_STATUS_DELETED:
+				delete(filesByName, name)
+				for i, file := range files {
+					if file == existing {
+						files = append(files[:i], files[i+1:]...)
+						break
+					}
+				}
+			case existing.GetStatus() == githubutils.COMMIT_FILE_STATUS_DELETED && status == githubutils.COMMIT_FILE_STATUS_ADDED:
+				existing.Status = github.String(githubutils.COMMIT_FILE_STATUS_MODIFIED)
+			default:
+				existing.Status = github.String(status)
+			}
+			return
+		}
+		file := &github.CommitFile{
+			Filename: github.String(name),
+			Status:   github.String(status),
+		}
+		filesByName[name] = file
+		files = append(files, file)
+	}
+
+	for _, change := range changes {
+		action, err := change.Action()
+		if err != nil {
+			return nil, err
+		}
+		switch action {
+		case merkletrie.Insert:
+			addFile(change.To.Name, githubutils.COMMIT_FILE_STATUS_ADDED)
+		case merkletrie.Modify:
+			if change.From.Name != change.To.Name {
+				addFile(change.To.Name, githubutils.COMMIT_FILE_STATUS_RENAMED)
+			} else {
+				addFile(change.To.Name, githubutils.COMMIT_FILE_STATUS_MODIFIED)
+			}
+		case merkletrie.Delete:
+			addFile(change.From.Name, githubutils.COMMIT_FILE_STATUS_DELETED)
+		}
+	}
+
+	if sha == "" {
+		// include changes that have not been committed yet
+		status, err := l.worktree.Status()
+		if err != nil {
+			return nil, err
+		}
+		for name, fileStatus := range status {
+			if commitFileStatus := getWorkingTreeFileStatus(fileStatus); commitFileStatus != "" {
+				addFile(filepath.ToSlash(name), commitFileStatus)
+			}
+		}
+	}
+
+	return &github.CommitsComparison{
+		BaseCommit:      &github.RepositoryCommit{SHA: github.String(baseCommit.Hash.String())},
+		MergeBaseCommit: &github.RepositoryCommit{SHA: github.String(mergeBases[0].Hash.String())},
+		Files:           files,
+	}, nil
+}
+
+// Returns the status of a file in the working tree relative to HEAD, as reported by CompareCommits, or "" if it is
+// unchanged
+func getWorkingTreeFileStatus(status *git.FileStatus) string {
+	switch {
+	case status.Staging == git.Added && status.Worktree == git.Deleted:
+		// staged, then deleted before being committed
+		return ""
+	case status.Staging == git.Deleted || status.Worktree == git.Deleted:
+		return githubutils.COMMIT_FILE_STATUS_DELETED
+	case status.Staging == git.Renamed:
+		return githubutils.COMMIT_FILE_STATUS_RENAMED
+	case status.Staging == git.Added || status.Worktree == git.Untracked:
+		return githubutils.COMMIT_FILE_STATUS_ADDED
+	case status.Staging == git.Modified || status.Worktree == git.Modified:
+		return githubutils.COMMIT_FILE_STATUS_MODIFIED
+	}
+	return ""
+}
+
+func (l *localRepoHistory) DirectoryExists(ctx context.Context, sha, directory string) (bool, error) {
+	if sha == "" {
+		info, err := os.Stat(filepath.Join(l.worktree.Filesystem.Root(), directory))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return info.IsDir(), nil
+	}
+	tree, err := l.resolveTree(sha)
+	if err != nil {
+		if eris.Is(err, plumbing.ErrReferenceNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if _, err := tree.Tree(directory); err != nil {
+		if err == object.ErrDirectoryNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+func (l *localRepoHistory) FileExists(ctx context.Context, sha, path string) (bool, error) {
+	if sha == "" {
+		info, err := os.Stat(filepath.Join(l.worktree.Filesystem.Root(), path))
+		if err != nil {
+			if os.IsNotExist(err) {
+				return false, nil
+			}
+			return false, err
+		}
+		return !info.IsDir(), nil
+	}
+	tree, err := l.resolveTree(sha)
+	if err != nil {
+		if eris.Is(err, plumbing.ErrReferenceNotFound) {
+			return false, nil
+		}
+		return false, err
+	}
+	if _, err := tree.File(path); err != nil {
+		if err == object.ErrFileNotFound {
+			return false, nil
+		}
+		return false, err
+	}
+	return true, nil
+}
+
+// Returns the greatest semver tag that is an ancestor of (or points at) sha.
+// Tags that are not valid semver versions are ignored.
+func (l *localRepoHistory) FindLatestTagIncludingPrereleaseBeforeSha(ctx context.Context, sha string) (string, error) {
+	commit, err := l.resolveCommit(sha)
+	if err != nil {
+		return "", err
+	}
+	tags, err := l.repo.Tags()
+	if err != nil {
+		return "", err
+	}
+	var latest *versionutils.Version
+	err = tags.ForEach(func(ref *plumbing.Reference) error {
+		version, err := versionutils.ParseVersion(ref.Name().Short())
+		if err != nil {
+			return nil
+		}
+		tagCommit, err := l.peelToCommit(ref.Hash())
+		if err != nil {
+			return err
+		}
+		isAncestor, err := tagCommit.IsAncestor(commit)
+		if err != nil {
+			return err
+		}
+		if isAncestor && (latest == nil || version.MustIsGreaterThan(*latest)) {
+			latest = version
+		}
+		return nil
+	})
+	if err != nil {
+		return "", err
+	}
+	if latest == nil {
+		return "", githubutils.NoReleaseBeforeShaFound
+	}
+	return latest.String(), nil
+}
+
+// an empty ref resolves to HEAD
+func (l *localRepoHistory) resolveCommit(ref string) (*object.Commit, error) {
+	if ref == "" {
+		ref = plumbing.HEAD.String()
+	}
+	hash, err := l.repo.ResolveRevision(plumbing.Revision(ref))
+	if err != nil {
+		return nil, ResolveRefError(err, ref)
+	}
+	return l.peelToCommit(*hash)
+}
+
+func (l *localRepoHistory) resolveTree(ref string) (*object.Tree, error) {
+	commit, err := l.resolveCommit(ref)
+	if err != nil {
+		return nil, err
+	}
+	return commit.Tree()
+}
+
+// annotated tags point at a tag object rather than directly at a commit
+func (l *localRepoHistory) peelToCommit(hash plumbing.Hash) (*object.Commit, error) {
+	if tag, err := l.repo.TagObject(hash); err == nil {
+		return tag.Commit()
+	}
+	return l.repo.CommitObject(hash)
+}
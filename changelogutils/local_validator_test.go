@@ -0,0 +1,229 @@
+package changelogutils_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/google/go-github/v32/github"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/solo-io/go-utils/changelogutils"
+	"github.com/solo-io/go-utils/githubutils"
+	"github.com/solo-io/go-utils/vfsutils"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("local changelog validator", func() {
+
+	var (
+		ctx  = context.Background()
+		repo *testGitRepo
+	)
+
+	newValidator := func() changelogutils.ChangelogReportValidator {
+		code, err := vfsutils.NewLocalMountedRepoForFs(repo.root, "solo-io", "testrepo")
+		Expect(err).NotTo(HaveOccurred())
+		validator, err := changelogutils.NewLocalChangelogValidator(repo.root, code, changelogutils.MasterBranch)
+		Expect(err).NotTo(HaveOccurred())
+		return validator
+	}
+
+	BeforeEach(func() {
+		repo = newTestGitRepo()
+	})
+
+	AfterEach(func() {
+		repo.cleanup()
+	})
+
+	It("validates a committed changelog file", func() {
+		repo.writeFile("changelog/v0.1.1/fix.yaml", validChangelog2)
+		repo.commit("changelog")
+
+		file, err := newValidator().ValidateChangelog(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(file.Entries).To(HaveLen(2))
+	})
+
+	It("validates a changelog file that has not been committed", func() {
+		repo.writeFile("changelog/v0.1.1/fix.yaml", validChangelog2)
+
+		file, err := newValidator().ValidateChangelog(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(file.Entries).To(HaveLen(2))
+	})
+
+	It("compares the working tree with the status of each changed file", func() {
+		repo.writeFile("README.md", "readme")
+		repo.writeFile("changelog/v0.1.1/fix.yaml", validChangelog2)
+		repo.writeFile("changelog/v0.1.1/deleted.yaml", validChangelog2)
+		repo.commit("README.md", "changelog")
+		repo.writeFile("changelog/v0.1.0/initial.yaml", validChangelog2)
+		repo.writeFile("changelog/v0.1.1/fix.yaml", validChangelog1)
+		repo.writeFile("new.md", "new")
+		Expect(os.Remove(filepath.Join(repo.root, "README.md"))).To(Succeed())
+		Expect(os.Remove(filepath.Join(repo.root, "changelog/v0.1.1/deleted.yaml"))).To(Succeed())
+
+		history, err := changelogutils.NewLocalRepoHistory(repo.root)
+		Expect(err).NotTo(HaveOccurred())
+		comparison, err := history.CompareCommits(ctx, changelogutils.MasterBranch, "")
+		Expect(err).NotTo(HaveOccurred())
+		statuses := make(map[string]string)
+		for _, file := range comparison.Files {
+			statuses[file.GetFilename()] = file.GetStatus()
+		}
+		Expect(statuses).To(Equal(map[string]string{
+			"changelog/v0.1.0/initial.yaml": githubutils.COMMIT_FILE_STATUS_MODIFIED,
+			"changelog/v0.1.1/fix.yaml":     githubutils.COMMIT_FILE_STATUS_ADDED,
+			"new.md":                        githubutils.COMMIT_FILE_STATUS_ADDED,
+		}))
+
+		Expect(os.Remove(filepath.Join(repo.root, "changelog/v0.1.0/initial.yaml"))).To(Succeed())
+		comparison, err = history.CompareCommits(ctx, changelogutils.MasterBranch, "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(comparison.Files).To(ContainElement(&github.CommitFile{
+			Filename: github.String("changelog/v0.1.0/initial.yaml"),
+			Status:   github.String(githubutils.COMMIT_FILE_STATUS_DELETED),
+		}))
+	})
+
+	It("ignores a committed changelog file that was deleted from the working tree", func() {
+		repo.writeFile("changelog/v0.1.1/fix.yaml", validChangelog2)
+		repo.commit("changelog")
+		Expect(os.Remove(filepath.Join(repo.root, "changelog/v0.1.1/fix.yaml"))).To(Succeed())
+
+		_, err := newValidator().ValidateChangelog(ctx)
+		Expect(err).To(MatchError(changelogutils.NoChangelogFileAddedError))
+	})
+
+	It("errors when no changelog file was added", func() {
+		repo.writeFile("README.md", "readme")
+		repo.commit("README.md")
+
+		_, err := newValidator().ValidateChangelog(ctx)
+		Expect(err).To(MatchError(changelogutils.NoChangelogFileAddedError))
+	})
+
+	It("errors when more than one changelog file was added", func() {
+		repo.writeFile("changelog/v0.1.1/fix.yaml", validChangelog2)
+		repo.writeFile("changelog/v0.1.1/fix2.yaml", validChangelog2)
+		repo.commit("changelog")
+
+		_, err := newValidator().ValidateChangelog(ctx)
+		Expect(err).To(MatchError(changelogutils.TooManyChangelogFilesAddedError(2)))
+	})
+
+	It("errors when the version is not the expected increment of the latest tag", func() {
+		repo.writeFile("changelog/v0.2.0/fix.yaml", validChangelog2)
+		repo.commit("changelog")
+
+		_, err := newValidator().ValidateChangelog(ctx)
+		Expect(err).To(MatchError(changelogutils.UnexpectedProposedVersionError("v0.1.1", "v0.2.0")))
+	})
+
+	It("uses the greatest tag reachable from the base ref", func() {
+		repo.writeFile("changelog/v0.1.1/fix.yaml", validChangelog2)
+		hash := repo.commit("changelog")
+		repo.tag("v0.1.1", hash)
+
+		history, err := changelogutils.NewLocalRepoHistory(repo.root)
+		Expect(err).NotTo(HaveOccurred())
+		tag, err := history.FindLatestTagIncludingPrereleaseBeforeSha(ctx, changelogutils.MasterBranch)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tag).To(Equal("v0.1.0"))
+		tag, err = history.FindLatestTagIncludingPrereleaseBeforeSha(ctx, "feature")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(tag).To(Equal("v0.1.1"))
+	})
+
+	It("reports every violation at once", func() {
+		repo.writeFile("changelog/v0.2.0/fix.yaml", changelogMultipleProblems)
+		repo.writeFile("changelog/v0.2.0/fix2.yaml", validChangelog2)
+		repo.commit("changelog")
+
+		_, report, err := newValidator().ValidateChangelogReport(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		var rules []string
+		for _, violation := range report.Violations {
+			rules = append(rules, violation.RuleId)
+		}
+		Expect(rules).To(ConsistOf(
+			changelogutils.RuleTooManyChangelogFiles,
+			changelogutils.RuleTooManyChangelogFiles,
+			changelogutils.RuleMissingIssueLink,
+			changelogutils.RuleMissingDescription,
+			changelogutils.RuleMissingDependencyOwner,
+			changelogutils.RuleMissingDependencyTag,
+			changelogutils.RuleVersionBump,
+		))
+	})
+
+	It("honours the version bump of custom entry types", func() {
+		repo.writeFile(changelogutils.GetValidationSettingsPath(), customEntryTypesYaml)
+		repo.writeFile("changelog/v0.1.1/security.yaml", `
+changelog:
+  - type: SECURITY
+    description: Fixed CVE-1234.
+    issueLink: https://github.com/solo-io/testrepo/issues/1
+`)
+		repo.commit("changelog")
+
+		_, err := newValidator().ValidateChangelog(ctx)
+		Expect(err).To(MatchError(changelogutils.UnexpectedProposedVersionError("v0.2.0", "v0.1.1")))
+	})
+
+	It("includes the enabled lint rules in the report", func() {
+		repo.writeFile(changelogutils.GetValidationSettingsPath(), `
+lint:
+  rules:
+    issue-link-repo: error
+`)
+		repo.writeFile("changelog/v0.1.1/fix.yaml", `
+changelog:
+  - type: FIX
+    description: Fixed the thing that was broken.
+    issueLink: https://github.com/solo-io/other/issues/1
+`)
+		repo.commit("changelog")
+
+		_, report, err := newValidator().ValidateChangelogReport(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Violations).To(HaveLen(1))
+		Expect(report.Violations[0].RuleId).To(Equal(changelogutils.RuleIssueLinkRepo))
+		Expect(report.HasErrors()).To(BeTrue())
+
+		_, err = newValidator().ValidateChangelog(ctx)
+		Expect(err).To(MatchError(report.Violations[0].Err))
+	})
+
+	It("validates a changelog nested by the migration tool", func() {
+		repo.checkout(changelogutils.MasterBranch, false)
+		repo.writeFile("changelog/v0.2.0/feature.yaml", validChangelog1)
+		repo.tag("v0.2.0", repo.commit("changelog"))
+		code, err := vfsutils.NewLocalMountedRepoForFs(repo.root, "solo-io", "testrepo")
+		Expect(err).NotTo(HaveOccurred())
+		migration, err := changelogutils.PlanChangelogMigration(ctx, code, changelogutils.ChangelogMigrationOptions{Nest: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(migration.Apply(afero.NewOsFs(), repo.root)).To(Succeed())
+		repo.commitAll()
+		repo.checkout("nested", true)
+
+		// the first version of a minor version outside of the active subdirectory
+		repo.writeFile("changelog/v0.3/v0.3.0/breaking.yaml", validBreakingChangelog)
+		repo.commit("changelog")
+		file, report, err := newValidator().ValidateChangelogReport(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(report.Violations).To(BeEmpty())
+		Expect(file).NotTo(BeNil())
+
+		repo.writeFile("changelog/v0.2/v0.3.1/fix.yaml", validChangelog2)
+		_, report, err = newValidator().ValidateChangelogReport(ctx)
+		Expect(err).NotTo(HaveOccurred())
+		violations := map[string]string{}
+		for _, violation := range report.Violations {
+			violations[violation.File] = violation.RuleId
+		}
+		Expect(violations).To(HaveKeyWithValue("changelog/v0.2/v0.3.1", changelogutils.RuleInvalidVersionDirectory))
+	})
+})
@@ -4,10 +4,12 @@ import (
 	"context"
 	"html/template"
 	"io"
+	"path/filepath"
 	"sort"
 	"strings"
 
 	"github.com/pkg/errors"
+	"github.com/solo-io/go-utils/versionutils"
 
 	"github.com/solo-io/go-utils/vfsutils"
 )
@@ -24,6 +26,8 @@ new features
 fixes
 closing
 
+custom entry types declared in validation.yaml are rendered between these sections, according to their order
+
 */
 
 var (
@@ -42,6 +46,10 @@ var (
 )
 
 func GenerateChangelogFromLocalDirectory(ctx context.Context, repoRootPath, owner, repo, changelogDirPath string, w io.Writer) error {
+	return GenerateChangelogFromLocalDirectoryWithRenderer(ctx, repoRootPath, owner, repo, changelogDirPath, &MarkdownRenderer{}, w)
+}
+
+func GenerateChangelogFromLocalDirectoryWithRenderer(ctx context.Context, repoRootPath, owner, repo, changelogDirPath string, renderer ChangelogRenderer, w io.Writer) error {
 	mountedRepo, err := vfsutils.NewLocalMountedRepoForFs(repoRootPath, owner, repo)
 	if err != nil {
 		return MountLocalDirectoryError(err)
@@ -52,15 +60,35 @@ func GenerateChangelogFromLocalDirectory(ctx context.Context, repoRootPath, owne
 	}
 	var tags []string
 	for _, file := range files {
-		if file.IsDir() {
+		if !file.IsDir() {
+			continue
+		}
+		if versionutils.MatchesRegex(file.Name()) {
 			tags = append(tags, file.Name())
+			continue
+		}
+		// a minor version directory nesting version directories, see ValidationSettings.NestedVersionDirectories
+		nested, err := mountedRepo.ListFiles(ctx, filepath.Join(changelogDirPath, file.Name()))
+		if err != nil {
+			return ReadChangelogDirError(err)
+		}
+		for _, child := range nested {
+			if child.IsDir() && versionutils.MatchesRegex(child.Name()) {
+				tags = append(tags, child.Name())
+			}
 		}
 	}
 	reader := NewChangelogReader(mountedRepo)
-	return GenerateChangelogForTags(ctx, tags, reader, w)
+	return GenerateChangelogForTagsWithRenderer(ctx, tags, reader, renderer, w)
 }
 
 func GenerateChangelogForTags(ctx context.Context, tags []string, reader ChangelogReader, w io.Writer) error {
+	return GenerateChangelogForTagsWithRenderer(ctx, tags, reader, &MarkdownRenderer{}, w)
+}
+
+// Renders the changelogs for the tags, newest first, with the given renderer (see NewChangelogRenderer and
+// NewTemplateRenderer)
+func GenerateChangelogForTagsWithRenderer(ctx context.Context, tags []string, reader ChangelogReader, renderer ChangelogRenderer, w io.Writer) error {
 	changelogs := make(ChangelogList, len(tags))
 	var err error
 	for i, tag := range tags {
@@ -69,11 +97,7 @@ func GenerateChangelogForTags(ctx context.Context, tags []string, reader Changel
 		}
 	}
 	sort.Sort(sort.Reverse(changelogs))
-	tmplData := changelogSummaryTmplDataFromChangelogs(changelogs)
-	if err := changelogSummaryTmpl.Execute(w, tmplData); err != nil {
-		return GenerateChangelogSummaryTemplateError(err)
-	}
-	return nil
+	return renderer.Render(w, changelogs)
 }
 
 func GenerateChangelogMarkdown(changelog *Changelog) string {
@@ -82,34 +106,16 @@ func GenerateChangelogMarkdown(changelog *Changelog) string {
 		output = output + "\n\n"
 	}
 
-	dependencyBumps := renderDependencyBumps(changelog)
-	if dependencyBumps != "" {
-		output = output + "**Dependency Bumps**\n\n" + dependencyBumps + "\n"
-	}
-
-	breakingChanges := renderChangelogEntries(changelog, BREAKING_CHANGE)
-	if breakingChanges != "" {
-		output = output + "**Breaking Changes**\n\n" + breakingChanges + "\n"
-	}
-
-	upgradeNotes := renderChangelogEntries(changelog, UPGRADE)
-	if upgradeNotes != "" {
-		output = output + "**Upgrade Notes**\n\n" + upgradeNotes + "\n"
-	}
-
-	helmChanges := renderChangelogEntries(changelog, HELM)
-	if helmChanges != "" {
-		output = output + "**Helm Changes**\n\n" + helmChanges + "\n"
-	}
-
-	newFeatures := renderChangelogEntries(changelog, NEW_FEATURE)
-	if newFeatures != "" {
-		output = output + "**New Features**\n\n" + newFeatures + "\n"
-	}
-
-	fixes := renderChangelogEntries(changelog, FIX)
-	if fixes != "" {
-		output = output + "**Fixes**\n\n" + fixes + "\n"
+	for _, section := range changelog.EntryTypes.Sections() {
+		var entries string
+		if section.Type == DEPENDENCY_BUMP {
+			entries = renderDependencyBumps(changelog)
+		} else {
+			entries = renderChangelogEntries(changelog, section.Type)
+		}
+		if entries != "" {
+			output = output + "**" + section.Heading + "**\n\n" + entries + "\n"
+		}
 	}
 
 	if changelog.Closing != "" {
@@ -127,7 +133,12 @@ func renderDependencyBumps(changelog *Changelog) string {
 	for _, file := range changelog.Files {
 		for _, entry := range file.Entries {
 			if entry.Type == DEPENDENCY_BUMP {
-				output = output + "- " + entry.DependencyOwner + "/" + entry.DependencyRepo + " has been upgraded to " + entry.DependencyTag + ".\n"
+				output = output + "- " + changelogEntryText(entry) + "\n"
+				if notes := changelog.dependencyNotesFor(entry); notes != nil {
+					var b strings.Builder
+					renderDependencyNotes(&b, notes)
+					output = output + b.String()
+				}
 			}
 		}
 	}
@@ -149,6 +160,10 @@ func renderChangelogEntries(changelog *Changelog, entryType ChangelogEntryType)
 func renderChangelogEntry(entry *ChangelogEntry) string {
 	description := strings.TrimSpace(entry.Description)
 	link := strings.TrimSpace(entry.IssueLink)
+	if link == "" {
+		// only possible for custom entry types that don't require an issue link
+		return "- " + description
+	}
 	return "- " + description + " (" + link + ")"
 }
 
@@ -0,0 +1,463 @@
+package changelogutils
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/versionutils"
+	"github.com/solo-io/go-utils/vfsutils"
+	"github.com/spf13/afero"
+	yamlv3 "gopkg.in/yaml.v3"
+)
+
+var (
+	GenerateChangelogBeforeMigrationError = func(err error) error {
+		return eris.Wrapf(err, "Unable to generate the changelog before migrating it")
+	}
+	GenerateChangelogAfterMigrationError = func(err error) error {
+		return eris.Wrapf(err, "Unable to generate the migrated changelog")
+	}
+	MigrationChangesChangelogError = func(tag string) error {
+		return eris.Errorf("Migrating the changelog would change the generated changelog for %s", tag)
+	}
+	UnableToMergeChangelogFileError = func(path, reason string) error {
+		return eris.Errorf("Unable to merge changelog file %s: %s", path, reason)
+	}
+)
+
+const DefaultCompactedFileName = "changelog.yaml"
+
+type ChangelogMigrationOptions struct {
+	// Move every version directory into a directory for its minor version (e.g. changelog/v1.10/v1.10.3), and set
+	// nestedVersionDirectories and activeSubdirectory (to the minor version of the greatest version) in validation.yaml
+	Nest bool
+	// Merge the changelog files of a released version into a single file, if it has at least this many.
+	// 0 disables merging.
+	CompactMinFiles int
+	// The name of the merged file. Defaults to DefaultCompactedFileName.
+	CompactedFileName string
+	// The latest release. Versions greater than it are not released yet, and are not merged so that the pending
+	// changes of each PR stay in their own file. If empty, every version is considered released.
+	LatestTag string
+}
+
+// Moving or merging changelog files into a new file
+type ChangelogMigrationStep struct {
+	// the files the new file is made from, relative to the repo root
+	From []string
+	To   string
+}
+
+func (s *ChangelogMigrationStep) String() string {
+	if len(s.From) == 1 {
+		return fmt.Sprintf("move %s to %s", s.From[0], s.To)
+	}
+	return fmt.Sprintf("merge %s into %s", strings.Join(s.From, ", "), s.To)
+}
+
+// The changes to the changelog directory computed by PlanChangelogMigration. The changelog generated for every
+// version has been verified to be the same before and after the changes.
+type ChangelogMigration struct {
+	Steps []*ChangelogMigrationStep
+	// the new contents of validation.yaml, or nil if it doesn't change
+	ValidationSettings []byte
+	// the versions the generated changelog was verified for
+	Versions []string
+
+	// new file contents, by path relative to the repo root
+	files map[string][]byte
+	// files to remove once the new files are written
+	removed []string
+}
+
+func (m *ChangelogMigration) IsEmpty() bool {
+	return len(m.Steps) == 0 && m.ValidationSettings == nil
+}
+
+func (m *ChangelogMigration) RenderText(w io.Writer) error {
+	if m.IsEmpty() {
+		_, err := fmt.Fprintln(w, "The changelog directory is already migrated.")
+		return err
+	}
+	for _, step := range m.Steps {
+		if _, err := fmt.Fprintln(w, step.String()); err != nil {
+			return err
+		}
+	}
+	if m.ValidationSettings != nil {
+		if _, err := fmt.Fprintf(w, "update %s:\n%s", GetValidationSettingsPath(), m.ValidationSettings); err != nil {
+			return err
+		}
+	}
+	_, err := fmt.Fprintf(w, "The generated changelog is unchanged for %d versions.\n", len(m.Versions))
+	return err
+}
+
+// Writes the migrated changelog directory to fs, under repoRootPath, and removes the files and directories it replaces
+func (m *ChangelogMigration) Apply(fs afero.Fs, repoRootPath string) error {
+	if err := writeMigratedFiles(fs, repoRootPath, m.files); err != nil {
+		return err
+	}
+	if m.ValidationSettings != nil {
+		if err := afero.WriteFile(fs, filepath.Join(repoRootPath, GetValidationSettingsPath()), m.ValidationSettings, 0644); err != nil {
+			return err
+		}
+	}
+	dirs := make(map[string]bool)
+	for _, path := range m.removed {
+		if err := fs.Remove(filepath.Join(repoRootPath, path)); err != nil {
+			return err
+		}
+		for dir := filepath.Dir(path); dir != ChangelogDirectory && dir != "."; dir = filepath.Dir(dir) {
+			dirs[dir] = true
+		}
+	}
+	// remove directories that were emptied, deepest first
+	var emptied []string
+	for dir := range dirs {
+		emptied = append(emptied, dir)
+	}
+	sort.Slice(emptied, func(i, j int) bool {
+		return len(emptied[i]) > len(emptied[j])
+	})
+	for _, dir := range emptied {
+		children, err := afero.ReadDir(fs, filepath.Join(repoRootPath, dir))
+		if err != nil {
+			return err
+		}
+		if len(children) == 0 {
+			if err := fs.Remove(filepath.Join(repoRootPath, dir)); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+func writeMigratedFiles(fs afero.Fs, repoRootPath string, files map[string][]byte) error {
+	for path, contents := range files {
+		fullPath := filepath.Join(repoRootPath, path)
+		if err := fs.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			return err
+		}
+		if err := afero.WriteFile(fs, fullPath, contents, 0644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Computes how to nest the version directories of the repo and merge their changelog files, as configured by opts,
+// and verifies that GenerateChangelogForTags renders exactly the same changelog for every version afterwards.
+// Nothing is written until Apply is called on the returned migration.
+func PlanChangelogMigration(ctx context.Context, code vfsutils.MountedRepo, opts ChangelogMigrationOptions) (*ChangelogMigration, error) {
+	settings, err := readValidationSettings(ctx, code)
+	if err != nil {
+		return nil, UnableToGetSettingsError(err)
+	}
+	tags, err := listChangelogVersions(ctx, code, settings)
+	if err != nil {
+		return nil, err
+	}
+	compactedFileName := opts.CompactedFileName
+	if compactedFileName == "" {
+		compactedFileName = DefaultCompactedFileName
+	}
+
+	migration := &ChangelogMigration{
+		Versions: tags,
+		files:    make(map[string][]byte),
+	}
+
+	newSettings := *settings
+	if opts.Nest {
+		var greatest *versionutils.Version
+		for _, tag := range tags {
+			version, err := versionutils.ParseVersion(tag)
+			if err != nil {
+				return nil, err
+			}
+			if greatest == nil || version.MustIsGreaterThan(*greatest) {
+				greatest = version
+			}
+		}
+		newSettings.NestedVersionDirectories = true
+		if greatest != nil {
+			newSettings.ActiveSubdirectory = MinorVersionDirectory(greatest)
+		}
+		if newSettings.NestedVersionDirectories != settings.NestedVersionDirectories || newSettings.ActiveSubdirectory != settings.ActiveSubdirectory {
+			existing, _ := code.GetFileContents(ctx, GetValidationSettingsPath())
+			migration.ValidationSettings, err = setValidationSettings(existing, map[string]interface{}{
+				"activeSubdirectory":       newSettings.ActiveSubdirectory,
+				"nestedVersionDirectories": true,
+			})
+			if err != nil {
+				return nil, UnableToGetSettingsError(err)
+			}
+		}
+	}
+
+	for _, tag := range tags {
+		from := getVersionDirectory(settings, tag)
+		to := getVersionDirectory(&newSettings, tag)
+		children, err := code.ListFiles(ctx, from)
+		if err != nil {
+			return nil, UnableToListFilesError(err, from)
+		}
+		var changelogFiles []string
+		for _, child := range children {
+			path := filepath.Join(from, child.Name())
+			if child.IsDir() {
+				return nil, UnexpectedDirectoryError(child.Name(), from)
+			}
+			if child.Name() == SummaryFile || child.Name() == ClosingFile {
+				if err := migration.addFile(ctx, code, []string{path}, filepath.Join(to, child.Name())); err != nil {
+					return nil, err
+				}
+				continue
+			}
+			changelogFiles = append(changelogFiles, path)
+		}
+
+		if opts.CompactMinFiles > 0 && len(changelogFiles) >= opts.CompactMinFiles && isReleased(tag, opts.LatestTag) {
+			if err := migration.addFile(ctx, code, changelogFiles, filepath.Join(to, compactedFileName)); err != nil {
+				return nil, err
+			}
+			continue
+		}
+		for _, path := range changelogFiles {
+			if err := migration.addFile(ctx, code, []string{path}, filepath.Join(to, filepath.Base(path))); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	if err := migration.verify(ctx, code); err != nil {
+		return nil, err
+	}
+	return migration, nil
+}
+
+// Adds a file to the migrated directory, made of the given files. Files that stay where they are don't result in a step.
+func (m *ChangelogMigration) addFile(ctx context.Context, code vfsutils.MountedRepo, from []string, to string) error {
+	var contents []byte
+	if len(from) == 1 {
+		var err error
+		if contents, err = code.GetFileContents(ctx, from[0]); err != nil {
+			return err
+		}
+	} else {
+		var files [][]byte
+		for _, path := range from {
+			file, err := code.GetFileContents(ctx, path)
+			if err != nil {
+				return err
+			}
+			files = append(files, file)
+		}
+		var err error
+		if contents, err = mergeChangelogFiles(from, files); err != nil {
+			return err
+		}
+	}
+	m.files[to] = contents
+	if len(from) == 1 && from[0] == to {
+		return nil
+	}
+	m.Steps = append(m.Steps, &ChangelogMigrationStep{From: from, To: to})
+	for _, path := range from {
+		if path != to {
+			m.removed = append(m.removed, path)
+		}
+	}
+	return nil
+}
+
+// Renders the changelog of every version from the repo and from the migrated files, and errors if they differ
+func (m *ChangelogMigration) verify(ctx context.Context, code vfsutils.MountedRepo) error {
+	migrated := afero.NewMemMapFs()
+	if err := writeMigratedFiles(migrated, "/", m.files); err != nil {
+		return err
+	}
+	settings := m.ValidationSettings
+	if settings == nil {
+		settings, _ = code.GetFileContents(ctx, GetValidationSettingsPath())
+	}
+	if settings != nil {
+		if err := afero.WriteFile(migrated, filepath.Join("/", GetValidationSettingsPath()), settings, 0644); err != nil {
+			return err
+		}
+	}
+	before := NewChangelogReader(code)
+	after := NewChangelogReader(vfsutils.NewMountedRepoForFs(migrated, "/", code.GetOwner(), code.GetRepo()))
+
+	var expected, actual bytes.Buffer
+	if err := GenerateChangelogForTags(ctx, m.Versions, before, &expected); err != nil {
+		return GenerateChangelogBeforeMigrationError(err)
+	}
+	if err := GenerateChangelogForTags(ctx, m.Versions, after, &actual); err != nil {
+		return GenerateChangelogAfterMigrationError(err)
+	}
+	if bytes.Equal(expected.Bytes(), actual.Bytes()) {
+		return nil
+	}
+	for _, tag := range m.Versions {
+		expected.Reset()
+		actual.Reset()
+		_ = GenerateChangelogForTags(ctx, []string{tag}, before, &expected)
+		_ = GenerateChangelogForTags(ctx, []string{tag}, after, &actual)
+		if !bytes.Equal(expected.Bytes(), actual.Bytes()) {
+			return MigrationChangesChangelogError(tag)
+		}
+	}
+	return MigrationChangesChangelogError(strings.Join(m.Versions, ", "))
+}
+
+func isReleased(tag, latestTag string) bool {
+	if latestTag == "" {
+		return true
+	}
+	greaterThan, determinable, err := versionutils.IsGreaterThanTag(tag, latestTag)
+	return err == nil && determinable && !greaterThan
+}
+
+// Merges changelog files into a single file, keeping the text of each entry (including comments and block styles)
+// as it is, in the order the files are listed in.
+func mergeChangelogFiles(paths []string, files [][]byte) ([]byte, error) {
+	var merged strings.Builder
+	merged.WriteString("changelog:\n")
+	releaseStableApi := false
+	for i, contents := range files {
+		entries, stableApi, err := changelogEntriesText(contents)
+		if err != nil {
+			return nil, UnableToMergeChangelogFileError(paths[i], err.Error())
+		}
+		merged.WriteString(entries)
+		releaseStableApi = releaseStableApi || stableApi
+	}
+	if releaseStableApi {
+		merged.WriteString("releaseStableApi: true\n")
+	}
+	return []byte(merged.String()), nil
+}
+
+// Returns the text of the entries in the changelog list of a file, indented as items of a top level list,
+// and whether the file sets releaseStableApi
+func changelogEntriesText(contents []byte) (string, bool, error) {
+	var file ChangelogFile
+	if err := yaml.Unmarshal(contents, &file); err != nil {
+		return "", false, err
+	}
+	var root yamlv3.Node
+	if err := yamlv3.Unmarshal(contents, &root); err != nil {
+		return "", false, err
+	}
+	if len(root.Content) == 0 || root.Content[0].Kind != yamlv3.MappingNode {
+		return "", false, eris.New("not a changelog file")
+	}
+	doc := root.Content[0]
+	lines := strings.Split(string(contents), "\n")
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value != "changelog" {
+			continue
+		}
+		list := doc.Content[i+1]
+		if list.Kind != yamlv3.SequenceNode || list.Style&yamlv3.FlowStyle != 0 {
+			return "", false, eris.New("the changelog entries are not a block list")
+		}
+		// the entries end where the next top level key starts, or at the end of the file
+		end := len(lines)
+		if i+2 < len(doc.Content) {
+			end = doc.Content[i+2].Line - 1
+		}
+		var text strings.Builder
+		for j, item := range list.Content {
+			itemEnd := end
+			if j+1 < len(list.Content) {
+				itemEnd = list.Content[j+1].Line - 1
+			}
+			// the dash of the item is the last one before the first key of the entry
+			dash := strings.LastIndex(lines[item.Line-1][:item.Column-1], "-")
+			if dash < 0 {
+				return "", false, eris.New("each entry must start on the line of its dash")
+			}
+			// comments before the first entry are kept with it
+			itemStart := item.Line - 1
+			if j == 0 {
+				itemStart = doc.Content[i].Line
+			}
+			itemLines := lines[itemStart:itemEnd]
+			for len(itemLines) > 0 && strings.TrimSpace(itemLines[len(itemLines)-1]) == "" {
+				itemLines = itemLines[:len(itemLines)-1]
+			}
+			for _, line := range itemLines {
+				indent := len(line) - len(strings.TrimLeft(line, " "))
+				if indent > dash {
+					indent = dash
+				}
+				if strings.TrimSpace(line) == "" {
+					text.WriteString("\n")
+					continue
+				}
+				text.WriteString("  " + line[indent:] + "\n")
+			}
+		}
+		return text.String(), file.GetReleaseStableApi(), nil
+	}
+	return "", false, eris.New("no changelog entries found")
+}
+
+// Sets top level keys in a yaml document, keeping the rest of it as it is
+func setValidationSettings(contents []byte, values map[string]interface{}) ([]byte, error) {
+	var root yamlv3.Node
+	if len(bytes.TrimSpace(contents)) > 0 {
+		if err := yamlv3.Unmarshal(contents, &root); err != nil {
+			return nil, err
+		}
+	}
+	if len(root.Content) == 0 {
+		root = yamlv3.Node{Kind: yamlv3.DocumentNode, Content: []*yamlv3.Node{{Kind: yamlv3.MappingNode}}}
+	}
+	doc := root.Content[0]
+	if doc.Kind != yamlv3.MappingNode {
+		return nil, eris.New("validation settings must be a map")
+	}
+	var keys []string
+	for key := range values {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	for _, key := range keys {
+		var value yamlv3.Node
+		if err := value.Encode(values[key]); err != nil {
+			return nil, err
+		}
+		found := false
+		for i := 0; i+1 < len(doc.Content); i += 2 {
+			if doc.Content[i].Value == key {
+				doc.Content[i+1] = &value
+				found = true
+			}
+		}
+		if !found {
+			doc.Content = append(doc.Content, &yamlv3.Node{Kind: yamlv3.ScalarNode, Value: key}, &value)
+		}
+	}
+	var buf bytes.Buffer
+	encoder := yamlv3.NewEncoder(&buf)
+	encoder.SetIndent(2)
+	if err := encoder.Encode(&root); err != nil {
+		return nil, err
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
@@ -0,0 +1,137 @@
+package changelogutils_test
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/solo-io/go-utils/changelogutils"
+	"github.com/solo-io/go-utils/vfsutils"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("changelog migration", func() {
+
+	var (
+		ctx  = context.Background()
+		root string
+		tags = []string{"v0.1.0", "v0.1.1", "v0.2.0"}
+	)
+
+	writeFile := func(path, contents string) {
+		fullPath := filepath.Join(root, path)
+		Expect(os.MkdirAll(filepath.Dir(fullPath), 0755)).To(Succeed())
+		Expect(os.WriteFile(fullPath, []byte(contents), 0644)).To(Succeed())
+	}
+
+	readFile := func(path string) string {
+		contents, err := os.ReadFile(filepath.Join(root, path))
+		Expect(err).NotTo(HaveOccurred())
+		return string(contents)
+	}
+
+	mount := func() vfsutils.MountedRepo {
+		code, err := vfsutils.NewLocalMountedRepoForFs(root, "solo-io", "testrepo")
+		Expect(err).NotTo(HaveOccurred())
+		return code
+	}
+
+	generate := func() string {
+		var buf bytes.Buffer
+		Expect(changelogutils.GenerateChangelogForTags(ctx, tags, changelogutils.NewChangelogReader(mount()), &buf)).To(Succeed())
+		return buf.String()
+	}
+
+	BeforeEach(func() {
+		var err error
+		root, err = os.MkdirTemp("", "changelog-migration")
+		Expect(err).NotTo(HaveOccurred())
+		writeFile(changelogutils.GetValidationSettingsPath(), "# settings for the changelog bot\nrequireLabel: false\n")
+		writeFile("changelog/v0.1.0/a.yaml", validChangelog1)
+		writeFile("changelog/v0.1.0/b.yaml", `changelog:
+# a comment that is kept
+- type: FIX
+  description: >
+    Fixed the thing
+    that was broken.
+  issueLink: https://github.com/solo-io/testrepo/issues/3
+`)
+		writeFile("changelog/v0.1.0/summary.md", "The first release.")
+		writeFile("changelog/v0.1.1/a.yaml", validChangelog2)
+		writeFile("changelog/v0.2.0/a.yaml", validBreakingChangelog)
+		writeFile("changelog/v0.2.0/b.yaml", validChangelog3)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(root)
+	})
+
+	It("nests and compacts released versions without changing the generated changelog", func() {
+		before := generate()
+
+		migration, err := changelogutils.PlanChangelogMigration(ctx, mount(), changelogutils.ChangelogMigrationOptions{
+			Nest:            true,
+			CompactMinFiles: 2,
+			LatestTag:       "v0.1.1",
+		})
+		Expect(err).NotTo(HaveOccurred())
+		var steps []string
+		for _, step := range migration.Steps {
+			steps = append(steps, step.String())
+		}
+		Expect(steps).To(Equal([]string{
+			"move changelog/v0.1.0/summary.md to changelog/v0.1/v0.1.0/summary.md",
+			"merge changelog/v0.1.0/a.yaml, changelog/v0.1.0/b.yaml into changelog/v0.1/v0.1.0/changelog.yaml",
+			"move changelog/v0.1.1/a.yaml to changelog/v0.1/v0.1.1/a.yaml",
+			"move changelog/v0.2.0/a.yaml to changelog/v0.2/v0.2.0/a.yaml",
+			"move changelog/v0.2.0/b.yaml to changelog/v0.2/v0.2.0/b.yaml",
+		}))
+		Expect(string(migration.ValidationSettings)).To(Equal(`# settings for the changelog bot
+requireLabel: false
+activeSubdirectory: v0.2
+nestedVersionDirectories: true
+`))
+
+		Expect(migration.Apply(afero.NewOsFs(), root)).To(Succeed())
+		Expect(filepath.Join(root, "changelog/v0.1.0")).NotTo(BeADirectory())
+		Expect(readFile("changelog/v0.1/v0.1.0/changelog.yaml")).To(Equal(`changelog:
+  - type: FIX
+    description: foo1
+    issueLink: bar1
+  - type: NEW_FEATURE
+    description: foo2
+    issueLink: bar2
+  # a comment that is kept
+  - type: FIX
+    description: >
+      Fixed the thing
+      that was broken.
+    issueLink: https://github.com/solo-io/testrepo/issues/3
+`))
+		Expect(generate()).To(Equal(before))
+
+		var fromDirectory bytes.Buffer
+		Expect(changelogutils.GenerateChangelogFromLocalDirectory(ctx, root, "solo-io", "testrepo", "changelog", &fromDirectory)).To(Succeed())
+		Expect(fromDirectory.String()).To(Equal(before))
+
+		migration, err = changelogutils.PlanChangelogMigration(ctx, mount(), changelogutils.ChangelogMigrationOptions{Nest: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(migration.IsEmpty()).To(BeTrue())
+	})
+
+	It("only compacts when asked to", func() {
+		migration, err := changelogutils.PlanChangelogMigration(ctx, mount(), changelogutils.ChangelogMigrationOptions{CompactMinFiles: 3})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(migration.IsEmpty()).To(BeTrue())
+		Expect(migration.Versions).To(ConsistOf(tags))
+	})
+
+	It("errors on files it can't merge", func() {
+		writeFile("changelog/v0.2.0/b.yaml", "changelog: [{type: FIX, description: foo, issueLink: bar}]\n")
+		_, err := changelogutils.PlanChangelogMigration(ctx, mount(), changelogutils.ChangelogMigrationOptions{CompactMinFiles: 2})
+		Expect(err).To(MatchError(changelogutils.UnableToMergeChangelogFileError("changelog/v0.2.0/b.yaml", "the changelog entries are not a block list")))
+	})
+})
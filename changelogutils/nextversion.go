@@ -0,0 +1,277 @@
+package changelogutils
+
+import (
+	"context"
+	"path/filepath"
+
+	"github.com/pkg/errors"
+	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/stringutils"
+	"github.com/solo-io/go-utils/versionutils"
+	"github.com/solo-io/go-utils/vfsutils"
+	"github.com/spf13/afero"
+)
+
+var (
+	NoPendingChangelogError = eris.Errorf("No pending changelog entries found.")
+	LabelNotGreaterError    = func(label, latest string, labelOrder []string) error {
+		return eris.Errorf("Label %s is not greater than the label of the latest release %s, according to label order %v", label, latest, labelOrder)
+	}
+	ChangelogFileExistsError = func(path string) error {
+		return eris.Errorf("Changelog file %s already exists", path)
+	}
+	MoveChangelogFileError = func(err error, from, to string) error {
+		return errors.Wrapf(err, "Unable to move changelog file %s to %s", from, to)
+	}
+)
+
+type NextVersionOptions struct {
+	// If set, the next version will be a prerelease with this label (e.g. "rc"). This is used to start a
+	// prerelease series, or to switch labels (e.g. v1.0.0-beta3 -> v1.0.0-rc1).
+	// If empty, the label of the latest release is kept.
+	Label string
+
+	// If true, the next version of a prerelease is the release it precedes (e.g. v1.0.0-rc2 -> v1.0.0)
+	DropLabel bool
+
+	// Tie-break order for labels, as used by NewChangelogValidatorWithLabelOrder. Labels ordered earlier are greater.
+	LabelOrder []string
+
+	// Changelog files, relative to the repo root, to include in addition to the files already in pending version
+	// directories. Usually a new changelog file that hasn't been placed in a version directory yet.
+	AdditionalFiles []string
+}
+
+type NextVersion struct {
+	LatestTag string
+	Version   *versionutils.Version
+	// The changelog directory, which accounts for ValidationSettings.ActiveSubdirectory
+	ChangelogDirectory string
+	// Directories, relative to the repo root, of versions greater than the latest release.
+	// When the changelog is valid there is at most one.
+	PendingDirectories []string
+}
+
+// Returns the directory, relative to the repo root, that the changelog files for the next version belong in
+func (n *NextVersion) Directory() string {
+	return filepath.Join(n.ChangelogDirectory, n.Version.String())
+}
+
+// Computes the next version from the latest release before base and the pending changelog entries, following
+// the same rules as ChangelogValidator. Use NewLocalRepoHistory to compute the version from a local checkout.
+func ComputeNextVersion(ctx context.Context, client RepoHistory, code vfsutils.MountedRepo, base string, opts NextVersionOptions) (*NextVersion, error) {
+	latestTag, err := client.FindLatestTagIncludingPrereleaseBeforeSha(ctx, base)
+	if err != nil {
+		return nil, ListReleasesError(err)
+	}
+	settings, err := getValidationSettings(ctx, code, client)
+	if err != nil {
+		return nil, err
+	}
+	entryTypes, _ := settings.GetEntryTypes()
+
+	next := &NextVersion{
+		LatestTag:          latestTag,
+		ChangelogDirectory: getChangelogDirectory(settings),
+	}
+	children, err := code.ListFiles(ctx, next.ChangelogDirectory)
+	if err != nil {
+		return nil, err
+	}
+	var additionalFiles []string
+	for _, file := range opts.AdditionalFiles {
+		additionalFiles = append(additionalFiles, filepath.Clean(file))
+	}
+	var pendingFiles []string
+	for _, child := range children {
+		childPath := filepath.Join(next.ChangelogDirectory, child.Name())
+		if !child.IsDir() {
+			if !IsKnownChangelogFile(childPath) && !stringutils.ContainsString(childPath, additionalFiles) {
+				return nil, UnexpectedFileInChangelogDirectoryError(child.Name())
+			}
+			continue
+		}
+		if !versionutils.MatchesRegex(child.Name()) {
+			return nil, InvalidChangelogSubdirectoryNameError(child.Name())
+		}
+		greaterThan, determinable, err := isGreaterThanTag(child.Name(), latestTag, opts.LabelOrder)
+		if err != nil {
+			return nil, err
+		}
+		if !greaterThan && determinable {
+			continue
+		}
+		next.PendingDirectories = append(next.PendingDirectories, childPath)
+		files, err := code.ListFiles(ctx, childPath)
+		if err != nil {
+			return nil, UnableToListFilesError(err, childPath)
+		}
+		for _, file := range files {
+			if file.IsDir() {
+				return nil, UnexpectedDirectoryError(file.Name(), childPath)
+			}
+			if file.Name() == SummaryFile || file.Name() == ClosingFile {
+				continue
+			}
+			pendingFiles = append(pendingFiles, filepath.Join(childPath, file.Name()))
+		}
+	}
+	for _, file := range additionalFiles {
+		if !stringutils.ContainsString(file, pendingFiles) {
+			pendingFiles = append(pendingFiles, file)
+		}
+	}
+	if len(pendingFiles) == 0 {
+		return nil, NoPendingChangelogError
+	}
+
+	var changelogFiles []*ChangelogFile
+	for _, file := range pendingFiles {
+		changelogFile, err := readChangelogFile(ctx, code, file, entryTypes)
+		if err != nil {
+			return nil, err
+		}
+		changelogFiles = append(changelogFiles, changelogFile)
+	}
+
+	next.Version, err = GetNextVersion(latestTag, changelogFiles, settings, opts)
+	if err != nil {
+		return nil, err
+	}
+	return next, nil
+}
+
+// Computes the version that the changelog files should be released as, given the latest release
+func GetNextVersion(latestTag string, changelogFiles []*ChangelogFile, settings *ValidationSettings, opts NextVersionOptions) (*versionutils.Version, error) {
+	if settings == nil {
+		settings = &defaultSettings
+	}
+	latestVersion, err := versionutils.ParseVersion(latestTag)
+	if err != nil {
+		return nil, err
+	}
+	entryTypes, err := settings.GetEntryTypes()
+	if err != nil {
+		return nil, UnableToGetSettingsError(err)
+	}
+
+	breakingChanges := false
+	newFeature := false
+	releaseStableApi := false
+	for _, file := range changelogFiles {
+		for _, entry := range file.Entries {
+			breakingChanges = breakingChanges || entryTypes.BreakingChange(entry.Type)
+			newFeature = newFeature || entryTypes.NewFeature(entry.Type)
+		}
+		releaseStableApi = releaseStableApi || file.GetReleaseStableApi()
+	}
+
+	var nextVersion *versionutils.Version
+	switch {
+	case releaseStableApi:
+		nextVersion = nextStableApiVersion(latestVersion)
+	case opts.DropLabel && latestVersion.IsPrerelease():
+		nextVersion = versionutils.NewVersion(latestVersion.Major, latestVersion.Minor, latestVersion.Patch, "", 0)
+	case opts.Label != "" && opts.Label != latestVersion.Label:
+		if !latestVersion.IsPrerelease() {
+			// start a prerelease series for the version that would otherwise be released
+			nextVersion = latestVersion.IncrementVersion(breakingChanges, newFeature)
+		} else {
+			// switch labels for the same release
+			nextVersion = versionutils.NewVersion(latestVersion.Major, latestVersion.Minor, latestVersion.Patch, "", 0)
+		}
+		nextVersion.Label = opts.Label
+		nextVersion.LabelVersion = 1
+		if latestVersion.IsPrerelease() && len(opts.LabelOrder) > 0 {
+			greaterThan, determinable := nextVersion.IsGreaterThanWithLabelOrder(*latestVersion, opts.LabelOrder)
+			if !greaterThan || !determinable {
+				return nil, LabelNotGreaterError(opts.Label, latestTag, opts.LabelOrder)
+			}
+		}
+	default:
+		nextVersion = latestVersion.IncrementVersion(breakingChanges, newFeature)
+	}
+
+	if settings.RequireLabel && !nextVersion.IsPrerelease() {
+		return nil, ExpectedVersionLabelError(nextVersion.String())
+	}
+	if nextVersion.IsPrerelease() && len(settings.AllowedLabels) > 0 {
+		if !stringutils.ContainsString(nextVersion.Label, settings.AllowedLabels) {
+			return nil, InvalidLabelError(nextVersion.Label, settings.AllowedLabels)
+		}
+	}
+	return nextVersion, nil
+}
+
+// a stable API release must be at least v1.0.0, with a patch version of 0 and no label
+func nextStableApiVersion(latestVersion *versionutils.Version) *versionutils.Version {
+	stableApiVersion := versionutils.StableApiVersion()
+	if !latestVersion.MustIsGreaterThanOrEqualTo(stableApiVersion) {
+		return &stableApiVersion
+	}
+	if latestVersion.IsPrerelease() && latestVersion.Patch == 0 {
+		// e.g. v1.1.0-rc2 -> v1.1.0
+		return versionutils.NewVersion(latestVersion.Major, latestVersion.Minor, 0, "", 0)
+	}
+	return versionutils.NewVersion(latestVersion.Major, latestVersion.Minor+1, 0, "", 0)
+}
+
+func isGreaterThanTag(greaterTag, lesserTag string, labelOrder []string) (bool, bool, error) {
+	if len(labelOrder) > 0 {
+		return versionutils.IsGreaterThanTagWithLabelOrder(greaterTag, lesserTag, labelOrder)
+	}
+	return versionutils.IsGreaterThanTag(greaterTag, lesserTag)
+}
+
+// Moves the changelog file at path into the directory of the next version, creating the directory if needed.
+// Files in other pending version directories (e.g. v0.1.1 when a breaking change means the next version is v0.2.0)
+// are moved as well, and the emptied directories removed, so that all pending changelog files end up in the
+// directory of the next version. Paths are relative to repoRootPath. Returns the new path of the file.
+func PlaceChangelogFile(fs afero.Fs, repoRootPath string, next *NextVersion, path string) (string, error) {
+	targetDir := next.Directory()
+	if err := fs.MkdirAll(filepath.Join(repoRootPath, targetDir), 0755); err != nil {
+		return "", err
+	}
+
+	move := func(from string) (string, error) {
+		to := filepath.Join(targetDir, filepath.Base(from))
+		if filepath.Clean(from) == to {
+			return to, nil
+		}
+		if exists, err := afero.Exists(fs, filepath.Join(repoRootPath, to)); err != nil {
+			return "", err
+		} else if exists {
+			return "", ChangelogFileExistsError(to)
+		}
+		if err := fs.Rename(filepath.Join(repoRootPath, from), filepath.Join(repoRootPath, to)); err != nil {
+			return "", MoveChangelogFileError(err, from, to)
+		}
+		return to, nil
+	}
+
+	for _, dir := range next.PendingDirectories {
+		if filepath.Clean(dir) == targetDir {
+			continue
+		}
+		files, err := afero.ReadDir(fs, filepath.Join(repoRootPath, dir))
+		if err != nil {
+			return "", UnableToListFilesError(err, dir)
+		}
+		for _, file := range files {
+			if _, err := move(filepath.Join(dir, file.Name())); err != nil {
+				return "", err
+			}
+		}
+		if err := fs.Remove(filepath.Join(repoRootPath, dir)); err != nil {
+			return "", err
+		}
+	}
+
+	if exists, err := afero.Exists(fs, filepath.Join(repoRootPath, path)); err != nil {
+		return "", err
+	} else if !exists {
+		// the file was in a pending directory that has already been moved
+		return filepath.Join(targetDir, filepath.Base(path)), nil
+	}
+	return move(path)
+}
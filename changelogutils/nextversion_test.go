@@ -0,0 +1,145 @@
+package changelogutils_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/solo-io/go-utils/changelogutils"
+	"github.com/solo-io/go-utils/vfsutils"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("next version", func() {
+
+	var (
+		fix = &changelogutils.ChangelogFile{Entries: []*changelogutils.ChangelogEntry{
+			{Type: changelogutils.FIX, Description: "fix", IssueLink: "link"},
+		}}
+		feature = &changelogutils.ChangelogFile{Entries: []*changelogutils.ChangelogEntry{
+			{Type: changelogutils.NEW_FEATURE, Description: "feature", IssueLink: "link"},
+		}}
+		breaking = &changelogutils.ChangelogFile{Entries: []*changelogutils.ChangelogEntry{
+			{Type: changelogutils.BREAKING_CHANGE, Description: "breaking", IssueLink: "link"},
+		}}
+		stable = &changelogutils.ChangelogFile{
+			Entries:          []*changelogutils.ChangelogEntry{{Type: changelogutils.NON_USER_FACING}},
+			ReleaseStableApi: &[]bool{true}[0],
+		}
+	)
+
+	DescribeTable("computes the next version",
+		func(latestTag string, files []*changelogutils.ChangelogFile, opts changelogutils.NextVersionOptions, expected string) {
+			version, err := changelogutils.GetNextVersion(latestTag, files, nil, opts)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(version.String()).To(Equal(expected))
+		},
+		Entry("pre-1.0 fix", "v0.1.0", []*changelogutils.ChangelogFile{fix}, changelogutils.NextVersionOptions{}, "v0.1.1"),
+		Entry("pre-1.0 feature", "v0.1.0", []*changelogutils.ChangelogFile{fix, feature}, changelogutils.NextVersionOptions{}, "v0.1.1"),
+		Entry("pre-1.0 breaking change", "v0.1.3", []*changelogutils.ChangelogFile{fix, breaking}, changelogutils.NextVersionOptions{}, "v0.2.0"),
+		Entry("fix", "v1.2.3", []*changelogutils.ChangelogFile{fix}, changelogutils.NextVersionOptions{}, "v1.2.4"),
+		Entry("feature", "v1.2.3", []*changelogutils.ChangelogFile{fix, feature}, changelogutils.NextVersionOptions{}, "v1.3.0"),
+		Entry("breaking change", "v1.2.3", []*changelogutils.ChangelogFile{feature, breaking}, changelogutils.NextVersionOptions{}, "v2.0.0"),
+		Entry("stable api", "v0.20.3", []*changelogutils.ChangelogFile{stable}, changelogutils.NextVersionOptions{}, "v1.0.0"),
+		Entry("stable api after rc", "v1.1.0-rc2", []*changelogutils.ChangelogFile{stable}, changelogutils.NextVersionOptions{}, "v1.1.0"),
+		Entry("next prerelease", "v1.0.0-rc1", []*changelogutils.ChangelogFile{breaking}, changelogutils.NextVersionOptions{}, "v1.0.0-rc2"),
+		Entry("start prerelease", "v1.2.3", []*changelogutils.ChangelogFile{breaking}, changelogutils.NextVersionOptions{Label: "beta"}, "v2.0.0-beta1"),
+		Entry("switch label", "v2.0.0-beta4", []*changelogutils.ChangelogFile{fix},
+			changelogutils.NextVersionOptions{Label: "rc", LabelOrder: []string{"rc", "beta"}}, "v2.0.0-rc1"),
+		Entry("drop label", "v2.0.0-rc3", []*changelogutils.ChangelogFile{fix}, changelogutils.NextVersionOptions{DropLabel: true}, "v2.0.0"),
+		Entry("drop numeric prerelease", "v2.0.0-0.3.7", []*changelogutils.ChangelogFile{fix}, changelogutils.NextVersionOptions{DropLabel: true}, "v2.0.0"),
+		Entry("stable api after numeric prerelease", "v1.1.0-1", []*changelogutils.ChangelogFile{stable}, changelogutils.NextVersionOptions{}, "v1.1.0"),
+	)
+
+	It("errors when switching to a lesser label", func() {
+		_, err := changelogutils.GetNextVersion("v2.0.0-rc1", []*changelogutils.ChangelogFile{fix}, nil,
+			changelogutils.NextVersionOptions{Label: "beta", LabelOrder: []string{"rc", "beta"}})
+		Expect(err).To(MatchError(changelogutils.LabelNotGreaterError("beta", "v2.0.0-rc1", []string{"rc", "beta"})))
+	})
+
+	It("honours RequireLabel and AllowedLabels", func() {
+		settings := &changelogutils.ValidationSettings{RequireLabel: true, AllowedLabels: []string{"patch"}}
+		_, err := changelogutils.GetNextVersion("v1.2.3", []*changelogutils.ChangelogFile{fix}, settings, changelogutils.NextVersionOptions{})
+		Expect(err).To(MatchError(changelogutils.ExpectedVersionLabelError("v1.2.4")))
+
+		_, err = changelogutils.GetNextVersion("v1.2.3", []*changelogutils.ChangelogFile{fix}, settings, changelogutils.NextVersionOptions{Label: "rc"})
+		Expect(err).To(MatchError(changelogutils.InvalidLabelError("rc", []string{"patch"})))
+
+		version, err := changelogutils.GetNextVersion("v1.2.3", []*changelogutils.ChangelogFile{fix}, settings, changelogutils.NextVersionOptions{Label: "patch"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(version.String()).To(Equal("v1.2.4-patch1"))
+	})
+
+	Context("local checkout", func() {
+
+		var (
+			ctx  = context.Background()
+			repo *testGitRepo
+		)
+
+		computeNextVersion := func(additionalFiles ...string) (*changelogutils.NextVersion, error) {
+			code, err := vfsutils.NewLocalMountedRepoForFs(repo.root, "solo-io", "testrepo")
+			Expect(err).NotTo(HaveOccurred())
+			history, err := changelogutils.NewLocalRepoHistory(repo.root)
+			Expect(err).NotTo(HaveOccurred())
+			return changelogutils.ComputeNextVersion(ctx, history, code, changelogutils.MasterBranch,
+				changelogutils.NextVersionOptions{AdditionalFiles: additionalFiles})
+		}
+
+		BeforeEach(func() {
+			repo = newTestGitRepo()
+		})
+
+		AfterEach(func() {
+			repo.cleanup()
+		})
+
+		It("errors when there are no pending changes", func() {
+			_, err := computeNextVersion()
+			Expect(err).To(Equal(changelogutils.NoPendingChangelogError))
+		})
+
+		It("places a new changelog file in the directory of the next version", func() {
+			repo.writeFile("changelog/my-change.yaml", validChangelog2)
+
+			next, err := computeNextVersion("changelog/my-change.yaml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(next.LatestTag).To(Equal("v0.1.0"))
+			Expect(next.Version.String()).To(Equal("v0.1.1"))
+			Expect(next.PendingDirectories).To(BeEmpty())
+
+			newPath, err := changelogutils.PlaceChangelogFile(afero.NewOsFs(), repo.root, next, "changelog/my-change.yaml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newPath).To(Equal("changelog/v0.1.1/my-change.yaml"))
+			Expect(filepath.Join(repo.root, newPath)).To(BeARegularFile())
+
+			repo.commit("changelog")
+			code, err := vfsutils.NewLocalMountedRepoForFs(repo.root, "solo-io", "testrepo")
+			Expect(err).NotTo(HaveOccurred())
+			validator, err := changelogutils.NewLocalChangelogValidator(repo.root, code, changelogutils.MasterBranch)
+			Expect(err).NotTo(HaveOccurred())
+			_, err = validator.ValidateChangelog(ctx)
+			Expect(err).NotTo(HaveOccurred())
+		})
+
+		It("moves pending changelog files when the next version changes", func() {
+			repo.writeFile("changelog/v0.1.1/fix.yaml", validChangelog2)
+			repo.commit("changelog")
+			repo.writeFile("changelog/breaking.yaml", validBreakingChangelog)
+
+			next, err := computeNextVersion("changelog/breaking.yaml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(next.Version.String()).To(Equal("v0.2.0"))
+			Expect(next.PendingDirectories).To(Equal([]string{"changelog/v0.1.1"}))
+
+			newPath, err := changelogutils.PlaceChangelogFile(afero.NewOsFs(), repo.root, next, "changelog/breaking.yaml")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(newPath).To(Equal("changelog/v0.2.0/breaking.yaml"))
+			Expect(filepath.Join(repo.root, "changelog/v0.2.0/fix.yaml")).To(BeARegularFile())
+			_, err = os.Stat(filepath.Join(repo.root, "changelog/v0.1.1"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+})
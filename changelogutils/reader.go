@@ -2,6 +2,7 @@ package changelogutils
 
 import (
 	"context"
+	"fmt"
 	"path/filepath"
 
 	"github.com/ghodss/yaml"
@@ -53,35 +54,156 @@ func NewChangelogReader(code vfsutils.MountedRepo) ChangelogReader {
 }
 
 func (c *changelogReader) GetChangelogDirectory(ctx context.Context) string {
-	var settings ValidationSettings
-	bytes, err := c.code.GetFileContents(ctx, GetValidationSettingsPath())
+	settings, err := readValidationSettings(ctx, c.code)
 	if err != nil {
-		// unable to read validtion.yaml ~= "validation.yaml is not there"
-		return "changelog"
-	}
-
-	if err := yaml.Unmarshal(bytes, &settings); err != nil {
 		// suppressing error, because we _should_ always know the changelog dir
 		return "changelog"
 	}
+	return getChangelogDirectory(settings)
+}
 
+func getChangelogDirectory(settings *ValidationSettings) string {
 	if settings.ActiveSubdirectory != "" {
 		return "changelog/" + settings.ActiveSubdirectory
 	}
 	return "changelog"
 }
 
+// Returns the directory holding the changelog files of a version. With nestedVersionDirectories, every version is
+// in the directory of its minor version, regardless of the active subdirectory.
+func getVersionDirectory(settings *ValidationSettings, tag string) string {
+	if settings.NestedVersionDirectories {
+		if version, err := versionutils.ParseVersion(tag); err == nil {
+			return filepath.Join(ChangelogDirectory, MinorVersionDirectory(version), tag)
+		}
+	}
+	return filepath.Join(getChangelogDirectory(settings), tag)
+}
+
+// Returns the name of the directory that nests the versions of a minor release, e.g. v1.10 for v1.10.3
+func MinorVersionDirectory(version *versionutils.Version) string {
+	return fmt.Sprintf("v%d.%d", version.Major, version.Minor)
+}
+
+// Returns the names of the version directories in the changelog directory of the repo. With
+// nestedVersionDirectories, the versions in every minor version directory are returned, except those in the
+// directory of another minor version.
+func listChangelogVersions(ctx context.Context, code vfsutils.MountedRepo, settings *ValidationSettings) ([]string, error) {
+	return findChangelogVersions(ctx, code, settings, nil)
+}
+
+// Like listChangelogVersions, but also adds a violation to the report, if any, for every file or directory where
+// only version directories (or, with nestedVersionDirectories, minor version directories) are expected
+func findChangelogVersions(ctx context.Context, code vfsutils.MountedRepo, settings *ValidationSettings, report *ValidationReport) ([]string, error) {
+	addError := func(path, ruleId string, err error) {
+		if report != nil {
+			report.AddError(path, ruleId, err)
+		}
+	}
+	parent := getVersionsParentDirectory(settings)
+	dirs := []string{parent}
+	if settings.NestedVersionDirectories {
+		dirs = nil
+		children, err := code.ListFiles(ctx, parent)
+		if err != nil {
+			return nil, UnableToListFilesError(err, parent)
+		}
+		for _, child := range children {
+			childPath := filepath.Join(parent, child.Name())
+			switch {
+			case !child.IsDir():
+				if !IsKnownChangelogFile(childPath) {
+					addError(childPath, RuleUnexpectedFile, UnexpectedFileInChangelogDirectoryError(child.Name()))
+				}
+			case !minorVersionDirectoryRegex.MatchString(child.Name()):
+				addError(childPath, RuleInvalidVersionDirectory, InvalidMinorVersionDirectoryNameError(child.Name()))
+			default:
+				dirs = append(dirs, childPath)
+			}
+		}
+	}
+	var versions []string
+	for _, dir := range dirs {
+		children, err := code.ListFiles(ctx, dir)
+		if err != nil {
+			return nil, UnableToListFilesError(err, dir)
+		}
+		for _, child := range children {
+			childPath := filepath.Join(dir, child.Name())
+			switch {
+			case !child.IsDir():
+				if !IsKnownChangelogFile(childPath) {
+					addError(childPath, RuleUnexpectedFile, UnexpectedFileInChangelogDirectoryError(child.Name()))
+				}
+			case !versionutils.MatchesRegex(child.Name()):
+				addError(childPath, RuleInvalidVersionDirectory, InvalidChangelogSubdirectoryNameError(child.Name()))
+			case getVersionDirectory(settings, child.Name()) != childPath:
+				addError(childPath, RuleInvalidVersionDirectory, MisplacedVersionDirectoryError(child.Name(), dir))
+			default:
+				versions = append(versions, child.Name())
+			}
+		}
+	}
+	return versions, nil
+}
+
+// Returns the directory that holds the version directories or, with nestedVersionDirectories, the directories of
+// their minor versions
+func getVersionsParentDirectory(settings *ValidationSettings) string {
+	if settings.NestedVersionDirectories {
+		return ChangelogDirectory
+	}
+	return getChangelogDirectory(settings)
+}
+
+// Reads validation.yaml directly from the mounted repo. Returns the default settings if the file can't be read.
+func readValidationSettings(ctx context.Context, code vfsutils.MountedRepo) (*ValidationSettings, error) {
+	var settings ValidationSettings
+	bytes, err := code.GetFileContents(ctx, GetValidationSettingsPath())
+	if err != nil {
+		// unable to read validtion.yaml ~= "validation.yaml is not there"
+		return &defaultSettings, nil
+	}
+
+	if err := yaml.Unmarshal(bytes, &settings); err != nil {
+		return nil, err
+	}
+	return &settings, nil
+}
+
+// Returns the entry types declared in validation.yaml. Invalid settings are reported by the validator, so here
+// they are treated as declaring no custom entry types.
+func readEntryTypes(ctx context.Context, code vfsutils.MountedRepo) *EntryTypes {
+	settings, err := readValidationSettings(ctx, code)
+	if err != nil {
+		return nil
+	}
+	return entryTypesOrDefault(settings)
+}
+
+func entryTypesOrDefault(settings *ValidationSettings) *EntryTypes {
+	entryTypes, err := settings.GetEntryTypes()
+	if err != nil {
+		return nil
+	}
+	return entryTypes
+}
+
 func (c *changelogReader) GetChangelogForTag(ctx context.Context, tag string) (*Changelog, error) {
 	version, err := versionutils.ParseVersion(tag)
 	if err != nil {
 		return nil, err
 	}
+	settings, err := readValidationSettings(ctx, c.code)
+	if err != nil {
+		// suppressing error, because we _should_ always know the changelog dir
+		settings = &defaultSettings
+	}
 	changelog := Changelog{
-		Version: version,
+		Version:    version,
+		EntryTypes: entryTypesOrDefault(settings),
 	}
-	dir := c.GetChangelogDirectory(ctx)
-
-	changelogPath := filepath.Join(dir, tag)
+	changelogPath := getVersionDirectory(settings, tag)
 	files, err := c.code.ListFiles(ctx, changelogPath)
 	if err != nil {
 		return nil, UnableToListFilesError(err, changelogPath)
@@ -104,7 +226,7 @@ func (c *changelogReader) GetChangelogForTag(ctx context.Context, tag string) (*
 			}
 			changelog.Closing = string(closing)
 		} else {
-			changelogFile, err := c.ReadChangelogFile(ctx, changelogFilePath)
+			changelogFile, err := readChangelogFile(ctx, c.code, changelogFilePath, changelog.EntryTypes)
 			if err != nil {
 				return nil, err
 			}
@@ -116,41 +238,41 @@ func (c *changelogReader) GetChangelogForTag(ctx context.Context, tag string) (*
 }
 
 func (c *changelogReader) ReadChangelogFile(ctx context.Context, path string) (*ChangelogFile, error) {
-	var changelog ChangelogFile
-	bytes, err := c.code.GetFileContents(ctx, path)
+	return readChangelogFile(ctx, c.code, path, readEntryTypes(ctx, c.code))
+}
+
+func readChangelogFile(ctx context.Context, code vfsutils.MountedRepo, path string, entryTypes *EntryTypes) (*ChangelogFile, error) {
+	changelog, report, err := readChangelogFileWithReport(ctx, code, path, entryTypes)
 	if err != nil {
 		return nil, err
 	}
-
-	if err := yaml.Unmarshal(bytes, &changelog); err != nil {
-		return nil, UnableToParseChangelogError(err, path)
+	if err := report.FirstError(); err != nil {
+		return nil, err
 	}
+	return changelog, nil
+}
 
-	if len(changelog.Entries) == 0 {
-		return nil, NoEntriesInChangelogError(path)
+// Reads and validates a changelog file like ChangelogReader.ReadChangelogFile, but collects every problem with the
+// file in the returned report instead of stopping at the first one. An error is only returned if the file can't be read.
+// The changelog file is nil if it can't be parsed.
+func ReadChangelogFileWithReport(ctx context.Context, code vfsutils.MountedRepo, path string) (*ChangelogFile, *ValidationReport, error) {
+	return readChangelogFileWithReport(ctx, code, path, readEntryTypes(ctx, code))
+}
+
+func readChangelogFileWithReport(ctx context.Context, code vfsutils.MountedRepo, path string, entryTypes *EntryTypes) (*ChangelogFile, *ValidationReport, error) {
+	var changelog ChangelogFile
+	bytes, err := code.GetFileContents(ctx, path)
+	if err != nil {
+		return nil, nil, err
 	}
 
-	for _, entry := range changelog.Entries {
-		if entry.Type != NON_USER_FACING && entry.Type != DEPENDENCY_BUMP {
-			if entry.IssueLink == "" {
-				return nil, MissingIssueLinkError
-			}
-			if entry.Description == "" {
-				return nil, MissingDescriptionError
-			}
-		}
-		if entry.Type == DEPENDENCY_BUMP {
-			if entry.DependencyOwner == "" {
-				return nil, MissingOwnerError
-			}
-			if entry.DependencyRepo == "" {
-				return nil, MissingRepoError
-			}
-			if entry.DependencyTag == "" {
-				return nil, MissingTagError
-			}
-		}
+	report := NewValidationReport()
+	if err := yaml.Unmarshal(bytes, &changelog); err != nil {
+		report.AddError(path, RuleInvalidChangelogFile, UnableToParseChangelogError(err, path))
+		return nil, report, nil
 	}
 
-	return &changelog, nil
+	entryTypes.resolve(&changelog)
+	validateChangelogEntries(path, bytes, &changelog, entryTypes, report)
+	return &changelog, report, nil
 }
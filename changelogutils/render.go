@@ -0,0 +1,391 @@
+package changelogutils
+
+import (
+	"encoding/json"
+	"fmt"
+	htmltemplate "html/template"
+	"io"
+	"os"
+	"strings"
+	"text/template"
+
+	"github.com/pkg/errors"
+	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/stringutils"
+)
+
+var (
+	UnknownOutputFormatError = func(format string) error {
+		return eris.Errorf("Unknown changelog output format %s, must be one of %v", format, OutputFormats)
+	}
+	ParseChangelogTemplateError = func(err error) error {
+		return errors.Wrapf(err, "unable to parse changelog template")
+	}
+)
+
+// Output formats supported by NewChangelogRenderer
+const (
+	MarkdownFormat       = "markdown"
+	KeepAChangelogFormat = "keepachangelog"
+	JSONFormat           = "json"
+	HTMLFormat           = "html"
+	AsciiDocFormat       = "asciidoc"
+)
+
+var OutputFormats = []string{MarkdownFormat, KeepAChangelogFormat, JSONFormat, HTMLFormat, AsciiDocFormat}
+
+const noUserFacingChanges = "This release contained no user-facing changes."
+
+// Renders the release notes for a list of changelogs, in the order given (GenerateChangelogForTags passes them
+// newest first)
+type ChangelogRenderer interface {
+	Render(w io.Writer, changelogs ChangelogList) error
+}
+
+// Returns the renderer for one of the OutputFormats
+func NewChangelogRenderer(format string) (ChangelogRenderer, error) {
+	switch format {
+	case MarkdownFormat, "":
+		return &MarkdownRenderer{}, nil
+	case KeepAChangelogFormat:
+		return &KeepAChangelogRenderer{}, nil
+	case JSONFormat:
+		return &JSONRenderer{}, nil
+	case HTMLFormat:
+		return &HTMLRenderer{}, nil
+	case AsciiDocFormat:
+		return &AsciiDocRenderer{}, nil
+	}
+	return nil, UnknownOutputFormatError(format)
+}
+
+// The release notes of a single version, with entries grouped into sections in rendering order.
+// This is the data passed to custom templates (see NewTemplateRenderer).
+type ReleaseNotes struct {
+	Version  string                `json:"version"`
+	Summary  string                `json:"summary,omitempty"`
+	Sections []ReleaseNotesSection `json:"sections"`
+	Closing  string                `json:"closing,omitempty"`
+	// Upstream release notes of the dependency bumps, see AddDependencyNotes
+	DependencyNotes []*DependencyNotes `json:"dependencyNotes,omitempty"`
+}
+
+type ReleaseNotesSection struct {
+	Type    ChangelogEntryType `json:"type"`
+	Heading string             `json:"heading"`
+	Entries []*ChangelogEntry  `json:"entries"`
+
+	// the name of a custom type, as declared in the validation settings of the repo
+	typeName string
+}
+
+// Returns the name of the type of the entries in the section
+func (s ReleaseNotesSection) TypeName() string {
+	if s.typeName != "" {
+		return s.typeName
+	}
+	return s.Type.String()
+}
+
+type releaseNotesSectionFields ReleaseNotesSection
+
+func (s ReleaseNotesSection) MarshalJSON() ([]byte, error) {
+	return json.Marshal(struct {
+		Type string `json:"type"`
+		*releaseNotesSectionFields
+	}{
+		Type:                      s.TypeName(),
+		releaseNotesSectionFields: (*releaseNotesSectionFields)(&s),
+	})
+}
+
+// Sections of a custom type are read with an undeclared type, like the entries in them
+func (s *ReleaseNotesSection) UnmarshalJSON(data []byte) error {
+	section := struct {
+		Type string `json:"type"`
+		*releaseNotesSectionFields
+	}{
+		releaseNotesSectionFields: (*releaseNotesSectionFields)(s),
+	}
+	if err := json.Unmarshal(data, &section); err != nil {
+		return err
+	}
+	s.Type, s.typeName = undeclaredEntryType, ""
+	if entryType, err := ParseChangelogEntryType(section.Type); err == nil {
+		s.Type = entryType
+	} else {
+		s.typeName = section.Type
+	}
+	return nil
+}
+
+// Groups the entries of a changelog into sections, omitting empty sections and non-user facing entries
+func NewReleaseNotes(changelog *Changelog) *ReleaseNotes {
+	notes := &ReleaseNotes{
+		Summary:         changelog.Summary,
+		Closing:         changelog.Closing,
+		Sections:        []ReleaseNotesSection{},
+		DependencyNotes: changelog.DependencyNotes,
+	}
+	if changelog.Version != nil {
+		notes.Version = changelog.Version.String()
+	}
+	for _, section := range changelog.EntryTypes.Sections() {
+		var entries []*ChangelogEntry
+		for _, file := range changelog.Files {
+			for _, entry := range file.Entries {
+				if entry.Type == section.Type {
+					entries = append(entries, entry)
+				}
+			}
+		}
+		if len(entries) > 0 {
+			notes.Sections = append(notes.Sections, ReleaseNotesSection{
+				Type:     section.Type,
+				Heading:  section.Heading,
+				Entries:  entries,
+				typeName: changelog.EntryTypes.Name(section.Type),
+			})
+		}
+	}
+	return notes
+}
+
+func (n *ReleaseNotes) HasUserFacingChanges() bool {
+	return n.Summary != "" || n.Closing != "" || len(n.Sections) > 0
+}
+
+func releaseNotesForChangelogs(changelogs ChangelogList) []*ReleaseNotes {
+	notes := make([]*ReleaseNotes, len(changelogs))
+	for i, changelog := range changelogs {
+		notes[i] = NewReleaseNotes(changelog)
+	}
+	return notes
+}
+
+// Returns the text of an entry, without its issue link
+func changelogEntryText(entry *ChangelogEntry) string {
+	if entry.Type == DEPENDENCY_BUMP {
+		return entry.DependencyOwner + "/" + entry.DependencyRepo + " has been upgraded to " + entry.DependencyTag + "."
+	}
+	return strings.TrimSpace(entry.Description)
+}
+
+// The layout used by GenerateChangelogMarkdown, with a "### <version>" heading per release
+type MarkdownRenderer struct{}
+
+func (r *MarkdownRenderer) Render(w io.Writer, changelogs ChangelogList) error {
+	tmplData := changelogSummaryTmplDataFromChangelogs(changelogs)
+	if err := changelogSummaryTmpl.Execute(w, tmplData); err != nil {
+		return GenerateChangelogSummaryTemplateError(err)
+	}
+	return nil
+}
+
+// Renders in the style of https://keepachangelog.com: entries are grouped under Added, Changed, Fixed etc.
+// Custom entry types are rendered under their own heading, unless it is one of the Keep a Changelog categories.
+type KeepAChangelogRenderer struct{}
+
+var keepAChangelogCategories = []string{"Added", "Changed", "Deprecated", "Removed", "Fixed", "Security"}
+
+func keepAChangelogCategory(section ReleaseNotesSection) string {
+	switch section.Type {
+	case NEW_FEATURE:
+		return "Added"
+	case BREAKING_CHANGE, UPGRADE, HELM, DEPENDENCY_BUMP:
+		return "Changed"
+	case FIX:
+		return "Fixed"
+	}
+	for _, category := range keepAChangelogCategories {
+		if strings.EqualFold(category, section.Heading) {
+			return category
+		}
+	}
+	return section.Heading
+}
+
+func (r *KeepAChangelogRenderer) Render(w io.Writer, changelogs ChangelogList) error {
+	// paragraphs are separated by a blank line
+	paragraphs := []string{"# Changelog"}
+	for _, notes := range releaseNotesForChangelogs(changelogs) {
+		paragraphs = append(paragraphs, fmt.Sprintf("## [%s]", strings.TrimPrefix(notes.Version, "v")))
+		if !notes.HasUserFacingChanges() {
+			paragraphs = append(paragraphs, noUserFacingChanges)
+			continue
+		}
+		if notes.Summary != "" {
+			paragraphs = append(paragraphs, strings.TrimSpace(notes.Summary))
+		}
+
+		var customCategories []string
+		entries := make(map[string][]string)
+		for _, section := range notes.Sections {
+			category := keepAChangelogCategory(section)
+			if _, ok := entries[category]; !ok && !stringutils.ContainsString(category, keepAChangelogCategories) {
+				customCategories = append(customCategories, category)
+			}
+			for _, entry := range section.Entries {
+				text := "- " + changelogEntryText(entry)
+				if section.Type == BREAKING_CHANGE {
+					text = "- **Breaking:** " + changelogEntryText(entry)
+				}
+				if link := strings.TrimSpace(entry.IssueLink); link != "" {
+					text = text + " (" + link + ")"
+				}
+				entries[category] = append(entries[category], text)
+			}
+		}
+		// Keep a Changelog categories first, in their conventional order, then custom headings
+		categories := append(append([]string{}, keepAChangelogCategories...), customCategories...)
+		for _, category := range categories {
+			if lines, ok := entries[category]; ok {
+				paragraphs = append(paragraphs, "### "+category, strings.Join(lines, "\n"))
+			}
+		}
+
+		if notes.Closing != "" {
+			paragraphs = append(paragraphs, strings.TrimSpace(notes.Closing))
+		}
+	}
+	_, err := io.WriteString(w, strings.Join(paragraphs, "\n\n")+"\n")
+	return err
+}
+
+// Renders a JSON array of ReleaseNotes
+type JSONRenderer struct {
+	// If true, the output is not indented
+	Compact bool
+}
+
+func (r *JSONRenderer) Render(w io.Writer, changelogs ChangelogList) error {
+	encoder := json.NewEncoder(w)
+	if !r.Compact {
+		encoder.SetIndent("", "  ")
+	}
+	return encoder.Encode(releaseNotesForChangelogs(changelogs))
+}
+
+// Renders an HTML fragment with a section per release. Each section has the version as its id, so releases can be
+// linked to with an anchor (e.g. #v1.2.3).
+type HTMLRenderer struct{}
+
+var changelogHTMLTmpl = htmltemplate.Must(htmltemplate.New("changelog html").Funcs(htmltemplate.FuncMap{
+	"entryText": changelogEntryText,
+	"trim":      strings.TrimSpace,
+}).Parse(`{{ range . -}}
+<section id="{{ .Version }}">
+<h2>{{ .Version }}</h2>
+{{- if not .HasUserFacingChanges }}
+<p>` + noUserFacingChanges + `</p>
+{{- end }}
+{{- if .Summary }}
+<p>{{ trim .Summary }}</p>
+{{- end }}
+{{- range .Sections }}
+<h3>{{ .Heading }}</h3>
+<ul>
+{{- range .Entries }}
+<li>{{ entryText . }}{{ with trim .IssueLink }} (<a href="{{ . }}">{{ . }}</a>){{ end }}</li>
+{{- end }}
+</ul>
+{{- end }}
+{{- if .Closing }}
+<p>{{ trim .Closing }}</p>
+{{- end }}
+</section>
+{{ end -}}
+`))
+
+func (r *HTMLRenderer) Render(w io.Writer, changelogs ChangelogList) error {
+	if err := changelogHTMLTmpl.Execute(w, releaseNotesForChangelogs(changelogs)); err != nil {
+		return GenerateChangelogSummaryTemplateError(err)
+	}
+	return nil
+}
+
+// Renders an AsciiDoc document with a level 1 section per release
+type AsciiDocRenderer struct{}
+
+var changelogAsciiDocTmpl = template.Must(template.New("changelog asciidoc").Funcs(template.FuncMap{
+	"entryText": changelogEntryText,
+	"trim":      strings.TrimSpace,
+}).Parse(`= Changelog
+{{ range . }}
+[#{{ .Version }}]
+== {{ .Version }}
+{{ if not .HasUserFacingChanges }}
+` + noUserFacingChanges + `
+{{ end -}}
+{{ if .Summary }}
+{{ trim .Summary }}
+{{ end -}}
+{{ range .Sections }}
+=== {{ .Heading }}
+
+{{ range .Entries -}}
+* {{ entryText . }}{{ with trim .IssueLink }} ({{ . }}[]){{ end }}
+{{ end -}}
+{{ end -}}
+{{ if .Closing }}
+{{ trim .Closing }}
+{{ end -}}
+{{ end -}}
+`))
+
+func (r *AsciiDocRenderer) Render(w io.Writer, changelogs ChangelogList) error {
+	if err := changelogAsciiDocTmpl.Execute(w, releaseNotesForChangelogs(changelogs)); err != nil {
+		return GenerateChangelogSummaryTemplateError(err)
+	}
+	return nil
+}
+
+// Renders with a user supplied text/template, executed with a []*ReleaseNotes. In addition to the standard template
+// functions, templates can use:
+//
+//	entryText:  the text of an entry without its issue link (e.g. "solo-io/gloo has been upgraded to v1.2.3.")
+//	markdown:   the release notes of a version as rendered by GenerateChangelogMarkdown
+type TemplateRenderer struct {
+	tmpl *template.Template
+}
+
+func NewTemplateRenderer(text string) (*TemplateRenderer, error) {
+	tmpl, err := template.New("changelog").Funcs(template.FuncMap{
+		"entryText": changelogEntryText,
+		// replaced with a function that has access to the changelogs when rendering
+		"markdown": func(*ReleaseNotes) string { return "" },
+	}).Parse(text)
+	if err != nil {
+		return nil, ParseChangelogTemplateError(err)
+	}
+	return &TemplateRenderer{tmpl: tmpl}, nil
+}
+
+func NewTemplateRendererFromFile(path string) (*TemplateRenderer, error) {
+	text, err := os.ReadFile(path)
+	if err != nil {
+		return nil, ParseChangelogTemplateError(err)
+	}
+	return NewTemplateRenderer(string(text))
+}
+
+func (r *TemplateRenderer) Render(w io.Writer, changelogs ChangelogList) error {
+	notes := releaseNotesForChangelogs(changelogs)
+	changelogsByNotes := make(map[*ReleaseNotes]*Changelog, len(notes))
+	for i := range notes {
+		changelogsByNotes[notes[i]] = changelogs[i]
+	}
+	tmpl, err := r.tmpl.Clone()
+	if err != nil {
+		return GenerateChangelogSummaryTemplateError(err)
+	}
+	tmpl.Funcs(template.FuncMap{
+		"markdown": func(notes *ReleaseNotes) string {
+			return GenerateChangelogMarkdown(changelogsByNotes[notes])
+		},
+	})
+	if err := tmpl.Execute(w, notes); err != nil {
+		return GenerateChangelogSummaryTemplateError(err)
+	}
+	return nil
+}
@@ -0,0 +1,267 @@
+package changelogutils_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"os"
+	"path/filepath"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/solo-io/go-utils/changelogutils"
+	"github.com/solo-io/go-utils/versionutils"
+	"github.com/solo-io/go-utils/vfsutils"
+)
+
+var _ = Describe("changelog renderers", func() {
+
+	var changelogs changelogutils.ChangelogList
+
+	BeforeEach(func() {
+		changelogs = changelogutils.ChangelogList{
+			{
+				Version: versionutils.NewVersion(1, 1, 0, "", 0),
+				Summary: "Widgets!",
+				Files: []*changelogutils.ChangelogFile{{Entries: []*changelogutils.ChangelogEntry{
+					{Type: changelogutils.FIX, Description: "Fixed a <bug>.", IssueLink: "https://github.com/solo-io/testrepo/issues/2"},
+					{Type: changelogutils.NEW_FEATURE, Description: "Added widgets.", IssueLink: "https://github.com/solo-io/testrepo/issues/1"},
+					{Type: changelogutils.BREAKING_CHANGE, Description: "Removed gadgets.", IssueLink: "https://github.com/solo-io/testrepo/issues/3"},
+					{Type: changelogutils.DEPENDENCY_BUMP, DependencyOwner: "solo-io", DependencyRepo: "solo-kit", DependencyTag: "v0.2.0"},
+				}}},
+			},
+			{
+				Version: versionutils.NewVersion(1, 0, 0, "", 0),
+				Files: []*changelogutils.ChangelogFile{{Entries: []*changelogutils.ChangelogEntry{
+					{Type: changelogutils.NON_USER_FACING},
+				}}},
+			},
+		}
+	})
+
+	render := func(renderer changelogutils.ChangelogRenderer) string {
+		var buf bytes.Buffer
+		Expect(renderer.Render(&buf, changelogs)).To(Succeed())
+		return buf.String()
+	}
+
+	It("renders markdown", func() {
+		renderer, err := changelogutils.NewChangelogRenderer(changelogutils.MarkdownFormat)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(render(renderer)).To(Equal(`
+
+### v1.1.0
+
+Widgets!
+
+**Dependency Bumps**
+
+- solo-io/solo-kit has been upgraded to v0.2.0.
+
+**Breaking Changes**
+
+- Removed gadgets. (https://github.com/solo-io/testrepo/issues/3)
+
+**New Features**
+
+- Added widgets. (https://github.com/solo-io/testrepo/issues/1)
+
+**Fixes**
+
+- Fixed a &lt;bug&gt;. (https://github.com/solo-io/testrepo/issues/2)
+
+
+### v1.0.0
+
+This release contained no user-facing changes.
+
+`))
+	})
+
+	It("renders Keep a Changelog", func() {
+		renderer, err := changelogutils.NewChangelogRenderer(changelogutils.KeepAChangelogFormat)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(render(renderer)).To(Equal(`# Changelog
+
+## [1.1.0]
+
+Widgets!
+
+### Added
+
+- Added widgets. (https://github.com/solo-io/testrepo/issues/1)
+
+### Changed
+
+- solo-io/solo-kit has been upgraded to v0.2.0.
+- **Breaking:** Removed gadgets. (https://github.com/solo-io/testrepo/issues/3)
+
+### Fixed
+
+- Fixed a <bug>. (https://github.com/solo-io/testrepo/issues/2)
+
+## [1.0.0]
+
+This release contained no user-facing changes.
+`))
+	})
+
+	It("renders JSON", func() {
+		renderer, err := changelogutils.NewChangelogRenderer(changelogutils.JSONFormat)
+		Expect(err).NotTo(HaveOccurred())
+		var notes []*changelogutils.ReleaseNotes
+		Expect(json.Unmarshal([]byte(render(renderer)), &notes)).To(Succeed())
+		Expect(notes).To(HaveLen(2))
+		Expect(notes[0].Version).To(Equal("v1.1.0"))
+		Expect(notes[0].Summary).To(Equal("Widgets!"))
+		Expect(notes[0].Sections).To(HaveLen(4))
+		Expect(notes[0].Sections[1].Type).To(Equal(changelogutils.BREAKING_CHANGE))
+		Expect(notes[0].Sections[1].Heading).To(Equal("Breaking Changes"))
+		Expect(notes[0].Sections[1].Entries[0].Description).To(Equal("Removed gadgets."))
+		Expect(notes[1].Sections).To(BeEmpty())
+	})
+
+	It("renders the names of custom types in JSON", func() {
+		entryTypes, err := changelogutils.NewEntryTypes([]*changelogutils.EntryTypeSettings{{Name: "SECURITY", Heading: "Security"}})
+		Expect(err).NotTo(HaveOccurred())
+		security, err := entryTypes.ParseChangelogEntryType("SECURITY")
+		Expect(err).NotTo(HaveOccurred())
+		entry := &changelogutils.ChangelogEntry{Description: "Patched a CVE.", IssueLink: "https://github.com/solo-io/testrepo/issues/4"}
+		entryTypes.SetType(entry, security)
+		changelogs = changelogutils.ChangelogList{{
+			Version:    versionutils.NewVersion(1, 2, 0, "", 0),
+			EntryTypes: entryTypes,
+			Files:      []*changelogutils.ChangelogFile{{Entries: []*changelogutils.ChangelogEntry{entry}}},
+		}}
+
+		renderer, err := changelogutils.NewChangelogRenderer(changelogutils.JSONFormat)
+		Expect(err).NotTo(HaveOccurred())
+		output := render(renderer)
+		Expect(output).NotTo(ContainSubstring("ChangelogEntryType("))
+		var raw []struct {
+			Sections []struct {
+				Type    string `json:"type"`
+				Heading string `json:"heading"`
+				Entries []struct {
+					Type string `json:"type"`
+				} `json:"entries"`
+			} `json:"sections"`
+		}
+		Expect(json.Unmarshal([]byte(output), &raw)).To(Succeed())
+		Expect(raw).To(HaveLen(1))
+		Expect(raw[0].Sections).To(HaveLen(1))
+		Expect(raw[0].Sections[0].Type).To(Equal("SECURITY"))
+		Expect(raw[0].Sections[0].Heading).To(Equal("Security"))
+		Expect(raw[0].Sections[0].Entries[0].Type).To(Equal("SECURITY"))
+
+		var notes []*changelogutils.ReleaseNotes
+		Expect(json.Unmarshal([]byte(output), &notes)).To(Succeed())
+		Expect(notes[0].Sections[0].TypeName()).To(Equal("SECURITY"))
+	})
+
+	It("renders HTML", func() {
+		renderer, err := changelogutils.NewChangelogRenderer(changelogutils.HTMLFormat)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(render(renderer)).To(Equal(`<section id="v1.1.0">
+<h2>v1.1.0</h2>
+<p>Widgets!</p>
+<h3>Dependency Bumps</h3>
+<ul>
+<li>solo-io/solo-kit has been upgraded to v0.2.0.</li>
+</ul>
+<h3>Breaking Changes</h3>
+<ul>
+<li>Removed gadgets. (<a href="https://github.com/solo-io/testrepo/issues/3">https://github.com/solo-io/testrepo/issues/3</a>)</li>
+</ul>
+<h3>New Features</h3>
+<ul>
+<li>Added widgets. (<a href="https://github.com/solo-io/testrepo/issues/1">https://github.com/solo-io/testrepo/issues/1</a>)</li>
+</ul>
+<h3>Fixes</h3>
+<ul>
+<li>Fixed a &lt;bug&gt;. (<a href="https://github.com/solo-io/testrepo/issues/2">https://github.com/solo-io/testrepo/issues/2</a>)</li>
+</ul>
+</section>
+<section id="v1.0.0">
+<h2>v1.0.0</h2>
+<p>This release contained no user-facing changes.</p>
+</section>
+`))
+	})
+
+	It("renders AsciiDoc", func() {
+		renderer, err := changelogutils.NewChangelogRenderer(changelogutils.AsciiDocFormat)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(render(renderer)).To(Equal(`= Changelog
+
+[#v1.1.0]
+== v1.1.0
+
+Widgets!
+
+=== Dependency Bumps
+
+* solo-io/solo-kit has been upgraded to v0.2.0.
+
+=== Breaking Changes
+
+* Removed gadgets. (https://github.com/solo-io/testrepo/issues/3[])
+
+=== New Features
+
+* Added widgets. (https://github.com/solo-io/testrepo/issues/1[])
+
+=== Fixes
+
+* Fixed a <bug>. (https://github.com/solo-io/testrepo/issues/2[])
+
+[#v1.0.0]
+== v1.0.0
+
+This release contained no user-facing changes.
+`))
+	})
+
+	It("renders a custom template", func() {
+		renderer, err := changelogutils.NewTemplateRenderer(`{{ range . }}{{ .Version }}:{{ range .Sections }} {{ .Heading }}={{ len .Entries }}{{ end }}
+{{ end }}{{ markdown (index . 1) }}`)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(render(renderer)).To(Equal(`v1.1.0: Dependency Bumps=1 Breaking Changes=1 New Features=1 Fixes=1
+v1.0.0:
+This release contained no user-facing changes.
+
+`))
+
+		_, err = changelogutils.NewTemplateRenderer(`{{ range }}`)
+		Expect(err).To(HaveOccurred())
+	})
+
+	It("errors on unknown formats", func() {
+		_, err := changelogutils.NewChangelogRenderer("rst")
+		Expect(err).To(MatchError(changelogutils.UnknownOutputFormatError("rst")))
+	})
+
+	It("renders the changelogs for tags with the selected renderer", func() {
+		repoRoot, err := os.MkdirTemp("", "changelog-renderers")
+		Expect(err).NotTo(HaveOccurred())
+		defer os.RemoveAll(repoRoot)
+		for path, contents := range map[string]string{
+			"changelog/v0.1.0/a.yaml": validChangelog1,
+			"changelog/v0.1.1/b.yaml": validChangelog2,
+		} {
+			Expect(os.MkdirAll(filepath.Join(repoRoot, filepath.Dir(path)), 0755)).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(repoRoot, path), []byte(contents), 0644)).To(Succeed())
+		}
+		code, err := vfsutils.NewLocalMountedRepoForFs(repoRoot, "solo-io", "testrepo")
+		Expect(err).NotTo(HaveOccurred())
+		reader := changelogutils.NewChangelogReader(code)
+
+		var buf bytes.Buffer
+		err = changelogutils.GenerateChangelogForTagsWithRenderer(context.Background(), []string{"v0.1.0", "v0.1.1"}, reader, &changelogutils.JSONRenderer{Compact: true}, &buf)
+		Expect(err).NotTo(HaveOccurred())
+		var notes []*changelogutils.ReleaseNotes
+		Expect(json.Unmarshal(buf.Bytes(), &notes)).To(Succeed())
+		Expect(notes[0].Version).To(Equal("v0.1.1"))
+		Expect(notes[1].Version).To(Equal("v0.1.0"))
+	})
+})
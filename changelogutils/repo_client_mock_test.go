@@ -11,6 +11,7 @@ import (
 	gomock "github.com/golang/mock/gomock"
 	github "github.com/google/go-github/v32/github"
 	githubutils "github.com/solo-io/go-utils/githubutils"
+	versionutils "github.com/solo-io/go-utils/versionutils"
 )
 
 // MockRepoClient is a mock of RepoClient interface
@@ -36,6 +37,21 @@ func (m *MockRepoClient) EXPECT() *MockRepoClientMockRecorder {
 	return m.recorder
 }
 
+// CherryPickCommit mocks base method
+func (m *MockRepoClient) CherryPickCommit(arg0 context.Context, arg1, arg2 string) (*github.Commit, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CherryPickCommit", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*github.Commit)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CherryPickCommit indicates an expected call of CherryPickCommit
+func (mr *MockRepoClientMockRecorder) CherryPickCommit(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CherryPickCommit", reflect.TypeOf((*MockRepoClient)(nil).CherryPickCommit), arg0, arg1, arg2)
+}
+
 // CompareCommits mocks base method
 func (m *MockRepoClient) CompareCommits(arg0 context.Context, arg1, arg2 string) (*github.CommitsComparison, error) {
 	m.ctrl.T.Helper()
@@ -66,6 +82,36 @@ func (mr *MockRepoClientMockRecorder) CreateBranch(arg0, arg1 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBranch", reflect.TypeOf((*MockRepoClient)(nil).CreateBranch), arg0, arg1)
 }
 
+// CreateBranchFrom mocks base method
+func (m *MockRepoClient) CreateBranchFrom(arg0 context.Context, arg1, arg2 string) (*github.Reference, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateBranchFrom", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*github.Reference)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateBranchFrom indicates an expected call of CreateBranchFrom
+func (mr *MockRepoClientMockRecorder) CreateBranchFrom(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateBranchFrom", reflect.TypeOf((*MockRepoClient)(nil).CreateBranchFrom), arg0, arg1, arg2)
+}
+
+// CreateCheckRun mocks base method
+func (m *MockRepoClient) CreateCheckRun(arg0 context.Context, arg1 githubutils.CheckRunSpec) (*github.CheckRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreateCheckRun", arg0, arg1)
+	ret0, _ := ret[0].(*github.CheckRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreateCheckRun indicates an expected call of CreateCheckRun
+func (mr *MockRepoClientMockRecorder) CreateCheckRun(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateCheckRun", reflect.TypeOf((*MockRepoClient)(nil).CreateCheckRun), arg0, arg1)
+}
+
 // CreateComment mocks base method
 func (m *MockRepoClient) CreateComment(arg0 context.Context, arg1 int, arg2 *github.IssueComment) (*github.IssueComment, error) {
 	m.ctrl.T.Helper()
@@ -95,6 +141,21 @@ func (mr *MockRepoClientMockRecorder) CreatePR(arg0, arg1, arg2 interface{}) *go
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePR", reflect.TypeOf((*MockRepoClient)(nil).CreatePR), arg0, arg1, arg2)
 }
 
+// CreatePRWithBase mocks base method
+func (m *MockRepoClient) CreatePRWithBase(arg0 context.Context, arg1, arg2 string, arg3 githubutils.PRSpec) (*github.PullRequest, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "CreatePRWithBase", arg0, arg1, arg2, arg3)
+	ret0, _ := ret[0].(*github.PullRequest)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// CreatePRWithBase indicates an expected call of CreatePRWithBase
+func (mr *MockRepoClientMockRecorder) CreatePRWithBase(arg0, arg1, arg2, arg3 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreatePRWithBase", reflect.TypeOf((*MockRepoClient)(nil).CreatePRWithBase), arg0, arg1, arg2, arg3)
+}
+
 // CreateStatus mocks base method
 func (m *MockRepoClient) CreateStatus(arg0 context.Context, arg1 string, arg2 *github.RepoStatus) (*github.RepoStatus, error) {
 	m.ctrl.T.Helper()
@@ -110,6 +171,20 @@ func (mr *MockRepoClientMockRecorder) CreateStatus(arg0, arg1, arg2 interface{})
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateStatus", reflect.TypeOf((*MockRepoClient)(nil).CreateStatus), arg0, arg1, arg2)
 }
 
+// DeleteBranch mocks base method
+func (m *MockRepoClient) DeleteBranch(arg0 context.Context, arg1 string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteBranch", arg0, arg1)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteBranch indicates an expected call of DeleteBranch
+func (mr *MockRepoClientMockRecorder) DeleteBranch(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteBranch", reflect.TypeOf((*MockRepoClient)(nil).DeleteBranch), arg0, arg1)
+}
+
 // DeleteComment mocks base method
 func (m *MockRepoClient) DeleteComment(arg0 context.Context, arg1 int64) error {
 	m.ctrl.T.Helper()
@@ -154,6 +229,21 @@ func (mr *MockRepoClientMockRecorder) FileExists(arg0, arg1, arg2 interface{}) *
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FileExists", reflect.TypeOf((*MockRepoClient)(nil).FileExists), arg0, arg1, arg2)
 }
 
+// FindLatestReleaseBySemverWithConstraint mocks base method
+func (m *MockRepoClient) FindLatestReleaseBySemverWithConstraint(arg0 context.Context, arg1 *versionutils.Constraint) (string, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "FindLatestReleaseBySemverWithConstraint", arg0, arg1)
+	ret0, _ := ret[0].(string)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// FindLatestReleaseBySemverWithConstraint indicates an expected call of FindLatestReleaseBySemverWithConstraint
+func (mr *MockRepoClientMockRecorder) FindLatestReleaseBySemverWithConstraint(arg0, arg1 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "FindLatestReleaseBySemverWithConstraint", reflect.TypeOf((*MockRepoClient)(nil).FindLatestReleaseBySemverWithConstraint), arg0, arg1)
+}
+
 // FindLatestReleaseTagIncudingPrerelease mocks base method
 func (m *MockRepoClient) FindLatestReleaseTagIncudingPrerelease(arg0 context.Context) (string, error) {
 	m.ctrl.T.Helper()
@@ -244,6 +334,51 @@ func (mr *MockRepoClientMockRecorder) GetShaForTag(arg0, arg1 interface{}) *gomo
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "GetShaForTag", reflect.TypeOf((*MockRepoClient)(nil).GetShaForTag), arg0, arg1)
 }
 
+// ListBranches mocks base method
+func (m *MockRepoClient) ListBranches(arg0 context.Context) ([]*github.Branch, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListBranches", arg0)
+	ret0, _ := ret[0].([]*github.Branch)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListBranches indicates an expected call of ListBranches
+func (mr *MockRepoClientMockRecorder) ListBranches(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListBranches", reflect.TypeOf((*MockRepoClient)(nil).ListBranches), arg0)
+}
+
+// ListReleases mocks base method
+func (m *MockRepoClient) ListReleases(arg0 context.Context) ([]*github.RepositoryRelease, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "ListReleases", arg0)
+	ret0, _ := ret[0].([]*github.RepositoryRelease)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// ListReleases indicates an expected call of ListReleases
+func (mr *MockRepoClientMockRecorder) ListReleases(arg0 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "ListReleases", reflect.TypeOf((*MockRepoClient)(nil).ListReleases), arg0)
+}
+
+// UpdateCheckRun mocks base method
+func (m *MockRepoClient) UpdateCheckRun(arg0 context.Context, arg1 int64, arg2 githubutils.CheckRunSpec) (*github.CheckRun, error) {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "UpdateCheckRun", arg0, arg1, arg2)
+	ret0, _ := ret[0].(*github.CheckRun)
+	ret1, _ := ret[1].(error)
+	return ret0, ret1
+}
+
+// UpdateCheckRun indicates an expected call of UpdateCheckRun
+func (mr *MockRepoClientMockRecorder) UpdateCheckRun(arg0, arg1, arg2 interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "UpdateCheckRun", reflect.TypeOf((*MockRepoClient)(nil).UpdateCheckRun), arg0, arg1, arg2)
+}
+
 // UpdateRelease mocks base method
 func (m *MockRepoClient) UpdateRelease(arg0 context.Context, arg1 *github.RepositoryRelease) (*github.RepositoryRelease, error) {
 	m.ctrl.T.Helper()
@@ -0,0 +1,336 @@
+package changelogutils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"sort"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/hashicorp/go-multierror"
+	"gopkg.in/yaml.v3"
+)
+
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+	SeverityNotice  Severity = "notice"
+)
+
+// Rule IDs identify the check that produced a violation
+const (
+	RuleInvalidChangelogFile     = "invalid-changelog-file"
+	RuleNoEntries                = "no-entries"
+	RuleUnknownEntryType         = "unknown-entry-type"
+	RuleMissingIssueLink         = "missing-issue-link"
+	RuleMissingDescription       = "missing-description"
+	RuleMissingDependencyOwner   = "missing-dependency-owner"
+	RuleMissingDependencyRepo    = "missing-dependency-repo"
+	RuleMissingDependencyTag     = "missing-dependency-tag"
+	RuleNoChangelogFileAdded     = "no-changelog-file-added"
+	RuleTooManyChangelogFiles    = "too-many-changelog-files"
+	RuleUnexpectedFile           = "unexpected-file"
+	RuleInvalidVersionDirectory  = "invalid-version-directory"
+	RuleMultipleNewVersions      = "multiple-new-versions"
+	RuleNoNewVersion             = "no-new-version"
+	RuleChangelogInOldVersion    = "changelog-in-old-version"
+	RuleInvalidValidationSetting = "invalid-validation-settings"
+	RuleVersionBump              = "version-bump"
+)
+
+// EntryIndex of a violation that does not refer to a specific changelog entry
+const NoEntryIndex = -1
+
+// A single problem found while validating a changelog
+type Violation struct {
+	// path of the offending file, relative to the repo root
+	File string `json:"file,omitempty"`
+	// index of the offending entry in the file, or NoEntryIndex
+	EntryIndex int `json:"entryIndex"`
+	// name of the offending entry field, if any
+	Field string `json:"field,omitempty"`
+	// 1-based line of the offending entry in the file, or 0 if unknown
+	Line     int      `json:"line,omitempty"`
+	RuleId   string   `json:"ruleId"`
+	Severity Severity `json:"severity"`
+	Message  string   `json:"message"`
+	// true if FixChangelogFileContents can fix the problem
+	Fixable bool `json:"fixable,omitempty"`
+	// the error the validator would have returned for this violation
+	Err error `json:"-"`
+}
+
+func (v *Violation) String() string {
+	location := v.File
+	if location == "" {
+		location = ChangelogDirectory
+	}
+	if v.Line > 0 {
+		location = fmt.Sprintf("%s:%d", location, v.Line)
+	}
+	if v.EntryIndex != NoEntryIndex {
+		location = fmt.Sprintf("%s (entry %d", location, v.EntryIndex)
+		if v.Field != "" {
+			location = fmt.Sprintf("%s, field %s", location, v.Field)
+		}
+		location = location + ")"
+	}
+	if v.Fixable {
+		return fmt.Sprintf("%s: %s [%s] %s (fixable)", location, v.Severity, v.RuleId, v.Message)
+	}
+	return fmt.Sprintf("%s: %s [%s] %s", location, v.Severity, v.RuleId, v.Message)
+}
+
+// Collects every violation found while validating a changelog, rather than stopping at the first one
+type ValidationReport struct {
+	Violations []*Violation `json:"violations"`
+}
+
+func NewValidationReport() *ValidationReport {
+	return &ValidationReport{}
+}
+
+func (r *ValidationReport) Add(violation *Violation) {
+	if violation.Message == "" && violation.Err != nil {
+		violation.Message = violation.Err.Error()
+	}
+	if violation.Severity == "" {
+		violation.Severity = SeverityError
+	}
+	r.Violations = append(r.Violations, violation)
+}
+
+// Adds a violation that doesn't refer to a specific changelog entry
+func (r *ValidationReport) AddError(file, ruleId string, err error) {
+	r.Add(&Violation{
+		File:       file,
+		EntryIndex: NoEntryIndex,
+		RuleId:     ruleId,
+		Err:        err,
+	})
+}
+
+func (r *ValidationReport) Merge(other *ValidationReport) {
+	if other == nil {
+		return
+	}
+	r.Violations = append(r.Violations, other.Violations...)
+}
+
+func (r *ValidationReport) HasErrors() bool {
+	for _, v := range r.Violations {
+		if v.Severity == SeverityError {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns the error of the first violation with error severity, or nil
+func (r *ValidationReport) FirstError() error {
+	for _, v := range r.Violations {
+		if v.Severity == SeverityError {
+			return v.Err
+		}
+	}
+	return nil
+}
+
+// Like FirstError, but returns the error as a ViolationError, so it can annotate the offending file and line on a
+// Github check run
+func (r *ValidationReport) FirstViolationError() error {
+	for _, v := range r.Violations {
+		if v.Severity == SeverityError {
+			return &ViolationError{Violation: v}
+		}
+	}
+	return nil
+}
+
+// Returns all violations with error severity combined into a single error, or nil. Each error is a
+// ViolationError, so it can annotate the offending file and line on a Github check run.
+func (r *ValidationReport) ToError() error {
+	var result *multierror.Error
+	for _, v := range r.Violations {
+		if v.Severity == SeverityError {
+			result = multierror.Append(result, &ViolationError{Violation: v})
+		}
+	}
+	return result.ErrorOrNil()
+}
+
+// The error of a violation, that knows where the violation is. It unwraps to the violation's Err.
+type ViolationError struct {
+	Violation *Violation
+}
+
+func (e *ViolationError) Error() string {
+	if e.Violation.Err == nil {
+		return e.Violation.Message
+	}
+	return e.Violation.Err.Error()
+}
+
+func (e *ViolationError) Unwrap() error {
+	return e.Violation.Err
+}
+
+// Implements githubutils.CheckAnnotator
+func (e *ViolationError) GithubAnnotations() []*github.CheckRunAnnotation {
+	return []*github.CheckRunAnnotation{e.Violation.githubAnnotation()}
+}
+
+func (r *ValidationReport) RenderText(w io.Writer) error {
+	if len(r.Violations) == 0 {
+		_, err := fmt.Fprintln(w, "No changelog problems found.")
+		return err
+	}
+	for _, v := range r.sorted() {
+		if _, err := fmt.Fprintln(w, v.String()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (r *ValidationReport) RenderJSON(w io.Writer) error {
+	out := ValidationReport{Violations: r.sorted()}
+	if out.Violations == nil {
+		out.Violations = []*Violation{}
+	}
+	encoder := json.NewEncoder(w)
+	encoder.SetIndent("", "  ")
+	return encoder.Encode(out)
+}
+
+// Converts the report into annotations that can be attached to a GitHub check run.
+// Violations that don't refer to a specific line are attached to the first line of the file.
+func (r *ValidationReport) GithubAnnotations() []*github.CheckRunAnnotation {
+	var annotations []*github.CheckRunAnnotation
+	for _, v := range r.sorted() {
+		annotations = append(annotations, v.githubAnnotation())
+	}
+	return annotations
+}
+
+func (v *Violation) githubAnnotation() *github.CheckRunAnnotation {
+	path := v.File
+	if path == "" {
+		path = ChangelogDirectory
+	}
+	line := v.Line
+	if line == 0 {
+		line = 1
+	}
+	return &github.CheckRunAnnotation{
+		Path:            github.String(path),
+		StartLine:       github.Int(line),
+		EndLine:         github.Int(line),
+		AnnotationLevel: github.String(annotationLevel(v.Severity)),
+		Title:           github.String(v.RuleId),
+		Message:         github.String(v.Message),
+	}
+}
+
+func annotationLevel(severity Severity) string {
+	switch severity {
+	case SeverityWarning:
+		return "warning"
+	case SeverityNotice:
+		return "notice"
+	default:
+		return "failure"
+	}
+}
+
+func (r *ValidationReport) sorted() []*Violation {
+	violations := make([]*Violation, len(r.Violations))
+	copy(violations, r.Violations)
+	sort.SliceStable(violations, func(i, j int) bool {
+		if violations[i].File != violations[j].File {
+			return violations[i].File < violations[j].File
+		}
+		return violations[i].EntryIndex < violations[j].EntryIndex
+	})
+	return violations
+}
+
+// Validates the entries of a parsed changelog file, adding a violation to the report for each problem found
+func validateChangelogEntries(path string, contents []byte, changelog *ChangelogFile, entryTypes *EntryTypes, report *ValidationReport) {
+	if len(changelog.Entries) == 0 {
+		report.AddError(path, RuleNoEntries, NoEntriesInChangelogError(path))
+		return
+	}
+
+	lines := entryLines(contents)
+	addViolation := func(index int, field, ruleId string, err error) {
+		violation := &Violation{
+			File:       path,
+			EntryIndex: index,
+			Field:      field,
+			RuleId:     ruleId,
+			Err:        err,
+		}
+		if index < len(lines) {
+			violation.Line = lines[index]
+		}
+		report.Add(violation)
+	}
+
+	for i, entry := range changelog.Entries {
+		if !entryTypes.IsKnown(entry.Type) {
+			addViolation(i, "type", RuleUnknownEntryType, UndeclaredEntryTypeError(entry.TypeName()))
+			continue
+		}
+		for _, field := range entryTypes.RequiredFields(entry.Type) {
+			switch field {
+			case IssueLinkField:
+				if entry.IssueLink == "" {
+					addViolation(i, field, RuleMissingIssueLink, MissingIssueLinkError)
+				}
+			case DescriptionField:
+				if entry.Description == "" {
+					addViolation(i, field, RuleMissingDescription, MissingDescriptionError)
+				}
+			case DependencyOwnerField:
+				if entry.DependencyOwner == "" {
+					addViolation(i, field, RuleMissingDependencyOwner, MissingOwnerError)
+				}
+			case DependencyRepoField:
+				if entry.DependencyRepo == "" {
+					addViolation(i, field, RuleMissingDependencyRepo, MissingRepoError)
+				}
+			case DependencyTagField:
+				if entry.DependencyTag == "" {
+					addViolation(i, field, RuleMissingDependencyTag, MissingTagError)
+				}
+			}
+		}
+	}
+}
+
+// Returns the 1-based line of each entry in the "changelog" list of a changelog file.
+// Returns nil if the file can't be parsed.
+func entryLines(contents []byte) []int {
+	var root yaml.Node
+	if err := yaml.Unmarshal(contents, &root); err != nil || len(root.Content) == 0 {
+		return nil
+	}
+	doc := root.Content[0]
+	if doc.Kind != yaml.MappingNode {
+		return nil
+	}
+	for i := 0; i+1 < len(doc.Content); i += 2 {
+		if doc.Content[i].Value != "changelog" || doc.Content[i+1].Kind != yaml.SequenceNode {
+			continue
+		}
+		var lines []int
+		for _, item := range doc.Content[i+1].Content {
+			lines = append(lines, item.Line)
+		}
+		return lines
+	}
+	return nil
+}
@@ -0,0 +1,144 @@
+package changelogutils_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"os"
+
+	"github.com/golang/mock/gomock"
+	"github.com/hashicorp/go-multierror"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/solo-io/go-utils/changelogutils"
+)
+
+var _ = Describe("ValidationReport", func() {
+
+	const path = "changelog/v0.1.1/multiple-problems.yaml"
+
+	var (
+		ctx  = context.Background()
+		ctrl *gomock.Controller
+		code *MockMountedRepo
+	)
+
+	BeforeEach(func() {
+		ctrl = gomock.NewController(test)
+		code = NewMockMountedRepo(ctrl)
+		code.EXPECT().GetFileContents(ctx, changelogutils.GetValidationSettingsPath()).Return(nil, os.ErrNotExist).AnyTimes()
+	})
+
+	AfterEach(func() {
+		ctrl.Finish()
+	})
+
+	readReport := func(contents string) (*changelogutils.ChangelogFile, *changelogutils.ValidationReport) {
+		code.EXPECT().GetFileContents(ctx, path).Return([]byte(contents), nil)
+		file, report, err := changelogutils.ReadChangelogFileWithReport(ctx, code, path)
+		Expect(err).NotTo(HaveOccurred())
+		return file, report
+	}
+
+	It("collects every problem in a changelog file", func() {
+		file, report := readReport(changelogMultipleProblems)
+		Expect(file.Entries).To(HaveLen(3))
+		Expect(report.HasErrors()).To(BeTrue())
+		Expect(report.Violations).To(HaveLen(4))
+
+		Expect(*report.Violations[0]).To(Equal(changelogutils.Violation{
+			File:       path,
+			EntryIndex: 0,
+			Field:      "issueLink",
+			Line:       3,
+			RuleId:     changelogutils.RuleMissingIssueLink,
+			Severity:   changelogutils.SeverityError,
+			Message:    changelogutils.MissingIssueLinkError.Error(),
+			Err:        changelogutils.MissingIssueLinkError,
+		}))
+		Expect(report.Violations[1].RuleId).To(Equal(changelogutils.RuleMissingDescription))
+		Expect(report.Violations[1].Line).To(Equal(5))
+		Expect(report.Violations[2].RuleId).To(Equal(changelogutils.RuleMissingDependencyOwner))
+		Expect(report.Violations[2].EntryIndex).To(Equal(2))
+		Expect(report.Violations[2].Line).To(Equal(7))
+		Expect(report.Violations[3].RuleId).To(Equal(changelogutils.RuleMissingDependencyTag))
+		Expect(report.FirstError()).To(Equal(changelogutils.MissingIssueLinkError))
+	})
+
+	It("returns the first violation from ReadChangelogFile", func() {
+		code.EXPECT().GetFileContents(ctx, path).Return([]byte(changelogMultipleProblems), nil)
+		_, err := changelogutils.NewChangelogReader(code).ReadChangelogFile(ctx, path)
+		Expect(err).To(Equal(changelogutils.MissingIssueLinkError))
+	})
+
+	It("reports files that can't be parsed", func() {
+		file, report := readReport("changelog:\n  - type: not a type\n")
+		Expect(file).To(BeNil())
+		Expect(report.Violations).To(HaveLen(1))
+		Expect(report.Violations[0].RuleId).To(Equal(changelogutils.RuleInvalidChangelogFile))
+		Expect(report.Violations[0].EntryIndex).To(Equal(changelogutils.NoEntryIndex))
+	})
+
+	It("has no errors for a valid file", func() {
+		_, report := readReport(validChangelog1)
+		Expect(report.HasErrors()).To(BeFalse())
+		Expect(report.ToError()).To(BeNil())
+	})
+
+	Context("rendering", func() {
+
+		var report *changelogutils.ValidationReport
+
+		BeforeEach(func() {
+			_, report = readReport(changelogMultipleProblems)
+		})
+
+		It("renders text", func() {
+			var buf bytes.Buffer
+			Expect(report.RenderText(&buf)).To(Succeed())
+			Expect(buf.String()).To(ContainSubstring(path + ":3 (entry 0, field issueLink): error [missing-issue-link] Changelog entries must have an issue link\n"))
+			Expect(buf.String()).To(ContainSubstring(path + ":7 (entry 2, field dependencyTag): error [missing-dependency-tag] Dependency bumps must have a tag\n"))
+		})
+
+		It("renders json", func() {
+			var buf bytes.Buffer
+			Expect(report.RenderJSON(&buf)).To(Succeed())
+			var decoded changelogutils.ValidationReport
+			Expect(json.Unmarshal(buf.Bytes(), &decoded)).To(Succeed())
+			Expect(decoded.Violations).To(HaveLen(4))
+			Expect(decoded.Violations[3].Field).To(Equal("dependencyTag"))
+			Expect(decoded.Violations[3].Message).To(Equal(changelogutils.MissingTagError.Error()))
+		})
+
+		It("renders github annotations", func() {
+			annotations := report.GithubAnnotations()
+			Expect(annotations).To(HaveLen(4))
+			Expect(annotations[0].GetPath()).To(Equal(path))
+			Expect(annotations[0].GetStartLine()).To(Equal(3))
+			Expect(annotations[0].GetEndLine()).To(Equal(3))
+			Expect(annotations[0].GetAnnotationLevel()).To(Equal("failure"))
+			Expect(annotations[0].GetTitle()).To(Equal(changelogutils.RuleMissingIssueLink))
+		})
+
+		It("annotates the errors of violations", func() {
+			violationErr := report.ToError().(*multierror.Error).Errors[0].(*changelogutils.ViolationError)
+			Expect(violationErr).To(MatchError(changelogutils.MissingIssueLinkError.Error()))
+			Expect(errors.Is(violationErr, changelogutils.MissingIssueLinkError)).To(BeTrue())
+			annotations := violationErr.GithubAnnotations()
+			Expect(annotations).To(HaveLen(1))
+			Expect(annotations[0].GetPath()).To(Equal(path))
+			Expect(annotations[0].GetStartLine()).To(Equal(3))
+		})
+	})
+})
+
+const changelogMultipleProblems = `
+changelog:
+  - type: FIX
+    description: missing link
+  - type: NEW_FEATURE
+    issueLink: https://github.com/solo-io/testrepo/issues/1
+  - type: DEPENDENCY_BUMP
+    dependencyRepo: bar
+`
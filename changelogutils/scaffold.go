@@ -0,0 +1,452 @@
+package changelogutils
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/ghodss/yaml"
+	"github.com/go-git/go-git/v5"
+	"github.com/google/go-github/v32/github"
+	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/githubutils"
+	"github.com/solo-io/go-utils/surveyutils"
+	"github.com/solo-io/go-utils/vfsutils"
+	"github.com/spf13/afero"
+	"gopkg.in/AlecAivazis/survey.v1"
+)
+
+var (
+	InvalidScaffoldedChangelogError = func(report *ValidationReport) error {
+		return eris.Wrapf(report.ToError(), "Generated changelog entry is not valid, set the missing fields")
+	}
+	NoFileNameError = eris.Errorf("Unable to derive a name for the changelog file from the change, specify one")
+)
+
+// Information about a change (usually a PR) that a changelog entry is generated from
+type ChangeInfo struct {
+	// The repo the change will be merged into, used to turn short issue references (#123) into links
+	Owner string
+	Repo  string
+	// PR number, or 0 for a local branch
+	Number int
+	Title  string
+	Body   string
+	Branch string
+	Labels []string
+}
+
+// Builds change info from a PR, as returned by RepoClient.GetPR
+func ChangeInfoFromPR(pr *github.PullRequest) *ChangeInfo {
+	info := &ChangeInfo{
+		Number: pr.GetNumber(),
+		Title:  pr.GetTitle(),
+		Body:   pr.GetBody(),
+		Branch: pr.GetHead().GetRef(),
+	}
+	if baseRepo := pr.GetBase().GetRepo(); baseRepo != nil {
+		info.Owner = baseRepo.GetOwner().GetLogin()
+		info.Repo = baseRepo.GetName()
+	}
+	for _, label := range pr.Labels {
+		info.Labels = append(info.Labels, label.GetName())
+	}
+	return info
+}
+
+func GetChangeInfoForPR(ctx context.Context, client githubutils.RepoClient, owner, repo string, num int) (*ChangeInfo, error) {
+	pr, err := client.GetPR(ctx, num)
+	if err != nil {
+		return nil, err
+	}
+	info := ChangeInfoFromPR(pr)
+	if info.Owner == "" {
+		info.Owner, info.Repo = owner, repo
+	}
+	return info, nil
+}
+
+// Builds change info from the checked out branch of a local repo, using the message of the latest commit as
+// the title and body of the change
+func GetChangeInfoForLocalBranch(repoRootPath, owner, repo string) (*ChangeInfo, error) {
+	gitRepo, err := git.PlainOpenWithOptions(repoRootPath, &git.PlainOpenOptions{DetectDotGit: true})
+	if err != nil {
+		return nil, OpenLocalRepoError(err, repoRootPath)
+	}
+	head, err := gitRepo.Head()
+	if err != nil {
+		return nil, ResolveRefError(err, "HEAD")
+	}
+	info := &ChangeInfo{Owner: owner, Repo: repo}
+	if head.Name().IsBranch() {
+		info.Branch = head.Name().Short()
+	}
+	commit, err := gitRepo.CommitObject(head.Hash())
+	if err != nil {
+		return nil, ResolveRefError(err, "HEAD")
+	}
+	lines := strings.SplitN(strings.TrimSpace(commit.Message), "\n", 2)
+	info.Title = strings.TrimSpace(lines[0])
+	if len(lines) > 1 {
+		info.Body = strings.TrimSpace(lines[1])
+	}
+	return info, nil
+}
+
+// Labels that default the type of a generated changelog entry. Labels named after an entry type, ignoring case and
+// with dashes or spaces instead of underscores (e.g. "new-feature", or "security" for a custom SECURITY type),
+// are recognized as well.
+var DefaultLabelEntryTypes = map[string]ChangelogEntryType{
+	"bug":             FIX,
+	"enhancement":     NEW_FEATURE,
+	"feature":         NEW_FEATURE,
+	"breaking":        BREAKING_CHANGE,
+	"dependencies":    DEPENDENCY_BUMP,
+	"documentation":   NON_USER_FACING,
+	"chore":           NON_USER_FACING,
+	"skip-changelog":  NON_USER_FACING,
+	"non user facing": NON_USER_FACING,
+}
+
+// Overrides for the fields of a generated changelog entry, e.g. from command line flags.
+// Empty fields are inferred from the change.
+type ScaffoldOptions struct {
+	// Name of the entry type, e.g. FIX
+	Type            string
+	Description     string
+	IssueLink       string
+	ResolvesIssue   *bool
+	DependencyOwner string
+	DependencyRepo  string
+	DependencyTag   string
+
+	// Type of the entry when none can be inferred from the labels or title of the change. Defaults to FIX.
+	DefaultType string
+	// Additional label -> entry type name mappings, taking precedence over DefaultLabelEntryTypes
+	LabelEntryTypes map[string]string
+
+	// Name of the changelog file, without extension. Defaults to the branch of the change.
+	FileName string
+
+	// If true, the user is prompted for each field, with the inferred values as defaults
+	Interactive bool
+}
+
+var (
+	// e.g. "fixes #12", "Closes solo-io/gloo#12", "resolves https://github.com/solo-io/gloo/issues/12"
+	issueReferenceRegex = regexp.MustCompile(`(?i)(?:\b(close[sd]?|fix(?:e[sd])?|resolve[sd]?):?\s+)?(?:https://github\.com/([\w.-]+)/([\w.-]+)/(?:issues|pull)/(\d+)|\b([\w.-]+)/([\w.-]+)#(\d+)|#(\d+))`)
+	// e.g. "feat: ...", "fix(changelog)!: ..."
+	conventionalTitleRegex = regexp.MustCompile(`^(\w+)(?:\([^)]*\))?(!)?:\s*`)
+	// e.g. "Bump github.com/solo-io/gloo from v1.2.0 to v1.3.0", as titled by dependabot
+	dependencyBumpTitleRegex  = regexp.MustCompile(`(?i)^bump\s+(?:github\.com/)?([\w.-]+)/([\w.-]+)(?:/\S*)?\s+from\s+\S+\s+to\s+(\S+)`)
+	fileNameInvalidCharsRegex = regexp.MustCompile(`[^a-z0-9]+`)
+)
+
+// Finds the issue a change refers to in its description. References with a closing keyword (fixes, closes,
+// resolves) are preferred and resolve the issue; otherwise the first reference is used and does not resolve it.
+// Returns an empty link if the description doesn't refer to an issue.
+func InferIssueLink(owner, repo, body string) (string, bool) {
+	var firstLink string
+	for _, match := range issueReferenceRegex.FindAllStringSubmatch(body, -1) {
+		var link string
+		switch {
+		case match[2] != "":
+			link = fmt.Sprintf("https://github.com/%s/%s/issues/%s", match[2], match[3], match[4])
+		case match[5] != "":
+			link = fmt.Sprintf("https://github.com/%s/%s/issues/%s", match[5], match[6], match[7])
+		default:
+			if owner == "" || repo == "" {
+				continue
+			}
+			link = fmt.Sprintf("https://github.com/%s/%s/issues/%s", owner, repo, match[8])
+		}
+		if match[1] != "" {
+			return link, true
+		}
+		if firstLink == "" {
+			firstLink = link
+		}
+	}
+	return firstLink, false
+}
+
+// Infers the type of a changelog entry from the labels of a change, falling back to a conventional commit prefix
+// of its title (e.g. "feat: ..."). If several labels map to types, the one with the greatest version bump wins.
+func InferEntryType(labels []string, title string, entryTypes *EntryTypes, labelEntryTypes map[string]string) (ChangelogEntryType, bool) {
+	var inferred []ChangelogEntryType
+	for _, label := range labels {
+		if entryType, ok := entryTypeForLabel(label, entryTypes, labelEntryTypes); ok {
+			inferred = append(inferred, entryType)
+		}
+	}
+	if len(inferred) > 0 {
+		for _, entryType := range inferred {
+			if entryTypes.BreakingChange(entryType) {
+				return entryType, true
+			}
+		}
+		for _, entryType := range inferred {
+			if entryTypes.NewFeature(entryType) {
+				return entryType, true
+			}
+		}
+		return inferred[0], true
+	}
+
+	match := conventionalTitleRegex.FindStringSubmatch(title)
+	if match == nil {
+		return 0, false
+	}
+	if match[2] != "" {
+		return BREAKING_CHANGE, true
+	}
+	switch strings.ToLower(match[1]) {
+	case "feat":
+		return NEW_FEATURE, true
+	case "fix":
+		return FIX, true
+	case "deps":
+		return DEPENDENCY_BUMP, true
+	case "chore", "docs", "test", "ci", "build", "refactor", "style":
+		return NON_USER_FACING, true
+	}
+	return 0, false
+}
+
+func entryTypeForLabel(label string, entryTypes *EntryTypes, labelEntryTypes map[string]string) (ChangelogEntryType, bool) {
+	normalized := strings.ToLower(strings.TrimSpace(label))
+	if name, ok := labelEntryTypes[normalized]; ok {
+		if entryType, err := entryTypes.ParseChangelogEntryType(name); err == nil {
+			return entryType, true
+		}
+	}
+	if entryType, ok := DefaultLabelEntryTypes[normalized]; ok {
+		return entryType, true
+	}
+	name := strings.ToUpper(strings.NewReplacer("-", "_", " ", "_").Replace(normalized))
+	if entryType, err := entryTypes.ParseChangelogEntryType(name); err == nil {
+		return entryType, true
+	}
+	return 0, false
+}
+
+// Turns the title of a change into a changelog description: without a conventional commit prefix, capitalized,
+// and ending in a period
+func descriptionFromTitle(title string) string {
+	description := strings.TrimSpace(conventionalTitleRegex.ReplaceAllString(strings.TrimSpace(title), ""))
+	if description == "" {
+		return ""
+	}
+	description = strings.ToUpper(description[:1]) + description[1:]
+	if !strings.HasSuffix(description, ".") {
+		description += "."
+	}
+	return description
+}
+
+// Generates a changelog entry for the change. Fields set in opts take precedence over inferred values.
+// The entry is not validated; see ScaffoldChangelogFile.
+func ScaffoldChangelogEntry(info *ChangeInfo, entryTypes *EntryTypes, opts ScaffoldOptions) (*ChangelogEntry, error) {
+	entry := &ChangelogEntry{}
+
+	switch {
+	case opts.Type != "":
+		entryType, err := entryTypes.ParseChangelogEntryType(opts.Type)
+		if err != nil {
+			return nil, err
+		}
+		entryTypes.SetType(entry, entryType)
+	default:
+		entryType, ok := InferEntryType(info.Labels, info.Title, entryTypes, opts.LabelEntryTypes)
+		if !ok {
+			entryType = FIX
+			if opts.DefaultType != "" {
+				var err error
+				if entryType, err = entryTypes.ParseChangelogEntryType(opts.DefaultType); err != nil {
+					return nil, err
+				}
+			}
+		}
+		entryTypes.SetType(entry, entryType)
+	}
+
+	entry.Description = opts.Description
+	if entry.Description == "" {
+		entry.Description = descriptionFromTitle(info.Title)
+	}
+
+	resolvesIssue := true
+	entry.IssueLink = opts.IssueLink
+	if entry.IssueLink == "" {
+		entry.IssueLink, resolvesIssue = InferIssueLink(info.Owner, info.Repo, info.Body)
+	}
+	if opts.ResolvesIssue != nil {
+		resolvesIssue = *opts.ResolvesIssue
+	}
+	// resolvesIssue defaults to true, so it is only written when the issue should stay open
+	if entry.IssueLink != "" && !resolvesIssue {
+		entry.ResolvesIssue = &resolvesIssue
+	}
+
+	if entry.Type == DEPENDENCY_BUMP {
+		if match := dependencyBumpTitleRegex.FindStringSubmatch(info.Title); match != nil {
+			entry.DependencyOwner, entry.DependencyRepo, entry.DependencyTag = match[1], match[2], match[3]
+		}
+	}
+	if opts.DependencyOwner != "" {
+		entry.DependencyOwner = opts.DependencyOwner
+	}
+	if opts.DependencyRepo != "" {
+		entry.DependencyRepo = opts.DependencyRepo
+	}
+	if opts.DependencyTag != "" {
+		entry.DependencyTag = opts.DependencyTag
+	}
+	return entry, nil
+}
+
+// Prompts for each field of the entry that is required by its type, using the current values as defaults
+func PromptChangelogEntry(entry *ChangelogEntry, entryTypes *EntryTypes) error {
+	var typeNames []string
+	for _, entryType := range entryTypes.All() {
+		typeNames = append(typeNames, entryTypes.Name(entryType))
+	}
+	typeName := entryTypes.Name(entry.Type)
+	if err := surveyutils.AskOne(&survey.Select{
+		Message: "Type of the change",
+		Options: typeNames,
+		Default: typeName,
+	}, &typeName, survey.Required); err != nil {
+		return err
+	}
+	entryType, err := entryTypes.ParseChangelogEntryType(typeName)
+	if err != nil {
+		return err
+	}
+	entryTypes.SetType(entry, entryType)
+
+	for _, field := range entryTypes.RequiredFields(entry.Type) {
+		switch field {
+		case DescriptionField:
+			if err := surveyutils.GetStringInputDefault("Description, as a complete sentence", &entry.Description, entry.Description); err != nil {
+				return err
+			}
+		case IssueLinkField:
+			if err := surveyutils.GetStringInputDefault("Link to the issue", &entry.IssueLink, entry.IssueLink); err != nil {
+				return err
+			}
+			resolvesIssue := entry.GetResolvesIssue()
+			if err := surveyutils.GetBoolInputDefault("Close the issue when the change is released?", &resolvesIssue, resolvesIssue); err != nil {
+				return err
+			}
+			entry.ResolvesIssue = nil
+			if !resolvesIssue {
+				entry.ResolvesIssue = &resolvesIssue
+			}
+		case DependencyOwnerField:
+			if err := surveyutils.GetStringInputDefault("Owner of the dependency", &entry.DependencyOwner, entry.DependencyOwner); err != nil {
+				return err
+			}
+		case DependencyRepoField:
+			if err := surveyutils.GetStringInputDefault("Repo of the dependency", &entry.DependencyRepo, entry.DependencyRepo); err != nil {
+				return err
+			}
+		case DependencyTagField:
+			if err := surveyutils.GetStringInputDefault("Tag of the new dependency version", &entry.DependencyTag, entry.DependencyTag); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
+// Returns the name of the changelog file for a change, derived from its branch (or its title, if it has no
+// branch), e.g. "fix-changelog-validation.yaml"
+func ScaffoldFileName(info *ChangeInfo) string {
+	name := fileNameInvalidCharsRegex.ReplaceAllString(strings.ToLower(info.Branch), "-")
+	name = strings.Trim(name, "-")
+	if name == "" || name == "master" || name == "main" {
+		name = strings.Trim(fileNameInvalidCharsRegex.ReplaceAllString(strings.ToLower(info.Title), "-"), "-")
+		if len(name) > 50 {
+			name = strings.TrimRight(name[:50], "-")
+		}
+	}
+	if name == "" && info.Number != 0 {
+		name = "pr-" + strconv.Itoa(info.Number)
+	}
+	return name + ".yaml"
+}
+
+type ScaffoldedChangelogFile struct {
+	// Path of the file relative to the repo root, in the changelog directory (which accounts for
+	// ValidationSettings.ActiveSubdirectory). Use ComputeNextVersion and PlaceChangelogFile to move it into the
+	// directory of the next version.
+	Path     string
+	File     *ChangelogFile
+	Contents []byte
+}
+
+// Generates a changelog file for the change, using the entry types and changelog directory from the validation
+// settings of the repo. Returns an InvalidScaffoldedChangelogError if the generated file would fail validation,
+// e.g. because no issue link could be inferred.
+func ScaffoldChangelogFile(ctx context.Context, code vfsutils.MountedRepo, info *ChangeInfo, opts ScaffoldOptions) (*ScaffoldedChangelogFile, error) {
+	settings, err := readValidationSettings(ctx, code)
+	if err != nil {
+		return nil, UnableToGetSettingsError(err)
+	}
+	entryTypes, err := settings.GetEntryTypes()
+	if err != nil {
+		return nil, UnableToGetSettingsError(err)
+	}
+
+	entry, err := ScaffoldChangelogEntry(info, entryTypes, opts)
+	if err != nil {
+		return nil, err
+	}
+	if opts.Interactive {
+		if err := PromptChangelogEntry(entry, entryTypes); err != nil {
+			return nil, err
+		}
+	}
+
+	name := ScaffoldFileName(info)
+	if opts.FileName != "" {
+		name = strings.TrimSuffix(opts.FileName, ".yaml") + ".yaml"
+	}
+	if name == ".yaml" {
+		return nil, NoFileNameError
+	}
+
+	scaffolded := &ScaffoldedChangelogFile{
+		Path: filepath.Join(getChangelogDirectory(settings), name),
+		File: &ChangelogFile{Entries: []*ChangelogEntry{entry}},
+	}
+	scaffolded.Contents, err = yaml.Marshal(scaffolded.File)
+	if err != nil {
+		return nil, err
+	}
+
+	report := NewValidationReport()
+	validateChangelogEntries(scaffolded.Path, scaffolded.Contents, scaffolded.File, entryTypes, report)
+	if report.HasErrors() {
+		return nil, InvalidScaffoldedChangelogError(report)
+	}
+	return scaffolded, nil
+}
+
+// Writes the changelog file under repoRootPath, without overwriting an existing file
+func WriteScaffoldedChangelogFile(fs afero.Fs, repoRootPath string, scaffolded *ScaffoldedChangelogFile) error {
+	fullPath := filepath.Join(repoRootPath, scaffolded.Path)
+	if exists, err := afero.Exists(fs, fullPath); err != nil {
+		return err
+	} else if exists {
+		return ChangelogFileExistsError(scaffolded.Path)
+	}
+	if err := fs.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+		return err
+	}
+	return afero.WriteFile(fs, fullPath, scaffolded.Contents, 0644)
+}
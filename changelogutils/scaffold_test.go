@@ -0,0 +1,180 @@
+package changelogutils_test
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+
+	"github.com/ghodss/yaml"
+	"github.com/golang/mock/gomock"
+	"github.com/google/go-github/v32/github"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/solo-io/go-utils/changelogutils"
+	"github.com/solo-io/go-utils/vfsutils"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("changelog scaffolding", func() {
+
+	var ctx = context.Background()
+
+	DescribeTable("infers the issue link from the PR body",
+		func(body, expectedLink string, expectedResolves bool) {
+			link, resolves := changelogutils.InferIssueLink("solo-io", "gloo", body)
+			Expect(link).To(Equal(expectedLink))
+			Expect(resolves).To(Equal(expectedResolves))
+		},
+		Entry("closing keyword", "Fixes #12", "https://github.com/solo-io/gloo/issues/12", true),
+		Entry("cross-repo reference", "This closes solo-io/solo-projects#3.", "https://github.com/solo-io/solo-projects/issues/3", true),
+		Entry("issue url", "resolves: https://github.com/solo-io/gloo/issues/7", "https://github.com/solo-io/gloo/issues/7", true),
+		Entry("mention only", "Related to #5", "https://github.com/solo-io/gloo/issues/5", false),
+		Entry("prefers closing keyword", "See #5, fixes #6", "https://github.com/solo-io/gloo/issues/6", true),
+		Entry("no reference", "Just a refactor", "", false),
+	)
+
+	DescribeTable("infers the entry type",
+		func(labels []string, title string, expected changelogutils.ChangelogEntryType) {
+			entryType, ok := changelogutils.InferEntryType(labels, title, nil, nil)
+			Expect(ok).To(BeTrue())
+			Expect(entryType).To(Equal(expected))
+		},
+		Entry("bug label", []string{"bug"}, "Some title", changelogutils.FIX),
+		Entry("label named after type", []string{"Type: whatever", "new-feature"}, "Some title", changelogutils.NEW_FEATURE),
+		Entry("greatest version bump wins", []string{"bug", "breaking"}, "Some title", changelogutils.BREAKING_CHANGE),
+		Entry("conventional title", nil, "feat(changelog): add things", changelogutils.NEW_FEATURE),
+		Entry("conventional breaking title", nil, "fix!: change things", changelogutils.BREAKING_CHANGE),
+	)
+
+	It("recognizes labels for custom entry types", func() {
+		entryTypes, err := changelogutils.NewEntryTypes([]*changelogutils.EntryTypeSettings{{Name: "SECURITY"}})
+		Expect(err).NotTo(HaveOccurred())
+		security, err := entryTypes.ParseChangelogEntryType("SECURITY")
+		Expect(err).NotTo(HaveOccurred())
+
+		entryType, ok := changelogutils.InferEntryType([]string{"security"}, "", entryTypes, nil)
+		Expect(ok).To(BeTrue())
+		Expect(entryType).To(Equal(security))
+		entryType, ok = changelogutils.InferEntryType([]string{"cve"}, "", entryTypes, map[string]string{"cve": "SECURITY"})
+		Expect(ok).To(BeTrue())
+		Expect(entryType).To(Equal(security))
+		_, ok = changelogutils.InferEntryType([]string{"security"}, "", nil, nil)
+		Expect(ok).To(BeFalse())
+	})
+
+	It("generates an entry from a PR", func() {
+		ctrl := gomock.NewController(test)
+		defer ctrl.Finish()
+		repoClient := NewMockRepoClient(ctrl)
+		repoClient.EXPECT().GetPR(ctx, 42).Return(&github.PullRequest{
+			Number: github.Int(42),
+			Title:  github.String("Bump github.com/solo-io/solo-kit from v0.1.0 to v0.2.0"),
+			Body:   github.String("Updates solo-kit, see #40"),
+			Head:   &github.PullRequestBranch{Ref: github.String("dependabot/go_modules/solo-kit")},
+			Base: &github.PullRequestBranch{Repo: &github.Repository{
+				Owner: &github.User{Login: github.String("solo-io")},
+				Name:  github.String("gloo"),
+			}},
+			Labels: []*github.Label{{Name: github.String("dependencies")}},
+		}, nil)
+
+		info, err := changelogutils.GetChangeInfoForPR(ctx, repoClient, "solo-io", "gloo", 42)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(changelogutils.ScaffoldFileName(info)).To(Equal("dependabot-go-modules-solo-kit.yaml"))
+
+		entry, err := changelogutils.ScaffoldChangelogEntry(info, nil, changelogutils.ScaffoldOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		resolvesIssue := false
+		Expect(entry).To(Equal(&changelogutils.ChangelogEntry{
+			Type:            changelogutils.DEPENDENCY_BUMP,
+			Description:     "Bump github.com/solo-io/solo-kit from v0.1.0 to v0.2.0.",
+			IssueLink:       "https://github.com/solo-io/gloo/issues/40",
+			ResolvesIssue:   &resolvesIssue,
+			DependencyOwner: "solo-io",
+			DependencyRepo:  "solo-kit",
+			DependencyTag:   "v0.2.0",
+		}))
+	})
+
+	It("prefers explicit options over inferred values", func() {
+		info := &changelogutils.ChangeInfo{Owner: "solo-io", Repo: "gloo", Title: "fix: a bug", Body: "Fixes #1"}
+		resolvesIssue := false
+		entry, err := changelogutils.ScaffoldChangelogEntry(info, nil, changelogutils.ScaffoldOptions{
+			Type:          "NEW_FEATURE",
+			Description:   "Something new.",
+			ResolvesIssue: &resolvesIssue,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry.Type).To(Equal(changelogutils.NEW_FEATURE))
+		Expect(entry.Description).To(Equal("Something new."))
+		Expect(entry.IssueLink).To(Equal("https://github.com/solo-io/gloo/issues/1"))
+		Expect(entry.GetResolvesIssue()).To(BeFalse())
+
+		_, err = changelogutils.ScaffoldChangelogEntry(info, nil, changelogutils.ScaffoldOptions{Type: "SECURITY"})
+		Expect(err).To(MatchError(`invalid ChangelogEntryType "SECURITY"`))
+
+		entryTypes, err := changelogutils.NewEntryTypes([]*changelogutils.EntryTypeSettings{{Name: "SECURITY"}})
+		Expect(err).NotTo(HaveOccurred())
+		entry, err = changelogutils.ScaffoldChangelogEntry(info, entryTypes, changelogutils.ScaffoldOptions{Type: "SECURITY"})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(entry.TypeName()).To(Equal("SECURITY"))
+		contents, err := yaml.Marshal(entry)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(contents)).To(ContainSubstring("type: SECURITY\n"))
+	})
+
+	Context("local checkout", func() {
+
+		var repo *testGitRepo
+
+		BeforeEach(func() {
+			repo = newTestGitRepo()
+		})
+
+		AfterEach(func() {
+			repo.cleanup()
+		})
+
+		It("writes a valid changelog file to the active subdirectory", func() {
+			repo.writeFile(changelogutils.GetValidationSettingsPath(), "activeSubdirectory: v2\n")
+			repo.writeFile("changelog/v2/v0.1.0/initial.yaml", validChangelog1)
+			repo.commitWithMessage("feat: support widgets\n\nCloses #9", "changelog")
+
+			info, err := changelogutils.GetChangeInfoForLocalBranch(repo.root, "solo-io", "testrepo")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Branch).To(Equal("feature"))
+			Expect(info.Title).To(Equal("feat: support widgets"))
+
+			code, err := vfsutils.NewLocalMountedRepoForFs(repo.root, "solo-io", "testrepo")
+			Expect(err).NotTo(HaveOccurred())
+			scaffolded, err := changelogutils.ScaffoldChangelogFile(ctx, code, info, changelogutils.ScaffoldOptions{})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(scaffolded.Path).To(Equal("changelog/v2/feature.yaml"))
+			Expect(string(scaffolded.Contents)).To(Equal(`changelog:
+- description: Support widgets.
+  issueLink: https://github.com/solo-io/testrepo/issues/9
+  type: NEW_FEATURE
+`))
+
+			fs := afero.NewOsFs()
+			Expect(changelogutils.WriteScaffoldedChangelogFile(fs, repo.root, scaffolded)).To(Succeed())
+			Expect(changelogutils.WriteScaffoldedChangelogFile(fs, repo.root, scaffolded)).To(MatchError(changelogutils.ChangelogFileExistsError(scaffolded.Path)))
+			reader := changelogutils.NewChangelogReader(code)
+			file, err := reader.ReadChangelogFile(ctx, scaffolded.Path)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(file).To(Equal(scaffolded.File))
+			Expect(filepath.Join(repo.root, scaffolded.Path)).To(BeARegularFile())
+		})
+
+		It("errors when the generated entry would be invalid", func() {
+			code, err := vfsutils.NewLocalMountedRepoForFs(repo.root, "solo-io", "testrepo")
+			Expect(err).NotTo(HaveOccurred())
+			info := &changelogutils.ChangeInfo{Branch: "fix-things", Title: "Fix things"}
+			_, err = changelogutils.ScaffoldChangelogFile(ctx, code, info, changelogutils.ScaffoldOptions{})
+			Expect(err).To(HaveOccurred())
+			Expect(err.Error()).To(ContainSubstring(changelogutils.MissingIssueLinkError.Error()))
+			_, err = os.Stat(filepath.Join(repo.root, "changelog/fix-things.yaml"))
+			Expect(os.IsNotExist(err)).To(BeTrue())
+		})
+	})
+})
@@ -0,0 +1,87 @@
+package changelogutils_test
+
+import (
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/gomega"
+	"github.com/solo-io/go-utils/changelogutils"
+)
+
+// A git repository in a temporary directory, for tests that run against a local checkout
+type testGitRepo struct {
+	root     string
+	repo     *git.Repository
+	worktree *git.Worktree
+}
+
+// Creates a repo with a master branch containing changelog/v0.1.0, tagged v0.1.0, and checks out a new feature branch
+func newTestGitRepo() *testGitRepo {
+	root, err := os.MkdirTemp("", "changelogutils-git-repo")
+	Expect(err).NotTo(HaveOccurred())
+	repo, err := git.PlainInit(root, false)
+	Expect(err).NotTo(HaveOccurred())
+	worktree, err := repo.Worktree()
+	Expect(err).NotTo(HaveOccurred())
+	r := &testGitRepo{root: root, repo: repo, worktree: worktree}
+
+	r.writeFile("changelog/v0.1.0/initial.yaml", validChangelog1)
+	hash := r.commit("changelog")
+	Expect(repo.Storer.SetReference(plumbing.NewHashReference(plumbing.NewBranchReferenceName(changelogutils.MasterBranch), hash))).To(Succeed())
+	r.tag("v0.1.0", hash)
+	r.checkout("feature", true)
+	return r
+}
+
+func (r *testGitRepo) writeFile(path, contents string) {
+	fullPath := filepath.Join(r.root, path)
+	Expect(os.MkdirAll(filepath.Dir(fullPath), 0755)).To(Succeed())
+	Expect(os.WriteFile(fullPath, []byte(contents), 0644)).To(Succeed())
+}
+
+func (r *testGitRepo) commit(paths ...string) plumbing.Hash {
+	return r.commitWithMessage("commit", paths...)
+}
+
+func (r *testGitRepo) commitWithMessage(message string, paths ...string) plumbing.Hash {
+	for _, path := range paths {
+		_, err := r.worktree.Add(path)
+		Expect(err).NotTo(HaveOccurred())
+	}
+	hash, err := r.worktree.Commit(message, &git.CommitOptions{
+		Author: &object.Signature{Name: "test", Email: "test@solo.io", When: time.Now()},
+	})
+	Expect(err).NotTo(HaveOccurred())
+	return hash
+}
+
+// Commits every change in the worktree, including deleted files
+func (r *testGitRepo) commitAll() plumbing.Hash {
+	Expect(r.worktree.AddWithOptions(&git.AddOptions{All: true})).To(Succeed())
+	hash, err := r.worktree.Commit("commit", &git.CommitOptions{
+		All:    true,
+		Author: &object.Signature{Name: "test", Email: "test@solo.io", When: time.Now()},
+	})
+	Expect(err).NotTo(HaveOccurred())
+	return hash
+}
+
+func (r *testGitRepo) tag(name string, hash plumbing.Hash) {
+	_, err := r.repo.CreateTag(name, hash, nil)
+	Expect(err).NotTo(HaveOccurred())
+}
+
+func (r *testGitRepo) checkout(branch string, create bool) {
+	Expect(r.worktree.Checkout(&git.CheckoutOptions{
+		Branch: plumbing.NewBranchReferenceName(branch),
+		Create: create,
+	})).To(Succeed())
+}
+
+func (r *testGitRepo) cleanup() {
+	os.RemoveAll(r.root)
+}
@@ -4,6 +4,7 @@ import (
 	"context"
 	"fmt"
 	"path/filepath"
+	"regexp"
 	"strings"
 
 	"github.com/ghodss/yaml"
@@ -41,6 +42,12 @@ var (
 	InvalidChangelogSubdirectoryNameError = func(name string) error {
 		return eris.Errorf("%s is not a valid changelog directory name, must be a semver version.", name)
 	}
+	InvalidMinorVersionDirectoryNameError = func(name string) error {
+		return eris.Errorf("%s is not a valid changelog directory name, must be a minor version such as v1.10.", name)
+	}
+	MisplacedVersionDirectoryError = func(name, directory string) error {
+		return eris.Errorf("Found version directory %s in %s, must be in the directory of its minor version.", name, directory)
+	}
 	ListReleasesError = func(err error) error {
 		return errors.Wrapf(err, "Error listing releases")
 	}
@@ -68,6 +75,8 @@ var (
 	ExpectedVersionLabelError = func(actual string) error {
 		return eris.Errorf("Expected version %s to to have a semver label suffix", actual)
 	}
+
+	minorVersionDirectoryRegex = regexp.MustCompile(`^v\d+\.\d+$`)
 )
 
 type ChangelogValidator interface {
@@ -75,9 +84,31 @@ type ChangelogValidator interface {
 	ValidateChangelog(ctx context.Context) (*ChangelogFile, error)
 }
 
+// A ChangelogValidator that can also report every violation at once. It is implemented by the validators
+// returned by NewChangelogValidator and NewLocalChangelogValidator.
+type ChangelogReportValidator interface {
+	ChangelogValidator
+	// Runs the same checks as ValidateChangelog, but collects every violation in the returned report rather than
+	// stopping at the first one. An error is only returned if the repo could not be inspected.
+	ValidateChangelogReport(ctx context.Context) (*ChangelogFile, *ValidationReport, error)
+}
+
 type TagComparator func(greaterThanTag, lessThanTag string) (bool, bool, error)
 
-func NewChangelogValidatorWithLabelOrder(client githubutils.RepoClient, code vfsutils.MountedRepo, base string, labelOrder []string) ChangelogValidator {
+// RepoHistory is the subset of githubutils.RepoClient the validator uses to inspect the history of the repo.
+// It is satisfied by githubutils.RepoClient, and by a local git checkout (see NewLocalChangelogValidator).
+type RepoHistory interface {
+	CompareCommits(ctx context.Context, base, sha string) (*github.CommitsComparison, error)
+	DirectoryExists(ctx context.Context, sha, directory string) (bool, error)
+	FileExists(ctx context.Context, sha, path string) (bool, error)
+	FindLatestTagIncludingPrereleaseBeforeSha(ctx context.Context, sha string) (string, error)
+}
+
+func NewChangelogValidatorWithLabelOrder(client githubutils.RepoClient, code vfsutils.MountedRepo, base string, labelOrder []string) ChangelogReportValidator {
+	return newChangelogValidator(client, code, base, labelOrder)
+}
+
+func newChangelogValidator(client RepoHistory, code vfsutils.MountedRepo, base string, labelOrder []string) *changelogValidator {
 	return &changelogValidator{
 		client:     client,
 		code:       code,
@@ -86,7 +117,7 @@ func NewChangelogValidatorWithLabelOrder(client githubutils.RepoClient, code vfs
 	}
 }
 
-func NewChangelogValidator(client githubutils.RepoClient, code vfsutils.MountedRepo, base string) ChangelogValidator {
+func NewChangelogValidator(client githubutils.RepoClient, code vfsutils.MountedRepo, base string) ChangelogReportValidator {
 	return NewChangelogValidatorWithLabelOrder(client, code, base, nil)
 }
 
@@ -105,12 +136,30 @@ type ValidationSettings struct {
 
 	// defaults to "".  When set, allows for a nested processing schema.  ex: "v1.10" would mean only files in "changelog/v1.10" would be processed
 	ActiveSubdirectory string `json:"activeSubdirectory"`
+
+	// Additional changelog entry types that can be used in this repo, on top of the built-in types
+	CustomEntryTypes []*EntryTypeSettings `json:"customEntryTypes"`
+
+	// If true, every version directory is nested in a directory for its minor version, e.g. changelog/v1.10/v1.10.3,
+	// as done by PlanChangelogMigration. Versions outside of the active subdirectory can then still be read.
+	NestedVersionDirectories bool `json:"nestedVersionDirectories"`
+
+	// Enables lint rules that check the content of changelog entries, on top of the checks above
+	Lint *LintSettings `json:"lint"`
+}
+
+// Returns the entry types that can be used in the repo
+func (s *ValidationSettings) GetEntryTypes() (*EntryTypes, error) {
+	if s == nil || len(s.CustomEntryTypes) == 0 {
+		return nil, nil
+	}
+	return NewEntryTypes(s.CustomEntryTypes)
 }
 
 type changelogValidator struct {
 	base   string
 	reader ChangelogReader
-	client githubutils.RepoClient
+	client RepoHistory
 	code   vfsutils.MountedRepo
 	// list of arbitrary labels whos order is used to tie-break tag comparisons between
 	// versions with different labels. Labels ordered earlier are greater.
@@ -118,7 +167,7 @@ type changelogValidator struct {
 }
 
 func (c *changelogValidator) ShouldCheckChangelog(ctx context.Context) (bool, error) {
-	dir := c.GetChangelogDirectory(ctx)
+	dir := getVersionsParentDirectory(c.getLayoutSettings(ctx))
 	masterHasChangelog, err := c.client.DirectoryExists(ctx, MasterBranch, dir)
 	if err != nil {
 		return false, err
@@ -133,82 +182,175 @@ func (c *changelogValidator) ShouldCheckChangelog(ctx context.Context) (bool, er
 	return branchHasChangelog, nil
 }
 
+// Returns the first problem found by ValidateChangelogReport
 func (c *changelogValidator) ValidateChangelog(ctx context.Context) (*ChangelogFile, error) {
-	check, err := c.ShouldCheckChangelog(ctx)
+	newChangelogFile, report, err := c.ValidateChangelogReport(ctx)
 	if err != nil {
 		return nil, err
-	} else if !check {
-		return nil, nil
 	}
+	if err := report.FirstViolationError(); err != nil {
+		return nil, err
+	}
+	return newChangelogFile, nil
+}
 
-	commitFile, newChangelogFile, err := c.validateChangelogInPr(ctx)
+func (c *changelogValidator) ValidateChangelogReport(ctx context.Context) (*ChangelogFile, *ValidationReport, error) {
+	report := NewValidationReport()
+	check, err := c.ShouldCheckChangelog(ctx)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	} else if !check {
+		return nil, report, nil
 	}
 
-	proposedTag, err := c.validateProposedTag(ctx)
+	settings := c.getLayoutSettings(ctx)
+	dir := getVersionsParentDirectory(settings)
+	changelogFiles, err := getChangelogFilesAdded(ctx, c.client, c.base, c.code.GetSha())
 	if err != nil {
-		return nil, err
+		return nil, nil, err
+	}
+	if len(changelogFiles) == 0 {
+		report.AddError(dir, RuleNoChangelogFileAdded, NoChangelogFileAddedError)
+	} else if len(changelogFiles) > 1 {
+		for _, file := range changelogFiles {
+			report.AddError(file.GetFilename(), RuleTooManyChangelogFiles, TooManyChangelogFilesAddedError(len(changelogFiles)))
+		}
+	}
+
+	var newChangelogFile *ChangelogFile
+	addedFiles := make(map[string]*ChangelogFile)
+	for _, file := range changelogFiles {
+		changelogFile, fileReport, err := ReadChangelogFileWithReport(ctx, c.code, file.GetFilename())
+		if err != nil {
+			return nil, nil, err
+		}
+		report.Merge(fileReport)
+		addedFiles[file.GetFilename()] = changelogFile
+		newChangelogFile = changelogFile
+	}
+	if len(changelogFiles) != 1 {
+		newChangelogFile = nil
 	}
 
-	// validate commit file for tag
-	if !strings.HasPrefix(commitFile.GetFilename(), fmt.Sprintf("%s/%s", c.GetChangelogDirectory(ctx), proposedTag)) {
-		return nil, AddedChangelogInOldVersionError(proposedTag)
+	proposedTag, err := c.reportProposedTag(ctx, settings, dir, addedFiles, report)
+	if err != nil {
+		return nil, nil, err
+	}
+	if proposedTag != "" {
+		for _, file := range changelogFiles {
+			if !strings.HasPrefix(file.GetFilename(), getVersionDirectory(settings, proposedTag)+"/") {
+				report.AddError(file.GetFilename(), RuleChangelogInOldVersion, AddedChangelogInOldVersionError(proposedTag))
+			}
+		}
 	}
 
-	return newChangelogFile, nil
+	return newChangelogFile, report, nil
 }
 
-func (c *changelogValidator) validateProposedTag(ctx context.Context) (string, error) {
+// Adds a violation to the report for each problem with the proposed version directory, and returns the
+// proposed version if exactly one was found. addedFiles have already been read and are not reported on again.
+func (c *changelogValidator) reportProposedTag(ctx context.Context, settings *ValidationSettings, dir string, addedFiles map[string]*ChangelogFile, report *ValidationReport) (string, error) {
 	latestTag, err := c.client.FindLatestTagIncludingPrereleaseBeforeSha(ctx, c.base)
 	if err != nil {
 		return "", ListReleasesError(err)
 	}
 
-	dir := c.GetChangelogDirectory(ctx)
-	children, err := c.code.ListFiles(ctx, dir)
+	tags, err := findChangelogVersions(ctx, c.code, settings, report)
 	if err != nil {
 		return "", err
 	}
-	proposedVersion := ""
-	for _, child := range children {
-		if !child.IsDir() {
-			if !IsKnownChangelogFile(filepath.Join(dir, child.Name())) {
-				return "", UnexpectedFileInChangelogDirectoryError(child.Name())
-			} else {
-				continue
-			}
-		}
-		if !versionutils.MatchesRegex(child.Name()) {
-			return "", InvalidChangelogSubdirectoryNameError(child.Name())
-		}
+	var proposedVersions []string
+	for _, tag := range tags {
 		var greaterThan, determinable bool
 		if len(c.labelOrder) > 0 {
-			greaterThan, determinable, err = versionutils.IsGreaterThanTagWithLabelOrder(child.Name(), latestTag, c.labelOrder)
-
+			greaterThan, determinable, err = versionutils.IsGreaterThanTagWithLabelOrder(tag, latestTag, c.labelOrder)
 		} else {
-			greaterThan, determinable, err = versionutils.IsGreaterThanTag(child.Name(), latestTag)
-
+			greaterThan, determinable, err = versionutils.IsGreaterThanTag(tag, latestTag)
 		}
 		if err != nil {
-			return "", err
+			report.AddError(getVersionDirectory(settings, tag), RuleInvalidVersionDirectory, err)
+			continue
 		}
 		if greaterThan || !determinable {
-			if proposedVersion != "" {
-				return "", MultipleNewVersionsFoundError(latestTag, proposedVersion, child.Name())
-			}
-			proposedVersion = child.Name()
+			proposedVersions = append(proposedVersions, tag)
+		}
+	}
+	if len(proposedVersions) == 0 {
+		report.AddError(dir, RuleNoNewVersion, NoNewVersionsFoundError(latestTag))
+		return "", nil
+	}
+	if len(proposedVersions) > 1 {
+		for _, version := range proposedVersions[1:] {
+			report.AddError(getVersionDirectory(settings, version), RuleMultipleNewVersions, MultipleNewVersionsFoundError(latestTag, proposedVersions[0], version))
+		}
+		return "", nil
+	}
+	proposedVersion := proposedVersions[0]
+
+	changelog, err := c.reportChangelogForTag(ctx, getVersionDirectory(settings, proposedVersion), proposedVersion, addedFiles, report)
+	if err != nil {
+		return "", err
+	}
+
+	settings, err = c.getValidationSettings(ctx)
+	if err != nil {
+		report.AddError(GetValidationSettingsPath(), RuleInvalidValidationSetting, err)
+		return proposedVersion, nil
+	}
+	if err := c.validateVersionBump(ctx, latestTag, changelog); err != nil {
+		report.AddError(getVersionDirectory(settings, proposedVersion), RuleVersionBump, err)
+	}
+	if settings.Lint != nil {
+		files, err := readLintFiles(ctx, c.code, getVersionDirectory(settings, proposedVersion))
+		if err != nil {
+			return "", err
 		}
+		report.Merge(LintChangelogFiles(proposedVersion, files, settings.Lint, LintOptions{
+			Owner:      c.code.GetOwner(),
+			Repo:       c.code.GetRepo(),
+			EntryTypes: entryTypesOrDefault(settings),
+		}))
 	}
-	if proposedVersion == "" {
-		return "", NoNewVersionsFoundError(latestTag)
+	return proposedVersion, nil
+}
+
+// Reads every changelog file for the tag, reporting problems with files that weren't already read as part of addedFiles.
+// Files that can't be parsed are left out of the returned changelog.
+func (c *changelogValidator) reportChangelogForTag(ctx context.Context, changelogPath, tag string, addedFiles map[string]*ChangelogFile, report *ValidationReport) (*Changelog, error) {
+	version, err := versionutils.ParseVersion(tag)
+	if err != nil {
+		return nil, err
+	}
+	changelog := &Changelog{
+		Version: version,
 	}
-	changelog, err := NewChangelogReader(c.code).GetChangelogForTag(ctx, proposedVersion)
+	files, err := c.code.ListFiles(ctx, changelogPath)
 	if err != nil {
-		return proposedVersion, err
+		return nil, UnableToListFilesError(err, changelogPath)
+	}
+	for _, changelogFileInfo := range files {
+		changelogFilePath := filepath.Join(changelogPath, changelogFileInfo.Name())
+		if changelogFileInfo.IsDir() {
+			report.AddError(changelogFilePath, RuleUnexpectedFile, UnexpectedDirectoryError(changelogFileInfo.Name(), changelogPath))
+			continue
+		}
+		if changelogFileInfo.Name() == SummaryFile || changelogFileInfo.Name() == ClosingFile {
+			continue
+		}
+		changelogFile, added := addedFiles[changelogFilePath]
+		if !added {
+			var fileReport *ValidationReport
+			changelogFile, fileReport, err = ReadChangelogFileWithReport(ctx, c.code, changelogFilePath)
+			if err != nil {
+				return nil, err
+			}
+			report.Merge(fileReport)
+		}
+		if changelogFile != nil {
+			changelog.Files = append(changelog.Files, changelogFile)
+		}
 	}
-	err = c.validateVersionBump(ctx, latestTag, changelog)
-	return proposedVersion, err
+	return changelog, nil
 }
 
 func (c *changelogValidator) validateVersionBump(ctx context.Context, latestTag string, changelog *Changelog) error {
@@ -230,21 +372,23 @@ func (c *changelogValidator) validateVersionBump(ctx context.Context, latestTag
 		return err
 	}
 
-	if settings.RequireLabel && len(changelog.Version.Label) == 0 {
+	if settings.RequireLabel && !changelog.Version.IsPrerelease() {
 		return ExpectedVersionLabelError(changelog.Version.String())
 	}
 
 	// If the settings contain specific allowed labels, ensure the label used here, if any, is in the list
-	if changelog.Version.Label != "" && len(settings.AllowedLabels) > 0 {
+	if changelog.Version.IsPrerelease() && len(settings.AllowedLabels) > 0 {
 		if !stringutils.ContainsString(changelog.Version.Label, settings.AllowedLabels) {
 			return InvalidLabelError(changelog.Version.Label, settings.AllowedLabels)
 		}
 	}
 
+	// already validated when reading the settings
+	entryTypes, _ := settings.GetEntryTypes()
 	for _, file := range changelog.Files {
 		for _, entry := range file.Entries {
-			breakingChanges = breakingChanges || entry.Type.BreakingChange()
-			newFeature = newFeature || entry.Type.NewFeature()
+			breakingChanges = breakingChanges || entryTypes.BreakingChange(entry.Type)
+			newFeature = newFeature || entryTypes.NewFeature(entry.Type)
 		}
 		releaseStableApi = releaseStableApi || file.GetReleaseStableApi()
 	}
@@ -272,7 +416,7 @@ func (c *changelogValidator) validateVersionBump(ctx context.Context, latestTag
 		return UnexpectedProposedVersionError(expectedVersion.String(), changelog.Version.String())
 	}
 
-	if changelog.Version.Label == "" && expectedVersion.Label == "" && !settings.RelaxSemverValidation {
+	if !changelog.Version.IsPrerelease() && !expectedVersion.IsPrerelease() && !settings.RelaxSemverValidation {
 		// since this isn't a labeled release or a stable release, the version should be incremented
 		// based on semver rules.
 		if changelog.Version.LabelVersion == 0 && !changelog.Version.Equals(expectedVersion) {
@@ -283,21 +427,11 @@ func (c *changelogValidator) validateVersionBump(ctx context.Context, latestTag
 	return nil
 }
 
-func (c *changelogValidator) validateChangelogInPr(ctx context.Context) (*github.CommitFile, *ChangelogFile, error) {
-	changelogFiles, err := GetChangelogFilesAdded(ctx, c.client, c.base, c.code.GetSha())
-	if err != nil {
-		return nil, nil, err
-	}
-	if len(changelogFiles) == 0 {
-		return nil, nil, NoChangelogFileAddedError
-	} else if len(changelogFiles) > 1 {
-		return nil, nil, TooManyChangelogFilesAddedError(len(changelogFiles))
-	}
-	parsedChangelog, err := NewChangelogReader(c.code).ReadChangelogFile(ctx, changelogFiles[0].GetFilename())
-	return &changelogFiles[0], parsedChangelog, err
+func GetChangelogFilesAdded(ctx context.Context, client githubutils.RepoClient, base, sha string) ([]github.CommitFile, error) {
+	return getChangelogFilesAdded(ctx, client, base, sha)
 }
 
-func GetChangelogFilesAdded(ctx context.Context, client githubutils.RepoClient, base, sha string) ([]github.CommitFile, error) {
+func getChangelogFilesAdded(ctx context.Context, client RepoHistory, base, sha string) ([]github.CommitFile, error) {
 	commitComparison, err := client.CompareCommits(ctx, base, sha)
 	if err != nil {
 		return nil, err
@@ -325,7 +459,17 @@ func IsKnownChangelogFile(path string) bool {
 }
 
 func (c *changelogValidator) getValidationSettings(ctx context.Context) (*ValidationSettings, error) {
-	return GetValidationSettings(ctx, c.code, c.client)
+	return getValidationSettings(ctx, c.code, c.client)
+}
+
+// Returns the settings that determine where the version directories are. Invalid settings are reported by
+// ValidateChangelogReport, and the default layout is assumed until they are fixed.
+func (c *changelogValidator) getLayoutSettings(ctx context.Context) *ValidationSettings {
+	settings, err := c.getValidationSettings(ctx)
+	if err != nil {
+		return &defaultSettings
+	}
+	return settings
 }
 
 func (c *changelogValidator) GetChangelogDirectory(ctx context.Context) string {
@@ -341,6 +485,10 @@ func (c *changelogValidator) GetChangelogDirectory(ctx context.Context) string {
 }
 
 func GetValidationSettings(ctx context.Context, code vfsutils.MountedRepo, client githubutils.RepoClient) (*ValidationSettings, error) {
+	return getValidationSettings(ctx, code, client)
+}
+
+func getValidationSettings(ctx context.Context, code vfsutils.MountedRepo, client RepoHistory) (*ValidationSettings, error) {
 	exists, err := client.FileExists(ctx, code.GetSha(), GetValidationSettingsPath())
 	if err != nil {
 		return nil, UnableToGetSettingsError(err)
@@ -358,5 +506,11 @@ func GetValidationSettings(ctx context.Context, code vfsutils.MountedRepo, clien
 	if err := yaml.Unmarshal(bytes, &settings); err != nil {
 		return nil, UnableToGetSettingsError(err)
 	}
+	if _, err := settings.GetEntryTypes(); err != nil {
+		return nil, UnableToGetSettingsError(err)
+	}
+	if err := settings.Lint.Validate(); err != nil {
+		return nil, UnableToGetSettingsError(err)
+	}
 	return &settings, nil
 }
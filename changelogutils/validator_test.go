@@ -157,7 +157,7 @@ var _ = Describe("changelog validator utils", func() {
 
 			code.EXPECT().
 				GetFileContents(ctx, nextTagFile).
-				Return([]byte(contents), nil).Times(2)
+				Return([]byte(contents), nil)
 
 			repoClient.EXPECT().
 				FindLatestTagIncludingPrereleaseBeforeSha(ctx, base).
@@ -211,11 +211,23 @@ var _ = Describe("changelog validator utils", func() {
 			repoClient.EXPECT().
 				CompareCommits(ctx, base, sha).
 				Return(&cc, nil)
+			// the rest of the changelog directory is still checked, but the missing file is the first problem
+			repoClient.EXPECT().
+				FindLatestTagIncludingPrereleaseBeforeSha(ctx, base).
+				Return(tag, nil)
+			code.EXPECT().
+				ListFiles(ctx, changelogutils.ChangelogDirectory).
+				Return(nil, nil)
 
 			expected := changelogutils.NoChangelogFileAddedError
 			file, err := validator.ValidateChangelog(ctx)
 			Expect(file).To(BeNil())
-			Expect(err).To(Equal(expected))
+			Expect(err).To(MatchError(expected))
+			// the error knows its violation, so it can annotate a check run
+			violationErr, ok := err.(*changelogutils.ViolationError)
+			Expect(ok).To(BeTrue())
+			Expect(violationErr.Violation.RuleId).To(Equal(changelogutils.RuleNoChangelogFileAdded))
+			Expect(violationErr.GithubAnnotations()).To(HaveLen(1))
 		})
 
 		It("errors when more than one changelog file added", func() {
@@ -226,6 +238,18 @@ var _ = Describe("changelog validator utils", func() {
 			repoClient.EXPECT().
 				CompareCommits(ctx, base, sha).
 				Return(&cc, nil)
+			code.EXPECT().
+				GetFileContents(ctx, path1).
+				Return([]byte(validChangelog1), nil)
+			code.EXPECT().
+				GetFileContents(ctx, path2).
+				Return([]byte(validChangelog1), nil)
+			repoClient.EXPECT().
+				FindLatestTagIncludingPrereleaseBeforeSha(ctx, base).
+				Return(tag, nil)
+			code.EXPECT().
+				ListFiles(ctx, changelogutils.ChangelogDirectory).
+				Return(nil, nil)
 
 			expected := changelogutils.TooManyChangelogFilesAddedError(2)
 			file, err := validator.ValidateChangelog(ctx)
@@ -379,7 +403,7 @@ var _ = Describe("changelog validator utils", func() {
 						Return(&cc, nil)
 					code.EXPECT().
 						GetFileContents(ctx, path1).
-						Return([]byte(validChangelog1), nil).Times(2)
+						Return([]byte(validChangelog1), nil)
 					repoClient.EXPECT().
 						FindLatestTagIncludingPrereleaseBeforeSha(ctx, base).
 						Return("v0.5.0", nil)
@@ -402,7 +426,7 @@ var _ = Describe("changelog validator utils", func() {
 						Return(&cc, nil)
 					code.EXPECT().
 						GetFileContents(ctx, path1).
-						Return([]byte(validBreakingChangelog), nil).Times(2)
+						Return([]byte(validBreakingChangelog), nil)
 					repoClient.EXPECT().
 						FindLatestTagIncludingPrereleaseBeforeSha(ctx, base).
 						Return("v0.5.0", nil)
@@ -429,7 +453,7 @@ var _ = Describe("changelog validator utils", func() {
 						Return(&cc, nil)
 					code.EXPECT().
 						GetFileContents(ctx, path).
-						Return([]byte(validBreakingChangelog), nil).Times(2)
+						Return([]byte(validBreakingChangelog), nil)
 					repoClient.EXPECT().
 						FindLatestTagIncludingPrereleaseBeforeSha(ctx, base).
 						Return("v0.5.0", nil)
@@ -474,7 +498,7 @@ var _ = Describe("changelog validator utils", func() {
 						Return(&cc, nil)
 					code.EXPECT().
 						GetFileContents(ctx, path).
-						Return([]byte(validStableReleaseChangelog), nil).Times(2)
+						Return([]byte(validStableReleaseChangelog), nil)
 					repoClient.EXPECT().
 						FindLatestTagIncludingPrereleaseBeforeSha(ctx, base).
 						Return("v0.5.0", nil)
@@ -499,7 +523,7 @@ var _ = Describe("changelog validator utils", func() {
 						Return(&cc, nil)
 					code.EXPECT().
 						GetFileContents(ctx, path).
-						Return([]byte(validStableReleaseChangelog), nil).Times(2)
+						Return([]byte(validStableReleaseChangelog), nil)
 					repoClient.EXPECT().
 						FindLatestTagIncludingPrereleaseBeforeSha(ctx, base).
 						Return("v0.5.0", nil)
@@ -537,7 +561,7 @@ var _ = Describe("changelog validator utils", func() {
 						Return(&cc, nil)
 					code.EXPECT().
 						GetFileContents(ctx, path1).
-						Return([]byte(validBreakingChangelog), nil).Times(2)
+						Return([]byte(validBreakingChangelog), nil)
 					repoClient.EXPECT().
 						FindLatestTagIncludingPrereleaseBeforeSha(ctx, base).
 						Return("v0.5.0", nil)
@@ -562,7 +586,7 @@ var _ = Describe("changelog validator utils", func() {
 						Return(&cc, nil)
 					code.EXPECT().
 						GetFileContents(ctx, path).
-						Return([]byte(validBreakingChangelog), nil).Times(2)
+						Return([]byte(validBreakingChangelog), nil)
 					repoClient.EXPECT().
 						FindLatestTagIncludingPrereleaseBeforeSha(ctx, base).
 						Return("v0.5.0", nil)
@@ -608,7 +632,7 @@ var _ = Describe("changelog validator utils", func() {
 						Return(&cc, nil)
 					code.EXPECT().
 						GetFileContents(ctx, path).
-						Return([]byte(validStableReleaseChangelog), nil).Times(2)
+						Return([]byte(validStableReleaseChangelog), nil)
 					repoClient.EXPECT().
 						FindLatestTagIncludingPrereleaseBeforeSha(ctx, base).
 						Return("v0.5.0", nil)
@@ -633,7 +657,7 @@ var _ = Describe("changelog validator utils", func() {
 						Return(&cc, nil)
 					code.EXPECT().
 						GetFileContents(ctx, path).
-						Return([]byte(validStableReleaseChangelog), nil).Times(2)
+						Return([]byte(validStableReleaseChangelog), nil)
 					repoClient.EXPECT().
 						FindLatestTagIncludingPrereleaseBeforeSha(ctx, base).
 						Return("v0.5.0", nil)
@@ -662,9 +686,6 @@ var _ = Describe("changelog validator utils", func() {
 				repoClient.EXPECT().
 					CompareCommits(ctx, base, sha).
 					Return(&cc, nil)
-				code.EXPECT().
-					GetFileContents(ctx, path).
-					Return([]byte(validBreakingChangelog), nil)
 				repoClient.EXPECT().
 					FindLatestTagIncludingPrereleaseBeforeSha(ctx, base).
 					Return(lastTag, nil)
@@ -718,7 +739,7 @@ var _ = Describe("changelog validator utils", func() {
 					Return(&cc, nil)
 				code.EXPECT().
 					GetFileContents(ctx, path).
-					Return([]byte(validBreakingChangelog), nil).Times(2)
+					Return([]byte(validBreakingChangelog), nil)
 				repoClient.EXPECT().
 					FindLatestTagIncludingPrereleaseBeforeSha(ctx, base).
 					Return("v0.5.0", nil)
@@ -760,7 +781,7 @@ var _ = Describe("changelog validator utils", func() {
 					Return(&cc, nil)
 				code.EXPECT().
 					GetFileContents(ctx, path1).
-					Return([]byte(validChangelog1), nil).Times(2)
+					Return([]byte(validChangelog1), nil)
 				repoClient.EXPECT().
 					FindLatestTagIncludingPrereleaseBeforeSha(ctx, base).
 					Return("v0.5.0", nil)
@@ -789,7 +810,7 @@ var _ = Describe("changelog validator utils", func() {
 					Return(&cc, nil)
 				code.EXPECT().
 					GetFileContents(ctx, path1).
-					Return([]byte(validChangelog1), nil).Times(2)
+					Return([]byte(validChangelog1), nil)
 				repoClient.EXPECT().
 					FindLatestTagIncludingPrereleaseBeforeSha(ctx, base).
 					Return("v0.5.0", nil)
@@ -0,0 +1,348 @@
+package githubutils
+
+import (
+	"context"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	"github.com/google/go-github/v32/github"
+	"github.com/rotisserie/eris"
+	"golang.org/x/oauth2"
+)
+
+const (
+	GITHUB_APP_ID               = "GITHUB_APP_ID"
+	GITHUB_APP_PRIVATE_KEY      = "GITHUB_APP_PRIVATE_KEY"
+	GITHUB_APP_PRIVATE_KEY_FILE = "GITHUB_APP_PRIVATE_KEY_FILE"
+	// The org or user whose installation GetClient uses when authenticating as the app
+	GITHUB_APP_INSTALLATION_OWNER = "GITHUB_APP_INSTALLATION_OWNER"
+
+	// Installation tokens expire an hour after they are minted
+	defaultTokenRefreshBefore = 5 * time.Minute
+	// Github rejects app JWTs that expire more than 10 minutes after they were issued
+	appJWTLifetime = 9 * time.Minute
+)
+
+var (
+	InvalidAppPrivateKeyError = func(err error, appID int64) error {
+		return eris.Wrapf(err, "invalid private key for Github app %d", appID)
+	}
+	AppNotInstalledError = func(err error, owner, repo string) error {
+		if repo == "" {
+			return eris.Wrapf(err, "the Github app is not installed for %s", owner)
+		}
+		return eris.Wrapf(err, "the Github app is not installed for %s/%s", owner, repo)
+	}
+	MintInstallationTokenError = func(err error, installationID int64) error {
+		return eris.Wrapf(err, "unable to mint a token for Github app installation %d", installationID)
+	}
+)
+
+type GithubAppConfig struct {
+	// The app's ID, called the integration ID by botconfig
+	AppID int64
+	// PEM encoded RSA private key of the app
+	PrivateKey []byte
+	// URL of Github Enterprise, e.g. https://github.example.com/ or its API at https://github.example.com/api/v3/;
+	// defaults to https://api.github.com/. The API and upload URLs of Github Enterprise are derived as
+	// github.NewEnterpriseClient does, while those of github.com (or api.github.com) are left as they are.
+	BaseURL string
+	// How long before they expire installation tokens are refreshed, defaults to 5 minutes
+	RefreshBefore time.Duration
+	// Sends the requests of the app and its installations, e.g. through a proxy or with a custom TLS config;
+	// defaults to http.DefaultTransport
+	Transport http.RoundTripper
+	// Options of the client authenticated as the app itself, which finds installations and mints their tokens,
+	// e.g. WithTransportOptions. The clients of installations take their options from Client.
+	ClientOptions []ClientOption
+}
+
+// Authenticates as a Github App, and mints installation tokens to act on the repos the app is installed on.
+// Installation IDs and tokens are cached, and tokens are refreshed before they expire, so a GithubApp can be
+// shared by every client of a process. Unlike ghinstallation, which has a transport per installation, a GithubApp
+// finds the installation of any owner or repo, and mints the tokens of different installations concurrently.
+type GithubApp struct {
+	appID         int64
+	refreshBefore time.Duration
+	transport     http.RoundTripper
+	// authenticated as the app itself, with a JWT
+	appClient *github.Client
+
+	// guards the maps, not the tokens in them
+	mu sync.Mutex
+	// by owner or owner/repo
+	installationIDs map[string]int64
+	tokens          map[int64]*installationToken
+}
+
+// The token of an installation. Its lock is held while the token is minted, so concurrent requests for the same
+// installation wait for a single token.
+type installationToken struct {
+	mu    sync.Mutex
+	token *oauth2.Token
+}
+
+func NewGithubApp(config GithubAppConfig) (*GithubApp, error) {
+	key, err := jwt.ParseRSAPrivateKeyFromPEM(config.PrivateKey)
+	if err != nil {
+		return nil, InvalidAppPrivateKeyError(err, config.AppID)
+	}
+	transport := config.Transport
+	if transport == nil {
+		transport = http.DefaultTransport
+	}
+	httpClient := withClientOptions(&http.Client{Transport: &appJWTTransport{
+		appID: config.AppID,
+		sign: func(claims jwt.Claims) (string, error) {
+			return jwt.NewWithClaims(jwt.SigningMethodRS256, claims).SignedString(key)
+		},
+		base: transport,
+	}}, config.ClientOptions)
+	appClient := github.NewClient(httpClient)
+	if config.BaseURL != "" && !isGithubDotCom(config.BaseURL) {
+		// the uploads API isn't under /api/v3/
+		host := strings.TrimSuffix(strings.TrimSuffix(config.BaseURL, "/"), "/api/v3")
+		appClient, err = github.NewEnterpriseClient(host, host, httpClient)
+		if err != nil {
+			return nil, err
+		}
+	}
+	refreshBefore := config.RefreshBefore
+	if refreshBefore == 0 {
+		refreshBefore = defaultTokenRefreshBefore
+	}
+	return &GithubApp{
+		appID:           config.AppID,
+		refreshBefore:   refreshBefore,
+		transport:       transport,
+		appClient:       appClient,
+		installationIDs: map[string]int64{},
+		tokens:          map[int64]*installationToken{},
+	}, nil
+}
+
+// Returns whether the URL is of github.com or its API, rather than of Github Enterprise
+func isGithubDotCom(baseURL string) bool {
+	u, err := url.Parse(baseURL)
+	return err == nil && (u.Host == "github.com" || u.Host == "api.github.com")
+}
+
+var (
+	envGithubAppMu  sync.Mutex
+	envGithubApp    *GithubApp
+	envGithubAppKey string
+)
+
+// Like GetGithubAppFromEnv, but returns the same GithubApp while the environment doesn't change, so that the
+// clients built by GetClient share its installation tokens
+func getCachedGithubAppFromEnv() (*GithubApp, error) {
+	envGithubAppMu.Lock()
+	defer envGithubAppMu.Unlock()
+	key := strings.Join([]string{os.Getenv(GITHUB_APP_ID), os.Getenv(GITHUB_APP_PRIVATE_KEY), os.Getenv(GITHUB_APP_PRIVATE_KEY_FILE)}, "\x00")
+	if envGithubApp != nil && key == envGithubAppKey {
+		return envGithubApp, nil
+	}
+	app, err := GetGithubAppFromEnv()
+	if err != nil || app == nil {
+		return app, err
+	}
+	envGithubApp, envGithubAppKey = app, key
+	return app, nil
+}
+
+// Returns a client authenticated as the installation, for owner, of the Github App configured in the environment,
+// or nil if there is none
+func getGithubAppClientFromEnv(ctx context.Context, options []ClientOption) (*github.Client, error) {
+	app, err := getCachedGithubAppFromEnv()
+	if err != nil || app == nil {
+		return nil, err
+	}
+	owner := os.Getenv(GITHUB_APP_INSTALLATION_OWNER)
+	if owner == "" {
+		return nil, eris.Errorf("Could not find %s in environment.", GITHUB_APP_INSTALLATION_OWNER)
+	}
+	return app.Client(ctx, owner, "", options...), nil
+}
+
+// Returns a client authenticated with GITHUB_TOKEN if it's set, and otherwise as the installation for owner/repo of
+// the Github App configured in the environment
+func GetClientForRepo(ctx context.Context, owner, repo string, options ...ClientOption) (*github.Client, error) {
+	if _, err := GetGithubToken(); err == nil {
+		return GetClient(ctx, options...)
+	}
+	app, err := getCachedGithubAppFromEnv()
+	if err != nil {
+		return nil, err
+	}
+	if app == nil {
+		return nil, eris.Errorf("Could not find %s or %s in environment.", GITHUB_TOKEN, GITHUB_APP_ID)
+	}
+	return app.Client(ctx, owner, repo, options...), nil
+}
+
+// Reads the app ID from GITHUB_APP_ID, and the private key from GITHUB_APP_PRIVATE_KEY or the file at
+// GITHUB_APP_PRIVATE_KEY_FILE. Returns nil if the app ID isn't set.
+func GetGithubAppFromEnv() (*GithubApp, error) {
+	appIDStr, found := os.LookupEnv(GITHUB_APP_ID)
+	if !found {
+		return nil, nil
+	}
+	appID, err := strconv.ParseInt(appIDStr, 10, 64)
+	if err != nil {
+		return nil, eris.Wrapf(err, "invalid %s %s", GITHUB_APP_ID, appIDStr)
+	}
+	privateKey := []byte(os.Getenv(GITHUB_APP_PRIVATE_KEY))
+	if file := os.Getenv(GITHUB_APP_PRIVATE_KEY_FILE); len(privateKey) == 0 && file != "" {
+		if privateKey, err = ioutil.ReadFile(file); err != nil {
+			return nil, eris.Wrapf(err, "unable to read %s %s", GITHUB_APP_PRIVATE_KEY_FILE, file)
+		}
+	}
+	if len(privateKey) == 0 {
+		return nil, eris.Errorf("Could not find %s or %s in environment.", GITHUB_APP_PRIVATE_KEY, GITHUB_APP_PRIVATE_KEY_FILE)
+	}
+	return NewGithubApp(GithubAppConfig{AppID: appID, PrivateKey: privateKey})
+}
+
+// Returns a client authenticated with the app's installation for owner/repo. If repo is empty, the installation
+// for the owner's account (an org or a user) is used.
+func (a *GithubApp) Client(ctx context.Context, owner, repo string, options ...ClientOption) *github.Client {
+	tc := &http.Client{Transport: &oauth2.Transport{
+		Source: oauth2.ReuseTokenSource(nil, a.TokenSource(ctx, owner, repo)),
+		Base:   a.transport,
+	}}
+	client := github.NewClient(withClientOptions(tc, options))
+	client.BaseURL = a.appClient.BaseURL
+	client.UploadURL = a.appClient.UploadURL
+	return client
+}
+
+// Returns a source of installation tokens for owner/repo, e.g. to clone with git
+func (a *GithubApp) TokenSource(ctx context.Context, owner, repo string) oauth2.TokenSource {
+	return &installationTokenSource{ctx: ctx, app: a, owner: owner, repo: repo}
+}
+
+type installationTokenSource struct {
+	ctx         context.Context
+	app         *GithubApp
+	owner, repo string
+}
+
+func (s *installationTokenSource) Token() (*oauth2.Token, error) {
+	return s.app.InstallationToken(s.ctx, s.owner, s.repo)
+}
+
+// Returns the cached token of the installation for owner/repo, or mints one if it's missing or about to expire
+func (a *GithubApp) InstallationToken(ctx context.Context, owner, repo string) (*oauth2.Token, error) {
+	installationID, err := a.InstallationID(ctx, owner, repo)
+	if err != nil {
+		return nil, err
+	}
+	a.mu.Lock()
+	cached, ok := a.tokens[installationID]
+	if !ok {
+		cached = &installationToken{}
+		a.tokens[installationID] = cached
+	}
+	a.mu.Unlock()
+
+	cached.mu.Lock()
+	defer cached.mu.Unlock()
+	if cached.token != nil && time.Until(cached.token.Expiry) > a.refreshBefore {
+		return cached.token, nil
+	}
+	minted, _, err := a.appClient.Apps.CreateInstallationToken(ctx, installationID, nil)
+	if err != nil {
+		return nil, MintInstallationTokenError(err, installationID)
+	}
+	cached.token = &oauth2.Token{
+		AccessToken: minted.GetToken(),
+		TokenType:   "token",
+		Expiry:      minted.GetExpiresAt(),
+	}
+	return cached.token, nil
+}
+
+// Returns the ID of the app's installation for owner/repo, or for the owner's account if repo is empty
+func (a *GithubApp) InstallationID(ctx context.Context, owner, repo string) (int64, error) {
+	key := owner
+	if repo != "" {
+		key = owner + "/" + repo
+	}
+	a.mu.Lock()
+	installationID, ok := a.installationIDs[key]
+	a.mu.Unlock()
+	if ok {
+		return installationID, nil
+	}
+
+	var (
+		installation *github.Installation
+		err          error
+	)
+	if repo != "" {
+		installation, _, err = a.appClient.Apps.FindRepositoryInstallation(ctx, owner, repo)
+	} else {
+		var resp *github.Response
+		installation, resp, err = a.appClient.Apps.FindOrganizationInstallation(ctx, owner)
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			installation, _, err = a.appClient.Apps.FindUserInstallation(ctx, owner)
+		}
+	}
+	if err != nil {
+		return 0, AppNotInstalledError(err, owner, repo)
+	}
+	a.mu.Lock()
+	defer a.mu.Unlock()
+	a.installationIDs[key] = installation.GetID()
+	return installation.GetID(), nil
+}
+
+// Authenticates requests as the app, with a JWT signed by its private key. The JWT is reused until it's about to
+// expire.
+type appJWTTransport struct {
+	appID int64
+	sign  func(claims jwt.Claims) (string, error)
+	base  http.RoundTripper
+
+	mu      sync.Mutex
+	jwt     string
+	expires time.Time
+}
+
+func (t *appJWTTransport) RoundTrip(req *http.Request) (*http.Response, error) {
+	token, err := t.token()
+	if err != nil {
+		return nil, err
+	}
+	req = req.Clone(req.Context())
+	req.Header.Set("Authorization", "Bearer "+token)
+	return t.base.RoundTrip(req)
+}
+
+func (t *appJWTTransport) token() (string, error) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	if t.jwt != "" && now.Add(time.Minute).Before(t.expires) {
+		return t.jwt, nil
+	}
+	// issued in the past, in case Github's clock is behind ours
+	claims := &jwt.RegisteredClaims{
+		IssuedAt:  jwt.NewNumericDate(now.Add(-time.Minute)),
+		ExpiresAt: jwt.NewNumericDate(now.Add(appJWTLifetime)),
+		Issuer:    strconv.FormatInt(t.appID, 10),
+	}
+	signed, err := t.sign(claims)
+	if err != nil {
+		return "", err
+	}
+	t.jwt, t.expires = signed, now.Add(appJWTLifetime)
+	return t.jwt, nil
+}
@@ -0,0 +1,242 @@
+package githubutils_test
+
+import (
+	"context"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/json"
+	"encoding/pem"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"time"
+
+	"github.com/golang-jwt/jwt/v4"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/solo-io/go-utils/githubutils"
+)
+
+var _ = Describe("GithubApp", func() {
+
+	var (
+		ctx        = context.Background()
+		key        *rsa.PrivateKey
+		privateKey []byte
+		fake       *fakeGithub
+		// how long the minted tokens are valid for
+		tokenLifetime time.Duration
+
+		minted       int
+		lookups      []string
+		repoRequests []string
+		// if set, minting a token for installation 42 is reported on mintStarted, then waits until blockMint is closed
+		blockMint, mintStarted chan struct{}
+	)
+
+	BeforeEach(func() {
+		var err error
+		key, err = rsa.GenerateKey(rand.Reader, 2048)
+		Expect(err).NotTo(HaveOccurred())
+		privateKey = pem.EncodeToMemory(&pem.Block{Type: "RSA PRIVATE KEY", Bytes: x509.MarshalPKCS1PrivateKey(key)})
+		tokenLifetime = time.Hour
+		minted, lookups, repoRequests, blockMint = 0, nil, nil, nil
+
+		fake = newFakeGithub(func(w http.ResponseWriter, r *http.Request) {
+			// the API of Github Enterprise
+			Expect(r.URL.Path).To(HavePrefix("/api/v3/"))
+			path := strings.TrimPrefix(r.URL.Path, "/api/v3")
+			if path == "/app/installations/42/access_tokens" && blockMint != nil {
+				mintStarted <- struct{}{}
+				<-blockMint
+			}
+			fake.Lock()
+			defer fake.Unlock()
+			auth := r.Header.Get("Authorization")
+			switch {
+			case path == "/repos/solo-io/gloo" || path == "/repos/solo-io/go-utils":
+				repoRequests = append(repoRequests, auth)
+				fmt.Fprint(w, `{"name":"gloo"}`)
+				return
+			case strings.HasPrefix(auth, "Bearer "):
+				// authenticated as the app
+				token, err := jwt.ParseWithClaims(strings.TrimPrefix(auth, "Bearer "), &jwt.RegisteredClaims{}, func(*jwt.Token) (interface{}, error) {
+					return &key.PublicKey, nil
+				})
+				Expect(err).NotTo(HaveOccurred())
+				Expect(token.Claims.(*jwt.RegisteredClaims).Issuer).To(Equal("1234"))
+			default:
+				Fail("unexpected authorization " + auth)
+			}
+
+			switch path {
+			case "/repos/solo-io/gloo/installation":
+				lookups = append(lookups, path)
+				fmt.Fprint(w, `{"id":42}`)
+			case "/orgs/solo-io/installation":
+				lookups = append(lookups, path)
+				fmt.Fprint(w, `{"id":42}`)
+			case "/orgs/someone/installation":
+				lookups = append(lookups, path)
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"message":"Not Found"}`)
+			case "/users/someone/installation":
+				lookups = append(lookups, path)
+				fmt.Fprint(w, `{"id":7}`)
+			case "/repos/solo-io/private/installation":
+				w.WriteHeader(http.StatusNotFound)
+				fmt.Fprint(w, `{"message":"Not Found"}`)
+			case "/app/installations/42/access_tokens", "/app/installations/7/access_tokens":
+				Expect(r.Method).To(Equal(http.MethodPost))
+				minted++
+				Expect(json.NewEncoder(w).Encode(map[string]interface{}{
+					"token":      fmt.Sprintf("token-%d", minted),
+					"expires_at": time.Now().Add(tokenLifetime).UTC().Format(time.RFC3339),
+				})).To(Succeed())
+			default:
+				Fail("unexpected request " + path)
+			}
+		})
+	})
+
+	newApp := func() *githubutils.GithubApp {
+		app, err := githubutils.NewGithubApp(githubutils.GithubAppConfig{
+			AppID:      1234,
+			PrivateKey: privateKey,
+			BaseURL:    fake.URL(),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		return app
+	}
+
+	It("authenticates clients with cached installation tokens", func() {
+		app := newApp()
+		client := app.Client(ctx, "solo-io", "gloo")
+		for i := 0; i < 3; i++ {
+			repo, _, err := client.Repositories.Get(ctx, "solo-io", "gloo")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repo.GetName()).To(Equal("gloo"))
+		}
+		// the org's installation is the same one, so its token is reused
+		_, _, err := app.Client(ctx, "solo-io", "").Repositories.Get(ctx, "solo-io", "go-utils")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(minted).To(Equal(1))
+		Expect(lookups).To(Equal([]string{"/repos/solo-io/gloo/installation", "/orgs/solo-io/installation"}))
+		Expect(repoRequests).To(Equal([]string{"token token-1", "token token-1", "token token-1", "token token-1"}))
+	})
+
+	It("refreshes installation tokens before they expire", func() {
+		tokenLifetime = 4 * time.Minute
+		app := newApp()
+		for i := 1; i <= 2; i++ {
+			token, err := app.InstallationToken(ctx, "solo-io", "gloo")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(token.AccessToken).To(Equal(fmt.Sprintf("token-%d", i)))
+		}
+		Expect(lookups).To(HaveLen(1))
+	})
+
+	It("derives the upload URL of Github Enterprise", func() {
+		for _, baseURL := range []string{fake.URL(), fake.URL() + "/api/v3/"} {
+			app, err := githubutils.NewGithubApp(githubutils.GithubAppConfig{AppID: 1234, PrivateKey: privateKey, BaseURL: baseURL})
+			Expect(err).NotTo(HaveOccurred())
+			client := app.Client(ctx, "solo-io", "gloo")
+			Expect(client.BaseURL.String()).To(Equal(fake.URL() + "/api/v3/"))
+			Expect(client.UploadURL.String()).To(Equal(fake.URL() + "/api/uploads/"))
+		}
+	})
+
+	It("sends requests through the configured transport and client options", func() {
+		var transported, optioned []string
+		app, err := githubutils.NewGithubApp(githubutils.GithubAppConfig{
+			AppID:      1234,
+			PrivateKey: privateKey,
+			BaseURL:    fake.URL(),
+			Transport: roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+				transported = append(transported, r.URL.Path)
+				return http.DefaultTransport.RoundTrip(r)
+			}),
+			ClientOptions: []githubutils.ClientOption{func(base http.RoundTripper) http.RoundTripper {
+				return roundTripperFunc(func(r *http.Request) (*http.Response, error) {
+					optioned = append(optioned, r.URL.Path)
+					return base.RoundTrip(r)
+				})
+			}},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		_, _, err = app.Client(ctx, "solo-io", "gloo").Repositories.Get(ctx, "solo-io", "gloo")
+		Expect(err).NotTo(HaveOccurred())
+
+		Expect(transported).To(Equal([]string{
+			"/api/v3/repos/solo-io/gloo/installation",
+			"/api/v3/app/installations/42/access_tokens",
+			"/api/v3/repos/solo-io/gloo",
+		}))
+		// the options are only of the app's own client
+		Expect(optioned).To(Equal(transported[:2]))
+	})
+
+	It("mints the tokens of different installations concurrently", func() {
+		app := newApp()
+		blockMint, mintStarted = make(chan struct{}), make(chan struct{}, 1)
+		blocked := make(chan error)
+		go func() {
+			_, err := app.InstallationToken(ctx, "solo-io", "gloo")
+			blocked <- err
+		}()
+		Eventually(mintStarted).Should(Receive())
+		token, err := app.InstallationToken(ctx, "someone", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(token.AccessToken).To(Equal("token-1"))
+
+		close(blockMint)
+		Eventually(blocked).Should(Receive(BeNil()))
+	})
+
+	It("falls back to the installation for a user", func() {
+		id, err := newApp().InstallationID(ctx, "someone", "")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(id).To(Equal(int64(7)))
+	})
+
+	It("reports apps that aren't installed and invalid keys", func() {
+		_, err := newApp().InstallationToken(ctx, "solo-io", "private")
+		Expect(err).To(MatchError(ContainSubstring("the Github app is not installed for solo-io/private")))
+
+		_, err = githubutils.NewGithubApp(githubutils.GithubAppConfig{AppID: 1234, PrivateKey: []byte("not a key")})
+		Expect(err).To(MatchError(ContainSubstring("invalid private key for Github app 1234")))
+	})
+
+	It("is configured by the environment", func() {
+		for name, value := range map[string]string{
+			githubutils.GITHUB_APP_ID:          "1234",
+			githubutils.GITHUB_APP_PRIVATE_KEY: string(privateKey),
+		} {
+			previous, set := os.LookupEnv(name)
+			Expect(os.Setenv(name, value)).To(Succeed())
+			DeferCleanup(func(name, previous string, set bool) {
+				if set {
+					os.Setenv(name, previous)
+				} else {
+					os.Unsetenv(name)
+				}
+			}, name, previous, set)
+		}
+		app, err := githubutils.GetGithubAppFromEnv()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(app).NotTo(BeNil())
+
+		Expect(os.Setenv(githubutils.GITHUB_APP_ID, "not a number")).To(Succeed())
+		_, err = githubutils.GetGithubAppFromEnv()
+		Expect(err).To(MatchError(ContainSubstring("invalid GITHUB_APP_ID not a number")))
+	})
+})
+
+type roundTripperFunc func(*http.Request) (*http.Response, error)
+
+func (f roundTripperFunc) RoundTrip(r *http.Request) (*http.Response, error) {
+	return f(r)
+}
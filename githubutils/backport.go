@@ -0,0 +1,313 @@
+package githubutils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/hashicorp/go-multierror"
+	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/versionutils"
+)
+
+const (
+	// statuses of the comparison of a release branch with a commit that it contains
+	COMPARISON_BEHIND    = "behind"
+	COMPARISON_IDENTICAL = "identical"
+)
+
+var (
+	// Release branches are named after the minor version they release, e.g. v1.14.x
+	releaseBranchRegex = regexp.MustCompile(`^v(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.x$`)
+
+	PRNotMergedError = func(number int) error {
+		return eris.Errorf("PR #%d is not merged", number)
+	}
+)
+
+// A branch that patch releases of a minor version are cut from, e.g. v1.14.x
+type ReleaseBranch struct {
+	Name string
+	// The minor version, e.g. v1.14.0 for v1.14.x
+	Version versionutils.Version
+	// The commit at the head of the branch
+	Sha string
+	// The greatest release of the minor version, empty if there is none yet
+	LatestTag string
+}
+
+// Lists the release branches of the repo, from the greatest minor version to the least, with the latest release
+// of each of them. If constraint is not nil, only the branches whose minor version satisfies it are listed.
+func ListReleaseBranches(ctx context.Context, client RepoClient, constraint *versionutils.Constraint) ([]*ReleaseBranch, error) {
+	branches, err := client.ListBranches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return listReleaseBranches(ctx, client, branches, constraint)
+}
+
+func listReleaseBranches(ctx context.Context, client RepoClient, branches []*github.Branch, constraint *versionutils.Constraint) ([]*ReleaseBranch, error) {
+	var versions []versionutils.Version
+	releaseBranches := map[versionutils.Version]*ReleaseBranch{}
+	for _, branch := range branches {
+		matches := releaseBranchRegex.FindStringSubmatch(branch.GetName())
+		if matches == nil {
+			continue
+		}
+		major, _ := strconv.Atoi(matches[1])
+		minor, _ := strconv.Atoi(matches[2])
+		version := versionutils.NewVersion(major, minor, 0, "", 0)
+		if constraint != nil && !constraint.Check(*version) {
+			continue
+		}
+		versions = append(versions, *version)
+		releaseBranches[*version] = &ReleaseBranch{
+			Name:    branch.GetName(),
+			Version: *version,
+			Sha:     branch.GetCommit().GetSHA(),
+		}
+	}
+	if len(versions) == 0 {
+		return nil, nil
+	}
+	versionutils.SortVersions(versions, nil)
+
+	releases, err := client.ListReleases(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var sorted []*ReleaseBranch
+	for _, version := range versions {
+		branch := releaseBranches[version]
+		minorConstraint, err := versionutils.ParseConstraint(fmt.Sprintf("~v%d.%d", version.Major, version.Minor))
+		if err != nil {
+			return nil, err
+		}
+		minorReleases := versionutils.FilterReleases(releases, minorConstraint)
+		if len(minorReleases) > 0 {
+			versionutils.SortReleases(minorReleases, nil)
+			branch.LatestTag = minorReleases[0].GetTagName()
+		}
+		sorted = append(sorted, branch)
+	}
+	return sorted, nil
+}
+
+type BackportOptions struct {
+	// Only backport to the release branches whose minor version satisfies the constraint, e.g. ">= v1.12"
+	// for the supported minor versions. All release branches are considered if nil.
+	Branches *versionutils.Constraint
+	// Name of the branch created for a backport, given the release branch and the commit.
+	// Defaults to backport/<release branch>/<short sha>.
+	BranchName func(releaseBranch *ReleaseBranch, sha string) string
+	// Report the branches and PRs that would be created, without creating them
+	DryRun bool
+}
+
+type Backport struct {
+	Branch *ReleaseBranch
+	// True if the release branch already contains the commit
+	Contained bool
+	// The branch the commit is cherry-picked onto, for release branches that don't contain it
+	BackportBranch string
+	// True if the backport branch already exists, e.g. from an earlier run that couldn't open the PR. It is
+	// reused as it is, rather than cherry-picked onto again.
+	BackportBranchExists bool
+	// Number and URL of the PR that was opened, when not a dry run
+	PRNumber int
+	PRUrl    string
+	// Set if the backport couldn't be opened, e.g. because the cherry-pick conflicts
+	Err error
+}
+
+// Which release branches contain a commit, and the backports to the others
+type BackportPlan struct {
+	Sha string
+	// Title of the backported change, used in the PRs
+	Title string
+	// The PR the commit was merged with, if known
+	PRNumber  int
+	Backports []*Backport
+
+	opts BackportOptions
+}
+
+// Returns the backports that are still needed
+func (p *BackportPlan) Missing() []*Backport {
+	var missing []*Backport
+	for _, backport := range p.Backports {
+		if !backport.Contained {
+			missing = append(missing, backport)
+		}
+	}
+	return missing
+}
+
+func (p *BackportPlan) RenderText(w io.Writer) error {
+	if _, err := fmt.Fprintf(w, "Backports of %s (%s):\n", p.Sha, p.Title); err != nil {
+		return err
+	}
+	for _, backport := range p.Backports {
+		latest := backport.Branch.LatestTag
+		if latest == "" {
+			latest = "unreleased"
+		}
+		var status string
+		switch {
+		case backport.Contained:
+			status = "already contains the change"
+		case backport.Err != nil:
+			status = fmt.Sprintf("failed: %v", backport.Err)
+		case backport.PRUrl != "":
+			status = fmt.Sprintf("opened %s", backport.PRUrl)
+		case p.opts.DryRun && backport.BackportBranchExists:
+			status = fmt.Sprintf("would open a PR from the existing %s", backport.BackportBranch)
+		case p.opts.DryRun:
+			status = fmt.Sprintf("would cherry-pick onto %s and open a PR", backport.BackportBranch)
+		default:
+			status = fmt.Sprintf("needs a backport on %s", backport.BackportBranch)
+		}
+		if _, err := fmt.Fprintf(w, "  %s (latest %s): %s\n", backport.Branch.Name, latest, status); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Reports which release branches already contain the commit that a merged PR was merged with
+func PlanPRBackport(ctx context.Context, client RepoClient, number int, opts BackportOptions) (*BackportPlan, error) {
+	pr, err := client.GetPR(ctx, number)
+	if err != nil {
+		return nil, err
+	}
+	if !pr.GetMerged() || pr.GetMergeCommitSHA() == "" {
+		return nil, PRNotMergedError(number)
+	}
+	plan, err := planBackport(ctx, client, pr.GetMergeCommitSHA(), fmt.Sprintf("%s (#%d)", pr.GetTitle(), number), opts)
+	if err != nil {
+		return nil, err
+	}
+	plan.PRNumber = number
+	return plan, nil
+}
+
+// Reports which release branches already contain a commit
+func PlanCommitBackport(ctx context.Context, client RepoClient, sha string, opts BackportOptions) (*BackportPlan, error) {
+	commit, err := client.GetCommit(ctx, sha)
+	if err != nil {
+		return nil, err
+	}
+	return planBackport(ctx, client, commit.GetSHA(), firstLine(commit.GetCommit().GetMessage()), opts)
+}
+
+func planBackport(ctx context.Context, client RepoClient, sha, title string, opts BackportOptions) (*BackportPlan, error) {
+	allBranches, err := client.ListBranches(ctx)
+	if err != nil {
+		return nil, err
+	}
+	branches, err := listReleaseBranches(ctx, client, allBranches, opts.Branches)
+	if err != nil {
+		return nil, err
+	}
+	existing := map[string]bool{}
+	for _, branch := range allBranches {
+		existing[branch.GetName()] = true
+	}
+	branchName := opts.BranchName
+	if branchName == nil {
+		branchName = defaultBackportBranchName
+	}
+	plan := &BackportPlan{
+		Sha:   sha,
+		Title: title,
+		opts:  opts,
+	}
+	for _, branch := range branches {
+		comparison, err := client.CompareCommits(ctx, branch.Name, sha)
+		if err != nil {
+			return nil, err
+		}
+		backport := &Backport{Branch: branch}
+		switch comparison.GetStatus() {
+		case COMPARISON_BEHIND, COMPARISON_IDENTICAL:
+			backport.Contained = true
+		default:
+			backport.BackportBranch = branchName(branch, sha)
+			backport.BackportBranchExists = existing[backport.BackportBranch]
+		}
+		plan.Backports = append(plan.Backports, backport)
+	}
+	return plan, nil
+}
+
+// Cherry-picks the commit onto a new branch for each release branch that doesn't contain it, and opens a PR
+// into the release branch. A failure is recorded on its backport and doesn't stop the others; the returned error
+// combines them. Does nothing in dry run mode.
+func (p *BackportPlan) Apply(ctx context.Context, client RepoClient) error {
+	if p.opts.DryRun {
+		return nil
+	}
+	var errs *multierror.Error
+	for _, backport := range p.Missing() {
+		if backport.PRUrl != "" {
+			continue
+		}
+		if backport.Err = p.open(ctx, client, backport); backport.Err != nil {
+			errs = multierror.Append(errs, eris.Wrapf(backport.Err, "unable to backport to %s", backport.Branch.Name))
+		}
+	}
+	return errs.ErrorOrNil()
+}
+
+func (p *BackportPlan) open(ctx context.Context, client RepoClient, backport *Backport) error {
+	if !backport.BackportBranchExists {
+		if err := p.cherryPick(ctx, client, backport); err != nil {
+			return err
+		}
+		backport.BackportBranchExists = true
+	}
+	body := fmt.Sprintf("Backport of %s to %s.", p.Sha, backport.Branch.Name)
+	if p.PRNumber != 0 {
+		body = fmt.Sprintf("Backport of #%d (%s) to %s.", p.PRNumber, p.Sha, backport.Branch.Name)
+	}
+	pr, err := client.CreatePRWithBase(ctx, backport.BackportBranch, backport.Branch.Name, PRSpec{
+		Message: fmt.Sprintf("[%s] %s", backport.Branch.Name, p.Title),
+		Body:    body,
+	})
+	if err != nil {
+		return err
+	}
+	backport.PRNumber = pr.GetNumber()
+	backport.PRUrl = pr.GetHTMLURL()
+	return nil
+}
+
+// Cherry-picks the commit onto a new backport branch, which is deleted again if the cherry-pick fails, so that a
+// later run starts over rather than reusing a branch without the change
+func (p *BackportPlan) cherryPick(ctx context.Context, client RepoClient, backport *Backport) error {
+	if _, err := client.CreateBranchFrom(ctx, backport.BackportBranch, backport.Branch.Name); err != nil {
+		return err
+	}
+	if _, err := client.CherryPickCommit(ctx, backport.BackportBranch, p.Sha); err != nil {
+		if deleteErr := client.DeleteBranch(ctx, backport.BackportBranch); deleteErr != nil {
+			return multierror.Append(err, eris.Wrapf(deleteErr, "unable to delete branch %s", backport.BackportBranch))
+		}
+		return err
+	}
+	return nil
+}
+
+func defaultBackportBranchName(releaseBranch *ReleaseBranch, sha string) string {
+	if len(sha) > 7 {
+		sha = sha[:7]
+	}
+	return fmt.Sprintf("backport/%s/%s", releaseBranch.Name, sha)
+}
+
+func firstLine(message string) string {
+	return strings.SplitN(message, "\n", 2)[0]
+}
@@ -0,0 +1,197 @@
+package githubutils_test
+
+import (
+	"bytes"
+	"context"
+
+	"github.com/google/go-github/v32/github"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/githubutils"
+	"github.com/solo-io/go-utils/versionutils"
+)
+
+// Implements the RepoClient methods used by the backport planner; the others panic
+type fakeBackportClient struct {
+	githubutils.RepoClient
+
+	branches []string
+	releases []string
+	// release branches that contain the commit
+	containing map[string]bool
+	// release branches that the commit can't be cherry-picked onto
+	conflicting map[string]bool
+
+	releaseListings int
+	createdBranches map[string]string
+	deletedBranches []string
+	cherryPicked    []string
+	prs             []*github.NewPullRequest
+}
+
+func (c *fakeBackportClient) ListBranches(_ context.Context) ([]*github.Branch, error) {
+	var branches []*github.Branch
+	for _, name := range c.branches {
+		branches = append(branches, &github.Branch{Name: github.String(name), Commit: &github.RepositoryCommit{SHA: github.String(name + "-sha")}})
+	}
+	return branches, nil
+}
+
+func (c *fakeBackportClient) ListReleases(_ context.Context) ([]*github.RepositoryRelease, error) {
+	c.releaseListings++
+	var releases []*github.RepositoryRelease
+	for _, tag := range c.releases {
+		releases = append(releases, &github.RepositoryRelease{TagName: github.String(tag)})
+	}
+	return releases, nil
+}
+
+func (c *fakeBackportClient) CompareCommits(_ context.Context, base, _ string) (*github.CommitsComparison, error) {
+	status := "diverged"
+	if c.containing[base] {
+		status = "behind"
+	}
+	return &github.CommitsComparison{Status: github.String(status)}, nil
+}
+
+func (c *fakeBackportClient) GetPR(_ context.Context, number int) (*github.PullRequest, error) {
+	return &github.PullRequest{
+		Number:         github.Int(number),
+		Title:          github.String("Fix the thing"),
+		Merged:         github.Bool(number == 42),
+		MergeCommitSHA: github.String("abcdef1234567890"),
+	}, nil
+}
+
+func (c *fakeBackportClient) CreateBranchFrom(_ context.Context, branchName, baseBranch string) (*github.Reference, error) {
+	c.createdBranches[branchName] = baseBranch
+	return &github.Reference{Ref: github.String("refs/heads/" + branchName)}, nil
+}
+
+func (c *fakeBackportClient) DeleteBranch(_ context.Context, branchName string) error {
+	c.deletedBranches = append(c.deletedBranches, branchName)
+	return nil
+}
+
+func (c *fakeBackportClient) CherryPickCommit(_ context.Context, branchName, sha string) (*github.Commit, error) {
+	if c.conflicting[c.createdBranches[branchName]] {
+		return nil, githubutils.CherryPickConflictError(eris.New("merge conflict"), sha, branchName)
+	}
+	c.cherryPicked = append(c.cherryPicked, branchName)
+	return &github.Commit{SHA: github.String("picked")}, nil
+}
+
+func (c *fakeBackportClient) CreatePRWithBase(_ context.Context, branchName, baseBranch string, spec githubutils.PRSpec) (*github.PullRequest, error) {
+	c.prs = append(c.prs, &github.NewPullRequest{
+		Title: github.String(spec.Message),
+		Head:  github.String(branchName),
+		Base:  github.String(baseBranch),
+		Body:  github.String(spec.Body),
+	})
+	return &github.PullRequest{Number: github.Int(100 + len(c.prs)), HTMLURL: github.String("https://github.com/solo-io/testrepo/pull/" + baseBranch)}, nil
+}
+
+var _ = Describe("backport planner", func() {
+
+	var (
+		ctx    = context.Background()
+		client *fakeBackportClient
+	)
+
+	BeforeEach(func() {
+		client = &fakeBackportClient{
+			branches:        []string{"master", "v1.12.x", "v1.14.x", "v1.13.x", "v1.15.x", "feature-v1.13.x"},
+			releases:        []string{"v1.12.9", "v1.13.2", "v1.13.10", "v1.14.0-beta1", "v1.14.0", "v1.15.0-beta2"},
+			containing:      map[string]bool{"v1.15.x": true},
+			conflicting:     map[string]bool{"v1.13.x": true},
+			createdBranches: map[string]string{},
+		}
+	})
+
+	It("lists the release branches with their latest releases", func() {
+		branches, err := githubutils.ListReleaseBranches(ctx, client, nil)
+		Expect(err).NotTo(HaveOccurred())
+		var names, latest []string
+		for _, branch := range branches {
+			names = append(names, branch.Name)
+			latest = append(latest, branch.LatestTag)
+		}
+		Expect(names).To(Equal([]string{"v1.15.x", "v1.14.x", "v1.13.x", "v1.12.x"}))
+		Expect(latest).To(Equal([]string{"", "v1.14.0", "v1.13.10", "v1.12.9"}))
+		Expect(branches[0].Sha).To(Equal("v1.15.x-sha"))
+		// the releases are listed once, not once per branch
+		Expect(client.releaseListings).To(Equal(1))
+	})
+
+	It("reports which branches contain a merged PR without changing anything in dry run mode", func() {
+		plan, err := githubutils.PlanPRBackport(ctx, client, 42, githubutils.BackportOptions{
+			Branches: versionutils.MustParseConstraint(">= v1.13"),
+			DryRun:   true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Missing()).To(HaveLen(2))
+		Expect(plan.Apply(ctx, client)).To(Succeed())
+		Expect(client.createdBranches).To(BeEmpty())
+
+		var out bytes.Buffer
+		Expect(plan.RenderText(&out)).To(Succeed())
+		Expect(out.String()).To(Equal(`Backports of abcdef1234567890 (Fix the thing (#42)):
+  v1.15.x (latest unreleased): already contains the change
+  v1.14.x (latest v1.14.0): would cherry-pick onto backport/v1.14.x/abcdef1 and open a PR
+  v1.13.x (latest v1.13.10): would cherry-pick onto backport/v1.13.x/abcdef1 and open a PR
+`))
+	})
+
+	It("opens the cherry-pick PRs", func() {
+		plan, err := githubutils.PlanPRBackport(ctx, client, 42, githubutils.BackportOptions{
+			Branches: versionutils.MustParseConstraint(">= v1.13"),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		err = plan.Apply(ctx, client)
+		Expect(err).To(MatchError(ContainSubstring("unable to backport to v1.13.x")))
+
+		Expect(client.createdBranches).To(Equal(map[string]string{
+			"backport/v1.14.x/abcdef1": "v1.14.x",
+			"backport/v1.13.x/abcdef1": "v1.13.x",
+		}))
+		Expect(client.cherryPicked).To(Equal([]string{"backport/v1.14.x/abcdef1"}))
+		Expect(client.prs).To(Equal([]*github.NewPullRequest{{
+			Title: github.String("[v1.14.x] Fix the thing (#42)"),
+			Head:  github.String("backport/v1.14.x/abcdef1"),
+			Base:  github.String("v1.14.x"),
+			Body:  github.String("Backport of #42 (abcdef1234567890) to v1.14.x."),
+		}}))
+		Expect(plan.Backports[1].PRNumber).To(Equal(101))
+		Expect(plan.Backports[2].Err).To(HaveOccurred())
+		// the branch of the failed cherry-pick is cleaned up
+		Expect(client.deletedBranches).To(Equal([]string{"backport/v1.13.x/abcdef1"}))
+	})
+
+	It("reuses a backport branch that already exists", func() {
+		client.branches = append(client.branches, "backport/v1.14.x/abcdef1")
+		plan, err := githubutils.PlanPRBackport(ctx, client, 42, githubutils.BackportOptions{
+			Branches: versionutils.MustParseConstraint("~v1.14"),
+			DryRun:   true,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		var out bytes.Buffer
+		Expect(plan.RenderText(&out)).To(Succeed())
+		Expect(out.String()).To(ContainSubstring("v1.14.x (latest v1.14.0): would open a PR from the existing backport/v1.14.x/abcdef1"))
+
+		plan, err = githubutils.PlanPRBackport(ctx, client, 42, githubutils.BackportOptions{
+			Branches: versionutils.MustParseConstraint("~v1.14"),
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(plan.Apply(ctx, client)).To(Succeed())
+		Expect(client.createdBranches).To(BeEmpty())
+		Expect(client.cherryPicked).To(BeEmpty())
+		Expect(client.prs).To(HaveLen(1))
+		Expect(client.prs[0].GetHead()).To(Equal("backport/v1.14.x/abcdef1"))
+	})
+
+	It("errors on PRs that aren't merged", func() {
+		_, err := githubutils.PlanPRBackport(ctx, client, 7, githubutils.BackportOptions{})
+		Expect(err).To(MatchError(githubutils.PRNotMergedError(7)))
+	})
+})
@@ -0,0 +1,259 @@
+package githubutils
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/hashicorp/go-multierror"
+	"github.com/rotisserie/eris"
+)
+
+const (
+	CHECK_STATUS_QUEUED      = "queued"
+	CHECK_STATUS_IN_PROGRESS = "in_progress"
+	CHECK_STATUS_COMPLETED   = "completed"
+
+	CHECK_CONCLUSION_SUCCESS         = "success"
+	CHECK_CONCLUSION_FAILURE         = "failure"
+	CHECK_CONCLUSION_NEUTRAL         = "neutral"
+	CHECK_CONCLUSION_CANCELLED       = "cancelled"
+	CHECK_CONCLUSION_SKIPPED         = "skipped"
+	CHECK_CONCLUSION_TIMED_OUT       = "timed_out"
+	CHECK_CONCLUSION_ACTION_REQUIRED = "action_required"
+
+	ANNOTATION_LEVEL_NOTICE  = "notice"
+	ANNOTATION_LEVEL_WARNING = "warning"
+	ANNOTATION_LEVEL_FAILURE = "failure"
+
+	// Github accepts at most 50 annotations per request; the rest are sent in further updates of the check run
+	MaxCheckRunAnnotationsPerRequest = 50
+	// Github shows at most 3 action buttons on a check run
+	MaxCheckRunActions = 3
+	// The maximum length of a check run's summary and text
+	maxCheckRunOutputLength = 65535
+)
+
+var (
+	MissingCheckRunFieldError = func(field string) error {
+		return eris.Errorf("check run must have a %s", field)
+	}
+	TooManyCheckRunActionsError = func(actions int) error {
+		return eris.Errorf("check run has %d actions, Github allows at most %d", actions, MaxCheckRunActions)
+	}
+	AddCheckRunAnnotationsError = func(err error, checkRunID int64) error {
+		return eris.Wrapf(err, "unable to add annotations to check run %d", checkRunID)
+	}
+)
+
+// Describes a check run, to create or update it. Empty fields are left unchanged by an update.
+type CheckRunSpec struct {
+	// The name of the check, e.g. "changelog"; required to create a check run
+	Name string
+	// The commit the check run is for; required to create a check run
+	HeadSHA string
+	// Links to the full details of the check, e.g. a CI build
+	DetailsURL string
+	// An ID of the check run in the system that runs it
+	ExternalID string
+	// One of the CHECK_STATUS_* constants. Setting a conclusion completes the check run.
+	Status string
+	// One of the CHECK_CONCLUSION_* constants
+	Conclusion string
+
+	// The title of the output, defaults to the name of the check
+	Title string
+	// Markdown summary shown at the top of the check run
+	Summary string
+	// Markdown details shown below the summary
+	Text string
+	// Line-level annotations; any number can be given, they are sent in batches
+	Annotations []*github.CheckRunAnnotation
+	// Buttons shown on the check run, at most MaxCheckRunActions. Clicking one sends a check_run webhook
+	// with the action's identifier.
+	Actions []*github.CheckRunAction
+}
+
+func (s CheckRunSpec) validate(create bool) error {
+	if create && s.Name == "" {
+		return MissingCheckRunFieldError("name")
+	}
+	if create && s.HeadSHA == "" {
+		return MissingCheckRunFieldError("head SHA")
+	}
+	if len(s.Actions) > MaxCheckRunActions {
+		return TooManyCheckRunActionsError(len(s.Actions))
+	}
+	return nil
+}
+
+func (s CheckRunSpec) hasOutput() bool {
+	return s.Title != "" || s.Summary != "" || s.Text != "" || len(s.Annotations) > 0
+}
+
+// Github requires a title and summary whenever the output is set
+func (s CheckRunSpec) output(annotations []*github.CheckRunAnnotation) *github.CheckRunOutput {
+	title := s.Title
+	if title == "" {
+		title = s.Name
+	}
+	output := &github.CheckRunOutput{
+		Title:       github.String(title),
+		Summary:     github.String(truncateCheckRunOutput(s.Summary)),
+		Annotations: annotations,
+	}
+	if s.Text != "" {
+		output.Text = github.String(truncateCheckRunOutput(s.Text))
+	}
+	return output
+}
+
+func truncateCheckRunOutput(markdown string) string {
+	if len(markdown) <= maxCheckRunOutputLength {
+		return markdown
+	}
+	const truncated = "\n\n_(truncated)_"
+	return markdown[:maxCheckRunOutputLength-len(truncated)] + truncated
+}
+
+func optionalString(s string) *string {
+	if s == "" {
+		return nil
+	}
+	return github.String(s)
+}
+
+// Creates a check run. If there are more annotations than Github accepts in one request, the rest are added
+// to the check run by further updates.
+func CreateCheckRun(ctx context.Context, client *github.Client, owner, repo string, spec CheckRunSpec) (*github.CheckRun, error) {
+	if err := spec.validate(true); err != nil {
+		return nil, err
+	}
+	first, rest := splitAnnotations(spec.Annotations)
+	opts := github.CreateCheckRunOptions{
+		Name:       spec.Name,
+		HeadSHA:    spec.HeadSHA,
+		DetailsURL: optionalString(spec.DetailsURL),
+		ExternalID: optionalString(spec.ExternalID),
+		Status:     optionalString(spec.Status),
+		Conclusion: optionalString(spec.Conclusion),
+		Actions:    spec.Actions,
+	}
+	if spec.Conclusion != "" {
+		opts.CompletedAt = &github.Timestamp{Time: time.Now()}
+	}
+	if spec.hasOutput() {
+		opts.Output = spec.output(first)
+	}
+	checkRun, _, err := client.Checks.CreateCheckRun(ctx, owner, repo, opts)
+	if err != nil {
+		return nil, err
+	}
+	return addCheckRunAnnotations(ctx, client, owner, repo, checkRun, spec, rest)
+}
+
+// Updates a check run. Annotations are added to the ones the check run already has, in batches if there are
+// more than Github accepts in one request. Setting any part of the output replaces the title, summary and text,
+// so they should be set along with the annotations.
+func UpdateCheckRun(ctx context.Context, client *github.Client, owner, repo string, checkRunID int64, spec CheckRunSpec) (*github.CheckRun, error) {
+	if err := spec.validate(false); err != nil {
+		return nil, err
+	}
+	if spec.Name == "" {
+		// the name is always sent, so keep the current one
+		current, _, err := client.Checks.GetCheckRun(ctx, owner, repo, checkRunID)
+		if err != nil {
+			return nil, err
+		}
+		spec.Name = current.GetName()
+	}
+	first, rest := splitAnnotations(spec.Annotations)
+	opts := github.UpdateCheckRunOptions{
+		Name:       spec.Name,
+		DetailsURL: optionalString(spec.DetailsURL),
+		ExternalID: optionalString(spec.ExternalID),
+		Status:     optionalString(spec.Status),
+		Conclusion: optionalString(spec.Conclusion),
+		Actions:    spec.Actions,
+	}
+	if spec.Conclusion != "" {
+		opts.CompletedAt = &github.Timestamp{Time: time.Now()}
+	}
+	if spec.hasOutput() {
+		opts.Output = spec.output(first)
+	}
+	checkRun, _, err := client.Checks.UpdateCheckRun(ctx, owner, repo, checkRunID, opts)
+	if err != nil {
+		return nil, err
+	}
+	return addCheckRunAnnotations(ctx, client, owner, repo, checkRun, spec, rest)
+}
+
+func splitAnnotations(annotations []*github.CheckRunAnnotation) (first, rest []*github.CheckRunAnnotation) {
+	if len(annotations) <= MaxCheckRunAnnotationsPerRequest {
+		return annotations, nil
+	}
+	return annotations[:MaxCheckRunAnnotationsPerRequest], annotations[MaxCheckRunAnnotationsPerRequest:]
+}
+
+// Github appends the annotations of each update to the ones the check run already has. The output is sent
+// again with each batch, since Github requires a title and summary with every annotation.
+func addCheckRunAnnotations(ctx context.Context, client *github.Client, owner, repo string, checkRun *github.CheckRun, spec CheckRunSpec, annotations []*github.CheckRunAnnotation) (*github.CheckRun, error) {
+	for len(annotations) > 0 {
+		var batch []*github.CheckRunAnnotation
+		batch, annotations = splitAnnotations(annotations)
+		updated, _, err := client.Checks.UpdateCheckRun(ctx, owner, repo, checkRun.GetID(), github.UpdateCheckRunOptions{
+			Name:   checkRun.GetName(),
+			Output: spec.output(batch),
+		})
+		if err != nil {
+			return nil, AddCheckRunAnnotationsError(err, checkRun.GetID())
+		}
+		checkRun = updated
+	}
+	return checkRun, nil
+}
+
+// Implemented by errors that know which file and lines they are about, so that they can be shown next to the
+// code on a check run, e.g. changelogutils.ViolationError and helmutils.WhiteSpaceError
+type CheckAnnotator interface {
+	GithubAnnotations() []*github.CheckRunAnnotation
+}
+
+// Maps errors to check run annotations. Errors combined with multierror (or errors.Join) are mapped one by one.
+// Returns the errors that don't implement CheckAnnotator separately, e.g. to list them in the summary.
+func AnnotateErrors(errs ...error) ([]*github.CheckRunAnnotation, []error) {
+	var (
+		annotations []*github.CheckRunAnnotation
+		unannotated []error
+	)
+	for _, err := range errs {
+		if err == nil {
+			continue
+		}
+		var (
+			multiErr  *multierror.Error
+			joined    interface{ Unwrap() []error }
+			annotator CheckAnnotator
+			nested    []error
+		)
+		// combined errors are checked first, since errors.As would only find the first annotator among them
+		switch {
+		case errors.As(err, &multiErr):
+			nested = multiErr.Errors
+		case errors.As(err, &joined):
+			nested = joined.Unwrap()
+		case errors.As(err, &annotator):
+			annotations = append(annotations, annotator.GithubAnnotations()...)
+			continue
+		default:
+			unannotated = append(unannotated, err)
+			continue
+		}
+		nestedAnnotations, nestedUnannotated := AnnotateErrors(nested...)
+		annotations = append(annotations, nestedAnnotations...)
+		unannotated = append(unannotated, nestedUnannotated...)
+	}
+	return annotations, unannotated
+}
@@ -0,0 +1,142 @@
+package githubutils_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/hashicorp/go-multierror"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/githubutils"
+)
+
+var _ = Describe("Check runs", func() {
+
+	var (
+		ctx    = context.Background()
+		fake   *fakeGithub
+		client *github.Client
+
+		// the bodies of the requests that aren't GETs
+		requests []map[string]interface{}
+	)
+
+	BeforeEach(func() {
+		requests = nil
+		fake = newFakeGithub(func(w http.ResponseWriter, r *http.Request) {
+			fake.Lock()
+			defer fake.Unlock()
+			if r.Method == http.MethodGet {
+				fmt.Fprint(w, `{"id":1,"name":"changelog"}`)
+				return
+			}
+			var body map[string]interface{}
+			Expect(json.NewDecoder(r.Body).Decode(&body)).To(Succeed())
+			requests = append(requests, body)
+			fmt.Fprintf(w, `{"id":1,"name":%q}`, body["name"])
+		})
+		client = fake.Client()
+	})
+
+	annotations := func(n int) []*github.CheckRunAnnotation {
+		var result []*github.CheckRunAnnotation
+		for i := 1; i <= n; i++ {
+			result = append(result, &github.CheckRunAnnotation{
+				Path:            github.String("changelog/v1.0.0/fix.yaml"),
+				StartLine:       github.Int(i),
+				EndLine:         github.Int(i),
+				AnnotationLevel: github.String(githubutils.ANNOTATION_LEVEL_FAILURE),
+				Message:         github.String("problem"),
+			})
+		}
+		return result
+	}
+
+	output := func(request map[string]interface{}) map[string]interface{} {
+		return request["output"].(map[string]interface{})
+	}
+
+	It("creates a check run, and adds annotations over the limit in batches", func() {
+		checkRun, err := githubutils.CreateCheckRun(ctx, client, "solo-io", "go-utils", githubutils.CheckRunSpec{
+			Name:        "changelog",
+			HeadSHA:     "abc123",
+			Conclusion:  githubutils.CHECK_CONCLUSION_FAILURE,
+			Summary:     "120 problems",
+			Text:        "## Details",
+			Annotations: annotations(120),
+			Actions: []*github.CheckRunAction{
+				{Label: "Fix", Description: "Fix the changelog", Identifier: "fix"},
+			},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(checkRun.GetID()).To(Equal(int64(1)))
+
+		Expect(fake.Paths()).To(Equal([]string{
+			"POST /repos/solo-io/go-utils/check-runs",
+			"PATCH /repos/solo-io/go-utils/check-runs/1",
+			"PATCH /repos/solo-io/go-utils/check-runs/1",
+		}))
+		Expect(requests[0]["head_sha"]).To(Equal("abc123"))
+		Expect(requests[0]["conclusion"]).To(Equal("failure"))
+		Expect(requests[0]["completed_at"]).NotTo(BeNil())
+		Expect(requests[0]["actions"]).To(HaveLen(1))
+		for i, batch := range []int{50, 50, 20} {
+			Expect(output(requests[i])["title"]).To(Equal("changelog"))
+			Expect(output(requests[i])["summary"]).To(Equal("120 problems"))
+			Expect(output(requests[i])["text"]).To(Equal("## Details"))
+			Expect(output(requests[i])["annotations"]).To(HaveLen(batch))
+		}
+		Expect(output(requests[2])["annotations"].([]interface{})[0].(map[string]interface{})["start_line"]).To(BeEquivalentTo(101))
+		// the batches only add annotations
+		Expect(requests[1]).NotTo(HaveKey("conclusion"))
+	})
+
+	It("updates a check run, keeping its name", func() {
+		_, err := githubutils.UpdateCheckRun(ctx, client, "solo-io", "go-utils", 1, githubutils.CheckRunSpec{
+			Status: githubutils.CHECK_STATUS_IN_PROGRESS,
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fake.Paths()).To(Equal([]string{
+			"GET /repos/solo-io/go-utils/check-runs/1",
+			"PATCH /repos/solo-io/go-utils/check-runs/1",
+		}))
+		Expect(requests[0]["name"]).To(Equal("changelog"))
+		Expect(requests[0]["status"]).To(Equal("in_progress"))
+		Expect(requests[0]).NotTo(HaveKey("output"))
+	})
+
+	It("validates check runs", func() {
+		_, err := githubutils.CreateCheckRun(ctx, client, "solo-io", "go-utils", githubutils.CheckRunSpec{HeadSHA: "abc123"})
+		Expect(err).To(MatchError(githubutils.MissingCheckRunFieldError("name").Error()))
+
+		actions := make([]*github.CheckRunAction, 4)
+		_, err = githubutils.UpdateCheckRun(ctx, client, "solo-io", "go-utils", 1, githubutils.CheckRunSpec{Name: "changelog", Actions: actions})
+		Expect(err).To(MatchError(githubutils.TooManyCheckRunActionsError(4).Error()))
+		Expect(fake.Paths()).To(BeEmpty())
+	})
+
+	It("maps errors to annotations", func() {
+		located := &annotatedError{annotations: annotations(2)}
+		err := fmt.Errorf("validation failed: %w", multierror.Append(located, eris.New("no location"), &annotatedError{annotations: annotations(1)}))
+		result, unannotated := githubutils.AnnotateErrors(err, nil, fmt.Errorf("wrapped: %w", located))
+		Expect(result).To(HaveLen(5))
+		Expect(unannotated).To(HaveLen(1))
+		Expect(unannotated[0]).To(MatchError("no location"))
+	})
+})
+
+type annotatedError struct {
+	annotations []*github.CheckRunAnnotation
+}
+
+func (e *annotatedError) Error() string {
+	return "annotated"
+}
+
+func (e *annotatedError) GithubAnnotations() []*github.CheckRunAnnotation {
+	return e.annotations
+}
@@ -0,0 +1,71 @@
+package githubutils_test
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"sync"
+
+	"github.com/google/go-github/v32/github"
+	. "github.com/onsi/ginkgo/v2"
+)
+
+// A fake Github API, serving each request with a handler and logging it. The server is closed when the spec
+// that created it ends. Handlers that share state with the spec lock the fake while they use it, and may block
+// before they do, e.g. to test concurrent requests.
+type fakeGithub struct {
+	sync.Mutex
+	server   *httptest.Server
+	requests []*http.Request
+}
+
+func newFakeGithub(handler http.HandlerFunc) *fakeGithub {
+	fake := &fakeGithub{}
+	fake.server = httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		defer GinkgoRecover()
+		fake.Lock()
+		fake.requests = append(fake.requests, r)
+		fake.Unlock()
+		handler(w, r)
+	}))
+	DeferCleanup(fake.server.Close)
+	return fake
+}
+
+func (f *fakeGithub) URL() string {
+	return f.server.URL
+}
+
+// Returns a client of the fake, which it also uploads to
+func (f *fakeGithub) Client() *github.Client {
+	return f.ClientWith(nil)
+}
+
+func (f *fakeGithub) ClientWith(httpClient *http.Client) *github.Client {
+	client := github.NewClient(httpClient)
+	client.BaseURL, _ = url.Parse(f.server.URL + "/")
+	client.UploadURL = client.BaseURL
+	return client
+}
+
+// Returns the requests served so far, oldest first
+func (f *fakeGithub) Requests() []*http.Request {
+	f.Lock()
+	defer f.Unlock()
+	return append([]*http.Request(nil), f.requests...)
+}
+
+// Returns the method and path of each request served so far, e.g. "GET /repos/solo-io/go-utils"
+func (f *fakeGithub) Paths() []string {
+	var paths []string
+	for _, r := range f.Requests() {
+		paths = append(paths, r.Method+" "+r.URL.Path)
+	}
+	return paths
+}
+
+func (f *fakeGithub) ResetRequests() {
+	f.Lock()
+	defer f.Unlock()
+	f.requests = nil
+}
@@ -0,0 +1,60 @@
+package githubutils
+
+import (
+	"context"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/rotisserie/eris"
+)
+
+const (
+	ISSUE_STATE_OPEN   = "open"
+	ISSUE_STATE_CLOSED = "closed"
+)
+
+// Works with issues across repos, e.g. the issues referenced by the changelog of a release
+type IssueClient interface {
+	GetIssue(ctx context.Context, owner, repo string, number int) (*github.Issue, error)
+	CreateIssueComment(ctx context.Context, owner, repo string, number int, body string) (*github.IssueComment, error)
+	CloseIssue(ctx context.Context, owner, repo string, number int) error
+}
+
+type issueClient struct {
+	client *github.Client
+}
+
+func NewIssueClient(client *github.Client) IssueClient {
+	return &issueClient{client: client}
+}
+
+func (c *issueClient) GetIssue(ctx context.Context, owner, repo string, number int) (*github.Issue, error) {
+	return GetIssue(ctx, c.client, owner, repo, number)
+}
+
+func (c *issueClient) CreateIssueComment(ctx context.Context, owner, repo string, number int, body string) (*github.IssueComment, error) {
+	return CreateIssueComment(ctx, c.client, owner, repo, number, body)
+}
+
+func (c *issueClient) CloseIssue(ctx context.Context, owner, repo string, number int) error {
+	return CloseIssue(ctx, c.client, owner, repo, number)
+}
+
+func GetIssue(ctx context.Context, client *github.Client, owner, repo string, number int) (*github.Issue, error) {
+	issue, _, err := client.Issues.Get(ctx, owner, repo, number)
+	if err != nil {
+		return nil, eris.Wrapf(err, "error getting issue %s/%s#%d", owner, repo, number)
+	}
+	return issue, nil
+}
+
+func CreateIssueComment(ctx context.Context, client *github.Client, owner, repo string, number int, body string) (*github.IssueComment, error) {
+	comment, _, err := client.Issues.CreateComment(ctx, owner, repo, number, &github.IssueComment{Body: github.String(body)})
+	if err != nil {
+		return nil, eris.Wrapf(err, "error commenting on issue %s/%s#%d", owner, repo, number)
+	}
+	return comment, nil
+}
+
+func CloseIssue(ctx context.Context, client *github.Client, owner, repo string, number int) error {
+	return UpdateIssue(ctx, client, owner, repo, number, &github.IssueRequest{State: github.String(ISSUE_STATE_CLOSED)})
+}
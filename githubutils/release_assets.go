@@ -0,0 +1,432 @@
+package githubutils
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"mime"
+	"net/http"
+	"net/url"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/avast/retry-go"
+	"github.com/google/go-github/v32/github"
+	"github.com/hashicorp/go-multierror"
+	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/errgroup"
+	"github.com/spf13/afero"
+)
+
+const (
+	DefaultChecksumsFile = "checksums.txt"
+
+	defaultAssetUploadConcurrency = 4
+)
+
+var (
+	ReadReleaseAssetError = func(err error, path string) error {
+		return eris.Wrapf(err, "unable to read release asset %s", path)
+	}
+	UploadReleaseAssetError = func(err error, name string) error {
+		return eris.Wrapf(err, "unable to upload release asset %s", name)
+	}
+	SignReleaseAssetError = func(err error, name string) error {
+		return eris.Wrapf(err, "unable to sign release asset %s", name)
+	}
+	DeleteReleaseAssetError = func(err error, name string) error {
+		return eris.Wrapf(err, "unable to delete release asset %s", name)
+	}
+	DownloadReleaseAssetError = func(err error, name string) error {
+		return eris.Wrapf(err, "unable to download release asset %s", name)
+	}
+	ReleaseAssetChecksumMismatchError = func(name, expected, actual string) error {
+		return eris.Errorf("release asset %s has sha256 %s after upload, expected %s", name, actual, expected)
+	}
+	ExistingReleaseAssetChecksumMismatchError = func(name, expected, actual string) error {
+		return eris.Errorf("release asset %s already exists with sha256 %s, expected %s", name, actual, expected)
+	}
+)
+
+// A local file to upload to a release
+type ReleaseAsset struct {
+	// Name of the asset on the release
+	Name string
+	// Path of the file to upload
+	Path string
+	// Also upload a <name>.sha256 file with the checksum of the asset
+	UploadSHA bool
+}
+
+// Produces detached signatures of release assets, e.g. with gpg or cosign
+type AssetSigner interface {
+	// Returns the signature of an asset's contents
+	Sign(ctx context.Context, name string, contents io.Reader) ([]byte, error)
+	// The extension appended to the name of an asset for the name of its signature, e.g. ".sig" or ".asc"
+	SignatureExtension() string
+}
+
+type commandSigner struct {
+	extension string
+	command   string
+	args      []string
+}
+
+// Signs assets by piping them to a command that writes the signature to stdout, e.g.
+// NewCommandSigner(".asc", "gpg", "--batch", "--armor", "--detach-sign", "--output", "-")
+func NewCommandSigner(extension, command string, args ...string) AssetSigner {
+	return &commandSigner{
+		extension: extension,
+		command:   command,
+		args:      args,
+	}
+}
+
+func (s *commandSigner) Sign(ctx context.Context, name string, contents io.Reader) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.CommandContext(ctx, s.command, s.args...)
+	cmd.Stdin = contents
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		return nil, eris.Wrapf(err, "%s failed: %s", s.command, strings.TrimSpace(stderr.String()))
+	}
+	return stdout.Bytes(), nil
+}
+
+func (s *commandSigner) SignatureExtension() string {
+	return s.extension
+}
+
+type ReleaseAssetOptions struct {
+	// Don't upload assets the release already has. If there is a checksums file, or Verify is set, the release's
+	// copies are downloaded so that the checksums file lists what the release has, and Verify checks that they
+	// match the local files.
+	SkipAlreadyExists bool
+	// Name of a file listing the sha256 of every asset, in the format of sha256sum, that is uploaded once all
+	// the assets are. Usually DefaultChecksumsFile; none is uploaded if empty. It replaces the release's
+	// existing checksums file, if any.
+	ChecksumsFile string
+	// Signs the assets and the checksums file, if set. The signatures are uploaded next to them.
+	Signer AssetSigner
+	// Download every uploaded file, and check that its sha256 matches the local one
+	Verify bool
+	// How many assets are uploaded at once, defaults to 4
+	Concurrency int
+}
+
+// A file uploaded to a release (or skipped because the release already had it)
+type UploadedReleaseAsset struct {
+	Name   string
+	SHA256 string
+	Size   int64
+	// The ID of the asset on the release
+	ID      int64
+	Skipped bool
+	// True if the asset was downloaded and its checksum matched the local file, after the upload or because it
+	// was skipped
+	Verified bool
+}
+
+type ReleaseAssetUploader struct {
+	fs     afero.Fs
+	client *github.Client
+	owner  string
+	repo   string
+	opts   ReleaseAssetOptions
+	// follows the redirect to the contents of downloaded assets
+	downloadClient *http.Client
+}
+
+// Uploads sets of release assets concurrently, returning errors rather than exiting
+func NewReleaseAssetUploader(client *github.Client, owner, repo string, opts ReleaseAssetOptions) *ReleaseAssetUploader {
+	return NewReleaseAssetUploaderForFs(afero.NewOsFs(), client, owner, repo, opts)
+}
+
+func NewReleaseAssetUploaderForFs(fs afero.Fs, client *github.Client, owner, repo string, opts ReleaseAssetOptions) *ReleaseAssetUploader {
+	if opts.Concurrency <= 0 {
+		opts.Concurrency = defaultAssetUploadConcurrency
+	}
+	return &ReleaseAssetUploader{
+		fs:             fs,
+		client:         client,
+		owner:          owner,
+		repo:           repo,
+		opts:           opts,
+		downloadClient: http.DefaultClient,
+	}
+}
+
+// Uploads the assets to the release, with their .sha256 files and signatures, then the checksums file.
+// Returns every file that was uploaded or skipped, in order, and an error combining the failure of each asset.
+// The checksums file isn't uploaded unless every asset was.
+func (u *ReleaseAssetUploader) Upload(ctx context.Context, release *github.RepositoryRelease, assets []ReleaseAsset) ([]*UploadedReleaseAsset, error) {
+	existing := map[string]int64{}
+	for _, asset := range release.Assets {
+		existing[asset.GetName()] = asset.GetID()
+	}
+
+	uploaded := make([][]*UploadedReleaseAsset, len(assets))
+	sem := make(chan struct{}, u.opts.Concurrency)
+	eg := errgroup.Group{}
+	for i, asset := range assets {
+		i, asset := i, asset
+		eg.Go(func() error {
+			sem <- struct{}{}
+			defer func() { <-sem }()
+			var err error
+			uploaded[i], err = u.uploadAsset(ctx, release, existing, asset)
+			return err
+		})
+	}
+	err := eg.Wait()
+
+	// the checksums file lists the assets, but not their .sha256 files or signatures
+	var result, checksummed []*UploadedReleaseAsset
+	for _, files := range uploaded {
+		result = append(result, files...)
+		if len(files) > 0 {
+			checksummed = append(checksummed, files[0])
+		}
+	}
+	if err != nil || u.opts.ChecksumsFile == "" {
+		return result, err
+	}
+
+	checksums, err := u.uploadChecksums(ctx, release, existing, checksummed)
+	return append(result, checksums...), err
+}
+
+func (u *ReleaseAssetUploader) uploadAsset(ctx context.Context, release *github.RepositoryRelease, existing map[string]int64, asset ReleaseAsset) ([]*UploadedReleaseAsset, error) {
+	sum, size, err := u.hashFile(asset.Path)
+	if err != nil {
+		return nil, err
+	}
+	open := func() (io.ReadCloser, error) {
+		return u.fs.Open(asset.Path)
+	}
+	uploaded := &UploadedReleaseAsset{Name: asset.Name, SHA256: sum, Size: size}
+	if u.opts.SkipAlreadyExists {
+		if id, ok := existing[asset.Name]; ok {
+			uploaded.ID, uploaded.Skipped = id, true
+			if u.opts.ChecksumsFile != "" || u.opts.Verify {
+				if err := u.hashExisting(ctx, uploaded); err != nil {
+					return nil, err
+				}
+			}
+			return []*UploadedReleaseAsset{uploaded}, nil
+		}
+	}
+	if err := u.upload(ctx, release, uploaded, open); err != nil {
+		return nil, err
+	}
+	result := []*UploadedReleaseAsset{uploaded}
+
+	if asset.UploadSHA {
+		// the format .sha256 files have always been uploaded with, so existing consumers can still parse them
+		contents := []byte(fmt.Sprintf("%s %s\n", sum, filepath.Base(asset.Path)))
+		shaFile, err := u.uploadBytes(ctx, release, asset.Name+".sha256", contents)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, shaFile)
+	}
+	if u.opts.Signer != nil {
+		signature, err := u.sign(ctx, release, asset.Name, open)
+		if err != nil {
+			return result, err
+		}
+		result = append(result, signature)
+	}
+	return result, nil
+}
+
+func (u *ReleaseAssetUploader) uploadChecksums(ctx context.Context, release *github.RepositoryRelease, existing map[string]int64, assets []*UploadedReleaseAsset) ([]*UploadedReleaseAsset, error) {
+	contents := []byte(ChecksumsFileContents(assets))
+	// the existing checksums file and its signature would conflict with the new ones
+	replaced := []string{u.opts.ChecksumsFile}
+	if u.opts.Signer != nil {
+		replaced = append(replaced, u.opts.ChecksumsFile+u.opts.Signer.SignatureExtension())
+	}
+	for _, name := range replaced {
+		if id, ok := existing[name]; ok {
+			if _, err := u.client.Repositories.DeleteReleaseAsset(ctx, u.owner, u.repo, id); err != nil {
+				return nil, DeleteReleaseAssetError(err, name)
+			}
+		}
+	}
+	checksums, err := u.uploadBytes(ctx, release, u.opts.ChecksumsFile, contents)
+	if err != nil {
+		return nil, err
+	}
+	result := []*UploadedReleaseAsset{checksums}
+	if u.opts.Signer != nil {
+		signature, err := u.sign(ctx, release, u.opts.ChecksumsFile, func() (io.ReadCloser, error) {
+			return io.NopCloser(bytes.NewReader(contents)), nil
+		})
+		if err != nil {
+			return result, err
+		}
+		result = append(result, signature)
+	}
+	return result, nil
+}
+
+// Returns the contents of a checksums file for the assets, one "<sha256>  <name>" line per asset sorted by name,
+// like the output of sha256sum
+func ChecksumsFileContents(assets []*UploadedReleaseAsset) string {
+	lines := make([]string, 0, len(assets))
+	for _, asset := range assets {
+		lines = append(lines, fmt.Sprintf("%s  %s\n", asset.SHA256, asset.Name))
+	}
+	sort.Strings(lines)
+	return strings.Join(lines, "")
+}
+
+func (u *ReleaseAssetUploader) sign(ctx context.Context, release *github.RepositoryRelease, name string, open func() (io.ReadCloser, error)) (*UploadedReleaseAsset, error) {
+	contents, err := open()
+	if err != nil {
+		return nil, SignReleaseAssetError(err, name)
+	}
+	defer contents.Close()
+	signature, err := u.opts.Signer.Sign(ctx, name, contents)
+	if err != nil {
+		return nil, SignReleaseAssetError(err, name)
+	}
+	return u.uploadBytes(ctx, release, name+u.opts.Signer.SignatureExtension(), signature)
+}
+
+func (u *ReleaseAssetUploader) uploadBytes(ctx context.Context, release *github.RepositoryRelease, name string, contents []byte) (*UploadedReleaseAsset, error) {
+	sum := sha256.Sum256(contents)
+	uploaded := &UploadedReleaseAsset{Name: name, SHA256: hex.EncodeToString(sum[:]), Size: int64(len(contents))}
+	err := u.upload(ctx, release, uploaded, func() (io.ReadCloser, error) {
+		return io.NopCloser(bytes.NewReader(contents)), nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return uploaded, nil
+}
+
+// Uploads the contents as the named asset with retries, then verifies it if enabled. Sets the asset's ID.
+func (u *ReleaseAssetUploader) upload(ctx context.Context, release *github.RepositoryRelease, asset *UploadedReleaseAsset, open func() (io.ReadCloser, error)) error {
+	// the contents are read again on each attempt, so failing to read them isn't retried
+	var readErr error
+	// Using default retry settings for now, 10 attempts, 100ms delay with backoff
+	err := retry.Do(func() error {
+		// we have seen when github is down and returns http 500 that it also closes the file we were trying to
+		// upload, hence the contents are opened again for each attempt
+		contents, err := open()
+		if err != nil {
+			readErr = ReadReleaseAssetError(err, asset.Name)
+			return readErr
+		}
+		defer contents.Close()
+		uploaded, err := u.uploadOnce(ctx, release, asset.Name, contents, asset.Size)
+		if err != nil {
+			// remove what may be a partial upload, so that the next attempt doesn't conflict with it
+			loadedRelease, _, loadErr := u.client.Repositories.GetRelease(ctx, u.owner, u.repo, release.GetID())
+			if loadErr != nil {
+				return multierror.Append(err, eris.Wrapf(loadErr, "unable to load release %d to remove a partial upload", release.GetID()))
+			}
+			if deleteErr := tryDeleteAsset(ctx, u.client, loadedRelease, u.owner, u.repo, asset.Name); deleteErr != nil {
+				return multierror.Append(err, DeleteReleaseAssetError(deleteErr, asset.Name))
+			}
+			return err
+		}
+		asset.ID = uploaded.GetID()
+		return nil
+	}, retry.LastErrorOnly(true), retry.RetryIf(func(error) bool {
+		return readErr == nil
+	}))
+	if readErr != nil {
+		return readErr
+	}
+	if err != nil {
+		return UploadReleaseAssetError(err, asset.Name)
+	}
+	if u.opts.Verify {
+		return u.verify(ctx, asset)
+	}
+	return nil
+}
+
+// Like client.Repositories.UploadReleaseAsset, but uploads from a reader rather than an *os.File
+func (u *ReleaseAssetUploader) uploadOnce(ctx context.Context, release *github.RepositoryRelease, name string, contents io.Reader, size int64) (*github.ReleaseAsset, error) {
+	path := fmt.Sprintf("repos/%s/%s/releases/%d/assets?name=%s", u.owner, u.repo, release.GetID(), url.QueryEscape(name))
+	mediaType := mime.TypeByExtension(filepath.Ext(name))
+	if mediaType == "" {
+		mediaType = "application/octet-stream"
+	}
+	req, err := u.client.NewUploadRequest(path, contents, size, mediaType)
+	if err != nil {
+		return nil, err
+	}
+	uploaded := new(github.ReleaseAsset)
+	if _, err := u.client.Do(ctx, req, uploaded); err != nil {
+		return nil, err
+	}
+	return uploaded, nil
+}
+
+func (u *ReleaseAssetUploader) verify(ctx context.Context, asset *UploadedReleaseAsset) error {
+	actual, _, err := u.download(ctx, asset)
+	if err != nil {
+		return err
+	}
+	if actual != asset.SHA256 {
+		return ReleaseAssetChecksumMismatchError(asset.Name, asset.SHA256, actual)
+	}
+	asset.Verified = true
+	return nil
+}
+
+// Sets the checksum and size of a skipped asset to those of the release's copy, which may not be the local file
+func (u *ReleaseAssetUploader) hashExisting(ctx context.Context, asset *UploadedReleaseAsset) error {
+	actual, size, err := u.download(ctx, asset)
+	if err != nil {
+		return err
+	}
+	if u.opts.Verify {
+		if actual != asset.SHA256 {
+			return ExistingReleaseAssetChecksumMismatchError(asset.Name, asset.SHA256, actual)
+		}
+		asset.Verified = true
+	}
+	asset.SHA256, asset.Size = actual, size
+	return nil
+}
+
+// Returns the sha256 and size of the asset on the release
+func (u *ReleaseAssetUploader) download(ctx context.Context, asset *UploadedReleaseAsset) (string, int64, error) {
+	contents, _, err := u.client.Repositories.DownloadReleaseAsset(ctx, u.owner, u.repo, asset.ID, u.downloadClient)
+	if err != nil {
+		return "", 0, DownloadReleaseAssetError(err, asset.Name)
+	}
+	defer contents.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, contents)
+	if err != nil {
+		return "", 0, DownloadReleaseAssetError(err, asset.Name)
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
+
+func (u *ReleaseAssetUploader) hashFile(path string) (string, int64, error) {
+	file, err := u.fs.Open(path)
+	if err != nil {
+		return "", 0, ReadReleaseAssetError(err, path)
+	}
+	defer file.Close()
+	h := sha256.New()
+	size, err := io.Copy(h, file)
+	if err != nil {
+		return "", 0, ReadReleaseAssetError(err, path)
+	}
+	return hex.EncodeToString(h.Sum(nil)), size, nil
+}
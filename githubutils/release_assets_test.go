@@ -0,0 +1,250 @@
+package githubutils_test
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v32/github"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/solo-io/go-utils/githubutils"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("ReleaseAssetUploader", func() {
+
+	var (
+		ctx    = context.Background()
+		fs     afero.Fs
+		fake   *fakeGithub
+		client *github.Client
+
+		// the contents of the release's assets by ID
+		contents map[int64][]byte
+		names    map[int64]string
+		nextID   int64
+		deleted  []int64
+		// names whose next upload fails
+		failOnce map[string]bool
+		// corrupts downloads, to fail verification
+		corrupt bool
+	)
+
+	sha := func(contents string) string {
+		sum := sha256.Sum256([]byte(contents))
+		return hex.EncodeToString(sum[:])
+	}
+
+	release := &github.RepositoryRelease{
+		ID: github.Int64(1),
+		Assets: []*github.ReleaseAsset{
+			{ID: github.Int64(100), Name: github.String("existing")},
+			{ID: github.Int64(101), Name: github.String(githubutils.DefaultChecksumsFile)},
+			{ID: github.Int64(102), Name: github.String(githubutils.DefaultChecksumsFile + ".sig")},
+		},
+	}
+
+	BeforeEach(func() {
+		fs = afero.NewMemMapFs()
+		Expect(afero.WriteFile(fs, "_output/hello", []byte("hello"), 0644)).To(Succeed())
+		Expect(afero.WriteFile(fs, "_output/world", []byte("world"), 0644)).To(Succeed())
+		Expect(afero.WriteFile(fs, "_output/existing", []byte("existing"), 0644)).To(Succeed())
+		contents = map[int64][]byte{100: []byte("existing"), 101: []byte("old checksums"), 102: []byte("old signature")}
+		names = map[int64]string{100: "existing", 101: githubutils.DefaultChecksumsFile, 102: githubutils.DefaultChecksumsFile + ".sig"}
+		nextID, deleted, failOnce, corrupt = 1, nil, map[string]bool{}, false
+
+		fake = newFakeGithub(func(w http.ResponseWriter, r *http.Request) {
+			fake.Lock()
+			defer fake.Unlock()
+			switch {
+			case r.Method == http.MethodPost && r.URL.Path == "/repos/solo-io/go-utils/releases/1/assets":
+				name := r.URL.Query().Get("name")
+				body, err := ioutil.ReadAll(r.Body)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(r.ContentLength).To(Equal(int64(len(body))))
+				if failOnce[name] {
+					failOnce[name] = false
+					w.WriteHeader(http.StatusInternalServerError)
+					return
+				}
+				for _, existing := range names {
+					if existing == name {
+						w.WriteHeader(http.StatusUnprocessableEntity)
+						fmt.Fprint(w, `{"message":"Validation Failed","errors":[{"resource":"ReleaseAsset","code":"already_exists","field":"name"}]}`)
+						return
+					}
+				}
+				id := nextID
+				nextID++
+				contents[id], names[id] = body, name
+				fmt.Fprintf(w, `{"id":%d,"name":%q}`, id, name)
+			case r.Method == http.MethodGet && r.URL.Path == "/repos/solo-io/go-utils/releases/1":
+				loaded := &github.RepositoryRelease{ID: github.Int64(1)}
+				for id, name := range names {
+					loaded.Assets = append(loaded.Assets, &github.ReleaseAsset{ID: github.Int64(id), Name: github.String(name)})
+				}
+				Expect(json.NewEncoder(w).Encode(loaded)).To(Succeed())
+			case r.Method == http.MethodGet && strings.HasPrefix(r.URL.Path, "/repos/solo-io/go-utils/releases/assets/"):
+				Expect(r.Header.Get("Accept")).To(Equal("application/octet-stream"))
+				id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/repos/solo-io/go-utils/releases/assets/"), 10, 64)
+				Expect(err).NotTo(HaveOccurred())
+				if corrupt {
+					fmt.Fprint(w, "corrupted")
+					return
+				}
+				w.Write(contents[id])
+			case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, "/repos/solo-io/go-utils/releases/assets/"):
+				id, err := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, "/repos/solo-io/go-utils/releases/assets/"), 10, 64)
+				Expect(err).NotTo(HaveOccurred())
+				deleted = append(deleted, id)
+				delete(contents, id)
+				delete(names, id)
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				Fail("unexpected request " + r.Method + " " + r.URL.String())
+			}
+		})
+		client = fake.Client()
+	})
+
+	uploadedContents := func() map[string]string {
+		result := map[string]string{}
+		for id, name := range names {
+			result[name] = string(contents[id])
+		}
+		return result
+	}
+
+	assets := []githubutils.ReleaseAsset{
+		{Name: "hello", Path: "_output/hello", UploadSHA: true},
+		{Name: "world", Path: "_output/world"},
+		{Name: "existing", Path: "_output/existing"},
+	}
+
+	It("uploads assets with checksums and signatures, and verifies them", func() {
+		failOnce["world"] = true
+		uploader := githubutils.NewReleaseAssetUploaderForFs(fs, client, "solo-io", "go-utils", githubutils.ReleaseAssetOptions{
+			SkipAlreadyExists: true,
+			ChecksumsFile:     githubutils.DefaultChecksumsFile,
+			Signer:            &fakeSigner{},
+			Verify:            true,
+		})
+		uploaded, err := uploader.Upload(ctx, release, assets)
+		Expect(err).NotTo(HaveOccurred())
+
+		var uploadedNames []string
+		for _, asset := range uploaded {
+			uploadedNames = append(uploadedNames, asset.Name)
+			Expect(asset.Verified).To(BeTrue())
+		}
+		Expect(uploadedNames).To(Equal([]string{
+			"hello", "hello.sha256", "hello.sig",
+			"world", "world.sig",
+			"existing",
+			"checksums.txt", "checksums.txt.sig",
+		}))
+		Expect(uploaded[5].Skipped).To(BeTrue())
+		Expect(uploaded[5].ID).To(Equal(int64(100)))
+		Expect(uploaded[0].SHA256).To(Equal(sha("hello")))
+		Expect(uploaded[0].Size).To(Equal(int64(5)))
+
+		lines := []string{sha("hello") + "  hello\n", sha("world") + "  world\n", sha("existing") + "  existing\n"}
+		sort.Strings(lines)
+		checksums := strings.Join(lines, "")
+		Expect(uploadedContents()).To(Equal(map[string]string{
+			"existing":          "existing",
+			"hello":             "hello",
+			"hello.sha256":      sha("hello") + " hello\n",
+			"hello.sig":         "signed hello " + sha("hello"),
+			"world":             "world",
+			"world.sig":         "signed world " + sha("world"),
+			"checksums.txt":     checksums,
+			"checksums.txt.sig": "signed checksums.txt " + sha(checksums),
+		}))
+		// the existing checksums file and its signature are deleted before the new ones are uploaded, rather than
+		// after their uploads conflict with them
+		Expect(deleted).To(Equal([]int64{101, 102}))
+		var checksumsUploads int
+		for _, r := range fake.Requests() {
+			if r.Method == http.MethodPost && strings.HasPrefix(r.URL.Query().Get("name"), githubutils.DefaultChecksumsFile) {
+				checksumsUploads++
+			}
+		}
+		Expect(checksumsUploads).To(Equal(2))
+	})
+
+	It("lists the checksums of the release's copies of skipped assets", func() {
+		Expect(afero.WriteFile(fs, "_output/existing", []byte("rebuilt"), 0644)).To(Succeed())
+		uploader := githubutils.NewReleaseAssetUploaderForFs(fs, client, "solo-io", "go-utils", githubutils.ReleaseAssetOptions{
+			SkipAlreadyExists: true,
+			ChecksumsFile:     githubutils.DefaultChecksumsFile,
+		})
+		uploaded, err := uploader.Upload(ctx, release, assets[2:])
+		Expect(err).NotTo(HaveOccurred())
+		Expect(uploaded).To(HaveLen(2))
+		Expect(uploaded[0].SHA256).To(Equal(sha("existing")))
+		Expect(uploaded[0].Verified).To(BeFalse())
+		Expect(uploadedContents()[githubutils.DefaultChecksumsFile]).To(Equal(sha("existing") + "  existing\n"))
+
+		uploader = githubutils.NewReleaseAssetUploaderForFs(fs, client, "solo-io", "go-utils", githubutils.ReleaseAssetOptions{
+			SkipAlreadyExists: true,
+			Verify:            true,
+		})
+		_, err = uploader.Upload(ctx, release, assets[2:])
+		Expect(err).To(MatchError(ContainSubstring(githubutils.ExistingReleaseAssetChecksumMismatchError("existing", sha("rebuilt"), sha("existing")).Error())))
+	})
+
+	It("returns errors instead of exiting", func() {
+		uploader := githubutils.NewReleaseAssetUploaderForFs(fs, client, "solo-io", "go-utils", githubutils.ReleaseAssetOptions{
+			ChecksumsFile: githubutils.DefaultChecksumsFile,
+		})
+		uploaded, err := uploader.Upload(ctx, release, []githubutils.ReleaseAsset{
+			{Name: "hello", Path: "_output/hello"},
+			{Name: "missing", Path: "_output/missing"},
+		})
+		Expect(err).To(MatchError(ContainSubstring("unable to read release asset _output/missing")))
+		Expect(uploaded).To(HaveLen(1))
+		// the checksums file isn't uploaded for an incomplete set of assets
+		Expect(uploadedContents()).To(HaveLen(4))
+		Expect(uploadedContents()[githubutils.DefaultChecksumsFile]).To(Equal("old checksums"))
+
+		corrupt = true
+		uploader = githubutils.NewReleaseAssetUploaderForFs(fs, client, "solo-io", "go-utils", githubutils.ReleaseAssetOptions{Verify: true})
+		_, err = uploader.Upload(ctx, release, assets[1:2])
+		Expect(err).To(MatchError(ContainSubstring(githubutils.ReleaseAssetChecksumMismatchError("world", sha("world"), sha("corrupted")).Error())))
+	})
+
+	It("signs with a command", func() {
+		signer := githubutils.NewCommandSigner(".sig", "sh", "-c", "printf signed; cat")
+		Expect(signer.SignatureExtension()).To(Equal(".sig"))
+		signature, err := signer.Sign(ctx, "hello", strings.NewReader("hello"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(string(signature)).To(Equal("signedhello"))
+
+		_, err = githubutils.NewCommandSigner(".sig", "sh", "-c", "echo no key >&2; exit 1").Sign(ctx, "hello", strings.NewReader("hello"))
+		Expect(err).To(MatchError(ContainSubstring("sh failed: no key")))
+	})
+})
+
+type fakeSigner struct{}
+
+func (s *fakeSigner) Sign(_ context.Context, name string, contents io.Reader) ([]byte, error) {
+	h := sha256.New()
+	if _, err := io.Copy(h, contents); err != nil {
+		return nil, err
+	}
+	return []byte(fmt.Sprintf("signed %s %s", name, hex.EncodeToString(h.Sum(nil)))), nil
+}
+
+func (s *fakeSigner) SignatureExtension() string {
+	return ".sig"
+}
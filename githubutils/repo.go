@@ -34,6 +34,7 @@ const (
 	COMMIT_FILE_STATUS_ADDED    = "added"
 	COMMIT_FILE_STATUS_MODIFIED = "modified"
 	COMMIT_FILE_STATUS_DELETED  = "deleted"
+	COMMIT_FILE_STATUS_RENAMED  = "renamed"
 
 	CONTENT_TYPE_FILE      = "file"
 	CONTENT_TYPE_DIRECTORY = "dir"
@@ -51,31 +52,51 @@ func GetGithubToken() (string, error) {
 	return token, nil
 }
 
-func GetClient(ctx context.Context) (*github.Client, error) {
+// Returns a client authenticated with GITHUB_TOKEN. Without it, a client authenticated as a Github App is returned
+// if GITHUB_APP_ID, GITHUB_APP_PRIVATE_KEY (or GITHUB_APP_PRIVATE_KEY_FILE) and GITHUB_APP_INSTALLATION_OWNER are set.
+func GetClient(ctx context.Context, options ...ClientOption) (*github.Client, error) {
 	token, err := GetGithubToken()
 	if err != nil {
+		if client, appErr := getGithubAppClientFromEnv(ctx, options); appErr != nil || client != nil {
+			return client, appErr
+		}
 		return nil, err
 	}
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
 	)
 	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
+	client := github.NewClient(withClientOptions(tc, options))
 	return client, nil
 }
 
-func GetClientWithOrWithoutToken(ctx context.Context) *github.Client {
+func GetClientWithOrWithoutToken(ctx context.Context, options ...ClientOption) *github.Client {
 	token, err := GetGithubToken()
 	if err != nil {
+		if client, appErr := getGithubAppClientFromEnv(ctx, options); client != nil {
+			return client
+		} else if appErr != nil {
+			err = appErr
+		}
 		logMsg := fmt.Sprintf("%v Private repositories will be unavailable and a strict rate limit will be enforced.", err.Error())
 		contextutils.LoggerFrom(ctx).Warnw(logMsg, zap.Error(err))
-		return github.NewClient(nil)
+		if len(options) == 0 {
+			return github.NewClient(nil)
+		}
+		return github.NewClient(withClientOptions(&http.Client{}, options))
 	}
 	ts := oauth2.StaticTokenSource(
 		&oauth2.Token{AccessToken: token},
 	)
 	tc := oauth2.NewClient(ctx, ts)
-	client := github.NewClient(tc)
+	client := github.NewClient(withClientOptions(tc, options))
+	return client
+}
+
+func withClientOptions(client *http.Client, options []ClientOption) *http.Client {
+	for _, option := range options {
+		client.Transport = option(client.Transport)
+	}
 	return client
 }
 
@@ -245,6 +266,22 @@ func FindLatestReleaseBySemver(ctx context.Context, client *github.Client, owner
 	return releases[0].GetName(), nil
 }
 
+// Like FindLatestReleaseBySemver, but only considers the releases whose tags satisfy the constraint,
+// e.g. "~v1.14" for the latest release of a minor version. Returns the tag of the release.
+func FindLatestReleaseBySemverWithConstraint(ctx context.Context, client *github.Client, owner, repo string, constraint *versionutils.Constraint) (string, error) {
+	releases, err := GetAllRepoReleases(ctx, client, owner, repo)
+	if err != nil {
+		return "", err
+	}
+	releases = versionutils.FilterReleases(releases, constraint)
+	if len(releases) == 0 {
+		// no release tags have been found, so the latest is "version zero"
+		return versionutils.SemverNilVersionValue, nil
+	}
+	versionutils.SortReleases(releases, nil)
+	return releases[0].GetTagName(), nil
+}
+
 func MarkInitialPending(ctx context.Context, client *github.Client, owner, repo, sha, description, label string) (*github.RepoStatus, error) {
 	return CreateStatus(ctx, client, owner, repo, sha, description, label, STATUS_PENDING)
 }
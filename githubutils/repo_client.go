@@ -2,6 +2,7 @@ package githubutils
 
 import (
 	"context"
+	"fmt"
 
 	"github.com/rotisserie/eris"
 
@@ -13,10 +14,23 @@ import (
 	"github.com/google/go-github/v32/github"
 )
 
-var NoReleaseBeforeShaFound = eris.Errorf("no release found before sha")
+var (
+	NoReleaseBeforeShaFound    = eris.Errorf("no release found before sha")
+	CherryPickMergeCommitError = func(sha string) error {
+		return eris.Errorf("commit %s is a merge commit and can't be cherry-picked", sha)
+	}
+	CherryPickConflictError = func(err error, sha, branchName string) error {
+		return eris.Wrapf(err, "unable to cherry-pick commit %s onto branch %s", sha, branchName)
+	}
+	CherryPickEmptyError = func(sha, branchName string) error {
+		return eris.Errorf("branch %s already has the changes of commit %s", branchName, sha)
+	}
+)
 
 type PRSpec struct {
+	// Title of the PR, and its body unless Body is set
 	Message string
+	Body    string
 }
 
 type RepoClient interface {
@@ -32,9 +46,18 @@ type RepoClient interface {
 	GetCommit(ctx context.Context, sha string) (*github.RepositoryCommit, error)
 	FindStatus(ctx context.Context, statusLabel, sha string) (*github.RepoStatus, error)
 	CreateStatus(ctx context.Context, sha string, status *github.RepoStatus) (*github.RepoStatus, error)
+	CreateCheckRun(ctx context.Context, spec CheckRunSpec) (*github.CheckRun, error)
+	UpdateCheckRun(ctx context.Context, checkRunID int64, spec CheckRunSpec) (*github.CheckRun, error)
 	CreateComment(ctx context.Context, pr int, comment *github.IssueComment) (*github.IssueComment, error)
 	DeleteComment(ctx context.Context, commentId int64) error
 	FindLatestTagIncludingPrereleaseBeforeSha(ctx context.Context, sha string) (string, error)
+	FindLatestReleaseBySemverWithConstraint(ctx context.Context, constraint *versionutils.Constraint) (string, error)
+	ListReleases(ctx context.Context) ([]*github.RepositoryRelease, error)
+	ListBranches(ctx context.Context) ([]*github.Branch, error)
+	CreateBranchFrom(ctx context.Context, branchName, baseBranch string) (*github.Reference, error)
+	DeleteBranch(ctx context.Context, branchName string) error
+	CreatePRWithBase(ctx context.Context, branchName, baseBranch string, spec PRSpec) (*github.PullRequest, error)
+	CherryPickCommit(ctx context.Context, branchName, sha string) (*github.Commit, error)
 }
 
 type repoClient struct {
@@ -134,19 +157,23 @@ func (c *repoClient) FileExists(ctx context.Context, sha, path string) (bool, er
 }
 
 func (c *repoClient) CreateBranch(ctx context.Context, branchName string) (*github.Reference, error) {
-	// get master branch reference
+	return c.CreateBranchFrom(ctx, branchName, "master")
+}
+
+func (c *repoClient) CreateBranchFrom(ctx context.Context, branchName, baseBranch string) (*github.Reference, error) {
+	// get base branch reference
 	// GitHub API docs: https://developer.github.com/v3/git/refs/#get-a-reference
-	masterRef, _, err := c.client.Git.GetRef(ctx, c.owner, c.repo, "refs/heads/master")
+	baseRef, _, err := c.client.Git.GetRef(ctx, c.owner, c.repo, "refs/heads/"+baseBranch)
 	if err != nil {
 		return nil, err
 	}
 
-	// create new branch from master branch
+	// create new branch from base branch
 	// GitHub API docs: https://developer.github.com/v3/git/refs/#create-a-reference
 	ref, _, err := c.client.Git.CreateRef(ctx, c.owner, c.repo, &github.Reference{
 		Ref: github.String("refs/heads/" + branchName),
 		Object: &github.GitObject{
-			SHA: masterRef.Object.SHA,
+			SHA: baseRef.Object.SHA,
 		},
 	})
 	if err != nil {
@@ -156,20 +183,134 @@ func (c *repoClient) CreateBranch(ctx context.Context, branchName string) (*gith
 }
 
 func (c *repoClient) CreatePR(ctx context.Context, branchName string, spec PRSpec) error {
+	_, err := c.CreatePRWithBase(ctx, branchName, "master", spec)
+	return err
+}
+
+func (c *repoClient) CreatePRWithBase(ctx context.Context, branchName, baseBranch string, spec PRSpec) (*github.PullRequest, error) {
+	body := spec.Body
+	if body == "" {
+		body = spec.Message
+	}
 	newPR := &github.NewPullRequest{
 		Title:               github.String(spec.Message),
 		Head:                github.String(branchName),
-		Base:                github.String("master"),
-		Body:                github.String(spec.Message),
+		Base:                github.String(baseBranch),
+		Body:                github.String(body),
 		MaintainerCanModify: github.Bool(true),
 	}
 	pr, _, err := c.client.PullRequests.Create(ctx, c.owner, c.repo, newPR)
 	if err != nil {
-		return err
+		return nil, err
 	}
 	contextutils.LoggerFrom(ctx).Infow("PR created",
 		zap.String("url", pr.GetHTMLURL()))
-	return nil
+	return pr, nil
+}
+
+func (c *repoClient) FindLatestReleaseBySemverWithConstraint(ctx context.Context, constraint *versionutils.Constraint) (string, error) {
+	return FindLatestReleaseBySemverWithConstraint(ctx, c.client, c.owner, c.repo, constraint)
+}
+
+func (c *repoClient) ListReleases(ctx context.Context) ([]*github.RepositoryRelease, error) {
+	return GetAllRepoReleases(ctx, c.client, c.owner, c.repo)
+}
+
+func (c *repoClient) ListBranches(ctx context.Context) ([]*github.Branch, error) {
+	var branches []*github.Branch
+	opts := &github.BranchListOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		page, resp, err := c.client.Repositories.ListBranches(ctx, c.owner, c.repo, opts)
+		if err != nil {
+			return nil, err
+		}
+		branches = append(branches, page...)
+		if resp.NextPage == 0 {
+			return branches, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// The Github API can't cherry-pick, so this applies the changes of the commit with a merge instead: a temporary
+// branch is created at a commit with the tree of the branch and the parent of the commit, so that merging the commit
+// into it only brings in the changes of the commit. The tree of that merge is committed on top of the branch, which
+// is then fast-forwarded to it. The branch itself is only moved once, and never if the cherry-pick fails.
+func (c *repoClient) CherryPickCommit(ctx context.Context, branchName, sha string) (*github.Commit, error) {
+	commit, _, err := c.client.Git.GetCommit(ctx, c.owner, c.repo, sha)
+	if err != nil {
+		return nil, err
+	}
+	if len(commit.Parents) != 1 {
+		return nil, CherryPickMergeCommitError(sha)
+	}
+	branchRef, _, err := c.client.Git.GetRef(ctx, c.owner, c.repo, "refs/heads/"+branchName)
+	if err != nil {
+		return nil, err
+	}
+	head, _, err := c.client.Git.GetCommit(ctx, c.owner, c.repo, branchRef.GetObject().GetSHA())
+	if err != nil {
+		return nil, err
+	}
+
+	sibling, _, err := c.client.Git.CreateCommit(ctx, c.owner, c.repo, &github.Commit{
+		Message: github.String("temporary commit to cherry-pick " + sha),
+		Tree:    head.Tree,
+		Parents: []*github.Commit{{SHA: commit.Parents[0].SHA}},
+	})
+	if err != nil {
+		return nil, err
+	}
+	tmpBranch := "tmp-cherry-pick-" + sha
+	if _, _, err := c.client.Git.CreateRef(ctx, c.owner, c.repo, &github.Reference{
+		Ref:    github.String("refs/heads/" + tmpBranch),
+		Object: &github.GitObject{SHA: sibling.SHA},
+	}); err != nil {
+		return nil, err
+	}
+	defer func() {
+		if err := c.DeleteBranch(ctx, tmpBranch); err != nil {
+			contextutils.LoggerFrom(ctx).Errorw("Unable to delete temporary cherry-pick branch",
+				zap.Error(err),
+				zap.String("branch", tmpBranch))
+		}
+	}()
+
+	merge, _, err := c.client.Repositories.Merge(ctx, c.owner, c.repo, &github.RepositoryMergeRequest{
+		Base:          github.String(tmpBranch),
+		Head:          github.String(sha),
+		CommitMessage: github.String("temporary merge to cherry-pick " + sha),
+	})
+	if err != nil {
+		return nil, CherryPickConflictError(err, sha, branchName)
+	}
+	if merge == nil || merge.GetCommit().Tree == nil {
+		// nothing was merged, i.e. the branch already has the changes of the commit
+		return nil, CherryPickEmptyError(sha, branchName)
+	}
+
+	picked, _, err := c.client.Git.CreateCommit(ctx, c.owner, c.repo, &github.Commit{
+		Message: github.String(fmt.Sprintf("%s\n\n(cherry picked from commit %s)", commit.GetMessage(), sha)),
+		Tree:    merge.GetCommit().Tree,
+		Parents: []*github.Commit{{SHA: head.SHA}},
+		Author:  commit.Author,
+	})
+	if err != nil {
+		return nil, err
+	}
+	// not forced, so this fails rather than dropping commits pushed to the branch in the meantime
+	if _, _, err := c.client.Git.UpdateRef(ctx, c.owner, c.repo, &github.Reference{
+		Ref:    github.String("refs/heads/" + branchName),
+		Object: &github.GitObject{SHA: picked.SHA},
+	}, false); err != nil {
+		return nil, err
+	}
+	return picked, nil
+}
+
+func (c *repoClient) DeleteBranch(ctx context.Context, branchName string) error {
+	_, err := c.client.Git.DeleteRef(ctx, c.owner, c.repo, "refs/heads/"+branchName)
+	return err
 }
 
 func (c *repoClient) GetShaForTag(ctx context.Context, tag string) (string, error) {
@@ -221,6 +362,14 @@ func (c *repoClient) CreateStatus(ctx context.Context, sha string, status *githu
 	return st, err
 }
 
+func (c *repoClient) CreateCheckRun(ctx context.Context, spec CheckRunSpec) (*github.CheckRun, error) {
+	return CreateCheckRun(ctx, c.client, c.owner, c.repo, spec)
+}
+
+func (c *repoClient) UpdateCheckRun(ctx context.Context, checkRunID int64, spec CheckRunSpec) (*github.CheckRun, error) {
+	return UpdateCheckRun(ctx, c.client, c.owner, c.repo, checkRunID, spec)
+}
+
 func (c *repoClient) CreateComment(ctx context.Context, pr int, comment *github.IssueComment) (*github.IssueComment, error) {
 	created, _, err := c.client.Issues.CreateComment(ctx, c.owner, c.repo, pr, comment)
 	return created, err
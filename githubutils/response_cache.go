@@ -0,0 +1,129 @@
+package githubutils
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"net/http"
+	"path/filepath"
+	"sync"
+
+	"github.com/rotisserie/eris"
+	"github.com/spf13/afero"
+)
+
+var (
+	WriteResponseCacheError = func(err error, path string) error {
+		return eris.Wrapf(err, "unable to write cached Github response %s", path)
+	}
+)
+
+// A response that can be revalidated with a conditional request
+type CachedResponse struct {
+	StatusCode int
+	Header     http.Header
+	Body       []byte
+}
+
+func (r *CachedResponse) etag() string {
+	return r.Header.Get("ETag")
+}
+
+func (r *CachedResponse) lastModified() string {
+	return r.Header.Get("Last-Modified")
+}
+
+// Stores the responses of the caching transport, keyed by request. Implementations must be safe for concurrent use.
+type ResponseCache interface {
+	// Returns false if no response is cached for the key
+	Get(key string) (*CachedResponse, bool)
+	Set(key string, response *CachedResponse) error
+}
+
+type memoryResponseCache struct {
+	mu        sync.RWMutex
+	responses map[string]*CachedResponse
+}
+
+// Caches responses for the lifetime of the process
+func NewMemoryResponseCache() ResponseCache {
+	return &memoryResponseCache{responses: map[string]*CachedResponse{}}
+}
+
+func (c *memoryResponseCache) Get(key string) (*CachedResponse, bool) {
+	c.mu.RLock()
+	defer c.mu.RUnlock()
+	response, ok := c.responses[key]
+	return response, ok
+}
+
+func (c *memoryResponseCache) Set(key string, response *CachedResponse) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.responses[key] = response
+	return nil
+}
+
+type diskResponseCache struct {
+	fs  afero.Fs
+	dir string
+}
+
+// Caches responses in a directory, one file per request, so that they can be revalidated by later runs,
+// e.g. of a nightly job
+func NewDiskResponseCache(dir string) ResponseCache {
+	return NewDiskResponseCacheForFs(afero.NewOsFs(), dir)
+}
+
+func NewDiskResponseCacheForFs(fs afero.Fs, dir string) ResponseCache {
+	return &diskResponseCache{
+		fs:  fs,
+		dir: dir,
+	}
+}
+
+func (c *diskResponseCache) path(key string) string {
+	sum := sha256.Sum256([]byte(key))
+	return filepath.Join(c.dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// An unreadable file is a cache miss; the response is cached again once it is fetched
+func (c *diskResponseCache) Get(key string) (*CachedResponse, bool) {
+	contents, err := afero.ReadFile(c.fs, c.path(key))
+	if err != nil {
+		return nil, false
+	}
+	var response CachedResponse
+	if err := json.Unmarshal(contents, &response); err != nil {
+		return nil, false
+	}
+	return &response, true
+}
+
+func (c *diskResponseCache) Set(key string, response *CachedResponse) error {
+	path := c.path(key)
+	contents, err := json.Marshal(response)
+	if err != nil {
+		return WriteResponseCacheError(err, path)
+	}
+	if err := c.fs.MkdirAll(c.dir, 0755); err != nil {
+		return WriteResponseCacheError(err, path)
+	}
+	// write then rename, so that concurrent readers never see a partial file
+	tmp, err := afero.TempFile(c.fs, c.dir, "response-*.tmp")
+	if err != nil {
+		return WriteResponseCacheError(err, path)
+	}
+	_, err = tmp.Write(contents)
+	if closeErr := tmp.Close(); err == nil {
+		err = closeErr
+	}
+	if err == nil {
+		err = c.fs.Rename(tmp.Name(), path)
+	}
+	if err != nil {
+		_ = c.fs.Remove(tmp.Name())
+		return WriteResponseCacheError(err, path)
+	}
+	return nil
+}
@@ -0,0 +1,222 @@
+package githubutils
+
+import (
+	"context"
+	"fmt"
+	"regexp"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/rotisserie/eris"
+)
+
+const (
+	DefaultStickyCommentPreviousVersions = 3
+
+	// Github rejects comments longer than this
+	maxCommentLength = 65536
+	// keeps the marker short enough that a comment always has room for its body
+	maxStickyCommentKeyLength = 100
+
+	stickyCommentHistoryMarker = "<!-- sticky-comment-history -->"
+	stickyCommentVersionMarker = "<!-- sticky-comment-version -->"
+)
+
+var (
+	InvalidStickyCommentKeyError = func(key string) error {
+		return eris.Errorf("invalid sticky comment key %q, must only contain letters, digits, '.', '_' and '-'", key)
+	}
+	StickyCommentKeyTooLongError = func(key string) error {
+		return eris.Errorf("sticky comment key %q is longer than %d characters", key, maxStickyCommentKeyLength)
+	}
+
+	stickyCommentKeyRegex = regexp.MustCompile(`^[\w.-]+$`)
+)
+
+type StickyCommentOptions struct {
+	// The login of the bot that posts the comment. If set, only its comments are considered, so a human quoting
+	// the comment doesn't create a second one.
+	Author string
+	// How many previous versions of the comment are kept, collapsed, below the current one.
+	// Defaults to DefaultStickyCommentPreviousVersions; negative keeps none.
+	PreviousVersions int
+}
+
+// A comment on a PR (or issue) that a bot keeps up to date, rather than posting a new comment on every push.
+// The comment is found by a hidden marker with its key, so several bots (or checks of one bot) can each keep
+// their own comment on a PR. The marker is found anywhere in the comment, so it survives edits that keep it,
+// and is written again with every update.
+type StickyComment struct {
+	client *github.Client
+	owner  string
+	repo   string
+	key    string
+	opts   StickyCommentOptions
+	marker *regexp.Regexp
+}
+
+func NewStickyComment(client *github.Client, owner, repo, key string, opts StickyCommentOptions) (*StickyComment, error) {
+	if !stickyCommentKeyRegex.MatchString(key) {
+		return nil, InvalidStickyCommentKeyError(key)
+	}
+	if len(key) > maxStickyCommentKeyLength {
+		return nil, StickyCommentKeyTooLongError(key)
+	}
+	if opts.PreviousVersions == 0 {
+		opts.PreviousVersions = DefaultStickyCommentPreviousVersions
+	}
+	return &StickyComment{
+		client: client,
+		owner:  owner,
+		repo:   repo,
+		key:    key,
+		opts:   opts,
+		marker: regexp.MustCompile(`<!--\s*sticky-comment:\s*` + regexp.QuoteMeta(key) + `\s*-->`),
+	}, nil
+}
+
+// Returns the sticky comments on the PR, oldest first. There is usually at most one, unless the comment was
+// posted concurrently or copied by hand.
+func (s *StickyComment) FindAll(ctx context.Context, pr int) ([]*github.IssueComment, error) {
+	var found []*github.IssueComment
+	opts := &github.IssueListCommentsOptions{ListOptions: github.ListOptions{PerPage: 100}}
+	for {
+		comments, resp, err := s.client.Issues.ListComments(ctx, s.owner, s.repo, pr, opts)
+		if err != nil {
+			return nil, err
+		}
+		for _, comment := range comments {
+			if s.matches(comment) {
+				found = append(found, comment)
+			}
+		}
+		if resp.NextPage == 0 {
+			return found, nil
+		}
+		opts.Page = resp.NextPage
+	}
+}
+
+// Returns the sticky comment on the PR, or nil if there is none
+func (s *StickyComment) Find(ctx context.Context, pr int) (*github.IssueComment, error) {
+	comments, err := s.FindAll(ctx, pr)
+	if err != nil || len(comments) == 0 {
+		return nil, err
+	}
+	return comments[0], nil
+}
+
+func (s *StickyComment) matches(comment *github.IssueComment) bool {
+	if s.opts.Author != "" && !strings.EqualFold(comment.GetUser().GetLogin(), s.opts.Author) {
+		return false
+	}
+	return s.marker.MatchString(comment.GetBody())
+}
+
+// Posts the body as the sticky comment on the PR, updating the existing comment in place. The version it
+// replaces is collapsed below it. Nothing is updated if the body hasn't changed.
+func (s *StickyComment) Update(ctx context.Context, pr int, body string) (*github.IssueComment, error) {
+	body = s.truncate(strings.TrimSpace(body))
+	existing, err := s.Find(ctx, pr)
+	if err != nil {
+		return nil, err
+	}
+	if existing == nil {
+		return s.create(ctx, pr, s.render(body, nil))
+	}
+	current, previous := s.parse(existing.GetBody())
+	if current == body {
+		return existing, nil
+	}
+	if current != "" {
+		previous = append([]string{current}, previous...)
+	}
+	edited, _, err := s.client.Issues.EditComment(ctx, s.owner, s.repo, existing.GetID(), &github.IssueComment{
+		Body: github.String(s.render(body, previous)),
+	})
+	return edited, err
+}
+
+func (s *StickyComment) create(ctx context.Context, pr int, body string) (*github.IssueComment, error) {
+	created, _, err := s.client.Issues.CreateComment(ctx, s.owner, s.repo, pr, &github.IssueComment{Body: github.String(body)})
+	return created, err
+}
+
+// Deletes the sticky comment from the PR, e.g. once the problem it reported is fixed. Does nothing if there
+// is no comment.
+func (s *StickyComment) Delete(ctx context.Context, pr int) error {
+	comments, err := s.FindAll(ctx, pr)
+	if err != nil {
+		return err
+	}
+	for _, comment := range comments {
+		if _, err := s.client.Issues.DeleteComment(ctx, s.owner, s.repo, comment.GetID()); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Renders the marker, the body, then as many previous versions as are kept and fit in a comment
+func (s *StickyComment) render(body string, previous []string) string {
+	if s.opts.PreviousVersions < 0 {
+		previous = nil
+	} else if len(previous) > s.opts.PreviousVersions {
+		previous = previous[:s.opts.PreviousVersions]
+	}
+	for {
+		rendered := s.renderVersions(body, previous)
+		if len(rendered) <= maxCommentLength || len(previous) == 0 {
+			return rendered
+		}
+		previous = previous[:len(previous)-1]
+	}
+}
+
+// Truncates a body that doesn't fit in a comment on its own
+func (s *StickyComment) truncate(body string) string {
+	excess := len(s.renderVersions(body, nil)) - maxCommentLength
+	if excess <= 0 {
+		return body
+	}
+	const truncated = "\n\n_(truncated)_"
+	end := len(body) - excess - len(truncated)
+	// don't cut a multi-byte character in half
+	for end > 0 && !utf8.RuneStart(body[end]) {
+		end--
+	}
+	return body[:end] + truncated
+}
+
+func (s *StickyComment) renderVersions(body string, previous []string) string {
+	var sb strings.Builder
+	fmt.Fprintf(&sb, "<!-- sticky-comment: %s -->\n%s\n", s.key, body)
+	if len(previous) == 0 {
+		return sb.String()
+	}
+	fmt.Fprintf(&sb, "\n%s\n<details>\n<summary>Previous versions</summary>\n", stickyCommentHistoryMarker)
+	for _, version := range previous {
+		fmt.Fprintf(&sb, "\n%s\n%s\n", stickyCommentVersionMarker, version)
+	}
+	sb.WriteString("</details>\n")
+	return sb.String()
+}
+
+// Returns the current version and the previous versions of a rendered comment. If a human edited the
+// comment so that the previous versions can't be found, they are considered part of the current one.
+func (s *StickyComment) parse(comment string) (string, []string) {
+	comment = strings.TrimSpace(s.marker.ReplaceAllString(comment, ""))
+	index := strings.Index(comment, stickyCommentHistoryMarker)
+	if index < 0 {
+		return comment, nil
+	}
+	current := strings.TrimSpace(comment[:index])
+	history := strings.TrimSpace(comment[index+len(stickyCommentHistoryMarker):])
+	history = strings.TrimSuffix(history, "</details>")
+	var previous []string
+	for _, version := range strings.Split(history, stickyCommentVersionMarker)[1:] {
+		previous = append(previous, strings.TrimSpace(version))
+	}
+	return current, previous
+}
@@ -0,0 +1,202 @@
+package githubutils_test
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"sort"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/google/go-github/v32/github"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/solo-io/go-utils/githubutils"
+)
+
+var _ = Describe("StickyComment", func() {
+
+	var (
+		ctx    = context.Background()
+		fake   *fakeGithub
+		client *github.Client
+
+		// the comments on PR 1 by ID
+		comments map[int64]*github.IssueComment
+		nextID   int64
+	)
+
+	addComment := func(login, body string) int64 {
+		nextID++
+		comments[nextID] = &github.IssueComment{
+			ID:   github.Int64(nextID),
+			Body: github.String(body),
+			User: &github.User{Login: github.String(login)},
+		}
+		return nextID
+	}
+
+	BeforeEach(func() {
+		comments, nextID = map[int64]*github.IssueComment{}, 0
+		fake = newFakeGithub(func(w http.ResponseWriter, r *http.Request) {
+			fake.Lock()
+			defer fake.Unlock()
+			const commentPrefix = "/repos/solo-io/go-utils/issues/comments/"
+			switch {
+			case r.Method == http.MethodGet && r.URL.Path == "/repos/solo-io/go-utils/issues/1/comments":
+				// one comment per page, to exercise pagination
+				var ids []int64
+				for id := range comments {
+					ids = append(ids, id)
+				}
+				sort.Slice(ids, func(i, j int) bool { return ids[i] < ids[j] })
+				page := 1
+				if p := r.URL.Query().Get("page"); p != "" {
+					page, _ = strconv.Atoi(p)
+				}
+				if page < len(ids) {
+					w.Header().Set("Link", fmt.Sprintf(`<%s%s?page=%d>; rel="next"`, fake.URL(), r.URL.Path, page+1))
+				}
+				var result []*github.IssueComment
+				if page <= len(ids) {
+					result = append(result, comments[ids[page-1]])
+				}
+				Expect(json.NewEncoder(w).Encode(result)).To(Succeed())
+			case r.Method == http.MethodPost && r.URL.Path == "/repos/solo-io/go-utils/issues/1/comments":
+				var comment github.IssueComment
+				Expect(json.NewDecoder(r.Body).Decode(&comment)).To(Succeed())
+				id := addComment("solo-bot", comment.GetBody())
+				Expect(json.NewEncoder(w).Encode(comments[id])).To(Succeed())
+			case r.Method == http.MethodPatch && strings.HasPrefix(r.URL.Path, commentPrefix):
+				id, _ := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, commentPrefix), 10, 64)
+				var comment github.IssueComment
+				Expect(json.NewDecoder(r.Body).Decode(&comment)).To(Succeed())
+				comments[id].Body = comment.Body
+				Expect(json.NewEncoder(w).Encode(comments[id])).To(Succeed())
+			case r.Method == http.MethodDelete && strings.HasPrefix(r.URL.Path, commentPrefix):
+				id, _ := strconv.ParseInt(strings.TrimPrefix(r.URL.Path, commentPrefix), 10, 64)
+				delete(comments, id)
+				w.WriteHeader(http.StatusNoContent)
+			default:
+				Fail("unexpected request " + r.Method + " " + r.URL.String())
+			}
+		})
+		client = fake.Client()
+	})
+
+	newStickyComment := func(opts githubutils.StickyCommentOptions) *githubutils.StickyComment {
+		sticky, err := githubutils.NewStickyComment(client, "solo-io", "go-utils", "changelog-bot", opts)
+		Expect(err).NotTo(HaveOccurred())
+		return sticky
+	}
+
+	It("updates the comment in place, collapsing previous versions", func() {
+		addComment("someone", "LGTM")
+		sticky := newStickyComment(githubutils.StickyCommentOptions{Author: "solo-bot", PreviousVersions: 2})
+
+		created, err := sticky.Update(ctx, 1, "v1")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(created.GetBody()).To(Equal("<!-- sticky-comment: changelog-bot -->\nv1\n"))
+
+		for _, body := range []string{"v2", "v3", "v4"} {
+			updated, err := sticky.Update(ctx, 1, body)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(updated.GetID()).To(Equal(created.GetID()))
+		}
+		Expect(comments).To(HaveLen(2))
+		Expect(comments[created.GetID()].GetBody()).To(Equal(`<!-- sticky-comment: changelog-bot -->
+v4
+
+<!-- sticky-comment-history -->
+<details>
+<summary>Previous versions</summary>
+
+<!-- sticky-comment-version -->
+v3
+
+<!-- sticky-comment-version -->
+v2
+</details>
+`))
+
+		// an unchanged body isn't posted again
+		fake.ResetRequests()
+		_, err = sticky.Update(ctx, 1, "v4\n")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fake.Paths()).To(Equal([]string{
+			"GET /repos/solo-io/go-utils/issues/1/comments",
+			"GET /repos/solo-io/go-utils/issues/1/comments",
+		}))
+	})
+
+	It("truncates a body that doesn't fit in a comment", func() {
+		sticky := newStickyComment(githubutils.StickyCommentOptions{})
+		_, err := sticky.Update(ctx, 1, "v1")
+		Expect(err).NotTo(HaveOccurred())
+
+		updated, err := sticky.Update(ctx, 1, strings.Repeat("a", 70000))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(updated.GetBody()).To(HaveLen(65536))
+		Expect(updated.GetBody()).To(HavePrefix("<!-- sticky-comment: changelog-bot -->\naaa"))
+		// the previous version doesn't fit either
+		Expect(updated.GetBody()).To(HaveSuffix("a\n\n_(truncated)_\n"))
+
+		// the truncated body is still unchanged
+		fake.ResetRequests()
+		_, err = sticky.Update(ctx, 1, strings.Repeat("a", 70000))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fake.Paths()).To(Equal([]string{"GET /repos/solo-io/go-utils/issues/1/comments"}))
+
+		// multi-byte characters are kept whole
+		updated, err = sticky.Update(ctx, 1, strings.Repeat("é", 35000))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(len(updated.GetBody())).To(BeNumerically("<=", 65536))
+		Expect(utf8.ValidString(updated.GetBody())).To(BeTrue())
+		Expect(updated.GetBody()).To(HaveSuffix("é\n\n_(truncated)_\n"))
+	})
+
+	It("finds the comment after a human edits it", func() {
+		id := addComment("solo-bot", "Please fix this\n\n<!--   sticky-comment:   changelog-bot -->\n(edited by a maintainer)")
+		// a human quoting the comment doesn't count, since they aren't the bot
+		addComment("someone", "> <!-- sticky-comment: changelog-bot -->")
+		sticky := newStickyComment(githubutils.StickyCommentOptions{Author: "solo-bot", PreviousVersions: -1})
+
+		found, err := sticky.Find(ctx, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found.GetID()).To(Equal(id))
+
+		_, err = sticky.Update(ctx, 1, "Fixed")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(comments[id].GetBody()).To(Equal("<!-- sticky-comment: changelog-bot -->\nFixed\n"))
+	})
+
+	It("deletes the comment when the condition clears", func() {
+		sticky := newStickyComment(githubutils.StickyCommentOptions{})
+		Expect(sticky.Delete(ctx, 1)).To(Succeed())
+
+		other, err := githubutils.NewStickyComment(client, "solo-io", "go-utils", "other-bot", githubutils.StickyCommentOptions{})
+		Expect(err).NotTo(HaveOccurred())
+		_, err = other.Update(ctx, 1, "other")
+		Expect(err).NotTo(HaveOccurred())
+		_, err = sticky.Update(ctx, 1, "problem")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(comments).To(HaveLen(2))
+
+		Expect(sticky.Delete(ctx, 1)).To(Succeed())
+		Expect(comments).To(HaveLen(1))
+		found, err := sticky.Find(ctx, 1)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(found).To(BeNil())
+	})
+
+	It("validates keys", func() {
+		_, err := githubutils.NewStickyComment(client, "solo-io", "go-utils", "not a -->", githubutils.StickyCommentOptions{})
+		Expect(err).To(MatchError(githubutils.InvalidStickyCommentKeyError("not a -->").Error()))
+
+		key := strings.Repeat("k", 101)
+		_, err = githubutils.NewStickyComment(client, "solo-io", "go-utils", key, githubutils.StickyCommentOptions{})
+		Expect(err).To(MatchError(githubutils.StickyCommentKeyTooLongError(key).Error()))
+	})
+})
@@ -0,0 +1,407 @@
+package githubutils
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"io/ioutil"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/solo-io/go-utils/contextutils"
+	"go.uber.org/zap"
+)
+
+const (
+	headerRateLimitRemaining = "X-RateLimit-Remaining"
+	headerRateLimitReset     = "X-RateLimit-Reset"
+	headerRetryAfter         = "Retry-After"
+
+	defaultMaxRetries       = 3
+	defaultInitialBackoff   = time.Second
+	defaultMaxBackoff       = time.Minute
+	defaultMaxRateLimitWait = time.Hour
+	// Github asks to wait at least a minute after a secondary rate limit without a Retry-After header
+	secondaryRateLimitWait = time.Minute
+)
+
+// Wraps the transport of the clients built by GetClient and GetClientWithOrWithoutToken
+type ClientOption func(base http.RoundTripper) http.RoundTripper
+
+// Sends the client's requests through a Transport with the given options
+func WithTransportOptions(opts TransportOptions) ClientOption {
+	return func(base http.RoundTripper) http.RoundTripper {
+		return NewTransport(base, opts)
+	}
+}
+
+type TransportOptions struct {
+	// Caches GET responses that have an ETag or Last-Modified header, and revalidates them with conditional
+	// requests. Github doesn't count a conditional request answered with 304 Not Modified against the rate limit.
+	// Nil disables caching.
+	Cache ResponseCache
+	// The longest the transport waits for a primary rate limit to reset before sending a request, or for a
+	// secondary rate limit to lift. A request that would have to wait longer gets the rate limited response.
+	// Defaults to an hour; negative never waits.
+	MaxRateLimitWait time.Duration
+	// How often a request is retried after a 5xx response, a secondary rate limit or a network error.
+	// Defaults to 3; negative never retries.
+	MaxRetries int
+	// The wait before the first retry, doubled for each one after it, up to MaxBackoff.
+	// Default to a second and a minute.
+	InitialBackoff, MaxBackoff time.Duration
+	// Waits for the given duration, or until the context is done. Defaults to a timer; tests may replace it.
+	Wait func(ctx context.Context, d time.Duration) error
+}
+
+// Counts of the requests sent through a Transport
+type TransportMetrics struct {
+	// Requests made by the client, however many times they were sent
+	Requests int64
+	// Requests answered from the cache after a 304 Not Modified
+	CacheHits int64
+	Retries   int64
+	// Responses with a 5xx status, including those that were retried
+	ServerErrors int64
+	// Times the transport waited for a rate limit, and for how long in total
+	RateLimitWaits    int64
+	RateLimitWaitTime time.Duration
+	// The last rate limit reported by Github, or -1 and the zero time if none was
+	RateLimitRemaining int
+	RateLimitReset     time.Time
+}
+
+// An http.RoundTripper for the Github API that caches responses with conditional requests, waits on primary and
+// secondary rate limits, retries 5xx responses with exponential backoff, and counts what it does.
+type Transport struct {
+	base http.RoundTripper
+	opts TransportOptions
+
+	mu      sync.Mutex
+	metrics TransportMetrics
+}
+
+func NewTransport(base http.RoundTripper, opts TransportOptions) *Transport {
+	if base == nil {
+		base = http.DefaultTransport
+	}
+	if opts.MaxRateLimitWait == 0 {
+		opts.MaxRateLimitWait = defaultMaxRateLimitWait
+	}
+	if opts.MaxRetries == 0 {
+		opts.MaxRetries = defaultMaxRetries
+	}
+	if opts.InitialBackoff == 0 {
+		opts.InitialBackoff = defaultInitialBackoff
+	}
+	if opts.MaxBackoff == 0 {
+		opts.MaxBackoff = defaultMaxBackoff
+	}
+	if opts.Wait == nil {
+		opts.Wait = wait
+	}
+	return &Transport{
+		base:    base,
+		opts:    opts,
+		metrics: TransportMetrics{RateLimitRemaining: -1},
+	}
+}
+
+func wait(ctx context.Context, d time.Duration) error {
+	timer := time.NewTimer(d)
+	defer timer.Stop()
+	select {
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-timer.C:
+		return nil
+	}
+}
+
+// Returns a snapshot of the transport's metrics
+func (t *Transport) Metrics() TransportMetrics {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.metrics
+}
+
+func (t *Transport) count(update func(metrics *TransportMetrics)) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	update(&t.metrics)
+}
+
+func (t *Transport) RoundTrip(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	t.count(func(m *TransportMetrics) { m.Requests++ })
+
+	key, cached := t.cachedResponse(req)
+	if cached != nil {
+		// RoundTrippers must not modify the request
+		req = req.Clone(ctx)
+		if etag := cached.etag(); etag != "" {
+			req.Header.Set("If-None-Match", etag)
+		} else {
+			req.Header.Set("If-Modified-Since", cached.lastModified())
+		}
+	}
+
+	resp, err := t.send(req)
+	if err != nil {
+		return nil, err
+	}
+	if cached != nil && resp.StatusCode == http.StatusNotModified {
+		t.count(func(m *TransportMetrics) { m.CacheHits++ })
+		return cachedHttpResponse(req, resp, cached), nil
+	}
+	if key != "" && resp.StatusCode == http.StatusOK {
+		return t.cacheResponse(ctx, key, resp)
+	}
+	return resp, nil
+}
+
+// Sends the request, waiting out rate limits and retrying failures
+func (t *Transport) send(req *http.Request) (*http.Response, error) {
+	ctx := req.Context()
+	for attempt := 0; ; attempt++ {
+		if err := t.waitForPrimaryRateLimit(ctx); err != nil {
+			return nil, err
+		}
+		if attempt > 0 {
+			t.count(func(m *TransportMetrics) { m.Retries++ })
+			if req.GetBody != nil {
+				body, err := req.GetBody()
+				if err != nil {
+					return nil, err
+				}
+				req = req.Clone(ctx)
+				req.Body = body
+			}
+		}
+		resp, err := t.base.RoundTrip(req)
+		retryable := attempt < t.opts.MaxRetries && (req.Body == nil || req.Body == http.NoBody || req.GetBody != nil)
+		if err != nil {
+			// the request may have been applied before the connection failed, so only idempotent ones are retried
+			if !retryable || !isIdempotent(req) || ctx.Err() != nil {
+				return nil, err
+			}
+			if waitErr := t.opts.Wait(ctx, t.backoff(attempt)); waitErr != nil {
+				return nil, err
+			}
+			continue
+		}
+		t.recordRateLimit(resp)
+
+		var delay time.Duration
+		switch {
+		case resp.StatusCode >= http.StatusInternalServerError:
+			t.count(func(m *TransportMetrics) { m.ServerErrors++ })
+			if !isIdempotent(req) {
+				// the request may have been applied anyway, e.g. a 502 of a proxy after Github created a comment
+				return resp, nil
+			}
+			delay = t.backoff(attempt)
+		case isRateLimited(resp) && resp.Header.Get(headerRateLimitRemaining) == "0":
+			// a primary rate limit: the next attempt waits for the reset, unless it is too far away
+			if _, ok := t.primaryRateLimitWait(); !ok {
+				return resp, nil
+			}
+		case isRateLimited(resp):
+			delay = secondaryRateLimitWait
+			if seconds, err := strconv.Atoi(resp.Header.Get(headerRetryAfter)); err == nil {
+				delay = time.Duration(seconds) * time.Second
+			}
+			if delay > t.opts.MaxRateLimitWait {
+				return resp, nil
+			}
+			t.count(func(m *TransportMetrics) {
+				m.RateLimitWaits++
+				m.RateLimitWaitTime += delay
+			})
+		default:
+			t.hideExhaustedRateLimit(resp)
+			return resp, nil
+		}
+		if !retryable {
+			return resp, nil
+		}
+		contextutils.LoggerFrom(ctx).Debugw("retrying Github request",
+			zap.String("url", req.URL.String()), zap.Int("status", resp.StatusCode), zap.Duration("delay", delay))
+		drain(resp)
+		if delay == 0 {
+			continue
+		}
+		if err := t.opts.Wait(ctx, delay); err != nil {
+			return nil, err
+		}
+	}
+}
+
+func (t *Transport) backoff(attempt int) time.Duration {
+	backoff := t.opts.InitialBackoff
+	for i := 0; i < attempt && backoff < t.opts.MaxBackoff; i++ {
+		backoff *= 2
+	}
+	if backoff > t.opts.MaxBackoff {
+		backoff = t.opts.MaxBackoff
+	}
+	return backoff
+}
+
+// Requests that are safe to send again after a server or network error. A rate limited request wasn't applied, so
+// it is retried whatever its method.
+func isIdempotent(req *http.Request) bool {
+	switch req.Method {
+	case http.MethodGet, http.MethodHead, http.MethodOptions:
+		return true
+	default:
+		return false
+	}
+}
+
+// Github reports rate limits with 403 Forbidden, or sometimes 429 Too Many Requests
+func isRateLimited(resp *http.Response) bool {
+	if resp.StatusCode == http.StatusTooManyRequests {
+		return true
+	}
+	if resp.StatusCode != http.StatusForbidden {
+		return false
+	}
+	if resp.Header.Get(headerRateLimitRemaining) == "0" || resp.Header.Get(headerRetryAfter) != "" {
+		return true
+	}
+	// a secondary (abuse) rate limit without a Retry-After header is only told apart by its message
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	if err != nil {
+		return false
+	}
+	message := strings.ToLower(string(body))
+	return strings.Contains(message, "secondary rate limit") || strings.Contains(message, "abuse")
+}
+
+func (t *Transport) recordRateLimit(resp *http.Response) {
+	remaining, err := strconv.Atoi(resp.Header.Get(headerRateLimitRemaining))
+	if err != nil {
+		return
+	}
+	reset, err := strconv.ParseInt(resp.Header.Get(headerRateLimitReset), 10, 64)
+	if err != nil {
+		return
+	}
+	t.count(func(m *TransportMetrics) {
+		m.RateLimitRemaining = remaining
+		m.RateLimitReset = time.Unix(reset, 0)
+	})
+}
+
+// Returns how long to wait for the primary rate limit to reset, and false if that's longer than MaxRateLimitWait
+func (t *Transport) primaryRateLimitWait() (time.Duration, bool) {
+	metrics := t.Metrics()
+	if metrics.RateLimitRemaining != 0 {
+		return 0, true
+	}
+	// Github's clock may be slightly ahead of ours
+	delay := time.Until(metrics.RateLimitReset) + time.Second
+	if delay <= 0 {
+		return 0, true
+	}
+	return delay, delay <= t.opts.MaxRateLimitWait
+}
+
+// go-github fails requests itself, without sending them, once a response reports that the rate limit is exhausted.
+// When the transport will wait for the reset instead, it hides the rate limit of the response from go-github;
+// it's still in the metrics.
+func (t *Transport) hideExhaustedRateLimit(resp *http.Response) {
+	if resp.Header.Get(headerRateLimitRemaining) != "0" {
+		return
+	}
+	if delay, ok := t.primaryRateLimitWait(); delay > 0 && ok {
+		resp.Header.Del(headerRateLimitRemaining)
+		resp.Header.Del(headerRateLimitReset)
+	}
+}
+
+func (t *Transport) waitForPrimaryRateLimit(ctx context.Context) error {
+	delay, ok := t.primaryRateLimitWait()
+	if delay == 0 || !ok {
+		// if the reset is too far away, the request is sent anyway and gets the rate limited response
+		return nil
+	}
+	contextutils.LoggerFrom(ctx).Infow("waiting for the Github rate limit to reset", zap.Duration("delay", delay))
+	t.count(func(m *TransportMetrics) {
+		m.RateLimitWaits++
+		m.RateLimitWaitTime += delay
+	})
+	if err := t.opts.Wait(ctx, delay); err != nil {
+		return err
+	}
+	t.count(func(m *TransportMetrics) { m.RateLimitRemaining = -1 })
+	return nil
+}
+
+// Returns the cache key of a cacheable request, and its cached response if there is one
+func (t *Transport) cachedResponse(req *http.Request) (string, *CachedResponse) {
+	if t.opts.Cache == nil || req.Method != http.MethodGet || req.Header.Get("Range") != "" {
+		return "", nil
+	}
+	// the same URL can be requested with different media types, e.g. raw file contents
+	key := req.Method + " " + req.URL.String() + " " + req.Header.Get("Accept")
+	cached, ok := t.opts.Cache.Get(key)
+	if !ok || (cached.etag() == "" && cached.lastModified() == "") {
+		return key, nil
+	}
+	return key, cached
+}
+
+func (t *Transport) cacheResponse(ctx context.Context, key string, resp *http.Response) (*http.Response, error) {
+	if resp.Header.Get("ETag") == "" && resp.Header.Get("Last-Modified") == "" {
+		return resp, nil
+	}
+	body, err := ioutil.ReadAll(resp.Body)
+	resp.Body.Close()
+	if err != nil {
+		return nil, err
+	}
+	resp.Body = ioutil.NopCloser(bytes.NewReader(body))
+	cached := &CachedResponse{
+		StatusCode: resp.StatusCode,
+		Header:     resp.Header.Clone(),
+		Body:       body,
+	}
+	if err := t.opts.Cache.Set(key, cached); err != nil {
+		// the response is still good, it just won't be revalidated next time
+		contextutils.LoggerFrom(ctx).Warnw("unable to cache Github response", zap.Error(err))
+	}
+	return resp, nil
+}
+
+// Rebuilds the cached response, with the fresh headers of the 304 response, e.g. the rate limit
+func cachedHttpResponse(req *http.Request, notModified *http.Response, cached *CachedResponse) *http.Response {
+	drain(notModified)
+	header := cached.Header.Clone()
+	for name, values := range notModified.Header {
+		header[name] = values
+	}
+	return &http.Response{
+		Status:        strconv.Itoa(cached.StatusCode) + " " + http.StatusText(cached.StatusCode),
+		StatusCode:    cached.StatusCode,
+		Proto:         notModified.Proto,
+		ProtoMajor:    notModified.ProtoMajor,
+		ProtoMinor:    notModified.ProtoMinor,
+		Header:        header,
+		Body:          ioutil.NopCloser(bytes.NewReader(cached.Body)),
+		ContentLength: int64(len(cached.Body)),
+		Request:       req,
+	}
+}
+
+// Reads the rest of the body and closes it, so that the connection can be reused
+func drain(resp *http.Response) {
+	_, _ = io.Copy(ioutil.Discard, resp.Body)
+	resp.Body.Close()
+}
@@ -0,0 +1,220 @@
+package githubutils_test
+
+import (
+	"context"
+	"fmt"
+	"net/http"
+	"strconv"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/solo-io/go-utils/githubutils"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("Transport", func() {
+
+	var (
+		ctx  = context.Background()
+		fake *fakeGithub
+		// responds to each request in turn, then with 200 OK
+		handlers []http.HandlerFunc
+		waits    []time.Duration
+	)
+
+	newClient := func(opts githubutils.TransportOptions) (*github.Client, *githubutils.Transport) {
+		opts.Wait = func(_ context.Context, d time.Duration) error {
+			waits = append(waits, d)
+			return nil
+		}
+		transport := githubutils.NewTransport(nil, opts)
+		return fake.ClientWith(&http.Client{Transport: transport}), transport
+	}
+
+	getRepo := func(client *github.Client) (*github.Repository, *github.Response, error) {
+		return client.Repositories.Get(ctx, "solo-io", "go-utils")
+	}
+
+	BeforeEach(func() {
+		handlers, waits = nil, nil
+		fake = newFakeGithub(func(w http.ResponseWriter, r *http.Request) {
+			fake.Lock()
+			var handler http.HandlerFunc
+			if len(handlers) > 0 {
+				handler, handlers = handlers[0], handlers[1:]
+			}
+			fake.Unlock()
+			if handler != nil {
+				handler(w, r)
+				return
+			}
+			w.Header().Set("X-RateLimit-Remaining", "4999")
+			w.Header().Set("X-RateLimit-Reset", strconv.FormatInt(time.Now().Add(time.Hour).Unix(), 10))
+			fmt.Fprint(w, `{"name":"go-utils"}`)
+		})
+	})
+
+	respond := func(status int, headers map[string]string, body string) http.HandlerFunc {
+		return func(w http.ResponseWriter, _ *http.Request) {
+			for name, value := range headers {
+				w.Header().Set(name, value)
+			}
+			w.WriteHeader(status)
+			fmt.Fprint(w, body)
+		}
+	}
+
+	It("revalidates cached responses with conditional requests", func() {
+		for _, cache := range []githubutils.ResponseCache{
+			githubutils.NewMemoryResponseCache(),
+			githubutils.NewDiskResponseCacheForFs(afero.NewMemMapFs(), "/cache"),
+		} {
+			fake.ResetRequests()
+			handlers = []http.HandlerFunc{
+				respond(http.StatusOK, map[string]string{"ETag": `"v1"`}, `{"name":"go-utils","description":"v1"}`),
+				func(w http.ResponseWriter, r *http.Request) {
+					Expect(r.Header.Get("If-None-Match")).To(Equal(`"v1"`))
+					w.Header().Set("X-RateLimit-Remaining", "4998")
+					w.WriteHeader(http.StatusNotModified)
+				},
+				respond(http.StatusOK, map[string]string{"ETag": `"v2"`}, `{"name":"go-utils","description":"v2"}`),
+			}
+			client, transport := newClient(githubutils.TransportOptions{Cache: cache})
+
+			repo, _, err := getRepo(client)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repo.GetDescription()).To(Equal("v1"))
+
+			repo, resp, err := getRepo(client)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(resp.StatusCode).To(Equal(http.StatusOK))
+			Expect(resp.Header.Get("X-RateLimit-Remaining")).To(Equal("4998"))
+			Expect(repo.GetDescription()).To(Equal("v1"))
+
+			repo, _, err = getRepo(client)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(repo.GetDescription()).To(Equal("v2"))
+			Expect(fake.Requests()[2].Header.Get("If-None-Match")).To(Equal(`"v1"`))
+
+			Expect(transport.Metrics().Requests).To(Equal(int64(3)))
+			Expect(transport.Metrics().CacheHits).To(Equal(int64(1)))
+		}
+	})
+
+	It("retries server errors with exponential backoff", func() {
+		handlers = []http.HandlerFunc{
+			respond(http.StatusBadGateway, nil, ""),
+			respond(http.StatusServiceUnavailable, nil, ""),
+			respond(http.StatusInternalServerError, nil, ""),
+		}
+		client, transport := newClient(githubutils.TransportOptions{InitialBackoff: time.Second, MaxBackoff: 3 * time.Second})
+		repo, _, err := getRepo(client)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(repo.GetName()).To(Equal("go-utils"))
+		Expect(waits).To(Equal([]time.Duration{time.Second, 2 * time.Second, 3 * time.Second}))
+
+		metrics := transport.Metrics()
+		Expect(metrics.Requests).To(Equal(int64(1)))
+		Expect(metrics.Retries).To(Equal(int64(3)))
+		Expect(metrics.ServerErrors).To(Equal(int64(3)))
+		Expect(metrics.RateLimitRemaining).To(Equal(4999))
+
+		handlers = []http.HandlerFunc{
+			respond(http.StatusBadGateway, nil, ""),
+			respond(http.StatusBadGateway, nil, ""),
+		}
+		client, _ = newClient(githubutils.TransportOptions{MaxRetries: 1})
+		_, resp, err := getRepo(client)
+		Expect(err).To(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadGateway))
+	})
+
+	It("retries rate limited posts with their body, but not failed ones", func() {
+		handlers = []http.HandlerFunc{respond(http.StatusTooManyRequests, map[string]string{"Retry-After": "1"}, "")}
+		client, _ := newClient(githubutils.TransportOptions{})
+		_, _, err := client.Issues.Create(ctx, "solo-io", "go-utils", &github.IssueRequest{Title: github.String("flaky")})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(fake.Paths()).To(Equal([]string{
+			"POST /repos/solo-io/go-utils/issues",
+			"POST /repos/solo-io/go-utils/issues",
+		}))
+
+		// the issue may have been created anyway
+		fake.ResetRequests()
+		handlers = []http.HandlerFunc{respond(http.StatusBadGateway, nil, "")}
+		_, resp, err := client.Issues.Create(ctx, "solo-io", "go-utils", &github.IssueRequest{Title: github.String("flaky")})
+		Expect(err).To(HaveOccurred())
+		Expect(resp.StatusCode).To(Equal(http.StatusBadGateway))
+		Expect(fake.Requests()).To(HaveLen(1))
+	})
+
+	It("waits on secondary rate limits", func() {
+		handlers = []http.HandlerFunc{
+			respond(http.StatusForbidden, map[string]string{"Retry-After": "30"}, `{"message":"You have exceeded a secondary rate limit."}`),
+			respond(http.StatusForbidden, nil, `{"message":"You have triggered an abuse detection mechanism."}`),
+		}
+		client, transport := newClient(githubutils.TransportOptions{})
+		_, _, err := getRepo(client)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(waits).To(Equal([]time.Duration{30 * time.Second, time.Minute}))
+		Expect(transport.Metrics().RateLimitWaits).To(Equal(int64(2)))
+		Expect(transport.Metrics().RateLimitWaitTime).To(Equal(90 * time.Second))
+
+		// a wait longer than allowed returns the rate limited response
+		waits = nil
+		handlers = []http.HandlerFunc{
+			respond(http.StatusForbidden, map[string]string{"Retry-After": "3600"}, `{"message":"You have exceeded a secondary rate limit."}`),
+		}
+		client, _ = newClient(githubutils.TransportOptions{MaxRateLimitWait: time.Minute})
+		_, _, err = getRepo(client)
+		Expect(err).To(HaveOccurred())
+		Expect(waits).To(BeEmpty())
+	})
+
+	It("waits for the primary rate limit to reset", func() {
+		reset := time.Now().Add(10 * time.Minute)
+		exhausted := map[string]string{
+			"X-RateLimit-Remaining": "0",
+			"X-RateLimit-Reset":     strconv.FormatInt(reset.Unix(), 10),
+		}
+		handlers = []http.HandlerFunc{
+			respond(http.StatusOK, exhausted, `{"name":"go-utils"}`),
+			respond(http.StatusForbidden, exhausted, `{"message":"API rate limit exceeded"}`),
+		}
+		client, transport := newClient(githubutils.TransportOptions{})
+
+		// the limit is exhausted by the first request, so the second one waits for the reset before it is sent
+		_, _, err := getRepo(client)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(waits).To(BeEmpty())
+		Expect(transport.Metrics().RateLimitRemaining).To(Equal(0))
+		Expect(transport.Metrics().RateLimitReset).To(Equal(time.Unix(reset.Unix(), 0)))
+
+		_, _, err = getRepo(client)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(waits).To(HaveLen(2))
+		for _, wait := range waits {
+			Expect(wait).To(BeNumerically("~", 10*time.Minute, 5*time.Second))
+		}
+		Expect(transport.Metrics().RateLimitWaits).To(Equal(int64(2)))
+		Expect(fake.Requests()).To(HaveLen(3))
+
+		// a reset further away than allowed returns the rate limited response
+		waits = nil
+		handlers = []http.HandlerFunc{
+			respond(http.StatusForbidden, exhausted, `{"message":"API rate limit exceeded"}`),
+		}
+		client, _ = newClient(githubutils.TransportOptions{MaxRateLimitWait: time.Minute})
+		_, _, err = getRepo(client)
+		Expect(err).To(BeAssignableToTypeOf(&github.RateLimitError{}))
+		Expect(waits).To(BeEmpty())
+	})
+
+	It("is a client option", func() {
+		options := githubutils.WithTransportOptions(githubutils.TransportOptions{})
+		Expect(options(http.DefaultTransport)).To(BeAssignableToTypeOf(&githubutils.Transport{}))
+		Expect(githubutils.GetClientWithOrWithoutToken(ctx, options)).NotTo(BeNil())
+	})
+})
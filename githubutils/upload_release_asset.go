@@ -2,15 +2,9 @@ package githubutils
 
 import (
 	"context"
-	"crypto/sha256"
-	"encoding/hex"
-	"io"
 	"io/ioutil"
-	"log"
-	"os"
 	"path/filepath"
 
-	"github.com/avast/retry-go"
 	"github.com/google/go-github/v32/github"
 	"github.com/solo-io/go-utils/contextutils"
 	"github.com/solo-io/go-utils/versionutils"
@@ -27,6 +21,10 @@ type UploadReleaseAssetSpec struct {
 	Repo              string
 	Assets            []ReleaseAssetSpec
 	SkipAlreadyExists bool
+	// Optional, see ReleaseAssetOptions
+	ChecksumsFile string
+	Signer        AssetSigner
+	Verify        bool
 }
 
 func UploadReleaseAssetCli(spec *UploadReleaseAssetSpec) {
@@ -38,75 +36,49 @@ func UploadReleaseAssetCli(spec *UploadReleaseAssetSpec) {
 }
 
 func uploadReleaseAssetsOrExit(ctx context.Context, client *github.Client, release *github.RepositoryRelease, spec *UploadReleaseAssetSpec) {
-	for _, assetSpec := range spec.Assets {
-		uploadReleaseAssetOrExit(ctx, client, release, spec, assetSpec)
+	var assets []ReleaseAsset
+	for _, asset := range spec.Assets {
+		assets = append(assets, ReleaseAsset{
+			Name:      asset.Name,
+			Path:      filepath.Join(asset.ParentPath, asset.Name),
+			UploadSHA: asset.UploadSHA,
+		})
 	}
-}
-
-func uploadReleaseAssetOrExit(ctx context.Context, client *github.Client, release *github.RepositoryRelease, spec *UploadReleaseAssetSpec, asset ReleaseAssetSpec) {
-	if spec.SkipAlreadyExists && assetAlreadyExists(release, asset.Name) {
-		return
-	}
-	path := filepath.Join(asset.ParentPath, asset.Name)
-	uploadFileOrExit(ctx, client, release, spec, asset.Name, path)
-	if asset.UploadSHA {
-		uploadShaOrExit(ctx, client, release, spec, asset)
-	}
-}
-
-func uploadShaOrExit(ctx context.Context, client *github.Client, release *github.RepositoryRelease, spec *UploadReleaseAssetSpec, asset ReleaseAssetSpec) {
-	path := filepath.Join(asset.ParentPath, asset.Name)
-	file, err := os.Open(path)
-	defer file.Close()
-	if err != nil {
-		log.Fatal(err)
-	}
-	shaName := asset.Name + ".sha256"
-	shaPath := filepath.Join(asset.ParentPath, shaName)
-	writeSha256OrExit(ctx, file, shaPath)
-	uploadFileOrExit(ctx, client, release, spec, shaName, shaPath)
-}
-
-func uploadFileOrExit(ctx context.Context, client *github.Client, release *github.RepositoryRelease, spec *UploadReleaseAssetSpec, name, path string) {
-	// Using default retry settings for now, 10 attempts, 100ms delay with backoff
-	err := retry.Do(func() error {
-		return tryUploadAsset(ctx, client, release, spec, name, path)
+	uploader := NewReleaseAssetUploader(client, spec.Owner, spec.Repo, ReleaseAssetOptions{
+		SkipAlreadyExists: spec.SkipAlreadyExists,
+		ChecksumsFile:     spec.ChecksumsFile,
+		Signer:            spec.Signer,
+		Verify:            spec.Verify,
 	})
-
+	uploaded, err := uploader.Upload(ctx, release, assets)
 	if err != nil {
 		contextutils.LoggerFrom(ctx).Fatalf("Error uploading assets. Error was: %s", err.Error())
 	}
+	writeShaFilesOrExit(ctx, spec, uploaded)
 }
 
-func tryUploadAsset(ctx context.Context, client *github.Client, release *github.RepositoryRelease, spec *UploadReleaseAssetSpec, name string, path string) error {
-
-	// we need to open/close the file here because it's in the retry loop
-	// we have seen when github is down and returns http 500 that it also closes the file we were trying to upload
-	// hence we need to ensure that the file is open before we attempt uploading the asset
-	file, err := os.Open(path)
-	defer file.Close()
-	if err != nil {
-		contextutils.LoggerFrom(ctx).Fatalf("Error reading file %s: %s", path, err.Error())
-	}
-
-	opts := &github.UploadOptions{
-		Name: name,
+// The .sha256 files used to be written next to the assets before they were uploaded, so keep writing them
+func writeShaFilesOrExit(ctx context.Context, spec *UploadReleaseAssetSpec, uploaded []*UploadedReleaseAsset) {
+	sums := make(map[string]string)
+	for _, asset := range uploaded {
+		sums[asset.Name] = asset.SHA256
 	}
-
-	_, _, err = client.Repositories.UploadReleaseAsset(ctx, spec.Owner, spec.Repo, release.GetID(), opts, file)
-	if err != nil {
-		loadedRelease, _, _ := client.Repositories.GetRelease(ctx, spec.Owner, spec.Repo, release.GetID())
-		if loadedRelease != nil {
-			tryDeleteAsset(ctx, client, loadedRelease, spec, name)
+	for _, asset := range spec.Assets {
+		if !asset.UploadSHA || sums[asset.Name+".sha256"] == "" {
+			continue
+		}
+		sha256String := sums[asset.Name] + " " + asset.Name + "\n"
+		err := ioutil.WriteFile(filepath.Join(asset.ParentPath, asset.Name+".sha256"), []byte(sha256String), 0700)
+		if err != nil {
+			contextutils.LoggerFrom(ctx).Fatal(err)
 		}
 	}
-	return err
 }
 
-func tryDeleteAsset(ctx context.Context, client *github.Client, release *github.RepositoryRelease, spec *UploadReleaseAssetSpec, name string) error {
+func tryDeleteAsset(ctx context.Context, client *github.Client, release *github.RepositoryRelease, owner, repo, name string) error {
 	for _, asset := range release.Assets {
 		if asset.GetName() == name {
-			_, err := client.Repositories.DeleteReleaseAsset(ctx, spec.Owner, spec.Repo, asset.GetID())
+			_, err := client.Repositories.DeleteReleaseAsset(ctx, owner, repo, asset.GetID())
 			if err != nil {
 				return err
 			}
@@ -115,27 +87,6 @@ func tryDeleteAsset(ctx context.Context, client *github.Client, release *github.
 	return nil
 }
 
-func writeSha256OrExit(ctx context.Context, file *os.File, outputPath string) {
-	h := sha256.New()
-	if _, err := io.Copy(h, file); err != nil {
-		contextutils.LoggerFrom(ctx).Fatal(err)
-	}
-	sha256String := hex.EncodeToString(h.Sum(nil)) + " " + filepath.Base(file.Name()) + "\n"
-	err := ioutil.WriteFile(outputPath, []byte(sha256String), 0700)
-	if err != nil {
-		contextutils.LoggerFrom(ctx).Fatal(err)
-	}
-}
-
-func assetAlreadyExists(release *github.RepositoryRelease, name string) bool {
-	for _, asset := range release.Assets {
-		if asset.GetName() == name {
-			return true
-		}
-	}
-	return false
-}
-
 func GetClientOrExit(ctx context.Context) *github.Client {
 	client, err := GetClient(ctx)
 	if err != nil {
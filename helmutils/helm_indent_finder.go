@@ -1,7 +1,11 @@
 package helmutils
 
 import (
+	"fmt"
 	"strings"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/hashicorp/go-multierror"
 )
 
 /*
@@ -24,10 +28,35 @@ import (
 // returns the windows of the helm chart that contain white spacing and formatting issues.
 func FindHelmChartWhiteSpaces(data string, opts HelmDetectOptions) [][]string {
 	lines := strings.Split(string(data), "\n")
+	badWindows := [][]string{}
+	for _, index := range findWhiteSpaceLines(lines, opts) {
+		badWindows = append(badWindows, Window(lines, index, 6))
+	}
+	return badWindows
+}
+
+// returns an error for each line of the helm chart in file that has white spacing and formatting issues,
+// combined into a single error, or nil. Each error is a *WhiteSpaceError, which can annotate the line on
+// a Github check run.
+func FindHelmChartWhiteSpaceErrors(file, data string, opts HelmDetectOptions) error {
+	lines := strings.Split(data, "\n")
+	var result *multierror.Error
+	for _, index := range findWhiteSpaceLines(lines, opts) {
+		result = multierror.Append(result, &WhiteSpaceError{
+			File:   file,
+			Line:   index + 1,
+			Window: Window(lines, index, 6),
+		})
+	}
+	return result.ErrorOrNil()
+}
+
+// returns the indexes of the lines with white spacing and formatting issues
+func findWhiteSpaceLines(lines []string, opts HelmDetectOptions) []int {
 	// we want to count each line, if the number of spaces at the begining is equal to 0, +2, or -2 from the previous line
 	// then we want to continue to the next line. Else we want to throw an error.
 	previous := previousInfo{NumOfSpaces: 0, BeganWithArray: false}
-	badWindows := [][]string{}
+	var badLines []int
 	specialBreak := false
 	for currentIndex, line := range lines {
 		s := NewSpaces(line)
@@ -93,11 +122,37 @@ func FindHelmChartWhiteSpaces(data string, opts HelmDetectOptions) [][]string {
 		if shouldContinue {
 			continue
 		} else {
-			windowLines := Window(lines, currentIndex, 6)
-			badWindows = append(badWindows, windowLines)
+			badLines = append(badLines, currentIndex)
 		}
 	}
-	return badWindows
+	return badLines
+}
+
+// WhiteSpaceError is a line of a helm chart with white spacing or formatting issues
+type WhiteSpaceError struct {
+	// path of the chart file, as it should be annotated
+	File string
+	// 1-based line with the issue
+	Line int
+	// the lines around the issue
+	Window []string
+}
+
+func (e *WhiteSpaceError) Error() string {
+	return fmt.Sprintf("%s:%d: unexpected indentation", e.File, e.Line)
+}
+
+// Implements githubutils.CheckAnnotator
+func (e *WhiteSpaceError) GithubAnnotations() []*github.CheckRunAnnotation {
+	return []*github.CheckRunAnnotation{{
+		Path:            github.String(e.File),
+		StartLine:       github.Int(e.Line),
+		EndLine:         github.Int(e.Line),
+		AnnotationLevel: github.String("failure"),
+		Title:           github.String("Unexpected indentation"),
+		Message:         github.String("Lines should be indented by at most 2 spaces more than the line above, or 4 below an array item."),
+		RawDetails:      github.String(strings.Join(e.Window, "\n")),
+	}}
 }
 
 // NewSpaces will return a new Spaces struct
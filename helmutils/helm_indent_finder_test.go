@@ -3,6 +3,7 @@ package helmutils_test
 import (
 	"fmt"
 
+	"github.com/hashicorp/go-multierror"
 	. "github.com/onsi/ginkgo/v2"
 	. "github.com/onsi/gomega"
 	"github.com/solo-io/go-utils/helmutils"
@@ -69,6 +70,31 @@ spec:
 			badWindows := helmutils.FindHelmChartWhiteSpaces(data, opts)
 			Expect(len(badWindows)).To(Equal(2))
 		})
+		It("should report the lines with white spaces as errors", func() {
+			data := `
+apiVersion: v1
+kind: Service
+metadata:
+   labels:
+    app: gloo
+    gloo: rate-limit
+spec:
+`
+			err := helmutils.FindHelmChartWhiteSpaceErrors("install/helm/gloo/templates/service.yaml", data, opts)
+			Expect(err).To(HaveOccurred())
+			errs := err.(*multierror.Error).Errors
+			Expect(errs).To(HaveLen(2))
+			whiteSpaceErr := errs[0].(*helmutils.WhiteSpaceError)
+			Expect(whiteSpaceErr.Line).To(Equal(5))
+			Expect(whiteSpaceErr.Error()).To(Equal("install/helm/gloo/templates/service.yaml:5: unexpected indentation"))
+			annotations := whiteSpaceErr.GithubAnnotations()
+			Expect(annotations).To(HaveLen(1))
+			Expect(annotations[0].GetPath()).To(Equal("install/helm/gloo/templates/service.yaml"))
+			Expect(annotations[0].GetStartLine()).To(Equal(5))
+			Expect(errs[1].(*helmutils.WhiteSpaceError).Line).To(Equal(6))
+
+			Expect(helmutils.FindHelmChartWhiteSpaceErrors("service.yaml", "spec:\n  ports: []\n", opts)).To(Succeed())
+		})
 		It("should detect white space at the end of an line", func() {
 			data := `
 apiVersion: v1
@@ -103,9 +103,9 @@ func (f *FormulaUpdater) Update(
 			Name: formulaOptions.Name,
 		}
 
-		// a version is not stable if it has a label, like "rc", "beta", etc.
+		// a version is not stable if it is a prerelease, like "rc", "beta", etc.
 		// in either case, silently mark it as updated and continue
-		if formulaOptions.DryRun || (version.Label != "" && !formulaOptions.PublishUnstableVersion) {
+		if formulaOptions.DryRun || (version.IsPrerelease() && !formulaOptions.PublishUnstableVersion) {
 			status.Updated = true
 			formulaStatuses = append(formulaStatuses, status)
 			continue
@@ -0,0 +1,400 @@
+package versionutils
+
+import (
+	"encoding/json"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/rotisserie/eris"
+)
+
+var (
+	InvalidConstraintError = func(constraint string, reason string) error {
+		return eris.Errorf("%s is not a valid version constraint: %s", constraint, reason)
+	}
+
+	// a version in a constraint, which may be partial (v1.2) or have wildcards (1.2.x)
+	constraintVersionRegex = regexp.MustCompile(`^v?(0|[1-9][0-9]*|[xX*])(?:\.(0|[1-9][0-9]*|[xX*]))?(?:\.(0|[1-9][0-9]*|[xX*]))?` +
+		`(?:-([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?(?:\+[0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*)?$`)
+	constraintOperatorRegex = regexp.MustCompile(`^(>=|<=|!=|==|=|>|<|~>|~|\^)?\s*`)
+)
+
+type ConstraintOptions struct {
+	// Tie-break order for labels, as used by IsGreaterThanWithLabelOrder. Labels ordered earlier are greater.
+	// Versions whose labels can't be compared to a bound don't satisfy it.
+	LabelOrder []string
+	// By default, a prerelease only satisfies a group of conditions if one of them is a prerelease of the same
+	// major, minor and patch version, so that ">= v1.8.0-beta1" matches v1.8.0-beta2 but "< v1.9.0" doesn't match
+	// v1.9.0-beta1. It must still be comparable to the bounds: v1.8.0-rc1 only matches ">= v1.8.0-beta1" with a
+	// LabelOrder that orders rc and beta. Set to compare prereleases like any other version.
+	IncludePrereleases bool
+}
+
+// A set of conditions on versions. The syntax is the one of most semver constraint libraries:
+//   - conditions are separated by commas or spaces and must all be satisfied, and groups of them are separated by "||"
+//   - operators are =, !=, >, >=, < and <=, and a version without one must be equal
+//   - ~v1.2.3 allows patch updates (>= v1.2.3, < v1.3.0), and ~v1 minor updates
+//   - ^v1.2.3 allows updates that don't change the left-most non-zero part (>= v1.2.3, < v2.0.0; ^v0.2.3 is < v0.3.0)
+//   - "v1.2 - v1.4.5" is an inclusive range
+//   - parts of a version can be omitted or be x, X or * to match any value, e.g. v1.2, v1.2.x or *
+//
+// The "v" prefix is optional.
+type Constraint struct {
+	source string
+	opts   ConstraintOptions
+	groups [][]condition
+}
+
+// A condition that a version must be within the bounds, or outside of them if negated. Nil bounds are unbounded.
+type condition struct {
+	lower, upper                   *Version
+	lowerInclusive, upperInclusive bool
+	negated                        bool
+}
+
+func ParseConstraint(constraint string) (*Constraint, error) {
+	return ParseConstraintWithOptions(constraint, ConstraintOptions{})
+}
+
+func ParseConstraintWithOptions(constraint string, opts ConstraintOptions) (*Constraint, error) {
+	c := &Constraint{
+		source: constraint,
+		opts:   opts,
+	}
+	for _, group := range strings.Split(constraint, "||") {
+		conditions, err := parseConditionGroup(group)
+		if err != nil {
+			return nil, InvalidConstraintError(constraint, err.Error())
+		}
+		c.groups = append(c.groups, conditions)
+	}
+	return c, nil
+}
+
+func MustParseConstraint(constraint string) *Constraint {
+	c, err := ParseConstraint(constraint)
+	if err != nil {
+		panic(err)
+	}
+	return c
+}
+
+func (c *Constraint) String() string {
+	return c.source
+}
+
+func (c *Constraint) MarshalJSON() ([]byte, error) {
+	return json.Marshal(c.source)
+}
+
+// Returns true if the version satisfies all of the conditions of at least one group
+func (c *Constraint) Check(v Version) bool {
+	for _, group := range c.groups {
+		if c.checkGroup(group, v) {
+			return true
+		}
+	}
+	return false
+}
+
+// Returns true if the tag is a version that satisfies the constraint
+func (c *Constraint) CheckTag(tag string) bool {
+	v, err := ParseVersion(tag)
+	return err == nil && c.Check(*v)
+}
+
+func (c *Constraint) checkGroup(group []condition, v Version) bool {
+	if v.IsPrerelease() && !c.opts.IncludePrereleases && !allowsPrerelease(group, v) {
+		return false
+	}
+	for _, cond := range group {
+		if !c.checkCondition(cond, v) {
+			return false
+		}
+	}
+	return true
+}
+
+func allowsPrerelease(group []condition, v Version) bool {
+	for _, cond := range group {
+		for _, bound := range []*Version{cond.lower, cond.upper} {
+			if bound != nil && bound.IsPrerelease() &&
+				bound.Major == v.Major && bound.Minor == v.Minor && bound.Patch == v.Patch {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func (c *Constraint) checkCondition(cond condition, v Version) bool {
+	within := true
+	if cond.lower != nil {
+		result, determinable := CompareWithLabelOrder(v, *cond.lower, c.opts.LabelOrder)
+		within = determinable && (result > 0 || (result == 0 && cond.lowerInclusive))
+	}
+	if within && cond.upper != nil {
+		result, determinable := CompareWithLabelOrder(v, *cond.upper, c.opts.LabelOrder)
+		within = determinable && (result < 0 || (result == 0 && cond.upperInclusive))
+	}
+	if cond.negated {
+		return !within
+	}
+	return within
+}
+
+// Returns -1, 0 or 1 if the version is less than, equal to or greater than the other version, and whether that is
+// determinable: like IsGreaterThanWithLabelOrder, versions with different labels are only comparable if both labels
+// are in the label order.
+func CompareWithLabelOrder(v, other Version, labelOrder []string) (int, bool) {
+	if v.Compare(other) == 0 {
+		return 0, true
+	}
+	if greater, determinable := v.IsGreaterThanWithLabelOrder(other, labelOrder); determinable && greater {
+		return 1, true
+	}
+	if greater, determinable := other.IsGreaterThanWithLabelOrder(v, labelOrder); determinable && greater {
+		return -1, true
+	}
+	return 0, false
+}
+
+func parseConditionGroup(group string) ([]condition, error) {
+	group = strings.TrimSpace(group)
+	if group == "" {
+		return nil, eris.New("empty group of conditions")
+	}
+	// an inclusive range, e.g. "v1.2 - v1.4.5"
+	if parts := strings.Split(group, " - "); len(parts) == 2 {
+		lower, err := parsePartialVersion(strings.TrimSpace(parts[0]))
+		if err != nil {
+			return nil, err
+		}
+		upper, err := parsePartialVersion(strings.TrimSpace(parts[1]))
+		if err != nil {
+			return nil, err
+		}
+		cond := condition{lower: lower.floor(), lowerInclusive: true}
+		cond.upper, cond.upperInclusive = upper.ceiling()
+		return []condition{cond}, nil
+	}
+
+	var conditions []condition
+	// operators may be separated from their version by spaces, e.g. ">= v1.2, < v1.4"
+	fields := strings.FieldsFunc(group, func(r rune) bool { return r == ',' || r == ' ' || r == '\t' })
+	for i := 0; i < len(fields); i++ {
+		field := fields[i]
+		if constraintOperatorRegex.FindString(field) == field && i+1 < len(fields) {
+			i++
+			field += fields[i]
+		}
+		cond, err := parseCondition(field)
+		if err != nil {
+			return nil, err
+		}
+		conditions = append(conditions, cond)
+	}
+	return conditions, nil
+}
+
+func parseCondition(text string) (condition, error) {
+	operator := strings.TrimSpace(constraintOperatorRegex.FindString(text))
+	version, err := parsePartialVersion(text[len(constraintOperatorRegex.FindString(text)):])
+	if err != nil {
+		return condition{}, err
+	}
+
+	switch operator {
+	case "", "=", "==":
+		return version.condition(), nil
+	case "!=":
+		cond := version.condition()
+		cond.negated = true
+		return cond, nil
+	case ">":
+		// greater than every version the partial version matches
+		cond := condition{}
+		if upper, inclusive := version.ceiling(); upper != nil {
+			cond.lower, cond.lowerInclusive = upper, !inclusive
+		} else {
+			// nothing is greater than *
+			cond.lower, cond.upper, cond.negated = version.floor(), nil, true
+			cond.lowerInclusive = true
+		}
+		return cond, nil
+	case ">=":
+		return condition{lower: version.floor(), lowerInclusive: true}, nil
+	case "<":
+		return condition{upper: version.floor(), upperInclusive: false}, nil
+	case "<=":
+		cond := condition{}
+		cond.upper, cond.upperInclusive = version.ceiling()
+		return cond, nil
+	case "~", "~>":
+		cond := condition{lower: version.floor(), lowerInclusive: true}
+		switch {
+		case version.parts > 1:
+			cond.upper = NewVersion(version.major, version.minor+1, 0, "", 0)
+		case version.parts == 1:
+			cond.upper = NewVersion(version.major+1, 0, 0, "", 0)
+		}
+		return cond, nil
+	case "^":
+		cond := condition{lower: version.floor(), lowerInclusive: true}
+		switch {
+		case version.parts == 0:
+		case version.major > 0 || version.parts == 1:
+			cond.upper = NewVersion(version.major+1, 0, 0, "", 0)
+		case version.minor > 0 || version.parts == 2:
+			cond.upper = NewVersion(0, version.minor+1, 0, "", 0)
+		default:
+			cond.upper = NewVersion(0, 0, version.patch+1, "", 0)
+		}
+		return cond, nil
+	}
+	return condition{}, eris.Errorf("unknown operator %s", operator)
+}
+
+// A version in a constraint, of which only the first parts are set (0 for *, 3 for a full version)
+type partialVersion struct {
+	major, minor, patch int
+	parts               int
+	// the full version, if all parts are set
+	version *Version
+}
+
+func parsePartialVersion(text string) (*partialVersion, error) {
+	matches := constraintVersionRegex.FindStringSubmatch(text)
+	if matches == nil {
+		return nil, eris.Errorf("%s is not a version", text)
+	}
+	p := &partialVersion{}
+	numbers := []*int{&p.major, &p.minor, &p.patch}
+	for i, part := range matches[1:4] {
+		if part == "" || strings.ContainsAny(part, "xX*") {
+			break
+		}
+		number, err := strconv.Atoi(part)
+		if err != nil {
+			return nil, err
+		}
+		*numbers[i] = number
+		p.parts++
+	}
+	if matches[4] != "" && p.parts < 3 {
+		return nil, eris.Errorf("%s has a prerelease but is not a full version", text)
+	}
+	if p.parts == 3 {
+		version, err := ParseVersion("v" + strings.TrimPrefix(text, "v"))
+		if err != nil {
+			return nil, err
+		}
+		p.version = version
+	}
+	return p, nil
+}
+
+// The least version that the partial version matches
+func (p *partialVersion) floor() *Version {
+	if p.version != nil {
+		return p.version
+	}
+	return NewVersion(p.major, p.minor, p.patch, "", 0)
+}
+
+// The upper bound of the versions that the partial version matches, and whether it is inclusive. Nil for *.
+func (p *partialVersion) ceiling() (*Version, bool) {
+	switch p.parts {
+	case 0:
+		return nil, false
+	case 1:
+		return NewVersion(p.major+1, 0, 0, "", 0), false
+	case 2:
+		return NewVersion(p.major, p.minor+1, 0, "", 0), false
+	}
+	return p.version, true
+}
+
+// The condition that a version is one of the versions that the partial version matches
+func (p *partialVersion) condition() condition {
+	cond := condition{lower: p.floor(), lowerInclusive: true}
+	cond.upper, cond.upperInclusive = p.ceiling()
+	if p.parts == 0 {
+		cond.lower = nil
+	}
+	return cond
+}
+
+// Returns the versions that satisfy the constraint, in the same order
+func FilterVersions(versions []Version, constraint *Constraint) []Version {
+	var filtered []Version
+	for _, v := range versions {
+		if constraint.Check(v) {
+			filtered = append(filtered, v)
+		}
+	}
+	return filtered
+}
+
+// Returns the releases whose tags are versions that satisfy the constraint, in the same order.
+// Releases whose tags aren't versions are left out.
+func FilterReleases(releases []*github.RepositoryRelease, constraint *Constraint) []*github.RepositoryRelease {
+	var filtered []*github.RepositoryRelease
+	for _, release := range releases {
+		if constraint.CheckTag(release.GetTagName()) {
+			filtered = append(filtered, release)
+		}
+	}
+	return filtered
+}
+
+// Sorts versions in place from greatest to least (v2.8.0, v1.7.0, v1.7.0-rc1...).
+// Versions are compared with IsGreaterThanWithLabelOrder. Prereleases that it can't order because their labels
+// differ are ordered by label: labels in the label order are greater than those that aren't, which are ordered
+// alphanumerically like MustIsGreaterThan does.
+func SortVersions(versions []Version, labelOrder []string) {
+	sort.SliceStable(versions, func(i, j int) bool {
+		return isGreater(versions[i], versions[j], labelOrder)
+	})
+}
+
+// Sorts releases in place by the versions of their tags, from greatest to least, like SortVersions.
+// Releases whose tags aren't versions are moved to the end.
+func SortReleases(releases []*github.RepositoryRelease, labelOrder []string) {
+	versions := make(map[*github.RepositoryRelease]*Version, len(releases))
+	for _, release := range releases {
+		if v, err := ParseVersion(release.GetTagName()); err == nil {
+			versions[release] = v
+		}
+	}
+	sort.SliceStable(releases, func(i, j int) bool {
+		vI, vJ := versions[releases[i]], versions[releases[j]]
+		if vI == nil || vJ == nil {
+			return vI != nil
+		}
+		return isGreater(*vI, *vJ, labelOrder)
+	})
+}
+
+func isGreater(v, other Version, labelOrder []string) bool {
+	if result, determinable := CompareWithLabelOrder(v, other, labelOrder); determinable {
+		return result > 0
+	}
+	// prereleases of the same version with different labels, which aren't both in the label order
+	if ordered, otherOrdered := containsLabel(labelOrder, v.Label), containsLabel(labelOrder, other.Label); ordered != otherOrdered {
+		return ordered
+	}
+	return v.Label > other.Label
+}
+
+func containsLabel(labelOrder []string, label string) bool {
+	for _, lbl := range labelOrder {
+		if lbl == label {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,133 @@
+package versionutils_test
+
+import (
+	"encoding/json"
+
+	"github.com/google/go-github/v32/github"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/solo-io/go-utils/versionutils"
+)
+
+var _ = Describe("Constraint", func() {
+
+	DescribeTable("Check",
+		func(constraint, tag string, expected bool) {
+			c, err := versionutils.ParseConstraint(constraint)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(c.CheckTag(tag)).To(Equal(expected))
+		},
+		Entry("exact version", "v1.2.3", "v1.2.3", true),
+		Entry("exact version without v", "=1.2.3", "v1.2.4", false),
+		Entry("partial version", "v1.2", "v1.2.9", true),
+		Entry("wildcard", "1.2.x", "v1.3.0", false),
+		Entry("star", "*", "v0.0.1", true),
+		Entry("not equal", "!= v1.2", "v1.2.5", false),
+		Entry("not equal", "!= v1.2", "v1.3.0", true),
+		Entry("greater than partial", "> v1.2", "v1.2.9", false),
+		Entry("greater than partial", "> v1.2", "v1.3.0", true),
+		Entry("less than or equal to partial", "<= v1.2", "v1.2.9", true),
+		Entry("and", ">= v1.2.0, < v1.4.0", "v1.3.9", true),
+		Entry("and with spaces", ">=v1.2.0 <v1.4.0", "v1.4.0", false),
+		Entry("or", "< v1.0.0 || >= v2.0.0", "v1.5.0", false),
+		Entry("or", "< v1.0.0 || >= v2.0.0", "v2.0.0", true),
+		Entry("tilde", "~v1.2.3", "v1.2.9", true),
+		Entry("tilde", "~v1.2.3", "v1.3.0", false),
+		Entry("tilde major", "~1", "v1.9.0", true),
+		Entry("caret", "^v1.2.3", "v1.9.0", true),
+		Entry("caret", "^v1.2.3", "v2.0.0", false),
+		Entry("caret zero major", "^v0.2.3", "v0.3.0", false),
+		Entry("caret zero minor", "^v0.0.3", "v0.0.4", false),
+		Entry("range", "v1.2 - v1.4", "v1.4.7", true),
+		Entry("range", "v1.2.3 - v1.4.0", "v1.4.1", false),
+		Entry("prerelease outside of bounds", "< v1.9.0", "v1.9.0-beta1", false),
+		Entry("prerelease of a bound", ">= v1.8.0-beta2", "v1.8.0-beta3", true),
+		Entry("prerelease of another version", ">= v1.8.0-beta2", "v1.9.0-beta3", false),
+		Entry("prerelease with another label", ">= v1.8.0-beta2", "v1.8.0-rc1", false),
+		Entry("not a version", "*", "latest", false),
+	)
+
+	It("matches prereleases with the label order", func() {
+		c, err := versionutils.ParseConstraintWithOptions(">= v1.8.0-beta2, < v1.8.0", versionutils.ConstraintOptions{
+			LabelOrder: []string{"rc", "beta"},
+		})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.CheckTag("v1.8.0-rc1")).To(BeTrue())
+		Expect(c.CheckTag("v1.8.0-beta1")).To(BeFalse())
+		Expect(c.CheckTag("v1.8.0-alpha5")).To(BeFalse())
+	})
+
+	It("can include every prerelease", func() {
+		c, err := versionutils.ParseConstraintWithOptions("~v1.8", versionutils.ConstraintOptions{IncludePrereleases: true})
+		Expect(err).NotTo(HaveOccurred())
+		Expect(c.CheckTag("v1.8.1-beta1")).To(BeTrue())
+		Expect(c.CheckTag("v1.9.0-beta1")).To(BeTrue())
+		Expect(c.CheckTag("v1.8.0-beta1")).To(BeFalse())
+	})
+
+	It("rejects invalid constraints", func() {
+		for _, constraint := range []string{"", ">= foo", "v1.2-rc1", "=> v1.2.3", "v1.2.3 ||"} {
+			_, err := versionutils.ParseConstraint(constraint)
+			Expect(err).To(HaveOccurred(), constraint)
+		}
+	})
+
+	It("marshals to json as a string", func() {
+		out, err := json.Marshal(versionutils.MustParseConstraint(">= v1.2, < v2"))
+		Expect(err).NotTo(HaveOccurred())
+		var source string
+		Expect(json.Unmarshal(out, &source)).To(Succeed())
+		Expect(source).To(Equal(">= v1.2, < v2"))
+	})
+
+	Context("filtering and sorting", func() {
+
+		parse := func(tags ...string) []versionutils.Version {
+			var versions []versionutils.Version
+			for _, tag := range tags {
+				v, err := versionutils.ParseVersion(tag)
+				Expect(err).NotTo(HaveOccurred())
+				versions = append(versions, *v)
+			}
+			return versions
+		}
+
+		It("filters versions", func() {
+			versions := parse("v1.7.3", "v1.8.0-beta1", "v1.8.0", "v1.8.2", "v1.9.0")
+			Expect(versionutils.FilterVersions(versions, versionutils.MustParseConstraint("~v1.8"))).To(Equal(parse("v1.8.0", "v1.8.2")))
+		})
+
+		It("sorts versions from greatest to least", func() {
+			versions := parse("v1.8.0-beta1", "v1.7.3", "v1.8.0", "v1.8.0-rc1", "v1.8.0-beta10", "v1.8.0-beta2", "v1.10.0")
+			versionutils.SortVersions(versions, []string{"rc", "beta"})
+			Expect(versions).To(Equal(parse("v1.10.0", "v1.8.0", "v1.8.0-rc1", "v1.8.0-beta10", "v1.8.0-beta2", "v1.8.0-beta1", "v1.7.3")))
+		})
+
+		It("sorts prereleases with labels missing from the label order consistently", func() {
+			for _, tags := range [][]string{
+				{"v1.0.0-b1", "v1.0.0-a1", "v1.0.0-c1", "v1.0.0-a10", "v1.0.0-a2"},
+				{"v1.0.0-a2", "v1.0.0-c1", "v1.0.0-a10", "v1.0.0-b1", "v1.0.0-a1"},
+			} {
+				versions := parse(tags...)
+				versionutils.SortVersions(versions, []string{"a", "c"})
+				Expect(versions).To(Equal(parse("v1.0.0-a10", "v1.0.0-a2", "v1.0.0-a1", "v1.0.0-c1", "v1.0.0-b1")))
+			}
+		})
+
+		It("filters and sorts releases", func() {
+			var releases []*github.RepositoryRelease
+			for _, tag := range []string{"v1.7.3", "latest", "v1.9.1", "v1.8.0", "v1.9.0"} {
+				releases = append(releases, &github.RepositoryRelease{TagName: github.String(tag)})
+			}
+			versionutils.SortReleases(releases, nil)
+			var tags []string
+			for _, release := range releases {
+				tags = append(tags, release.GetTagName())
+			}
+			Expect(tags).To(Equal([]string{"v1.9.1", "v1.9.0", "v1.8.0", "v1.7.3", "latest"}))
+
+			filtered := versionutils.FilterReleases(releases, versionutils.MustParseConstraint(">= v1.8"))
+			Expect(filtered).To(Equal(releases[:3]))
+		})
+	})
+})
@@ -0,0 +1,353 @@
+package git
+
+import (
+	"fmt"
+	"sort"
+
+	"github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	"github.com/rotisserie/eris"
+)
+
+const (
+	// Length of the abbreviated hashes in describe output, like git's default
+	abbrevLength = 7
+	// Number of tagged ancestors that are considered for the nearest tag, like git's default
+	maxDescribeCandidates = 10
+	dirtySuffix           = "-dirty"
+)
+
+var (
+	OpenRepoError = func(err error, dir string) error {
+		return eris.Wrapf(err, "unable to open git repo at %s", dir)
+	}
+	NoCommitsError = func(dir string) error {
+		return eris.Errorf("git repo at %s has no commits", dir)
+	}
+)
+
+// Provides the ref info of the git repo in a directory. The directory may be anywhere in the repo's
+// working tree, including a linked worktree.
+type RefInfoProvider interface {
+	GetRefInfo(repoDir string) (*RefInfo, error)
+}
+
+type goGitRefInfoProvider struct{}
+
+// Returns a RefInfoProvider that reads the repo with go-git, so it doesn't need the git binary.
+func NewRefInfoProvider() RefInfoProvider {
+	return goGitRefInfoProvider{}
+}
+
+func (goGitRefInfoProvider) GetRefInfo(repoDir string) (*RefInfo, error) {
+	repo, err := git.PlainOpenWithOptions(repoDir, &git.PlainOpenOptions{
+		DetectDotGit:          true,
+		EnableDotGitCommonDir: true,
+	})
+	if err != nil {
+		return nil, OpenRepoError(err, repoDir)
+	}
+	head, err := repo.Head()
+	if err != nil {
+		if err == plumbing.ErrReferenceNotFound {
+			return nil, NoCommitsError(repoDir)
+		}
+		return nil, err
+	}
+
+	info := &RefInfo{
+		Hash:     head.Hash().String(),
+		Detached: !head.Name().IsBranch(),
+	}
+	if !info.Detached {
+		info.Branch = head.Name().Short()
+	}
+
+	info.Dirty, err = isDirty(repo)
+	if err != nil {
+		return nil, err
+	}
+	info.NearestTag, info.CommitsSinceTag, err = describe(repo, head.Hash())
+	if err != nil {
+		return nil, err
+	}
+	info.Tag = info.Describe()
+	return info, nil
+}
+
+// Like 'git describe --dirty', untracked files don't make the working tree dirty
+func isDirty(repo *git.Repository) (bool, error) {
+	worktree, err := repo.Worktree()
+	if err == git.ErrIsBareRepository {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	status, err := worktree.Status()
+	if err != nil {
+		return false, err
+	}
+	for _, fileStatus := range status {
+		if fileStatus.Worktree != git.Untracked || fileStatus.Staging != git.Untracked {
+			return true, nil
+		}
+	}
+	return false, nil
+}
+
+type describeCandidate struct {
+	tag       string
+	annotated bool
+	when      int64
+	commit    plumbing.Hash
+	// number of walked commits that aren't reachable from the tag
+	depth int
+	// marks the commits reachable from the tag during the walk
+	flag uint
+}
+
+// Finds the tag that 'git describe --tags' would: of the tagged ancestors of the commit, the one with the fewest
+// commits that are reachable from the commit but not from the tag. Returns an empty tag if there is none.
+//
+// Like git, this walks the history once, newest first. Each candidate tag marks the commits reachable from it, so
+// its depth is the number of walked commits it doesn't mark. The walk stops once every commit left to walk is
+// reachable from the nearest candidate, since no other tag can be nearer then.
+func describe(repo *git.Repository, hash plumbing.Hash) (string, int, error) {
+	tagsByCommit, err := tagsByCommit(repo)
+	if err != nil {
+		return "", 0, err
+	}
+	if len(tagsByCommit) == 0 {
+		return "", 0, nil
+	}
+	commit, err := repo.CommitObject(hash)
+	if err != nil {
+		return "", 0, err
+	}
+
+	var candidates []*describeCandidate
+	walk := &describeWalk{repo: repo, flags: map[plumbing.Hash]uint{commit.Hash: 0}}
+	walk.push(commit)
+	seen := 0
+	for len(walk.queue) > 0 {
+		c := walk.pop()
+		seen++
+		if tags := tagsByCommit[c.Hash]; len(tags) > 0 && len(candidates) < maxDescribeCandidates {
+			candidate := tags[0]
+			candidate.depth = seen - 1
+			candidate.flag = 1 << uint(len(candidates))
+			walk.flags[c.Hash] |= candidate.flag
+			candidates = append(candidates, candidate)
+		}
+		for _, candidate := range candidates {
+			if walk.flags[c.Hash]&candidate.flag == 0 {
+				candidate.depth++
+			}
+		}
+		if err := walk.pushParents(c); err != nil {
+			return "", 0, err
+		}
+		if best := nearest(candidates); best != nil && walk.allFlagged(best.flag) {
+			break
+		}
+	}
+
+	best := nearest(candidates)
+	if best == nil {
+		return "", 0, nil
+	}
+	return best.tag, best.depth, nil
+}
+
+// Returns the candidate with the least depth, the first found of those that are equally near
+func nearest(candidates []*describeCandidate) *describeCandidate {
+	var best *describeCandidate
+	for _, candidate := range candidates {
+		if best == nil || candidate.depth < best.depth {
+			best = candidate
+		}
+	}
+	return best
+}
+
+// The commits left to walk, newest first, and the flags of the candidates each walked or queued commit is
+// reachable from
+type describeWalk struct {
+	repo  *git.Repository
+	queue []*object.Commit
+	flags map[plumbing.Hash]uint
+}
+
+func (w *describeWalk) push(commit *object.Commit) {
+	index := sort.Search(len(w.queue), func(i int) bool {
+		return w.queue[i].Committer.When.Before(commit.Committer.When)
+	})
+	w.queue = append(w.queue, nil)
+	copy(w.queue[index+1:], w.queue[index:])
+	w.queue[index] = commit
+}
+
+func (w *describeWalk) pop() *object.Commit {
+	commit := w.queue[0]
+	w.queue = w.queue[1:]
+	return commit
+}
+
+// Queues the parents that weren't seen yet, and marks all of them as reachable from the candidates the commit is
+func (w *describeWalk) pushParents(commit *object.Commit) error {
+	for _, parentHash := range commit.ParentHashes {
+		flags, seen := w.flags[parentHash]
+		w.flags[parentHash] = flags | w.flags[commit.Hash]
+		if seen {
+			continue
+		}
+		parent, err := w.repo.CommitObject(parentHash)
+		if err != nil {
+			return err
+		}
+		w.push(parent)
+	}
+	return nil
+}
+
+func (w *describeWalk) allFlagged(flag uint) bool {
+	for _, commit := range w.queue {
+		if w.flags[commit.Hash]&flag == 0 {
+			return false
+		}
+	}
+	return true
+}
+
+// Returns the tags of each tagged commit, the one describe prefers first: annotated tags before lightweight
+// ones, then the most recently tagged, then by name.
+func tagsByCommit(repo *git.Repository) (map[plumbing.Hash][]*describeCandidate, error) {
+	refs, err := repo.Tags()
+	if err != nil {
+		return nil, err
+	}
+	tags := map[plumbing.Hash][]*describeCandidate{}
+	err = refs.ForEach(func(ref *plumbing.Reference) error {
+		candidate := &describeCandidate{
+			tag:    ref.Name().Short(),
+			commit: ref.Hash(),
+		}
+		tagObject, err := repo.TagObject(ref.Hash())
+		switch err {
+		case nil:
+			tagged, err := tagObject.Commit()
+			if err != nil {
+				// the tag isn't of a commit
+				return nil
+			}
+			candidate.annotated = true
+			candidate.when = tagObject.Tagger.When.Unix()
+			candidate.commit = tagged.Hash
+		case plumbing.ErrObjectNotFound:
+		default:
+			return err
+		}
+		tags[candidate.commit] = append(tags[candidate.commit], candidate)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	for _, candidates := range tags {
+		sort.Slice(candidates, func(i, j int) bool {
+			if candidates[i].annotated != candidates[j].annotated {
+				return candidates[i].annotated
+			}
+			if candidates[i].when != candidates[j].when {
+				return candidates[i].when > candidates[j].when
+			}
+			return candidates[i].tag > candidates[j].tag
+		})
+	}
+	return tags, nil
+}
+
+// Renders the ref info like 'git describe --tags --dirty --always' does, e.g. v1.2.3, v1.2.3-4-gabcdef0,
+// or abcdef0-dirty if no tag is reachable.
+func (info *RefInfo) Describe() string {
+	var description string
+	switch {
+	case info.NearestTag == "":
+		description = abbrev(info.Hash)
+	case info.CommitsSinceTag == 0:
+		description = info.NearestTag
+	default:
+		description = fmt.Sprintf("%s-%d-g%s", info.NearestTag, info.CommitsSinceTag, abbrev(info.Hash))
+	}
+	if info.Dirty {
+		description += dirtySuffix
+	}
+	return description
+}
+
+func abbrev(hash string) string {
+	if len(hash) > abbrevLength {
+		return hash[:abbrevLength]
+	}
+	return hash
+}
+
+type commandRefInfoProvider struct{}
+
+// Returns a RefInfoProvider that runs the git binary. Only the Branch, Hash and Tag of the ref info are set.
+func NewCommandRefInfoProvider() RefInfoProvider {
+	return commandRefInfoProvider{}
+}
+
+func (commandRefInfoProvider) GetRefInfo(repoDir string) (*RefInfo, error) {
+	info := &RefInfo{}
+	repo := gitRepo{relativeDir: repoDir}
+
+	if tag, err := repo.getTag(); err != nil {
+		return nil, err
+	} else {
+		info.Tag = tag
+	}
+
+	if hash, err := repo.getCommitHash(); err != nil {
+		return nil, err
+	} else {
+		info.Hash = hash
+	}
+
+	if branch, err := repo.getBranch(); err != nil {
+		return nil, err
+	} else {
+		info.Branch = branch
+		info.Detached = branch == ""
+	}
+
+	return info, nil
+}
+
+// A RefInfoProvider for tests, that returns the ref info or error set for a directory
+type FakeRefInfoProvider struct {
+	RefInfos map[string]*RefInfo
+	Errors   map[string]error
+}
+
+func NewFakeRefInfoProvider() *FakeRefInfoProvider {
+	return &FakeRefInfoProvider{
+		RefInfos: map[string]*RefInfo{},
+		Errors:   map[string]error{},
+	}
+}
+
+func (f *FakeRefInfoProvider) GetRefInfo(repoDir string) (*RefInfo, error) {
+	if err := f.Errors[repoDir]; err != nil {
+		return nil, err
+	}
+	info, ok := f.RefInfos[repoDir]
+	if !ok {
+		return nil, OpenRepoError(git.ErrRepositoryNotExists, repoDir)
+	}
+	copied := *info
+	return &copied, nil
+}
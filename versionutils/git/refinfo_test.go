@@ -0,0 +1,230 @@
+package git_test
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	gogit "github.com/go-git/go-git/v5"
+	"github.com/go-git/go-git/v5/plumbing"
+	"github.com/go-git/go-git/v5/plumbing/object"
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/versionutils/git"
+)
+
+var _ = Describe("RefInfoProvider", func() {
+
+	var (
+		dir      string
+		repo     *gogit.Repository
+		provider = git.NewRefInfoProvider()
+		clock    time.Time
+	)
+
+	signature := func() *object.Signature {
+		clock = clock.Add(time.Minute)
+		return &object.Signature{Name: "test", Email: "test@solo.io", When: clock}
+	}
+
+	commit := func(file, contents string) plumbing.Hash {
+		Expect(os.WriteFile(filepath.Join(dir, file), []byte(contents), 0644)).To(Succeed())
+		worktree, err := repo.Worktree()
+		Expect(err).NotTo(HaveOccurred())
+		_, err = worktree.Add(file)
+		Expect(err).NotTo(HaveOccurred())
+		hash, err := worktree.Commit("update "+file, &gogit.CommitOptions{Author: signature()})
+		Expect(err).NotTo(HaveOccurred())
+		return hash
+	}
+
+	annotatedTag := func(name string, hash plumbing.Hash) {
+		_, err := repo.CreateTag(name, hash, &gogit.CreateTagOptions{Tagger: signature(), Message: name})
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	lightweightTag := func(name string, hash plumbing.Hash) {
+		_, err := repo.CreateTag(name, hash, nil)
+		Expect(err).NotTo(HaveOccurred())
+	}
+
+	BeforeEach(func() {
+		var err error
+		dir, err = os.MkdirTemp("", "refinfo")
+		Expect(err).NotTo(HaveOccurred())
+		repo, err = gogit.PlainInit(dir, false)
+		Expect(err).NotTo(HaveOccurred())
+		clock = time.Date(2020, 1, 1, 0, 0, 0, 0, time.UTC)
+	})
+
+	AfterEach(func() {
+		os.RemoveAll(dir)
+	})
+
+	It("describes a commit without tags by its hash", func() {
+		hash := commit("a.txt", "a")
+		info, err := provider.GetRefInfo(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info).To(Equal(&git.RefInfo{
+			Branch: "master",
+			Hash:   hash.String(),
+			Tag:    hash.String()[:7],
+		}))
+	})
+
+	It("describes a commit by its nearest tag", func() {
+		first := commit("a.txt", "a")
+		lightweightTag("v0.1.0", first)
+		second := commit("a.txt", "b")
+		annotatedTag("v0.2.0", second)
+		lightweightTag("v0.2.0-rc", second)
+		commit("b.txt", "a")
+		head := commit("b.txt", "b")
+
+		info, err := provider.GetRefInfo(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.NearestTag).To(Equal("v0.2.0"))
+		Expect(info.CommitsSinceTag).To(Equal(2))
+		Expect(info.Tag).To(Equal("v0.2.0-2-g" + head.String()[:7]))
+	})
+
+	It("describes a tagged commit by its tag", func() {
+		hash := commit("a.txt", "a")
+		lightweightTag("v0.1.0", hash)
+		info, err := provider.GetRefInfo(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Tag).To(Equal("v0.1.0"))
+	})
+
+	It("reports changes to tracked files as dirty", func() {
+		hash := commit("a.txt", "a")
+		lightweightTag("v0.1.0", hash)
+		Expect(os.WriteFile(filepath.Join(dir, "untracked.txt"), []byte("a"), 0644)).To(Succeed())
+		info, err := provider.GetRefInfo(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Dirty).To(BeFalse())
+
+		Expect(os.WriteFile(filepath.Join(dir, "a.txt"), []byte("b"), 0644)).To(Succeed())
+		info, err = provider.GetRefInfo(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Dirty).To(BeTrue())
+		Expect(info.Tag).To(Equal("v0.1.0-dirty"))
+	})
+
+	It("reports a detached HEAD", func() {
+		first := commit("a.txt", "a")
+		commit("a.txt", "b")
+		worktree, err := repo.Worktree()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(worktree.Checkout(&gogit.CheckoutOptions{Hash: first})).To(Succeed())
+
+		info, err := provider.GetRefInfo(dir)
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Detached).To(BeTrue())
+		Expect(info.Branch).To(BeEmpty())
+		Expect(info.Hash).To(Equal(first.String()))
+	})
+
+	It("finds the repo from a subdirectory", func() {
+		hash := commit("a.txt", "a")
+		Expect(os.MkdirAll(filepath.Join(dir, "sub", "dir"), 0755)).To(Succeed())
+		info, err := provider.GetRefInfo(filepath.Join(dir, "sub", "dir"))
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info.Hash).To(Equal(hash.String()))
+	})
+
+	It("errors on a repo without commits", func() {
+		_, err := provider.GetRefInfo(dir)
+		Expect(err).To(MatchError(git.NoCommitsError(dir)))
+	})
+
+	Context("with the git binary", func() {
+
+		runGit := func(dir string, args ...string) {
+			cmd := exec.Command("git", args...)
+			cmd.Dir = dir
+			output, err := cmd.CombinedOutput()
+			Expect(err).NotTo(HaveOccurred(), string(output))
+		}
+
+		BeforeEach(func() {
+			if _, err := exec.LookPath("git"); err != nil {
+				Skip("git is not installed")
+			}
+		})
+
+		It("agrees with git describe", func() {
+			first := commit("a.txt", "a")
+			annotatedTag("v0.1.0", first)
+			commit("a.txt", "b")
+			lightweightTag("v0.1.1", commit("a.txt", "c"))
+			commit("a.txt", "d")
+			Expect(os.WriteFile(filepath.Join(dir, "a.txt"), []byte("e"), 0644)).To(Succeed())
+
+			expected, err := git.NewCommandRefInfoProvider().GetRefInfo(dir)
+			Expect(err).NotTo(HaveOccurred())
+			info, err := provider.GetRefInfo(dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Tag).To(Equal(expected.Tag))
+			Expect(info.Hash).To(Equal(expected.Hash))
+			Expect(info.Branch).To(Equal(expected.Branch))
+		})
+
+		It("agrees with git describe on merged history", func() {
+			first := commit("a.txt", "a")
+			annotatedTag("v0.1.0", first)
+			second := commit("a.txt", "b")
+			worktree, err := repo.Worktree()
+			Expect(err).NotTo(HaveOccurred())
+			Expect(worktree.Checkout(&gogit.CheckoutOptions{Hash: first})).To(Succeed())
+			side := commit("b.txt", "a")
+			lightweightTag("v0.2.0", side)
+			Expect(worktree.Checkout(&gogit.CheckoutOptions{Branch: plumbing.Master})).To(Succeed())
+			Expect(os.WriteFile(filepath.Join(dir, "b.txt"), []byte("a"), 0644)).To(Succeed())
+			_, err = worktree.Add("b.txt")
+			Expect(err).NotTo(HaveOccurred())
+			_, err = worktree.Commit("merge", &gogit.CommitOptions{Author: signature(), Parents: []plumbing.Hash{second, side}})
+			Expect(err).NotTo(HaveOccurred())
+			commit("a.txt", "c")
+
+			expected, err := git.NewCommandRefInfoProvider().GetRefInfo(dir)
+			Expect(err).NotTo(HaveOccurred())
+			info, err := provider.GetRefInfo(dir)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.NearestTag).To(Equal("v0.2.0"))
+			Expect(info.CommitsSinceTag).To(Equal(3))
+			Expect(info.Tag).To(Equal(expected.Tag))
+		})
+
+		It("reads linked worktrees", func() {
+			commit("a.txt", "a")
+			linked := filepath.Join(dir, "linked")
+			runGit(dir, "worktree", "add", "-b", "feature", linked)
+			defer os.RemoveAll(linked)
+
+			info, err := provider.GetRefInfo(linked)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Branch).To(Equal("feature"))
+			Expect(info.Dirty).To(BeFalse())
+		})
+	})
+
+	Describe("FakeRefInfoProvider", func() {
+		It("returns the ref info set for a directory", func() {
+			fake := git.NewFakeRefInfoProvider()
+			fake.RefInfos["repo"] = &git.RefInfo{Branch: "master", Hash: "abc", Tag: "v1.0.0"}
+			fake.Errors["broken"] = eris.New("broken")
+
+			var subject git.RefInfoProvider = fake
+			info, err := subject.GetRefInfo("repo")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(info.Tag).To(Equal("v1.0.0"))
+			_, err = subject.GetRefInfo("broken")
+			Expect(err).To(MatchError("broken"))
+			_, err = subject.GetRefInfo("missing")
+			Expect(err).To(HaveOccurred())
+		})
+	})
+})
@@ -25,32 +25,22 @@ var (
 type RefInfo struct {
 	Branch string
 	Hash   string
-	Tag    string
+	// The ref described like 'git describe --tags --dirty --always' does
+	Tag string
+
+	// The nearest tag reachable from the ref, and the number of commits since it. NearestTag is empty if
+	// no tag is reachable.
+	NearestTag      string
+	CommitsSinceTag int
+	// True if tracked files have uncommitted changes
+	Dirty bool
+	// True if HEAD is not a branch
+	Detached bool
 }
 
+// Returns the ref info of the repo in the given directory, read with go-git
 func GetGitRefInfo(relativeRepoDir string) (*RefInfo, error) {
-	info := &RefInfo{}
-	repo := gitRepo{relativeDir: relativeRepoDir}
-
-	if tag, err := repo.getTag(); err != nil {
-		return nil, err
-	} else {
-		info.Tag = tag
-	}
-
-	if hash, err := repo.getCommitHash(); err != nil {
-		return nil, err
-	} else {
-		info.Hash = hash
-	}
-
-	if branch, err := repo.getBranch(); err != nil {
-		return nil, err
-	} else {
-		info.Branch = branch
-	}
-
-	return info, nil
+	return NewRefInfoProvider().GetRefInfo(relativeRepoDir)
 }
 
 func PinDependencyVersion(relativeRepoDir string, refName string) error {
@@ -0,0 +1,344 @@
+package gomod
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"os"
+	"path"
+	"strings"
+	"time"
+
+	"github.com/google/go-github/v32/github"
+	"github.com/pkg/errors"
+	"github.com/rotisserie/eris"
+	"github.com/solo-io/go-utils/githubutils"
+	"github.com/solo-io/go-utils/versionutils/dep"
+	"golang.org/x/mod/modfile"
+	"golang.org/x/mod/module"
+)
+
+const (
+	GoModFile = "go.mod"
+	GoSumFile = "go.sum"
+)
+
+var (
+	ParseGoModError = func(err error, file string) error {
+		return errors.Wrapf(err, "unable to parse %s", file)
+	}
+	ParseGoSumError = func(file string, line int, text string) error {
+		return eris.Errorf("unable to parse %s:%d: %q", file, line, text)
+	}
+	ModuleNotRequiredError = func(modulePath string) error {
+		return eris.Errorf("module %s is not required in go.mod", modulePath)
+	}
+	ExcludedVersionError = func(modulePath, version string) error {
+		return eris.Errorf("go.mod requires %s %s, which it also excludes", modulePath, version)
+	}
+	LocalReplacementError = func(modulePath, dir string) error {
+		return eris.Errorf("module %s is replaced by the local directory %s, which has no version", modulePath, dir)
+	}
+)
+
+// A module required by a go.mod file, after applying its replace and exclude directives.
+type Module struct {
+	// Path and Version as they are required
+	Path     string
+	Version  string
+	Indirect bool
+
+	// Set if a replace directive applies to the requirement. ReplacementVersion is empty
+	// if the replacement is a local directory.
+	ReplacementPath    string
+	ReplacementVersion string
+
+	// The go.sum hashes of the module that is used, empty if there is no go.sum or it has no entry for it
+	Sum      string
+	GoModSum string
+}
+
+func (m *Module) String() string {
+	if m.ReplacementPath == "" {
+		return fmt.Sprintf("%s %s", m.Path, m.Version)
+	}
+	return strings.TrimSpace(fmt.Sprintf("%s %s => %s %s", m.Path, m.Version, m.ReplacementPath, m.ReplacementVersion))
+}
+
+// The path of the module that is used, i.e. the replacement if there is one
+func (m *Module) UsedPath() string {
+	if m.ReplacementPath != "" {
+		return m.ReplacementPath
+	}
+	return m.Path
+}
+
+// The version of the module that is used, i.e. the version of the replacement if there is one.
+// Empty if the module is replaced by a local directory.
+func (m *Module) UsedVersion() string {
+	if m.ReplacementPath != "" {
+		return m.ReplacementVersion
+	}
+	return m.Version
+}
+
+// The version of the module itself that is used: the version of a replacement with another version of the same
+// module, or the required version if the module is replaced by a fork or a local directory.
+func (m *Module) UpstreamVersion() string {
+	if m.UsedPath() == m.Path {
+		return m.UsedVersion()
+	}
+	return m.Version
+}
+
+func (m *Module) IsLocalReplacement() bool {
+	return m.ReplacementPath != "" && m.ReplacementVersion == ""
+}
+
+// True if the used version is a pseudo-version, e.g. v0.0.0-20191109021931-daa7c04131f5
+func (m *Module) IsPseudoVersion() bool {
+	return module.IsPseudoVersion(m.UsedVersion())
+}
+
+// The commit of a pseudo-version, abbreviated to 12 characters
+func (m *Module) Revision() (string, error) {
+	return module.PseudoVersionRev(m.UsedVersion())
+}
+
+// The commit time of a pseudo-version
+func (m *Module) RevisionTime() (time.Time, error) {
+	return module.PseudoVersionTime(m.UsedVersion())
+}
+
+// The used version, as the dep package would describe it: the revision of a pseudo-version, or the version
+// (without any "+incompatible" suffix) otherwise.
+func (m *Module) VersionInfo() (*dep.VersionInfo, error) {
+	if m.IsLocalReplacement() {
+		return nil, LocalReplacementError(m.Path, m.ReplacementPath)
+	}
+	if m.IsPseudoVersion() {
+		rev, err := m.Revision()
+		if err != nil {
+			return nil, err
+		}
+		return &dep.VersionInfo{
+			Version: rev,
+			Type:    dep.Revision,
+		}, nil
+	}
+	return &dep.VersionInfo{
+		Version: strings.TrimSuffix(m.UsedVersion(), "+incompatible"),
+		Type:    dep.Version,
+	}, nil
+}
+
+// The hashes of a go.sum file, by module path and version
+type GoSum map[module.Version]GoSumHashes
+
+type GoSumHashes struct {
+	// Hash of the module's file tree
+	Sum string
+	// Hash of the module's go.mod file
+	GoModSum string
+}
+
+func ParseGoSum(file string, contents []byte) (GoSum, error) {
+	sum := GoSum{}
+	scanner := bufio.NewScanner(bytes.NewReader(contents))
+	scanner.Buffer(nil, len(contents)+1)
+	for line := 1; scanner.Scan(); line++ {
+		fields := strings.Fields(scanner.Text())
+		if len(fields) == 0 {
+			continue
+		}
+		if len(fields) != 3 {
+			return nil, ParseGoSumError(file, line, scanner.Text())
+		}
+		version := module.Version{Path: fields[0], Version: fields[1]}
+		hashes := sum[version]
+		if goModVersion := strings.TrimSuffix(version.Version, "/"+GoModFile); goModVersion != version.Version {
+			version.Version = goModVersion
+			hashes = sum[version]
+			hashes.GoModSum = fields[2]
+		} else {
+			hashes.Sum = fields[2]
+		}
+		sum[version] = hashes
+	}
+	return sum, scanner.Err()
+}
+
+// A parsed go.mod file, and optionally its go.sum
+type GoMod struct {
+	File *modfile.File
+	Sum  GoSum
+}
+
+// Parses a go.mod file. The file name is only used in errors.
+func ParseGoMod(file string, contents []byte) (*GoMod, error) {
+	f, err := modfile.Parse(file, contents, nil)
+	if err != nil {
+		return nil, ParseGoModError(err, file)
+	}
+	return &GoMod{File: f}, nil
+}
+
+// The path of the module defined by the go.mod file
+func (g *GoMod) ModulePath() string {
+	if g.File.Module == nil {
+		return ""
+	}
+	return g.File.Module.Mod.Path
+}
+
+// Resolves every requirement, in the order they appear in go.mod
+func (g *GoMod) Modules() ([]*Module, error) {
+	var modules []*Module
+	for _, req := range g.File.Require {
+		mod, err := g.resolve(req)
+		if err != nil {
+			return nil, err
+		}
+		modules = append(modules, mod)
+	}
+	return modules, nil
+}
+
+// Resolves the requirement of the given module. As go does, a replace directive for the required version
+// takes precedence over one for every version of the module.
+func (g *GoMod) Module(modulePath string) (*Module, error) {
+	for _, req := range g.File.Require {
+		if req.Mod.Path == modulePath {
+			return g.resolve(req)
+		}
+	}
+	return nil, ModuleNotRequiredError(modulePath)
+}
+
+// Returns the version info of the given module, like versionutils.GetDependencyVersionInfo does for Gopkg.toml
+func (g *GoMod) DependencyVersionInfo(modulePath string) (*dep.VersionInfo, error) {
+	mod, err := g.Module(modulePath)
+	if err != nil {
+		return nil, err
+	}
+	return mod.VersionInfo()
+}
+
+func (g *GoMod) resolve(req *modfile.Require) (*Module, error) {
+	for _, exclude := range g.File.Exclude {
+		if exclude.Mod == req.Mod {
+			return nil, ExcludedVersionError(req.Mod.Path, req.Mod.Version)
+		}
+	}
+	mod := &Module{
+		Path:     req.Mod.Path,
+		Version:  req.Mod.Version,
+		Indirect: req.Indirect,
+	}
+	var replacement *modfile.Replace
+	for _, replace := range g.File.Replace {
+		if replace.Old.Path != req.Mod.Path {
+			continue
+		}
+		if replace.Old.Version == req.Mod.Version {
+			replacement = replace
+			break
+		}
+		if replace.Old.Version == "" {
+			replacement = replace
+		}
+	}
+	if replacement != nil {
+		mod.ReplacementPath = replacement.New.Path
+		mod.ReplacementVersion = replacement.New.Version
+	}
+	if !mod.IsLocalReplacement() {
+		hashes := g.Sum[module.Version{Path: mod.UsedPath(), Version: mod.UsedVersion()}]
+		mod.Sum = hashes.Sum
+		mod.GoModSum = hashes.GoModSum
+	}
+	return mod, nil
+}
+
+// Reads files of a repo; satisfied by vfsutils.MountedRepo
+type FileReader interface {
+	GetFileContents(ctx context.Context, path string) ([]byte, error)
+}
+
+// Reads the go.mod file in the given directory of a repo, and its go.sum if there is one
+func ReadGoMod(ctx context.Context, code FileReader, dir string) (*GoMod, error) {
+	goModPath := path.Join(dir, GoModFile)
+	contents, err := code.GetFileContents(ctx, goModPath)
+	if err != nil {
+		return nil, err
+	}
+	goMod, err := ParseGoMod(goModPath, contents)
+	if err != nil {
+		return nil, err
+	}
+	goSumPath := path.Join(dir, GoSumFile)
+	contents, err = code.GetFileContents(ctx, goSumPath)
+	if err != nil {
+		if isNotFound(err) {
+			return goMod, nil
+		}
+		return nil, err
+	}
+	goMod.Sum, err = ParseGoSum(goSumPath, contents)
+	if err != nil {
+		return nil, err
+	}
+	return goMod, nil
+}
+
+// Returns the module that the go.mod at the root of owner/repo requires at the given ref (a tag, branch or sha).
+// This is the answer to "which version of module X does repo Y use at Z".
+func GetModuleAtRef(ctx context.Context, client *github.Client, owner, repo, ref, modulePath string) (*Module, error) {
+	goMod, err := ReadGoMod(ctx, NewGithubFileReader(client, owner, repo, ref), "")
+	if err != nil {
+		return nil, err
+	}
+	return goMod.Module(modulePath)
+}
+
+type githubFileReader struct {
+	client *github.Client
+	owner  string
+	repo   string
+	ref    string
+}
+
+// Reads single files of a repo at a ref with the contents API, without downloading the whole repo
+func NewGithubFileReader(client *github.Client, owner, repo, ref string) FileReader {
+	return &githubFileReader{
+		client: client,
+		owner:  owner,
+		repo:   repo,
+		ref:    ref,
+	}
+}
+
+func (r *githubFileReader) GetFileContents(ctx context.Context, path string) ([]byte, error) {
+	files, err := githubutils.GetFilesFromGit(ctx, r.client, r.owner, r.repo, r.ref, path)
+	if err != nil {
+		return nil, err
+	}
+	if len(files) != 1 || files[0].GetType() != "file" {
+		return nil, eris.Errorf("%s is not a file in %s/%s at %s", path, r.owner, r.repo, r.ref)
+	}
+	contents, err := files[0].GetContent()
+	if err != nil {
+		return nil, err
+	}
+	return []byte(contents), nil
+}
+
+func isNotFound(err error) bool {
+	if os.IsNotExist(errors.Cause(err)) {
+		return true
+	}
+	var githubErr *github.ErrorResponse
+	return errors.As(err, &githubErr) && githubErr.Response != nil && githubErr.Response.StatusCode == http.StatusNotFound
+}
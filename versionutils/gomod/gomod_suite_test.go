@@ -0,0 +1,13 @@
+package gomod_test
+
+import (
+	"testing"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+)
+
+func TestGoMod(t *testing.T) {
+	RegisterFailHandler(Fail)
+	RunSpecs(t, "Go Mod Suite")
+}
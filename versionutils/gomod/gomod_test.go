@@ -0,0 +1,140 @@
+package gomod_test
+
+import (
+	"context"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/solo-io/go-utils/versionutils/dep"
+	"github.com/solo-io/go-utils/versionutils/gomod"
+	"github.com/solo-io/go-utils/vfsutils"
+	"github.com/spf13/afero"
+)
+
+var _ = Describe("go.mod", func() {
+
+	const (
+		goModContents = `module github.com/solo-io/solo-projects
+
+go 1.16
+
+require (
+	github.com/solo-io/gloo v1.8.0
+	github.com/solo-io/go-utils v0.21.6
+	github.com/solo-io/k8s-utils v0.0.8 // indirect
+	github.com/solo-io/skv2 v0.17.4
+	github.com/solo-io/protoc-gen-ext v0.0.0-20210513204025-2a2f6b4f6b6d
+	github.com/solo-io/solo-kit v0.20.3
+	github.com/docker/docker v17.12.0-ce-rc1.0.20200309214505-aa6a9891b09c+incompatible
+)
+
+replace (
+	github.com/solo-io/gloo => github.com/solo-io/gloo v1.8.1
+	github.com/solo-io/skv2 v0.17.4 => github.com/solo-io/skv2 v0.17.5
+	github.com/solo-io/skv2 => github.com/solo-io/skv2 v0.17.0
+	github.com/solo-io/solo-kit => ../solo-kit
+)
+
+exclude github.com/solo-io/k8s-utils v0.0.8
+`
+		goSumContents = `github.com/solo-io/gloo v1.8.0/go.mod h1:old=
+github.com/solo-io/gloo v1.8.1 h1:tree=
+github.com/solo-io/gloo v1.8.1/go.mod h1:mod=
+github.com/solo-io/go-utils v0.21.6/go.mod h1:utils=
+`
+	)
+
+	var (
+		ctx   = context.Background()
+		goMod *gomod.GoMod
+	)
+
+	BeforeEach(func() {
+		fs := afero.NewMemMapFs()
+		Expect(afero.WriteFile(fs, "/repo/go.mod", []byte(goModContents), 0644)).To(Succeed())
+		Expect(afero.WriteFile(fs, "/repo/go.sum", []byte(goSumContents), 0644)).To(Succeed())
+		var err error
+		goMod, err = gomod.ReadGoMod(ctx, vfsutils.NewMountedRepoForFs(fs, "/repo", "solo-io", "solo-projects"), "")
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	It("reads the module path", func() {
+		Expect(goMod.ModulePath()).To(Equal("github.com/solo-io/solo-projects"))
+	})
+
+	It("applies replacements, preferring the one for the required version", func() {
+		gloo, err := goMod.Module("github.com/solo-io/gloo")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gloo.String()).To(Equal("github.com/solo-io/gloo v1.8.0 => github.com/solo-io/gloo v1.8.1"))
+		Expect(gloo.UsedVersion()).To(Equal("v1.8.1"))
+		Expect(gloo.Sum).To(Equal("h1:tree="))
+		Expect(gloo.GoModSum).To(Equal("h1:mod="))
+
+		skv2, err := goMod.Module("github.com/solo-io/skv2")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(skv2.UsedVersion()).To(Equal("v0.17.5"))
+		Expect(skv2.UpstreamVersion()).To(Equal("v0.17.5"))
+		Expect(skv2.Sum).To(BeEmpty())
+
+		// a local replacement isn't a version of the module itself
+		soloKit, err := goMod.Module("github.com/solo-io/solo-kit")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(soloKit.UsedVersion()).To(BeEmpty())
+		Expect(soloKit.UpstreamVersion()).To(Equal("v0.20.3"))
+	})
+
+	It("returns dep version info", func() {
+		info, err := goMod.DependencyVersionInfo("github.com/solo-io/go-utils")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info).To(Equal(&dep.VersionInfo{Version: "v0.21.6", Type: dep.Version}))
+
+		info, err = goMod.DependencyVersionInfo("github.com/solo-io/protoc-gen-ext")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info).To(Equal(&dep.VersionInfo{Version: "2a2f6b4f6b6d", Type: dep.Revision}))
+
+		info, err = goMod.DependencyVersionInfo("github.com/docker/docker")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(info).To(Equal(&dep.VersionInfo{Version: "aa6a9891b09c", Type: dep.Revision}))
+	})
+
+	It("reports the commit time of pseudo-versions", func() {
+		ext, err := goMod.Module("github.com/solo-io/protoc-gen-ext")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(ext.IsPseudoVersion()).To(BeTrue())
+		revisionTime, err := ext.RevisionTime()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(revisionTime.Format("2006-01-02T15:04:05")).To(Equal("2021-05-13T20:40:25"))
+	})
+
+	It("errors on modules it can't give a version for", func() {
+		_, err := goMod.DependencyVersionInfo("github.com/solo-io/solo-kit")
+		Expect(err).To(MatchError(gomod.LocalReplacementError("github.com/solo-io/solo-kit", "../solo-kit")))
+		_, err = goMod.Module("github.com/solo-io/k8s-utils")
+		Expect(err).To(MatchError(gomod.ExcludedVersionError("github.com/solo-io/k8s-utils", "v0.0.8")))
+		_, err = goMod.Module("github.com/solo-io/unknown")
+		Expect(err).To(MatchError(gomod.ModuleNotRequiredError("github.com/solo-io/unknown")))
+	})
+
+	It("resolves every requirement", func() {
+		goMod.File.DropExclude("github.com/solo-io/k8s-utils", "v0.0.8")
+		modules, err := goMod.Modules()
+		Expect(err).NotTo(HaveOccurred())
+		Expect(modules).To(HaveLen(7))
+		Expect(modules[2].Indirect).To(BeTrue())
+	})
+
+	It("does not need a go.sum", func() {
+		fs := afero.NewMemMapFs()
+		Expect(afero.WriteFile(fs, "/repo/sub/go.mod", []byte(goModContents), 0644)).To(Succeed())
+		goMod, err := gomod.ReadGoMod(ctx, vfsutils.NewMountedRepoForFs(fs, "/repo", "solo-io", "solo-projects"), "sub")
+		Expect(err).NotTo(HaveOccurred())
+		gloo, err := goMod.Module("github.com/solo-io/gloo")
+		Expect(err).NotTo(HaveOccurred())
+		Expect(gloo.Sum).To(BeEmpty())
+	})
+
+	It("errors on malformed go.sum lines", func() {
+		_, err := gomod.ParseGoSum("go.sum", []byte("github.com/solo-io/gloo v1.8.0\n"))
+		Expect(err).To(MatchError(gomod.ParseGoSumError("go.sum", 1, "github.com/solo-io/gloo v1.8.0")))
+	})
+})
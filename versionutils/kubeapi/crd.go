@@ -0,0 +1,326 @@
+package kubeapi
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/rotisserie/eris"
+)
+
+var (
+	NoVersionsError = eris.New("CRD must declare at least one version")
+
+	NoServedVersionError = eris.New("CRD must serve at least one version")
+
+	DuplicateVersionError = func(name string) error {
+		return eris.Errorf("CRD declares version %v more than once", name)
+	}
+
+	StorageVersionCountError = func(storage []string) error {
+		return eris.Errorf("CRD must have exactly one storage version, found %d: %v", len(storage), storage)
+	}
+
+	UpgradeHazardsError = func(hazards []UpgradeHazard) error {
+		messages := make([]string, 0, len(hazards))
+		for _, hazard := range hazards {
+			messages = append(messages, hazard.String())
+		}
+		return eris.Errorf("CRD version change is unsafe: %v", strings.Join(messages, "; "))
+	}
+)
+
+// CRDVersion is the part of a version declared in a CustomResourceDefinition's spec.versions that
+// version negotiation depends on.
+type CRDVersion struct {
+	Name       string
+	Served     bool
+	Storage    bool
+	Deprecated bool
+}
+
+// ComparePriority compares two CRD version names by the priority Kubernetes gives them in discovery: versions
+// that follow the Kubernetes version pattern come first, GA before beta before alpha, then by major and
+// prerelease version, highest first. Other names come last, in alphabetical order. Returns a positive number
+// if a has a higher priority than b, a negative number if it has a lower priority, and 0 if they are the same.
+// https://kubernetes.io/docs/tasks/extend-kubernetes/custom-resources/custom-resource-definition-versioning/#version-priority
+func ComparePriority(a, b string) int {
+	va, errA := ParseVersion(a)
+	vb, errB := ParseVersion(b)
+	switch {
+	case errA != nil && errB != nil:
+		return strings.Compare(b, a)
+	case errA != nil:
+		return -1
+	case errB != nil:
+		return 1
+	}
+	if va.PrereleaseModifier() != vb.PrereleaseModifier() {
+		return int(va.PrereleaseModifier()) - int(vb.PrereleaseModifier())
+	}
+	if va.Major() != vb.Major() {
+		return va.Major() - vb.Major()
+	}
+	return va.Prerelease() - vb.Prerelease()
+}
+
+// SortByPriority sorts version names from the highest priority to the lowest.
+func SortByPriority(names []string) {
+	sort.SliceStable(names, func(i, j int) bool {
+		return ComparePriority(names[i], names[j]) > 0
+	})
+}
+
+// DeprecatedVersion is a served version that clients should migrate off of.
+type DeprecatedVersion struct {
+	Name string
+	// The version that replaces it, if it is superseded by a more stable version of the same major version
+	SupersededBy string
+	Reason       string
+}
+
+// VersionNegotiation describes the versions of a CRD as the API server presents them.
+type VersionNegotiation struct {
+	// The served version with the highest priority, which discovery reports as the preferred version
+	// and which clients like kubectl use by default
+	Preferred string
+	// The version objects are persisted as
+	Storage string
+	// The served versions, from the highest priority to the lowest
+	Served []string
+	// The served versions that are marked deprecated or superseded, from the highest priority to the lowest
+	Deprecated []DeprecatedVersion
+}
+
+// Negotiate validates a CRD's version declarations and computes its preferred, storage and deprecated versions.
+func Negotiate(versions []CRDVersion) (*VersionNegotiation, error) {
+	if err := validateVersions(versions); err != nil {
+		return nil, err
+	}
+	negotiation := &VersionNegotiation{}
+	for _, v := range versions {
+		if v.Storage {
+			negotiation.Storage = v.Name
+		}
+		if v.Served {
+			negotiation.Served = append(negotiation.Served, v.Name)
+		}
+	}
+	if len(negotiation.Served) == 0 {
+		return nil, NoServedVersionError
+	}
+	SortByPriority(negotiation.Served)
+	negotiation.Preferred = negotiation.Served[0]
+
+	byName := versionsByName(versions)
+	for _, name := range negotiation.Served {
+		supersededBy := supersedingVersion(name, negotiation.Served)
+		switch {
+		case byName[name].Deprecated:
+			negotiation.Deprecated = append(negotiation.Deprecated, DeprecatedVersion{
+				Name:         name,
+				SupersededBy: supersededBy,
+				Reason:       "marked deprecated",
+			})
+		case supersededBy != "":
+			negotiation.Deprecated = append(negotiation.Deprecated, DeprecatedVersion{
+				Name:         name,
+				SupersededBy: supersededBy,
+				Reason:       fmt.Sprintf("superseded by %v", supersededBy),
+			})
+		}
+	}
+	return negotiation, nil
+}
+
+// Returns the highest priority served version with the same major version as the given one, if it has a
+// higher priority; e.g. v1 supersedes v1beta1, and v1beta2 supersedes v1alpha1, but v2 does not supersede v1.
+func supersedingVersion(name string, served []string) string {
+	version, err := ParseVersion(name)
+	if err != nil {
+		return ""
+	}
+	for _, other := range served {
+		otherVersion, err := ParseVersion(other)
+		if err != nil || otherVersion.Major() != version.Major() {
+			continue
+		}
+		if ComparePriority(other, name) > 0 {
+			return other
+		}
+		return ""
+	}
+	return ""
+}
+
+func validateVersions(versions []CRDVersion) error {
+	if len(versions) == 0 {
+		return NoVersionsError
+	}
+	seen := map[string]bool{}
+	var storage []string
+	for _, v := range versions {
+		if seen[v.Name] {
+			return DuplicateVersionError(v.Name)
+		}
+		seen[v.Name] = true
+		if v.Storage {
+			storage = append(storage, v.Name)
+		}
+	}
+	if len(storage) != 1 {
+		return StorageVersionCountError(storage)
+	}
+	return nil
+}
+
+func versionsByName(versions []CRDVersion) map[string]CRDVersion {
+	byName := make(map[string]CRDVersion, len(versions))
+	for _, v := range versions {
+		byName[v.Name] = v
+	}
+	return byName
+}
+
+type HazardKind int
+
+const (
+	// A version that was served is no longer served, so clients that use it break
+	ServedVersionRemoved HazardKind = iota + 1
+	// A version that objects may be stored as is no longer declared, so the API server can't read them
+	StoredVersionRemoved
+	// Objects are written as a different version; existing objects keep their old version until they are migrated
+	StorageVersionChanged
+	// Objects are written as a version with a lower priority than before
+	StorageVersionDowngraded
+	// Clients that rely on discovery, like kubectl, switch to a different version
+	PreferredVersionChanged
+)
+
+func (k HazardKind) String() string {
+	switch k {
+	case ServedVersionRemoved:
+		return "served version removed"
+	case StoredVersionRemoved:
+		return "stored version removed"
+	case StorageVersionChanged:
+		return "storage version changed"
+	case StorageVersionDowngraded:
+		return "storage version downgraded"
+	case PreferredVersionChanged:
+		return "preferred version changed"
+	default:
+		return ""
+	}
+}
+
+// UpgradeHazard is a consequence of changing the versions of a CRD that is already installed.
+type UpgradeHazard struct {
+	Kind HazardKind
+	// The version the hazard is about
+	Version string
+	// True if the change breaks existing clients or objects, rather than requiring care
+	Breaking bool
+	Message  string
+}
+
+func (h UpgradeHazard) String() string {
+	return fmt.Sprintf("%v %v: %v", h.Kind, h.Version, h.Message)
+}
+
+// CheckVersionChange reports the hazards of changing a CRD's versions from current to proposed. storedVersions
+// are the versions that objects may be persisted as, i.e. the CRD's status.storedVersions; the current storage
+// version is always assumed to be one of them. Returns an error if either set of versions is invalid.
+func CheckVersionChange(current, proposed []CRDVersion, storedVersions []string) ([]UpgradeHazard, error) {
+	currentNegotiation, err := Negotiate(current)
+	if err != nil {
+		return nil, eris.Wrap(err, "invalid current versions")
+	}
+	proposedNegotiation, err := Negotiate(proposed)
+	if err != nil {
+		return nil, eris.Wrap(err, "invalid proposed versions")
+	}
+	currentByName := versionsByName(current)
+	proposedByName := versionsByName(proposed)
+	var hazards []UpgradeHazard
+
+	for _, name := range currentNegotiation.Served {
+		if proposedByName[name].Served {
+			continue
+		}
+		if currentByName[name].Deprecated {
+			hazards = append(hazards, UpgradeHazard{
+				Kind:    ServedVersionRemoved,
+				Version: name,
+				Message: "deprecated version is no longer served; clients that still use it will fail",
+			})
+			continue
+		}
+		hazards = append(hazards, UpgradeHazard{
+			Kind:     ServedVersionRemoved,
+			Version:  name,
+			Breaking: true,
+			Message:  "version is no longer served without having been deprecated first",
+		})
+	}
+
+	stored := append([]string{currentNegotiation.Storage}, storedVersions...)
+	SortByPriority(stored)
+	for i, name := range stored {
+		if i > 0 && name == stored[i-1] {
+			continue
+		}
+		if _, ok := proposedByName[name]; ok {
+			continue
+		}
+		hazards = append(hazards, UpgradeHazard{
+			Kind:     StoredVersionRemoved,
+			Version:  name,
+			Breaking: true,
+			Message:  "objects may be stored as this version; migrate them and remove it from status.storedVersions before removing it",
+		})
+	}
+
+	if proposedNegotiation.Storage != currentNegotiation.Storage {
+		hazards = append(hazards, UpgradeHazard{
+			Kind:    StorageVersionChanged,
+			Version: proposedNegotiation.Storage,
+			Message: fmt.Sprintf("existing objects remain stored as %v until they are migrated", currentNegotiation.Storage),
+		})
+		if ComparePriority(proposedNegotiation.Storage, currentNegotiation.Storage) < 0 {
+			hazards = append(hazards, UpgradeHazard{
+				Kind:    StorageVersionDowngraded,
+				Version: proposedNegotiation.Storage,
+				Message: fmt.Sprintf("has a lower priority than the previous storage version %v", currentNegotiation.Storage),
+			})
+		}
+	}
+
+	if proposedNegotiation.Preferred != currentNegotiation.Preferred {
+		hazards = append(hazards, UpgradeHazard{
+			Kind:    PreferredVersionChanged,
+			Version: proposedNegotiation.Preferred,
+			Message: fmt.Sprintf("clients that use the preferred version switch from %v", currentNegotiation.Preferred),
+		})
+	}
+	return hazards, nil
+}
+
+// ValidateVersionChange is like CheckVersionChange, but returns an error if the change has any breaking hazards,
+// so that CRD generators can fail before an unsafe change is released.
+func ValidateVersionChange(current, proposed []CRDVersion, storedVersions []string) error {
+	hazards, err := CheckVersionChange(current, proposed, storedVersions)
+	if err != nil {
+		return err
+	}
+	var breaking []UpgradeHazard
+	for _, hazard := range hazards {
+		if hazard.Breaking {
+			breaking = append(breaking, hazard)
+		}
+	}
+	if len(breaking) > 0 {
+		return UpgradeHazardsError(breaking)
+	}
+	return nil
+}
@@ -0,0 +1,148 @@
+package kubeapi_test
+
+import (
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+	"github.com/solo-io/go-utils/versionutils/kubeapi"
+)
+
+var _ = Describe("CRD versions", func() {
+	Describe("SortByPriority", func() {
+		It("follows the kubernetes version priority", func() {
+			// the example from the kubernetes docs
+			names := []string{"foo1", "v11alpha2", "v1", "v3beta1", "foo10", "v10beta3", "v12alpha1", "v2", "v11beta2", "v10"}
+			kubeapi.SortByPriority(names)
+			Expect(names).To(Equal([]string{"v10", "v2", "v1", "v11beta2", "v10beta3", "v3beta1", "v12alpha1", "v11alpha2", "foo1", "foo10"}))
+		})
+	})
+
+	Describe("Negotiate", func() {
+		It("computes the preferred, storage and deprecated versions", func() {
+			negotiation, err := kubeapi.Negotiate([]kubeapi.CRDVersion{
+				{Name: "v1alpha1", Served: true, Deprecated: true},
+				{Name: "v1beta1", Served: true, Storage: true},
+				{Name: "v1", Served: true},
+				{Name: "v2alpha1", Served: true},
+				{Name: "v2alpha2"},
+			})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(negotiation).To(Equal(&kubeapi.VersionNegotiation{
+				Preferred: "v1",
+				Storage:   "v1beta1",
+				Served:    []string{"v1", "v1beta1", "v2alpha1", "v1alpha1"},
+				Deprecated: []kubeapi.DeprecatedVersion{
+					{Name: "v1beta1", SupersededBy: "v1", Reason: "superseded by v1"},
+					{Name: "v1alpha1", SupersededBy: "v1", Reason: "marked deprecated"},
+				},
+			}))
+		})
+
+		DescribeTable("it errors on invalid versions", func(versions []kubeapi.CRDVersion, expectedErr error) {
+			_, err := kubeapi.Negotiate(versions)
+			Expect(err).To(MatchError(expectedErr.Error()))
+		},
+			Entry("no versions", nil, kubeapi.NoVersionsError),
+			Entry("duplicate versions", []kubeapi.CRDVersion{{Name: "v1", Served: true, Storage: true}, {Name: "v1"}}, kubeapi.DuplicateVersionError("v1")),
+			Entry("no storage version", []kubeapi.CRDVersion{{Name: "v1", Served: true}}, kubeapi.StorageVersionCountError(nil)),
+			Entry("two storage versions", []kubeapi.CRDVersion{{Name: "v1", Storage: true}, {Name: "v2", Storage: true}}, kubeapi.StorageVersionCountError([]string{"v1", "v2"})),
+			Entry("no served version", []kubeapi.CRDVersion{{Name: "v1", Storage: true}}, kubeapi.NoServedVersionError),
+		)
+	})
+
+	Describe("CheckVersionChange", func() {
+		var current []kubeapi.CRDVersion
+
+		BeforeEach(func() {
+			current = []kubeapi.CRDVersion{
+				{Name: "v1alpha1", Served: true, Deprecated: true},
+				{Name: "v1beta1", Served: true, Storage: true},
+			}
+		})
+
+		It("reports no hazards for adding a version", func() {
+			hazards, err := kubeapi.CheckVersionChange(current, append(current, kubeapi.CRDVersion{Name: "v2alpha1", Served: true}), nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hazards).To(BeEmpty())
+		})
+
+		It("warns about promoting a new storage version", func() {
+			proposed := []kubeapi.CRDVersion{
+				{Name: "v1alpha1", Served: true, Deprecated: true},
+				{Name: "v1beta1", Served: true},
+				{Name: "v1", Served: true, Storage: true},
+			}
+			hazards, err := kubeapi.CheckVersionChange(current, proposed, []string{"v1beta1"})
+			Expect(err).NotTo(HaveOccurred())
+			var kinds []kubeapi.HazardKind
+			for _, hazard := range hazards {
+				Expect(hazard.Breaking).To(BeFalse())
+				kinds = append(kinds, hazard.Kind)
+			}
+			Expect(kinds).To(Equal([]kubeapi.HazardKind{kubeapi.StorageVersionChanged, kubeapi.PreferredVersionChanged}))
+			Expect(kubeapi.ValidateVersionChange(current, proposed, []string{"v1beta1"})).To(Succeed())
+		})
+
+		It("reports the breaking hazards of removing versions", func() {
+			proposed := []kubeapi.CRDVersion{
+				{Name: "v1alpha1", Served: true, Storage: true},
+			}
+			hazards, err := kubeapi.CheckVersionChange(current, proposed, []string{"v1beta1", "v1beta2"})
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hazards).To(Equal([]kubeapi.UpgradeHazard{
+				{
+					Kind:     kubeapi.ServedVersionRemoved,
+					Version:  "v1beta1",
+					Breaking: true,
+					Message:  "version is no longer served without having been deprecated first",
+				},
+				{
+					Kind:     kubeapi.StoredVersionRemoved,
+					Version:  "v1beta2",
+					Breaking: true,
+					Message:  "objects may be stored as this version; migrate them and remove it from status.storedVersions before removing it",
+				},
+				{
+					Kind:     kubeapi.StoredVersionRemoved,
+					Version:  "v1beta1",
+					Breaking: true,
+					Message:  "objects may be stored as this version; migrate them and remove it from status.storedVersions before removing it",
+				},
+				{
+					Kind:    kubeapi.StorageVersionChanged,
+					Version: "v1alpha1",
+					Message: "existing objects remain stored as v1beta1 until they are migrated",
+				},
+				{
+					Kind:    kubeapi.StorageVersionDowngraded,
+					Version: "v1alpha1",
+					Message: "has a lower priority than the previous storage version v1beta1",
+				},
+				{
+					Kind:    kubeapi.PreferredVersionChanged,
+					Version: "v1alpha1",
+					Message: "clients that use the preferred version switch from v1beta1",
+				},
+			}))
+
+			err = kubeapi.ValidateVersionChange(current, proposed, []string{"v1beta1", "v1beta2"})
+			Expect(err).To(MatchError(kubeapi.UpgradeHazardsError(hazards[:3]).Error()))
+		})
+
+		It("only warns about no longer serving a deprecated version", func() {
+			proposed := []kubeapi.CRDVersion{
+				{Name: "v1alpha1", Deprecated: true},
+				{Name: "v1beta1", Served: true, Storage: true},
+			}
+			hazards, err := kubeapi.CheckVersionChange(current, proposed, nil)
+			Expect(err).NotTo(HaveOccurred())
+			Expect(hazards).To(HaveLen(1))
+			Expect(hazards[0].Kind).To(Equal(kubeapi.ServedVersionRemoved))
+			Expect(hazards[0].Breaking).To(BeFalse())
+		})
+
+		It("errors on invalid proposed versions", func() {
+			_, err := kubeapi.CheckVersionChange(current, []kubeapi.CRDVersion{{Name: "v1", Served: true}}, nil)
+			Expect(err).To(MatchError(ContainSubstring("invalid proposed versions")))
+		})
+	})
+})
@@ -105,6 +105,7 @@ func GetTomlVersion(pkgName string, toml *TomlWrapper) (string, error) {
 }
 
 // Returns the version of the given package together with the type of version identifier, i.e. revision, version, branch.
+// Deprecated: Gopkg.toml is only used by dep; for go modules use gomod.GoMod.DependencyVersionInfo
 func GetDependencyVersionInfo(pkgName string, toml *TomlWrapper) (*dep.VersionInfo, error) {
 	for _, v := range toml.Overrides {
 		if version, found := getVersionInfoFromTree(v, pkgName); found {
@@ -160,6 +161,7 @@ type TomlWrapper struct {
 	Constraints []*toml.Tree
 }
 
+// Deprecated: Gopkg.toml is only used by dep; for go modules use gomod.ReadGoMod
 func ParseFullTomlFromDir(relativeDir string) (*TomlWrapper, error) {
 	overrides, err := ParseTomlOverridesFromDir(relativeDir)
 	if err != nil {
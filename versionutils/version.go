@@ -7,7 +7,6 @@ import (
 	"strconv"
 	"strings"
 
-	"github.com/pkg/errors"
 	"github.com/rotisserie/eris"
 )
 
@@ -20,22 +19,42 @@ const (
 
 var (
 	InvalidSemverVersionError = func(tag string) error {
-		return eris.Errorf("Tag %s is not a valid semver version, must be of the form vX.Y.Z[-<label>#]", tag)
+		return eris.Errorf("Tag %s is not a valid semver version, must be of the form vX.Y.Z[-<prerelease>][+<build>]", tag)
 	}
+
+	// https://semver.org/#is-there-a-suggested-regular-expression-regex-to-check-a-semver-string, with a "v" prefix
+	semverRegex = regexp.MustCompile(`^v(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)\.(0|[1-9][0-9]*)` +
+		`(?:-((?:0|[1-9][0-9]*|[0-9]*[a-zA-Z-][0-9a-zA-Z-]*)(?:\.(?:0|[1-9][0-9]*|[0-9]*[a-zA-Z-][0-9a-zA-Z-]*))*))?` +
+		`(?:\+([0-9a-zA-Z-]+(?:\.[0-9a-zA-Z-]+)*))?$`)
+	numericIdentifierRegex = regexp.MustCompile(`^[0-9]+$`)
+	labelVersionRegex      = regexp.MustCompile(`[0-9]+`)
 )
 
+// Versions have two orderings. IsGreaterThan, IsGreaterThanWithLabelOrder and the functions built on them are the
+// canonical ordering of this package, and the one the changelog and release tooling uses: prereleases with the same
+// label are ordered by label version (1.0.0-rc10 > 1.0.0-rc2), and prereleases with different labels can only be
+// ordered by a label order. Compare is the precedence defined by the semver spec, for interoperability with other
+// semver tools. Both agree on which versions are equal: those that only differ by build metadata.
 type Version struct {
 	Major int
 	Minor int
 	Patch int
 
 	// optional to support a version like "1.0.0-rc1", where "rc" is the label and "1" is the label version
-	// for comparisons:
+	// for comparisons with IsGreaterThan:
 	//  - "1.0.0-rc1" is greater than "0.X.Y" and less than "1.0.0"
 	//  - "1.0.0-rc5" is greater than "1.0.0-rc1"
 	//  - "1.0.0-aX" is not greater than or less than "1.0.0-bY", except by convention
 	Label        string
 	LabelVersion int
+
+	// The full prerelease, e.g. "rc.1.2" in "1.0.0-rc.1.2", set only when Label and LabelVersion can't represent it.
+	// Label and LabelVersion are then a compatibility view of it ("rc" and 1), and LabelVersion is 0 if the label
+	// version doesn't fit in an int. Use Prerelease() to get the prerelease of any version, and IsPrerelease() to
+	// check for one: Label is empty for prereleases that start with a number, e.g. "0.3.7" in "1.0.0-0.3.7".
+	ExtendedPrerelease string
+	// Build metadata, e.g. "sha.5114f85" in "1.0.0+sha.5114f85". It is ignored when comparing versions.
+	Build string
 }
 
 func NewVersion(major, minor, patch int, label string, labelVersion int) *Version {
@@ -49,10 +68,67 @@ func NewVersion(major, minor, patch int, label string, labelVersion int) *Versio
 }
 
 func (v *Version) String() string {
-	if v.LabelVersion == 0 {
-		return fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+	version := fmt.Sprintf("v%d.%d.%d", v.Major, v.Minor, v.Patch)
+	if prerelease := v.Prerelease(); prerelease != "" {
+		version += "-" + prerelease
+	}
+	if v.Build != "" {
+		version += "+" + v.Build
+	}
+	return version
+}
+
+// Returns the prerelease of the version, e.g. "rc1" or "rc.1.2", or "" if it is not a prerelease
+func (v Version) Prerelease() string {
+	if v.ExtendedPrerelease != "" {
+		return v.ExtendedPrerelease
+	}
+	return legacyPrerelease(v.Label, v.LabelVersion)
+}
+
+// Returns the dot separated identifiers of the prerelease, e.g. ["rc", "1", "2"] for "rc.1.2"
+func (v Version) PrereleaseIdentifiers() []string {
+	prerelease := v.Prerelease()
+	if prerelease == "" {
+		return nil
 	}
-	return fmt.Sprintf("v%d.%d.%d-%s%d", v.Major, v.Minor, v.Patch, v.Label, v.LabelVersion)
+	return strings.Split(prerelease, ".")
+}
+
+func (v Version) IsPrerelease() bool {
+	return v.Prerelease() != ""
+}
+
+func legacyPrerelease(label string, labelVersion int) string {
+	if labelVersion == 0 {
+		return label
+	}
+	return label + strconv.Itoa(labelVersion)
+}
+
+// Sets the prerelease, and the Label and LabelVersion compatibility view of it
+func (v *Version) setPrerelease(prerelease string) {
+	v.Label, v.LabelVersion, v.ExtendedPrerelease = "", 0, ""
+	if prerelease == "" {
+		return
+	}
+	label, labelVersion := parseLabelVersion(prerelease)
+	v.Label = strings.ReplaceAll(label, ".", "")
+	v.LabelVersion = labelVersion
+	if legacyPrerelease(v.Label, v.LabelVersion) != prerelease {
+		v.ExtendedPrerelease = prerelease
+	}
+}
+
+// Returns the digits of the label version, which may not fit in LabelVersion
+func (v Version) labelVersionDigits() string {
+	if v.ExtendedPrerelease == "" {
+		return strconv.Itoa(v.LabelVersion)
+	}
+	if digits := labelVersionRegex.FindString(v.ExtendedPrerelease); digits != "" {
+		return digits
+	}
+	return "0"
 }
 
 func (v *Version) MarshalJSON() ([]byte, error) {
@@ -77,7 +153,7 @@ func (v *Version) IsGreaterThanOrEqualToPtr(lesser *Version) (bool, bool, error)
 // isDeterminable is for incomparable versions because they have different labels, e.g. 1.0.0-foo1 vs 1.0.0-bar2
 // If you want to tiebreak indeterminate comparisons using alphanumeric ordering, try MustIsGreaterThanOrEqualTo
 func (v Version) IsGreaterThanOrEqualTo(lesser Version) (bool, bool) {
-	if v.Equals(&lesser) {
+	if v.Compare(lesser) == 0 {
 		return true, false
 	}
 	return v.IsGreaterThan(lesser)
@@ -100,6 +176,7 @@ func (v *Version) IsGreaterThanPtr(lesser *Version) (bool, bool, error) {
 // In order, returns isGreaterThanOrEqualTo, isDeterminable
 // isDeterminable is for incomparable versions because they have different labels, e.g. 1.0.0-foo1 vs 1.0.0-bar2
 // If you want to tiebreak indeterminate comparisons using alphanumeric ordering, try MustIsGreaterThan
+// This is the canonical ordering of versions, see Version.
 func (v Version) IsGreaterThan(lesser Version) (bool, bool) {
 	if v.Major > lesser.Major {
 		return true, true
@@ -119,9 +196,9 @@ func (v Version) IsGreaterThan(lesser Version) (bool, bool) {
 		return false, true
 	}
 
-	if len(v.Label) == 0 && lesser.Label != "" {
+	if !v.IsPrerelease() && lesser.IsPrerelease() {
 		return true, true
-	} else if len(v.Label) > 0 && len(lesser.Label) == 0 {
+	} else if v.IsPrerelease() && !lesser.IsPrerelease() {
 		return false, true
 	}
 
@@ -129,13 +206,91 @@ func (v Version) IsGreaterThan(lesser Version) (bool, bool) {
 		return false, false
 	}
 
-	if v.LabelVersion > lesser.LabelVersion {
-		return true, true
-	} else if v.LabelVersion < lesser.LabelVersion {
-		return false, true
+	if result := compareNumericIdentifiers(v.labelVersionDigits(), lesser.labelVersionDigits()); result != 0 {
+		return result > 0, true
+	}
+
+	// same label and label version, e.g. 1.0.0-rc.1.2 and 1.0.0-rc.1.10
+	return comparePrereleases(v.PrereleaseIdentifiers(), lesser.PrereleaseIdentifiers()) > 0, true
+}
+
+// Returns -1, 0 or 1 if the version has a lower, equal or higher precedence than the other version, as defined by
+// the semver spec (https://semver.org/#spec-item-11). Build metadata is ignored.
+// Unlike IsGreaterThan, the canonical ordering of versions, all versions are comparable, and prereleases are
+// compared by their identifiers rather than by label version: 1.0.0-beta1 < 1.0.0-rc1, and 1.0.0-rc10 < 1.0.0-rc2.
+func (v Version) Compare(other Version) int {
+	for _, parts := range [][2]int{{v.Major, other.Major}, {v.Minor, other.Minor}, {v.Patch, other.Patch}} {
+		if parts[0] != parts[1] {
+			return compareInts(parts[0], parts[1])
+		}
 	}
+	if !v.IsPrerelease() || !other.IsPrerelease() {
+		// a release has a higher precedence than its prereleases
+		return compareInts(len(other.Prerelease()), len(v.Prerelease()))
+	}
+	return comparePrereleases(v.PrereleaseIdentifiers(), other.PrereleaseIdentifiers())
+}
 
-	return false, true
+func comparePrereleases(identifiers, other []string) int {
+	for i := 0; i < len(identifiers) && i < len(other); i++ {
+		if result := compareIdentifiers(identifiers[i], other[i]); result != 0 {
+			return result
+		}
+	}
+	// a larger set of identifiers has a higher precedence if the preceding ones are equal
+	return compareInts(len(identifiers), len(other))
+}
+
+func compareIdentifiers(identifier, other string) int {
+	isNumeric, otherIsNumeric := numericIdentifierRegex.MatchString(identifier), numericIdentifierRegex.MatchString(other)
+	switch {
+	case isNumeric && otherIsNumeric:
+		return compareNumericIdentifiers(identifier, other)
+	case isNumeric:
+		// numeric identifiers have a lower precedence than alphanumeric ones
+		return -1
+	case otherIsNumeric:
+		return 1
+	}
+	return strings.Compare(identifier, other)
+}
+
+// Compares numbers of any length by their digits
+func compareNumericIdentifiers(number, other string) int {
+	number, other = trimLeadingZeros(number), trimLeadingZeros(other)
+	if len(number) != len(other) {
+		return compareInts(len(number), len(other))
+	}
+	return strings.Compare(number, other)
+}
+
+func trimLeadingZeros(number string) string {
+	if trimmed := strings.TrimLeft(number, "0"); trimmed != "" {
+		return trimmed
+	}
+	return "0"
+}
+
+// Adds one to a number of any length
+func incrementNumericIdentifier(number string) string {
+	digits := []byte(trimLeadingZeros(number))
+	for i := len(digits) - 1; i >= 0; i-- {
+		if digits[i] != '9' {
+			digits[i]++
+			return string(digits)
+		}
+		digits[i] = '0'
+	}
+	return "1" + string(digits)
+}
+
+func compareInts(a, b int) int {
+	if a < b {
+		return -1
+	} else if a > b {
+		return 1
+	}
+	return 0
 }
 
 // In order, returns isGreaterThanOrEqualTo, isDeterminable
@@ -168,7 +323,7 @@ func (v Version) IsGreaterThanWithLabelOrder(lesser Version, labelOrder []string
 // for incomparable versions, default to alphanumeric sort on label
 // e.g. 1.0.0-foo1 > 1.0.0-bar2
 func (v Version) MustIsGreaterThanOrEqualTo(lesser Version) bool {
-	if v.Equals(&lesser) {
+	if v.Compare(lesser) == 0 {
 		return true
 	}
 	return v.MustIsGreaterThan(lesser)
@@ -185,11 +340,16 @@ func (v Version) MustIsGreaterThan(lesser Version) bool {
 	return v.Label > lesser.Label
 }
 
+// Returns true if the versions are identical, including their build metadata. Versions that only differ by build
+// metadata are neither greater nor less than each other, and Compare returns 0 for them.
 func (v *Version) Equals(other *Version) bool {
 	return *v == *other
 }
 
 func (v *Version) IncrementVersion(breakingChange, newFeature bool) *Version {
+	if v.ExtendedPrerelease != "" {
+		return v.incrementExtendedPrerelease(breakingChange, newFeature)
+	}
 	newMajor := v.Major
 	newMinor := v.Minor
 	newPatch := v.Patch
@@ -224,6 +384,24 @@ func (v *Version) IncrementVersion(breakingChange, newFeature bool) *Version {
 	}
 }
 
+// Increments the last numeric identifier of the prerelease, e.g. 1.0.0-rc.1.2 to 1.0.0-rc.1.3. If there is none, the
+// version is incremented like one without a label version, keeping the prerelease.
+func (v *Version) incrementExtendedPrerelease(breakingChange, newFeature bool) *Version {
+	identifiers := v.PrereleaseIdentifiers()
+	for i := len(identifiers) - 1; i >= 0; i-- {
+		if !numericIdentifierRegex.MatchString(identifiers[i]) {
+			continue
+		}
+		identifiers[i] = incrementNumericIdentifier(identifiers[i])
+		incremented := &Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch}
+		incremented.setPrerelease(strings.Join(identifiers, "."))
+		return incremented
+	}
+	incremented := (&Version{Major: v.Major, Minor: v.Minor, Patch: v.Patch}).IncrementVersion(breakingChange, newFeature)
+	incremented.Label, incremented.LabelVersion, incremented.ExtendedPrerelease = v.Label, v.LabelVersion, v.ExtendedPrerelease
+	return incremented
+}
+
 func Zero() Version {
 	return Version{
 		Major: 0,
@@ -265,75 +443,49 @@ func IsGreaterThanTagWithLabelOrder(greaterTag, lesserTag string, labelOrder []s
 	return isGreaterThan, determinable, nil
 }
 
+// Parses a semver 2.0 version with a "v" prefix, e.g. v1.2.3, v1.2.3-rc1, v1.2.3-rc.1.2 or v1.2.3+sha.5114f85
 func ParseVersion(tag string) (*Version, error) {
-	if !MatchesRegex(tag) {
+	matches := semverRegex.FindStringSubmatch(tag)
+	if matches == nil {
 		return nil, InvalidSemverVersionError(tag)
 	}
-
-	versionString := tag[1:]
-	splitOnHyphen := strings.SplitN(versionString, "-", 2)
-	labelAndVersion := ""
-	if len(splitOnHyphen) > 1 {
-		labelAndVersion = splitOnHyphen[1]
-		versionString = splitOnHyphen[0]
-	}
-	versionParts := strings.Split(versionString, ".")
-	if len(versionParts) != 3 {
-		return nil, eris.Errorf("Version %s is not a valid semver version", versionString)
-	}
-	major, err := strconv.Atoi(versionParts[0])
+	major, err := strconv.Atoi(matches[1])
 	if err != nil {
-		return nil, eris.Errorf("Major version %s is not valid", versionParts[0])
+		return nil, eris.Errorf("Major version %s is not valid", matches[1])
 	}
-	minor, err := strconv.Atoi(versionParts[1])
+	minor, err := strconv.Atoi(matches[2])
 	if err != nil {
-		return nil, eris.Errorf("Minor version %s is not valid", versionParts[1])
+		return nil, eris.Errorf("Minor version %s is not valid", matches[2])
 	}
-	patch, err := strconv.Atoi(versionParts[2])
+	patch, err := strconv.Atoi(matches[3])
 	if err != nil {
-		return nil, eris.Errorf("Patch version %s is not valid", versionParts[2])
+		return nil, eris.Errorf("Patch version %s is not valid", matches[3])
 	}
 
 	version := &Version{
 		Major: major,
 		Minor: minor,
 		Patch: patch,
+		Build: matches[5],
 	}
-
-	if labelAndVersion != "" {
-		label, labelVersion, err := parseLabelVersion(labelAndVersion)
-		if err != nil {
-			return nil, err
-		}
-		version.Label = label
-		version.LabelVersion = labelVersion
-	}
-
-	isGtEq, _ := version.IsGreaterThanOrEqualTo(Zero())
-	if !isGtEq {
-		return nil, eris.Errorf("Version %s is not greater than or equal to v0.0.0", tag)
-	}
+	version.setPrerelease(matches[4])
 	return version, nil
 }
 
-func parseLabelVersion(labelAndVersion string) (string, int, error) {
-	numsRegex := regexp.MustCompile("[0-9]+")
-	numberGroups := numsRegex.FindAllString(labelAndVersion, -1)
-	if len(numberGroups) == 0 {
-		// valid label with no version, eg "wasm"
-		return labelAndVersion, 0, nil
-	}
-
-	label := numsRegex.ReplaceAllString(labelAndVersion, "")
-	labelVersionToParse := numberGroups[0]
-	labelVersion, err := strconv.Atoi(labelVersionToParse)
+// Splits a prerelease into a label and the first number in it, e.g. "rc" and 1 for "rc1". The label version is 0
+// if there is no number, or if it doesn't fit in an int.
+func parseLabelVersion(labelAndVersion string) (string, int) {
+	label := labelVersionRegex.ReplaceAllString(labelAndVersion, "")
+	labelVersion, err := strconv.Atoi(labelVersionRegex.FindString(labelAndVersion))
 	if err != nil {
-		return "", 0, errors.Wrapf(err, "invalid label version %s", labelVersionToParse)
+		// valid label with no version, eg "wasm"
+		return label, 0
 	}
-
-	return label, labelVersion, nil
+	return label, labelVersion
 }
 
+// Returns true if the tag follows the stricter convention for our own release tags: vX.Y.Z with an optional
+// hyphenated lowercase label and label version, e.g. v1.2.3-wasm-rc1. ParseVersion accepts any semver version.
 func MatchesRegex(tag string) bool {
 	regex := regexp.MustCompile("(v[0-9]+[.][0-9]+[.][0-9]+(-[a-z]+)*(-[a-z]+[0-9]*)?$)")
 	return regex.MatchString(tag)
@@ -231,4 +231,128 @@ var _ = Describe("Version", func() {
 		})
 	})
 
+	Context("semver 2.0", func() {
+
+		It("parses prerelease identifiers and build metadata", func() {
+			parsed, err := versionutils.ParseVersion("v1.18.0-rc.1.2+sha.5114f85")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parsed.Prerelease()).To(Equal("rc.1.2"))
+			Expect(parsed.PrereleaseIdentifiers()).To(Equal([]string{"rc", "1", "2"}))
+			Expect(parsed.Build).To(Equal("sha.5114f85"))
+			Expect(parsed.Label).To(Equal("rc"))
+			Expect(parsed.LabelVersion).To(Equal(1))
+			Expect(parsed.String()).To(Equal("v1.18.0-rc.1.2+sha.5114f85"))
+
+			for _, tag := range []string{"v1.2.3-rc-1", "v1.2.3-1", "v1.2.3+rc1", "v1.2.3-beta1-wasm", "v1.2.3-0.3.7", "v1.2.3-x.7.z.92", "v1.2.3-DEV"} {
+				parsed, err = versionutils.ParseVersion(tag)
+				Expect(err).NotTo(HaveOccurred(), tag)
+				Expect(parsed.String()).To(Equal(tag))
+			}
+		})
+
+		It("keeps versions that Label and LabelVersion represent equal to NewVersion", func() {
+			parsed, err := versionutils.ParseVersion("v1.2.3-wasm-rc1")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(*parsed).To(Equal(*versionutils.NewVersion(1, 2, 3, "wasm-rc", 1)))
+			Expect(parsed.String()).To(Equal("v1.2.3-wasm-rc1"))
+
+			parsed, err = versionutils.ParseVersion("v1.2.3-wasm")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parsed.String()).To(Equal("v1.2.3-wasm"))
+		})
+
+		It("rejects invalid versions", func() {
+			for _, tag := range []string{"v01.2.3", "v1.2.3-01", "v1.2.3-", "v1.2.3+", "v1.2.3-rc..1", "v1.2.3+b_1", "1.2.3"} {
+				_, err := versionutils.ParseVersion(tag)
+				Expect(err).To(MatchError(versionutils.InvalidSemverVersionError(tag).Error()), tag)
+			}
+		})
+
+		DescribeTable("Compare follows the semver precedence",
+			func(lesser, greater string) {
+				lesserVersion, err := versionutils.ParseVersion(lesser)
+				Expect(err).NotTo(HaveOccurred())
+				greaterVersion, err := versionutils.ParseVersion(greater)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(lesserVersion.Compare(*greaterVersion)).To(Equal(-1))
+				Expect(greaterVersion.Compare(*lesserVersion)).To(Equal(1))
+			},
+			Entry("1.0.0-alpha < 1.0.0-alpha.1", "v1.0.0-alpha", "v1.0.0-alpha.1"),
+			Entry("1.0.0-alpha.1 < 1.0.0-alpha.beta", "v1.0.0-alpha.1", "v1.0.0-alpha.beta"),
+			Entry("1.0.0-alpha.beta < 1.0.0-beta", "v1.0.0-alpha.beta", "v1.0.0-beta"),
+			Entry("1.0.0-beta < 1.0.0-beta.2", "v1.0.0-beta", "v1.0.0-beta.2"),
+			Entry("1.0.0-beta.2 < 1.0.0-beta.11", "v1.0.0-beta.2", "v1.0.0-beta.11"),
+			Entry("1.0.0-beta.11 < 1.0.0-rc.1", "v1.0.0-beta.11", "v1.0.0-rc.1"),
+			Entry("1.0.0-rc.1 < 1.0.0", "v1.0.0-rc.1", "v1.0.0"),
+			Entry("1.0.0 < 1.0.1-rc1", "v1.0.0", "v1.0.1-rc1"),
+			Entry("1.0.0-rc10 < 1.0.0-rc2", "v1.0.0-rc10", "v1.0.0-rc2"),
+			Entry("numeric identifiers longer than an int", "v1.0.0-rc.99999999999999999999", "v1.0.0-rc.100000000000000000000"),
+		)
+
+		It("ignores build metadata when comparing, but not in Equals", func() {
+			a, err := versionutils.ParseVersion("v1.0.0+a")
+			Expect(err).NotTo(HaveOccurred())
+			b, err := versionutils.ParseVersion("v1.0.0+b")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(a.Compare(*b)).To(Equal(0))
+			Expect(a.Equals(b)).To(BeFalse())
+			Expect(a.Equals(&versionutils.Version{Major: 1, Build: "a"})).To(BeTrue())
+			isGreaterOrEqual, _ := a.IsGreaterThanOrEqualTo(*b)
+			Expect(isGreaterOrEqual).To(BeTrue())
+			Expect(a.MustIsGreaterThanOrEqualTo(*b)).To(BeTrue())
+			Expect(a.MustIsGreaterThan(*b)).To(BeFalse())
+		})
+
+		It("treats prereleases that start with a number as prereleases", func() {
+			for _, tag := range []string{"v1.2.3-1", "v1.0.0-0.3.7"} {
+				parsed, err := versionutils.ParseVersion(tag)
+				Expect(err).NotTo(HaveOccurred())
+				Expect(parsed.IsPrerelease()).To(BeTrue())
+				Expect(parsed.Equals(versionutils.NewVersion(parsed.Major, parsed.Minor, parsed.Patch, "", 0))).To(BeFalse())
+			}
+		})
+
+		It("compares dotted prereleases with the same label", func() {
+			isGreater, determinable, err := versionutils.IsGreaterThanTag("v1.0.0-rc.1.10", "v1.0.0-rc.1.2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(determinable).To(BeTrue())
+			Expect(isGreater).To(BeTrue())
+
+			isGreater, determinable, err = versionutils.IsGreaterThanTag("v1.0.0-1", "v1.0.0")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(determinable).To(BeTrue())
+			Expect(isGreater).To(BeFalse())
+		})
+
+		It("compares label versions longer than an int by their digits", func() {
+			parsed, err := versionutils.ParseVersion("v1.0.0-rc99999999999999999999")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parsed.Label).To(Equal("rc"))
+			Expect(parsed.String()).To(Equal("v1.0.0-rc99999999999999999999"))
+
+			isGreater, determinable, err := versionutils.IsGreaterThanTag("v1.0.0-rc99999999999999999999", "v1.0.0-rc2")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(determinable).To(BeTrue())
+			Expect(isGreater).To(BeTrue())
+
+			isGreater, determinable, err = versionutils.IsGreaterThanTag("v1.0.0-rc99999999999999999999", "v1.0.0-rc100000000000000000000")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(determinable).To(BeTrue())
+			Expect(isGreater).To(BeFalse())
+
+			parsed, err = versionutils.ParseVersion("v1.0.0-rc.99999999999999999999")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parsed.IncrementVersion(false, false).String()).To(Equal("v1.0.0-rc.100000000000000000000"))
+		})
+
+		It("increments the last numeric prerelease identifier", func() {
+			parsed, err := versionutils.ParseVersion("v1.0.0-rc.1.2+sha.5114f85")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parsed.IncrementVersion(false, false).String()).To(Equal("v1.0.0-rc.1.3"))
+
+			parsed, err = versionutils.ParseVersion("v1.0.0-rc-foo.bar")
+			Expect(err).NotTo(HaveOccurred())
+			Expect(parsed.IncrementVersion(false, true).String()).To(Equal("v1.1.0-rc-foo.bar"))
+		})
+	})
 })
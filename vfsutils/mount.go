@@ -144,13 +144,18 @@ func NewLocalMountedRepoForFs(repoRootPath, owner, repo string) (MountedRepo, er
 	if repoRootPath == "" {
 		return nil, InvalidDefinitionError("must provide a repoRootPath when using a local filesystem")
 	}
-	fs := afero.NewOsFs()
+	return NewMountedRepoForFs(afero.NewOsFs(), repoRootPath, owner, repo), nil
+}
+
+// Creates a mounted repo for code in any filesystem, e.g. an afero.MemMapFs holding the result of a change before
+// it is written to disk.
+func NewMountedRepoForFs(fs afero.Fs, repoRootPath, owner, repo string) MountedRepo {
 	return &localFsRepo{
 		owner:        owner,
 		repo:         repo,
 		fs:           fs,
 		repoRootPath: repoRootPath,
-	}, nil
+	}
 }
 
 func (l *localFsRepo) GetOwner() string {